@@ -100,5 +100,29 @@ type (
 		LastUsedTime int64 `json:"last_used_time"`
 		// Size is the size of this image in bytes
 		Size int64 `json:"size"`
+		// Source is the location this image was fetched from, like an
+		// URL or a docker reference, if it was fetched from remote
+		Source string `json:"source,omitempty"`
+		// Digest is the digest or the ETag the remote announced for
+		// this image, if any
+		Digest string `json:"digest,omitempty"`
+		// Verification is the result of the verification of the
+		// signature of this image, if it is known
+		Verification string `json:"verification,omitempty"`
+	}
+
+	// ImageProvenance describes where an image of the store comes from
+	ImageProvenance struct {
+		// Source is the distribution URI this image was fetched from
+		Source string `json:"source,omitempty"`
+		// FetchTime indicates when this image was fetched in
+		// nanoseconds since the unix epoch
+		FetchTime int64 `json:"fetch_time"`
+		// Digest is the digest or the ETag the remote announced for
+		// this image, if any
+		Digest string `json:"digest,omitempty"`
+		// Verification is the result of the verification of the
+		// signature of this image, if it is known
+		Verification string `json:"verification,omitempty"`
 	}
 )
@@ -55,13 +55,24 @@ type App struct {
 	ReadOnlyRootFS    bool                              // read-only rootfs override.
 	Mounts            []schema.Mount                    // mounts for this app (superseding any mounts in rktApps.mounts of same MountPoint)
 	MemoryLimit       *types.ResourceMemory             // memory isolator override
+	MemorySwap        string                            // swap limit override, in the Kubernetes resource model format
+	MemorySwappiness  *int                              // memory swappiness override
+	Hugepages         []string                          // hugetlb pages granted, in the form PAGESIZE:LIMIT
 	CPULimit          *types.ResourceCPU                // cpu isolator override
 	CPUShares         *types.LinuxCPUShares             // cpu-shares isolator override
+	CPUSetCPUs        string                            // CPUs the app is pinned to, like "0-3,8"
+	CPUSetMems        string                            // NUMA memory nodes the app is pinned to, like "0"
+	CPURTRuntime      *uint64                           // real-time runtime of the app, in microseconds per second
+	CPURTPriority     *int                              // maximum real-time priority of the app
 	User, Group       string                            // user, group overrides
 	SupplementaryGIDs []int                             // supplementary gids override
 	CapsRetain        *types.LinuxCapabilitiesRetainSet // os/linux/capabilities-retain-set overrides
 	CapsRemove        *types.LinuxCapabilitiesRevokeSet // os/linux/capabilities-remove-set overrides
 	SeccompFilter     string                            // seccomp CLI overrides
+	NoNewPrivileges   *bool                             // os/linux/no-new-privileges isolator override
+	MaskedPaths       []string                          // paths made inaccessible to the app
+	ReadOnlyPaths     []string                          // paths made read-only for the app
+	RLimits           []string                          // resource limits override, in the form RESOURCE=SOFT[:HARD]
 	OOMScoreAdj       *types.LinuxOOMScoreAdj           // oom-score-adj isolator override
 	Annotations       map[string]string                 // the annotations of the app
 	UserAnnotations   map[string]string                 // the user annotations of the app.
@@ -0,0 +1,156 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package cgroup
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// ErrNoSwapAccounting is returned when the swap of a cgroup can't be
+// limited or reported because the kernel doesn't account it.
+var ErrNoSwapAccounting = errors.New("the kernel has no swap accounting, boot it with swapaccount=1 to enable it")
+
+// v1 memory limits are rounded down to pages, "unlimited" is the highest
+// multiple of the page size
+const v1MemoryUnlimited = uint64(1) << 62
+
+// MemoryStats are the memory and swap usage and limits of a cgroup, in
+// bytes. The limits are nil when there are none.
+type MemoryStats struct {
+	Usage     uint64  `json:"usage"`
+	Limit     *uint64 `json:"limit,omitempty"`
+	SwapUsage uint64  `json:"swap_usage"`
+	SwapLimit *uint64 `json:"swap_limit,omitempty"`
+}
+
+// GetMemoryStats returns the memory and swap usage and limits of a cgroup,
+// given by its path in the cgroup hierarchies. It returns the memory
+// stats along with ErrNoSwapAccounting when the kernel doesn't account
+// the swap, and an error satisfying os.IsNotExist when there is no such
+// cgroup.
+func GetMemoryStats(cgroup string) (*MemoryStats, error) {
+	unified, err := IsCgroupUnified("/")
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error determining cgroup version"), err)
+	}
+	cgroupPath := filepath.Join("/sys/fs/cgroup/memory", cgroup)
+	if unified {
+		cgroupPath = filepath.Join("/sys/fs/cgroup", cgroup)
+	}
+	if _, err := os.Stat(cgroupPath); err != nil {
+		return nil, err
+	}
+	if unified {
+		return getV2MemoryStats(cgroupPath)
+	}
+	return getV1MemoryStats(cgroupPath)
+}
+
+// The v1 memory controller accounts the memory and the swap together in
+// the memsw knobs.
+func getV1MemoryStats(cgroupPath string) (*MemoryStats, error) {
+	var stats MemoryStats
+	var err error
+	if stats.Usage, err = readUintKnob(cgroupPath, "memory.usage_in_bytes"); err != nil {
+		return nil, err
+	}
+	limit, err := readUintKnob(cgroupPath, "memory.limit_in_bytes")
+	if err != nil {
+		return nil, err
+	}
+	if limit < v1MemoryUnlimited {
+		stats.Limit = &limit
+	}
+
+	if _, err := os.Stat(filepath.Join(cgroupPath, "memory.memsw.usage_in_bytes")); os.IsNotExist(err) {
+		return &stats, ErrNoSwapAccounting
+	}
+	usage, err := readUintKnob(cgroupPath, "memory.memsw.usage_in_bytes")
+	if err != nil {
+		return nil, err
+	}
+	if usage > stats.Usage {
+		stats.SwapUsage = usage - stats.Usage
+	}
+	memswLimit, err := readUintKnob(cgroupPath, "memory.memsw.limit_in_bytes")
+	if err != nil {
+		return nil, err
+	}
+	if memswLimit < v1MemoryUnlimited && stats.Limit != nil {
+		swapLimit := memswLimit - limit
+		stats.SwapLimit = &swapLimit
+	}
+	return &stats, nil
+}
+
+func getV2MemoryStats(cgroupPath string) (*MemoryStats, error) {
+	var stats MemoryStats
+	var err error
+	if stats.Usage, err = readUintKnob(cgroupPath, "memory.current"); err != nil {
+		return nil, err
+	}
+	if stats.Limit, err = readMaxKnob(cgroupPath, "memory.max"); err != nil {
+		return nil, err
+	}
+
+	if _, err := os.Stat(filepath.Join(cgroupPath, "memory.swap.current")); os.IsNotExist(err) {
+		return &stats, ErrNoSwapAccounting
+	}
+	if stats.SwapUsage, err = readUintKnob(cgroupPath, "memory.swap.current"); err != nil {
+		return nil, err
+	}
+	if stats.SwapLimit, err = readMaxKnob(cgroupPath, "memory.swap.max"); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+func readUintKnob(cgroupPath, knob string) (uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(cgroupPath, knob))
+	if err != nil {
+		return 0, errwrap.Wrap(fmt.Errorf("error reading cgroup knob %q", knob), err)
+	}
+	n, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		return 0, errwrap.Wrap(fmt.Errorf("error parsing cgroup knob %q", knob), err)
+	}
+	return n, nil
+}
+
+// readMaxKnob reads a v2 limit knob, where "max" means unlimited.
+func readMaxKnob(cgroupPath, knob string) (*uint64, error) {
+	data, err := ioutil.ReadFile(filepath.Join(cgroupPath, knob))
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error reading cgroup knob %q", knob), err)
+	}
+	if strings.TrimSpace(string(data)) == "max" {
+		return nil, nil
+	}
+	n, err := readUintKnob(cgroupPath, knob)
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
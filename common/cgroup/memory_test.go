@@ -0,0 +1,119 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package cgroup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeKnobs(t *testing.T, knobs map[string]string) string {
+	dir, err := ioutil.TempDir("", "rkt-cgroup-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, value := range knobs {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func uint64p(n uint64) *uint64 {
+	return &n
+}
+
+func TestMemoryStats(t *testing.T) {
+	tests := []struct {
+		unified bool
+		knobs   map[string]string
+		stats   MemoryStats
+		noSwap  bool
+	}{
+		{
+			knobs: map[string]string{
+				"memory.usage_in_bytes":       "1000",
+				"memory.limit_in_bytes":       "4096",
+				"memory.memsw.usage_in_bytes": "1500",
+				"memory.memsw.limit_in_bytes": "8192",
+			},
+			stats: MemoryStats{Usage: 1000, Limit: uint64p(4096), SwapUsage: 500, SwapLimit: uint64p(4096)},
+		},
+		{
+			knobs: map[string]string{
+				"memory.usage_in_bytes":       "1000",
+				"memory.limit_in_bytes":       "9223372036854771712",
+				"memory.memsw.usage_in_bytes": "1000",
+				"memory.memsw.limit_in_bytes": "9223372036854771712",
+			},
+			stats: MemoryStats{Usage: 1000},
+		},
+		{
+			knobs: map[string]string{
+				"memory.usage_in_bytes": "1000",
+				"memory.limit_in_bytes": "4096",
+			},
+			stats:  MemoryStats{Usage: 1000, Limit: uint64p(4096)},
+			noSwap: true,
+		},
+		{
+			unified: true,
+			knobs: map[string]string{
+				"memory.current":      "1000",
+				"memory.max":          "4096",
+				"memory.swap.current": "200",
+				"memory.swap.max":     "max",
+			},
+			stats: MemoryStats{Usage: 1000, Limit: uint64p(4096), SwapUsage: 200},
+		},
+		{
+			unified: true,
+			knobs: map[string]string{
+				"memory.current": "1000",
+				"memory.max":     "max",
+			},
+			stats:  MemoryStats{Usage: 1000},
+			noSwap: true,
+		},
+	}
+
+	for i, tt := range tests {
+		dir := writeKnobs(t, tt.knobs)
+		defer os.RemoveAll(dir)
+
+		var stats *MemoryStats
+		var err error
+		if tt.unified {
+			stats, err = getV2MemoryStats(dir)
+		} else {
+			stats, err = getV1MemoryStats(dir)
+		}
+		switch {
+		case tt.noSwap && err != ErrNoSwapAccounting:
+			t.Errorf("#%d: expected ErrNoSwapAccounting, got %v", i, err)
+		case !tt.noSwap && err != nil:
+			t.Errorf("#%d: unexpected error: %v", i, err)
+		}
+		if stats == nil || !reflect.DeepEqual(*stats, tt.stats) {
+			t.Errorf("#%d: expected %+v, got %+v", i, tt.stats, stats)
+		}
+	}
+}
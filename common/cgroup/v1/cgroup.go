@@ -179,6 +179,70 @@ func JoinSubcgroup(controller string, subcgroup string) error {
 	return nil
 }
 
+// Knob is a cgroup knob and the value to write to it.
+type Knob struct {
+	Name  string
+	Value string
+}
+
+// WriteServiceKnobs creates the cgroup of a systemd service of the pod's
+// subcgroup on a particular controller, and writes the given knobs to it,
+// in order. systemd inside stage1 starts the service in this cgroup
+// later on, keeping the knobs it doesn't manage.
+func WriteServiceKnobs(controller, subcgroup, service string, knobs []Knob) error {
+	return WriteKnobs(controller, filepath.Join(subcgroup, "system.slice", service), knobs)
+}
+
+// ReserveRTRuntime makes sure that a cgroup on the cpu controller and all
+// its ancestors have at least the given real-time runtime, in
+// microseconds, to share among their children. The runtime of each
+// cgroup is raised from the top, as the kernel requires the runtime of a
+// cgroup to fit in the one of its parent.
+func ReserveRTRuntime(cgroup string, runtime uint64) error {
+	cgroupPath := "/sys/fs/cgroup/cpu"
+	for _, c := range strings.Split(strings.Trim(cgroup, "/"), "/") {
+		cgroupPath = filepath.Join(cgroupPath, c)
+		if err := os.MkdirAll(cgroupPath, 0755); err != nil {
+			return errwrap.Wrap(fmt.Errorf("error creating cgroup %q", cgroupPath), err)
+		}
+		knobPath := filepath.Join(cgroupPath, "cpu.rt_runtime_us")
+		current, err := ioutil.ReadFile(knobPath)
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("error reading %q", knobPath), err)
+		}
+		// -1 means no limit
+		if n, err := strconv.ParseInt(strings.TrimSpace(string(current)), 10, 64); err == nil && (n < 0 || uint64(n) >= runtime) {
+			continue
+		}
+		if err := ioutil.WriteFile(knobPath, []byte(strconv.FormatUint(runtime, 10)), 0644); err != nil {
+			return errwrap.Wrap(fmt.Errorf("error reserving a real-time runtime of %dus in %q", runtime, cgroupPath), err)
+		}
+	}
+	return nil
+}
+
+// IsSwapAccountingEnabled returns whether the memory controller accounts
+// the swap, which the memsw knobs require.
+func IsSwapAccountingEnabled() bool {
+	_, err := os.Stat("/sys/fs/cgroup/memory/memory.memsw.limit_in_bytes")
+	return err == nil
+}
+
+// WriteKnobs creates a cgroup on a particular controller if needed, and
+// writes the given knobs to it, in order.
+func WriteKnobs(controller, cgroup string, knobs []Knob) error {
+	cgroupPath := filepath.Join("/sys/fs/cgroup", controller, cgroup)
+	if err := os.MkdirAll(cgroupPath, 0755); err != nil {
+		return errwrap.Wrap(fmt.Errorf("error creating %q cgroup", cgroup), err)
+	}
+	for _, k := range knobs {
+		if err := ioutil.WriteFile(filepath.Join(cgroupPath, k.Name), []byte(k.Value), 0644); err != nil {
+			return errwrap.Wrap(fmt.Errorf("error writing cgroup knob %q of %q", k.Name, cgroup), err)
+		}
+	}
+	return nil
+}
+
 // Ensure that the hierarchy has consistent cpu restrictions.
 // This may fail; since this is "fixup" code, we should ignore
 // the error and proceed.
@@ -43,6 +43,11 @@ const (
 	stage1Dir        = "/stage1"
 	stage2Dir        = "/opt/stage2"
 	AppsInfoDir      = "/appsinfo"
+	secretsDir       = "/secrets"
+
+	// SecretsMountPath is where the pod secrets are made available inside
+	// every app.
+	SecretsMountPath = "/run/secrets"
 
 	EnvLockFd                    = "RKT_LOCK_FD"
 	EnvSELinuxContext            = "RKT_SELINUX_CONTEXT"
@@ -228,6 +233,11 @@ func SharedVolumesPath(root string) string {
 	return filepath.Join(root, sharedVolumesDir)
 }
 
+// SecretsPath returns the path to the tmpfs holding the secrets of a pod.
+func SecretsPath(root string) string {
+	return filepath.Join(root, secretsDir)
+}
+
 // CreateSharedVolumesPath ensures the sharedVolumePath for the pod root passed
 // in exists. It returns the shared volume path or an error.
 func CreateSharedVolumesPath(root string) (string, error) {
@@ -300,8 +310,7 @@ func (l *NetList) Set(value string) error {
 		case len(netArgsPair) == 1:
 			l.mapping[netName] = ""
 		case len(netArgsPair) == 2:
-			if netName == "all" ||
-				netName == "host" {
+			if netName == "all" {
 				return fmt.Errorf("arguments are not supported by special netname %q", netName)
 			}
 			l.mapping[netName] = netArgsPair[1]
@@ -344,7 +353,13 @@ func (l *NetList) StringsOnlyNames() (list []string) {
 
 // Check if host networking has been requested
 func (l *NetList) Host() bool {
-	return l.Specific("host")
+	return l.Specific("host") && l.SpecificArgs("host") == ""
+}
+
+// Check if host interfaces have been requested with --net=host:args, in a
+// network namespace of the pod
+func (l *NetList) HostIfaces() bool {
+	return l.Specific("host") && l.SpecificArgs("host") != ""
 }
 
 // Check if 'none' (loopback only) networking has been requested
@@ -16,6 +16,7 @@ package common
 
 import (
 	"testing"
+	"time"
 
 	"github.com/appc/spec/schema/types"
 )
@@ -54,3 +55,78 @@ func TestImageNameToAppName(t *testing.T) {
 		}
 	}
 }
+
+func TestTimeOffsets(t *testing.T) {
+	for _, tt := range []struct {
+		in        string
+		monotonic time.Duration
+		boottime  time.Duration
+		err       bool
+	}{
+		{in: "monotonic=24h", monotonic: 24 * time.Hour},
+		{in: "monotonic=-1h,boottime=720h", monotonic: -time.Hour, boottime: 720 * time.Hour},
+		{in: "boottime=0"},
+		{in: "realtime=1h", err: true},
+		{in: "monotonic", err: true},
+		{in: "monotonic=1 day", err: true},
+	} {
+		var o TimeOffsets
+		err := o.Set(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.in, err)
+			continue
+		}
+		if o.Empty() || o.Monotonic != tt.monotonic || o.Boottime != tt.boottime {
+			t.Errorf("%q: got %+v", tt.in, o)
+		}
+
+		// The string form is passed to stage1, which parses it back
+		var back TimeOffsets
+		if err := back.Set(o.String()); err != nil || back != o {
+			t.Errorf("%q: got %+v back from %q", tt.in, back, o.String())
+		}
+	}
+
+	var o TimeOffsets
+	if !o.Empty() || o.String() != "" {
+		t.Errorf("expected unset time offsets to be empty")
+	}
+}
+
+func TestNetListHost(t *testing.T) {
+	for _, tt := range []struct {
+		in         string
+		host       bool
+		hostIfaces bool
+		contained  bool
+		err        bool
+	}{
+		{in: "host", host: true},
+		{in: "host:move=eth1;macvlan=eth2", hostIfaces: true, contained: true},
+		{in: "host:move=eth1,default", hostIfaces: true, contained: true},
+		{in: "default", contained: true},
+		{in: "all:move=eth1", err: true},
+	} {
+		var l NetList
+		err := l.Set(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.in, err)
+			continue
+		}
+		if l.Host() != tt.host || l.HostIfaces() != tt.hostIfaces || l.Contained() != tt.contained {
+			t.Errorf("%q: got host %v, host interfaces %v, contained %v", tt.in, l.Host(), l.HostIfaces(), l.Contained())
+		}
+	}
+}
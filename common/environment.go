@@ -21,6 +21,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/rkt/rkt/pkg/user"
 
@@ -29,6 +30,17 @@ import (
 
 const DefaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
 
+// Host facts that can be referenced in the values of the apps' environment
+// variables using the %{fact} syntax, see ExpandEnvTemplates.
+const (
+	EnvFactHostname  = "hostname"   // hostname of the pod
+	EnvFactUUID      = "uuid"       // UUID of the pod
+	EnvFactDefaultIP = "default-ip" // pod IP on the default (forwardable) network
+	EnvFactHostIP    = "host-ip"    // host IP on the default (forwardable) network
+
+	envFactNetIPPrefix = "ip:"
+)
+
 var defaultEnv = map[string]string{
 	"PATH":    DefaultPath,
 	"SHELL":   "/bin/sh",
@@ -105,3 +117,52 @@ func ComposeEnviron(env types.Environment) []string {
 	}
 	return composed
 }
+
+// EnvFactNetIP returns the name of the fact holding the pod IP on the network
+// with the given name.
+func EnvFactNetIP(netName string) string {
+	return envFactNetIPPrefix + netName
+}
+
+// ExpandEnvTemplates replaces the %{fact} references in the values of env
+// with the provided facts, in place. "%%{" can be used to get a literal "%{".
+// References to unknown facts are left untouched, so values not meant as
+// templates are preserved.
+func ExpandEnvTemplates(env types.Environment, facts map[string]string) {
+	for i, e := range env {
+		if strings.Contains(e.Value, "%{") {
+			env[i].Value = expandEnvTemplate(e.Value, facts)
+		}
+	}
+}
+
+func expandEnvTemplate(value string, facts map[string]string) string {
+	var b bytes.Buffer
+	for {
+		i := strings.Index(value, "%{")
+		if i < 0 {
+			b.WriteString(value)
+			return b.String()
+		}
+		// escaped reference
+		if i > 0 && value[i-1] == '%' {
+			b.WriteString(value[:i-1])
+			b.WriteString("%{")
+			value = value[i+2:]
+			continue
+		}
+		b.WriteString(value[:i])
+		end := strings.IndexByte(value[i:], '}')
+		if end < 0 {
+			b.WriteString(value[i:])
+			return b.String()
+		}
+		ref := value[i : i+end+1]
+		if fact, ok := facts[ref[2:len(ref)-1]]; ok {
+			b.WriteString(fact)
+		} else {
+			b.WriteString(ref)
+		}
+		value = value[i+end+1:]
+	}
+}
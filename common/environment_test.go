@@ -198,3 +198,31 @@ func TestComposeEnv(t *testing.T) {
 		}
 	}
 }
+
+func TestExpandEnvTemplates(t *testing.T) {
+	facts := map[string]string{
+		EnvFactHostname:       "rkt-host",
+		EnvFactDefaultIP:      "172.16.28.2",
+		EnvFactNetIP("mynet"): "10.1.0.5",
+	}
+	tests := []struct {
+		in  string
+		out string
+	}{
+		{"plain", "plain"},
+		{"%{hostname}", "rkt-host"},
+		{"http://%{default-ip}:8080/", "http://172.16.28.2:8080/"},
+		{"%{ip:mynet},%{hostname}", "10.1.0.5,rkt-host"},
+		{"%{unknown}", "%{unknown}"},
+		{"%%{hostname}", "%{hostname}"},
+		{"100%", "100%"},
+		{"%{hostname", "%{hostname"},
+	}
+	for i, tt := range tests {
+		env := types.Environment{{Name: "VAR", Value: tt.in}}
+		ExpandEnvTemplates(env, facts)
+		if env[0].Value != tt.out {
+			t.Errorf("#%d: expected %q, got %q", i, tt.out, env[0].Value)
+		}
+	}
+}
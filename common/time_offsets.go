@@ -0,0 +1,71 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeOffsets are the offsets of the monotonic and boot-time clocks of a
+// pod running in its own time namespace, relative to the ones of the
+// host. It can be set from a flag in the form of
+// monotonic=DURATION,boottime=DURATION, either can be omitted; setting it
+// requests the time namespace, even without offsets.
+type TimeOffsets struct {
+	Monotonic time.Duration
+	Boottime  time.Duration
+	requested bool
+}
+
+func (o *TimeOffsets) String() string {
+	if !o.requested {
+		return ""
+	}
+	return fmt.Sprintf("monotonic=%s,boottime=%s", o.Monotonic, o.Boottime)
+}
+
+func (o *TimeOffsets) Set(value string) error {
+	for _, s := range strings.Split(value, ",") {
+		kv := strings.SplitN(s, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid time offset %q, expected CLOCK=DURATION", s)
+		}
+		d, err := time.ParseDuration(kv[1])
+		if err != nil {
+			return fmt.Errorf("invalid duration of time offset %q", s)
+		}
+		switch kv[0] {
+		case "monotonic":
+			o.Monotonic = d
+		case "boottime":
+			o.Boottime = d
+		default:
+			return fmt.Errorf("unknown clock %q, expected monotonic or boottime", kv[0])
+		}
+	}
+	o.requested = true
+	return nil
+}
+
+func (o *TimeOffsets) Type() string {
+	return "timeOffsets"
+}
+
+// Empty returns whether no time namespace is requested.
+func (o *TimeOffsets) Empty() bool {
+	return !o.requested
+}
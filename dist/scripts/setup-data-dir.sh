@@ -70,6 +70,7 @@ create_rkt_file "${datadir}/cas/db/.34a8b4c1ad933745146fdbfef3073706ee571625" 06
 make_directory "${datadir}/cas/imagelocks" 2770 "rkt"
 make_directory "${datadir}/cas/imageManifest" 2770 "rkt"
 make_directory "${datadir}/cas/blob" 2770 "rkt"
+make_directory "${datadir}/cas/manifestcache" 2770 "rkt"
 make_directory "${datadir}/cas/tmp" 2770 "rkt"
 make_directory "${datadir}/cas/tree" 2700 "rkt"
 make_directory "${datadir}/cas/treestorelocks" 2700 "rkt"
@@ -0,0 +1,117 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+
+	cnisysctl "github.com/containernetworking/cni/pkg/utils/sysctl"
+	"github.com/hashicorp/errwrap"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	arpRequest = 1
+	arpReply   = 2
+)
+
+// htons converts a short from the host to the network byte order.
+func htons(i uint16) uint16 {
+	return i<<8 | i>>8
+}
+
+// gratuitousARP returns an ARP packet of the given operation announcing
+// that ip is at mac.
+func gratuitousARP(op uint16, mac net.HardwareAddr, ip net.IP) []byte {
+	b := make([]byte, 28)
+	binary.BigEndian.PutUint16(b[0:2], syscall.ARPHRD_ETHER)
+	binary.BigEndian.PutUint16(b[2:4], syscall.ETH_P_IP)
+	b[4] = 6 // hardware address length
+	b[5] = 4 // protocol address length
+	binary.BigEndian.PutUint16(b[6:8], op)
+	copy(b[8:14], mac)
+	copy(b[14:18], ip)
+	// The target hardware address stays zeroed.
+	copy(b[24:28], ip)
+	return b
+}
+
+// announceIP broadcasts gratuitous ARP packets, both a request and a reply
+// as neighbors may only honor one of them, on an interface, so the
+// neighbors learn immediately that its IPv4 address moved to its MAC
+// address, like after a pod restart.
+func announceIP(ifName string, ip net.IP) error {
+	ip4 := ip.To4()
+	if ip4 == nil {
+		return fmt.Errorf("%v is not an IPv4 address", ip)
+	}
+	link, err := netlink.LinkByName(ifName)
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("cannot find the interface %q", ifName), err)
+	}
+	mac := link.Attrs().HardwareAddr
+	if len(mac) != 6 {
+		// Not an ethernet interface, nothing to announce.
+		return nil
+	}
+
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(htons(syscall.ETH_P_ARP)))
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot open a packet socket"), err)
+	}
+	defer syscall.Close(fd)
+
+	sa := &syscall.SockaddrLinklayer{
+		Protocol: htons(syscall.ETH_P_ARP),
+		Ifindex:  link.Attrs().Index,
+		Halen:    6,
+		Addr:     [8]byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+	}
+	for _, op := range []uint16{arpRequest, arpReply} {
+		if err := syscall.Sendto(fd, gratuitousARP(op, mac, ip4), 0, sa); err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot send a gratuitous ARP packet on %q", ifName), err)
+		}
+	}
+	return nil
+}
+
+// announceIPs announces the addresses of the pod on all its networks. A
+// failure only delays the updates of the neighbors, so it is not fatal.
+// It must be called in the pod network namespace.
+func (e *podEnv) announceIPs(nets []activeNet) {
+	for _, n := range nets {
+		if n.runtime.IP == nil {
+			continue
+		}
+		if err := announceIP(n.runtime.IfName, n.runtime.IP); err != nil {
+			stderr.WarnE(fmt.Sprintf("network %q: cannot announce the address of the pod", n.conf.Name), err)
+			continue
+		}
+		stderr.Debugf("network %q: announced %v on %q", n.conf.Name, n.runtime.IP, n.runtime.IfName)
+	}
+}
+
+// enableProxyARP makes the host answer the ARP requests on an interface
+// for the addresses it routes, like the kvm pod behind a ptp tap device.
+func enableProxyARP(ifName string) error {
+	if _, err := cnisysctl.Sysctl(fmt.Sprintf(IPv4InterfaceArpProxySysctlTemplate, ifName), "1"); err != nil {
+		return errwrap.Wrap(fmt.Errorf("cannot enable proxy ARP on %q", ifName), err)
+	}
+	return nil
+}
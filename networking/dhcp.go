@@ -0,0 +1,132 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+
+	"github.com/rkt/rkt/pkg/lock"
+)
+
+const (
+	// dhcpDaemonDir holds the state of the DHCP daemon started by rkt:
+	// its pid and its log.
+	dhcpDaemonDir = "/run/rkt/dhcp"
+	// dhcpSocketPath is the socket of the DHCP daemon, dialed by the dhcp
+	// IPAM plugin bundled in stage1.
+	dhcpSocketPath = "/run/cni/dhcp.sock"
+
+	dhcpIPAMType      = "dhcp"
+	dhcpStartTimeout  = 5 * time.Second
+	dhcpStartInterval = 100 * time.Millisecond
+)
+
+// usesDHCP returns whether one of the networks uses the dhcp IPAM plugin.
+func usesDHCP(nets []activeNet) bool {
+	for _, n := range nets {
+		if n.conf.IPAM.Type == dhcpIPAMType {
+			return true
+		}
+	}
+	return false
+}
+
+// dhcpDaemonListening returns whether a DHCP daemon accepts connections on
+// its socket, be it started by rkt or not.
+func dhcpDaemonListening() bool {
+	c, err := net.Dial("unix", dhcpSocketPath)
+	if err != nil {
+		return false
+	}
+	c.Close()
+	return true
+}
+
+// ensureDHCPDaemon starts the DHCP daemon of the dhcp IPAM plugin, if one
+// of the networks uses it and no daemon is running yet. The daemon keeps
+// running after the pod exits, to renew the leases of the other pods.
+func (e *podEnv) ensureDHCPDaemon(nets []activeNet) error {
+	if !usesDHCP(nets) || dhcpDaemonListening() {
+		return nil
+	}
+
+	if err := os.MkdirAll(dhcpDaemonDir, 0700); err != nil {
+		return errwrap.Wrap(errors.New("error creating the DHCP daemon directory"), err)
+	}
+	l, err := lock.ExclusiveLock(dhcpDaemonDir, lock.Dir)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error locking the DHCP daemon directory"), err)
+	}
+	defer l.Close()
+
+	// Another pod may have started it while we waited for the lock.
+	if dhcpDaemonListening() {
+		return nil
+	}
+
+	pluginPath := e.findNetPlugin(dhcpIPAMType)
+	if pluginPath == "" {
+		return fmt.Errorf("could not find plugin %q", dhcpIPAMType)
+	}
+
+	// A daemon which died leaves its socket behind.
+	if err := os.Remove(dhcpSocketPath); err != nil && !os.IsNotExist(err) {
+		return errwrap.Wrap(errors.New("error removing the stale DHCP daemon socket"), err)
+	}
+
+	logPath := filepath.Join(dhcpDaemonDir, "daemon.log")
+	logFile, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error opening the DHCP daemon log"), err)
+	}
+	defer logFile.Close()
+
+	cmd := exec.Cmd{
+		Path:        pluginPath,
+		Args:        []string{pluginPath, "daemon"},
+		Dir:         "/",
+		Stdout:      logFile,
+		Stderr:      logFile,
+		SysProcAttr: &syscall.SysProcAttr{Setsid: true},
+	}
+	if err := cmd.Start(); err != nil {
+		return errwrap.Wrap(errors.New("error starting the DHCP daemon"), err)
+	}
+	pid := cmd.Process.Pid
+	cmd.Process.Release()
+
+	if err := ioutil.WriteFile(filepath.Join(dhcpDaemonDir, "pid"), []byte(strconv.Itoa(pid)), 0600); err != nil {
+		stderr.PrintE("error writing the DHCP daemon pid", err)
+	}
+	stderr.Debugf("started the DHCP daemon with pid %d", pid)
+
+	for start := time.Now(); time.Since(start) < dhcpStartTimeout; time.Sleep(dhcpStartInterval) {
+		if dhcpDaemonListening() {
+			return nil
+		}
+	}
+	return fmt.Errorf("the DHCP daemon did not start listening on %q in %v, see %q", dhcpSocketPath, dhcpStartTimeout, logPath)
+}
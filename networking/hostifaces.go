@@ -0,0 +1,290 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/containernetworking/cni/pkg/ns"
+	"github.com/hashicorp/errwrap"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	// hostIfaceMove moves a host interface to the pod, with its IPv4
+	// addresses and routes.
+	hostIfaceMove = "move"
+	// hostIfaceMacvlan bridges a host interface to the pod with a macvlan
+	// interface, named after it in the pod.
+	hostIfaceMacvlan = "macvlan"
+
+	hostIfacesFile = "host-ifaces.json"
+)
+
+// hostIface is a host interface given to the pod with --net=host:args.
+type hostIface struct {
+	Name string `json:"name"`
+	Mode string `json:"mode"`
+	// Addrs and Routes are the IPv4 configuration of a moved interface,
+	// given back to the host at teardown.
+	Addrs  []string `json:"addrs,omitempty"`
+	Routes []Route  `json:"routes,omitempty"`
+}
+
+// parseHostIfaces parses the arguments of --net=host, like
+// "move=eth1;macvlan=eth2".
+func parseHostIfaces(args string) ([]hostIface, error) {
+	var ifaces []hostIface
+	seen := make(map[string]bool)
+	for _, arg := range strings.Split(args, ";") {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("invalid host interface %q, expected %s=IFACE or %s=IFACE", arg, hostIfaceMove, hostIfaceMacvlan)
+		}
+		mode, name := kv[0], kv[1]
+		if mode != hostIfaceMove && mode != hostIfaceMacvlan {
+			return nil, fmt.Errorf("unknown mode %q of host interface %q", mode, name)
+		}
+		if err := validateIfName(name); err != nil {
+			return nil, err
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("host interface %q given twice", name)
+		}
+		seen[name] = true
+		ifaces = append(ifaces, hostIface{Name: name, Mode: mode})
+	}
+	return ifaces, nil
+}
+
+func (e *podEnv) hostIfacesPath() string {
+	return filepath.Join(e.netDir(), hostIfacesFile)
+}
+
+func (e *podEnv) saveHostIfaces(ifaces []hostIface) error {
+	b, err := json.Marshal(ifaces)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(e.hostIfacesPath(), b, 0644)
+}
+
+func (e *podEnv) loadHostIfaces() ([]hostIface, error) {
+	b, err := ioutil.ReadFile(e.hostIfacesPath())
+	if err != nil {
+		return nil, err
+	}
+	var ifaces []hostIface
+	if err := json.Unmarshal(b, &ifaces); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error parsing %q", e.hostIfacesPath()), err)
+	}
+	return ifaces, nil
+}
+
+// setupHostIfaces gives the host interfaces requested with --net=host:args
+// to the pod. They are recorded before being given, so that the moved ones
+// go back to the host at teardown, even after a failed setup.
+func (e *podEnv) setupHostIfaces() (err error) {
+	ifaces, err := parseHostIfaces(e.netsLoadList.SpecificArgs("host"))
+	if err != nil {
+		return errwrap.Wrap(errors.New("invalid host interfaces"), err)
+	}
+	if err := os.MkdirAll(e.netDir(), 0755); err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			e.teardownHostIfaces()
+		}
+	}()
+
+	for i := range ifaces {
+		hi := &ifaces[i]
+		stderr.Debugf("giving host interface %v to the pod (%s)", hi.Name, hi.Mode)
+
+		link, err := netlink.LinkByName(hi.Name)
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot find host interface %q", hi.Name), err)
+		}
+		if hi.Mode == hostIfaceMacvlan {
+			if err := e.addHostMacvlan(link); err != nil {
+				return errwrap.Wrap(fmt.Errorf("cannot bridge host interface %q to the pod", hi.Name), err)
+			}
+			continue
+		}
+
+		if err := hi.saveConfig(link); err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot read the configuration of host interface %q", hi.Name), err)
+		}
+		if err := e.saveHostIfaces(ifaces[:i+1]); err != nil {
+			return errwrap.Wrap(errors.New("cannot record the host interfaces"), err)
+		}
+		if err := netlink.LinkSetNsFd(link, int(e.podNS.Fd())); err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot move host interface %q to the pod", hi.Name), err)
+		}
+		if err := e.podNS.Do(func(ns.NetNS) error {
+			return hi.configure()
+		}); err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot configure interface %q in the pod", hi.Name), err)
+		}
+	}
+	return nil
+}
+
+// saveConfig saves the IPv4 addresses and the routes via a gateway of the
+// link. The routes to the subnets of the addresses come with them.
+func (hi *hostIface) saveConfig(link netlink.Link) error {
+	addrs, err := netlink.AddrList(link, netlink.FAMILY_V4)
+	if err != nil {
+		return err
+	}
+	for _, a := range addrs {
+		hi.Addrs = append(hi.Addrs, a.IPNet.String())
+	}
+
+	routes, err := netlink.RouteList(link, netlink.FAMILY_V4)
+	if err != nil {
+		return err
+	}
+	for _, r := range routes {
+		if r.Gw == nil {
+			continue
+		}
+		dst := net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)}
+		if r.Dst != nil {
+			dst = *r.Dst
+		}
+		hi.Routes = append(hi.Routes, Route{Dst: dst, GW: r.Gw})
+	}
+	return nil
+}
+
+// configure sets the link up with its saved configuration, in the current
+// network namespace. The parts already there are left as is.
+func (hi *hostIface) configure() error {
+	link, err := netlink.LinkByName(hi.Name)
+	if err != nil {
+		return err
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return err
+	}
+	for _, a := range hi.Addrs {
+		addr, err := netlink.ParseAddr(a)
+		if err != nil {
+			return err
+		}
+		if err := netlink.AddrAdd(link, addr); err != nil && err != syscall.EEXIST {
+			return errwrap.Wrap(fmt.Errorf("cannot add address %s", a), err)
+		}
+	}
+	for _, r := range hi.Routes {
+		if err := addTableRoute(link.Attrs().Index, r, mainTable); err != nil && err != syscall.EEXIST {
+			return errwrap.Wrap(fmt.Errorf("cannot add route %q", r), err)
+		}
+	}
+	return nil
+}
+
+// addHostMacvlan adds a macvlan interface in bridge mode on top of master
+// to the pod, named after master.
+func (e *podEnv) addHostMacvlan(master netlink.Link) error {
+	// The interface is created on the host side under a free name, as
+	// the one of master is taken there.
+	tmpName, err := e.allocIfName("rkt-mvlan", ifKindMacvlan)
+	if err != nil {
+		return err
+	}
+	defer releaseIfName(tmpName)
+
+	link := &netlink.Macvlan{
+		LinkAttrs: netlink.LinkAttrs{
+			Name:        tmpName,
+			MTU:         master.Attrs().MTU,
+			ParentIndex: master.Attrs().Index,
+			Namespace:   netlink.NsFd(int(e.podNS.Fd())),
+		},
+		Mode: netlink.MACVLAN_MODE_BRIDGE,
+	}
+	if err := netlink.LinkAdd(link); err != nil {
+		return errwrap.Wrap(errors.New("cannot create macvlan interface"), err)
+	}
+
+	return e.podNS.Do(func(ns.NetNS) error {
+		l, err := netlink.LinkByName(tmpName)
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetName(l, master.Attrs().Name); err != nil {
+			return err
+		}
+		return netlink.LinkSetUp(l)
+	})
+}
+
+// teardownHostIfaces gives the moved host interfaces back to the host with
+// their configuration. The macvlan interfaces go away with the network
+// namespace of the pod.
+func (e *podEnv) teardownHostIfaces() {
+	ifaces, err := e.loadHostIfaces()
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		stderr.PrintE("error loading the host interfaces", err)
+		return
+	}
+
+	hostNS, err := ns.GetCurrentNS()
+	if err != nil {
+		stderr.PrintE("error getting the host network namespace", err)
+		return
+	}
+	defer hostNS.Close()
+
+	for _, hi := range ifaces {
+		if hi.Mode != hostIfaceMove {
+			continue
+		}
+		if e.podNS != nil {
+			if err := e.podNS.Do(func(ns.NetNS) error {
+				link, err := netlink.LinkByName(hi.Name)
+				if err != nil {
+					// Not moved yet, or already back.
+					return nil
+				}
+				return netlink.LinkSetNsFd(link, int(hostNS.Fd()))
+			}); err != nil {
+				stderr.PrintE(fmt.Sprintf("cannot move interface %q back to the host", hi.Name), err)
+				continue
+			}
+		}
+		if err := hi.configure(); err != nil {
+			stderr.PrintE(fmt.Sprintf("cannot restore the configuration of host interface %q", hi.Name), err)
+		}
+	}
+	if err := os.Remove(e.hostIfacesPath()); err != nil {
+		stderr.PrintE("error removing the record of the host interfaces", err)
+	}
+}
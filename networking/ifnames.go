@@ -0,0 +1,177 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/vishvananda/netlink"
+
+	"github.com/rkt/rkt/networking/tuntap"
+	"github.com/rkt/rkt/pkg/lock"
+)
+
+const (
+	// IfNamesDir is the host-wide registry of the interfaces rkt creates
+	// for its pods, in a file per interface name. The interfaces do not
+	// survive a reboot, so neither does the registry.
+	IfNamesDir = "/run/rkt/ifnames"
+
+	ifKindTap     = "tap"
+	ifKindMacVTap = "macvtap"
+	ifKindMacvlan = "macvlan"
+
+	// maxIfIndex bounds the allocation, keeping the names within the
+	// 15 characters of the kernel.
+	maxIfIndex = 99999
+)
+
+// ifNameRecord is the registry entry of an interface.
+type ifNameRecord struct {
+	Pod string `json:"pod"`
+	// DataDir is the rkt data directory of the pod.
+	DataDir string `json:"dataDir"`
+	Kind    string `json:"kind"`
+}
+
+func loadIfNameRecord(path string) (*ifNameRecord, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &ifNameRecord{}
+	if err := json.Unmarshal(b, r); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error parsing %q", path), err)
+	}
+	return r, nil
+}
+
+// lockIfNames creates the registry if needed and locks it.
+func lockIfNames() (*lock.FileLock, error) {
+	if err := os.MkdirAll(IfNamesDir, 0700); err != nil {
+		return nil, err
+	}
+	return lock.ExclusiveLock(IfNamesDir, lock.Dir)
+}
+
+// allocIfName allocates the lowest free name made of prefix and an index
+// for an interface of the pod, and records it in the registry. A name is
+// free if it is neither recorded nor used by an existing link.
+func (e *podEnv) allocIfName(prefix, kind string) (string, error) {
+	dataDir, err := e.dataDir()
+	if err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(ifNameRecord{
+		Pod:     e.podID.String(),
+		DataDir: dataDir,
+		Kind:    kind,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	l, err := lockIfNames()
+	if err != nil {
+		return "", errwrap.Wrap(errors.New("error locking the interface names registry"), err)
+	}
+	defer l.Close()
+
+	for i := 0; i <= maxIfIndex; i++ {
+		name := prefix + strconv.Itoa(i)
+		path := filepath.Join(IfNamesDir, name)
+		if _, err := os.Lstat(path); !os.IsNotExist(err) {
+			continue
+		}
+		if _, err := netlink.LinkByName(name); err == nil {
+			continue
+		}
+		if err := ioutil.WriteFile(path, b, 0600); err != nil {
+			return "", errwrap.Wrap(fmt.Errorf("error recording the interface name %q", name), err)
+		}
+		return name, nil
+	}
+	return "", fmt.Errorf("no free interface name with prefix %q", prefix)
+}
+
+// releaseIfName removes an interface name from the registry, once its
+// interface is removed.
+func releaseIfName(name string) {
+	if err := os.Remove(filepath.Join(IfNamesDir, name)); err != nil && !os.IsNotExist(err) {
+		stderr.WarnE(fmt.Sprintf("cannot release the interface name %q", name), err)
+	}
+}
+
+// removeIface removes the interface of a registry entry, if it exists.
+func removeIface(name, kind string) error {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		// Already gone.
+		return nil
+	}
+	if kind == ifKindTap {
+		return tuntap.RemovePersistentIface(name, tuntap.Tap)
+	}
+	return netlink.LinkDel(link)
+}
+
+// GCIfNames removes the interfaces of the pods of a rkt data directory
+// which do not exist anymore, and their names from the registry. It
+// returns the removed interfaces.
+func GCIfNames(dataDir string, podExists func(uuid string) bool) ([]string, error) {
+	if _, err := os.Stat(IfNamesDir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	l, err := lockIfNames()
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error locking the interface names registry"), err)
+	}
+	defer l.Close()
+
+	files, err := ioutil.ReadDir(IfNamesDir)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error listing the interface names registry"), err)
+	}
+
+	var removed []string
+	for _, f := range files {
+		name := f.Name()
+		path := filepath.Join(IfNamesDir, name)
+		r, err := loadIfNameRecord(path)
+		if err != nil {
+			// Not a record of ours, or a partial one.
+			continue
+		}
+		if r.DataDir != dataDir || podExists(r.Pod) {
+			continue
+		}
+
+		if err := removeIface(name, r.Kind); err != nil {
+			return removed, errwrap.Wrap(fmt.Errorf("error removing the interface %q of pod %q", name, r.Pod), err)
+		}
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, name)
+	}
+	return removed, nil
+}
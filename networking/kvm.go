@@ -50,18 +50,21 @@ const (
 
 type BridgeNetConf struct {
 	NetConf
-	BrName string `json:"bridge"`
-	IsGw   bool   `json:"isGateway"`
+	BrName    string         `json:"bridge"`
+	IsGw      bool           `json:"isGateway"`
+	Multicast *MulticastConf `json:"multicast,omitempty"`
 }
 
 // setupTapDevice creates persistent tap device
 // and returns a newly created netlink.Link structure
-func setupTapDevice(podID types.UUID) (netlink.Link, error) {
-	// network device names are limited to 16 characters
-	// the suffix %d will be replaced by the kernel with a suitable number
-	nameTemplate := fmt.Sprintf("rkt-%s-tap%%d", podID.String()[0:4])
-	ifName, err := tuntap.CreatePersistentIface(nameTemplate, tuntap.Tap)
+// named after the lowest free name in the interface names registry
+func (e *podEnv) setupTapDevice() (netlink.Link, error) {
+	ifName, err := e.allocIfName("rkt-tap", ifKindTap)
 	if err != nil {
+		return nil, err
+	}
+	if _, err := tuntap.CreatePersistentIface(ifName, tuntap.Tap); err != nil {
+		releaseIfName(ifName)
 		return nil, errwrap.Wrap(errors.New("tuntap persist"), err)
 	}
 
@@ -86,10 +89,10 @@ const (
 	IPv4InterfaceArpProxySysctlTemplate = "net.ipv4.conf.%s.proxy_arp"
 )
 
-// setupTapDevice creates persistent macvtap device
+// setupMacVTapDevice creates persistent macvtap device
 // and returns a newly created netlink.Link structure
-// using part of pod hash and interface number in interface name
-func setupMacVTapDevice(podID types.UUID, config MacVTapNetConf, interfaceNumber int) (netlink.Link, error) {
+// named after the lowest free name in the interface names registry
+func (e *podEnv) setupMacVTapDevice(config MacVTapNetConf) (netlink.Link, error) {
 	master, err := netlink.LinkByName(config.Master)
 	if err != nil {
 		return nil, errwrap.Wrap(fmt.Errorf("cannot find master device '%v'", config.Master), err)
@@ -113,7 +116,10 @@ func setupMacVTapDevice(podID types.UUID, config MacVTapNetConf, interfaceNumber
 	if config.MTU != 0 {
 		mtu = config.MTU
 	}
-	interfaceName := fmt.Sprintf("rkt-%s-vtap%d", podID.String()[0:4], interfaceNumber)
+	interfaceName, err := e.allocIfName("rkt-vtap", ifKindMacVTap)
+	if err != nil {
+		return nil, err
+	}
 	link := &netlink.Macvtap{
 		Macvlan: netlink.Macvlan{
 			LinkAttrs: netlink.LinkAttrs{
@@ -126,6 +132,7 @@ func setupMacVTapDevice(podID types.UUID, config MacVTapNetConf, interfaceNumber
 	}
 
 	if err := netlink.LinkAdd(link); err != nil {
+		releaseIfName(interfaceName)
 		return nil, errwrap.Wrap(errors.New("cannot create macvtap interface"), err)
 	}
 
@@ -134,12 +141,14 @@ func setupMacVTapDevice(podID types.UUID, config MacVTapNetConf, interfaceNumber
 	if _, err := cnisysctl.Sysctl(ipv4SysctlValueName, "1"); err != nil {
 		// remove the newly added link and ignore errors, because we already are in a failed state
 		_ = netlink.LinkDel(link)
+		releaseIfName(interfaceName)
 		return nil, errwrap.Wrap(fmt.Errorf("failed to set proxy_arp on newly added interface %q", interfaceName), err)
 	}
 
 	if err := netlink.LinkSetUp(link); err != nil {
 		// remove the newly added link and ignore errors, because we already are in a failed state
 		_ = netlink.LinkDel(link)
+		releaseIfName(interfaceName)
 		return nil, errwrap.Wrap(errors.New("cannot set up macvtap interface"), err)
 	}
 	return link, nil
@@ -173,6 +182,7 @@ func kvmSetupNetAddressing(network *Networking, n activeNet, ifName string) erro
 	}
 
 	n.runtime.MergeCNIResult(result)
+	network.recordLeaseOrWarn(&n)
 
 	return nil
 }
@@ -458,6 +468,14 @@ func kvmSetup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, n
 		return nil, errwrap.Wrap(errors.New("error loading network definitions"), e)
 	}
 
+	if err := network.ensureDHCPDaemon(network.nets); err != nil {
+		return nil, err
+	}
+
+	if err := ensureVLANs(network.nets); err != nil {
+		return nil, err
+	}
+
 	// did stage0 already make /etc/rkt-resolv.conf (i.e. --dns passed)
 	resolvPath := filepath.Join(common.Stage1RootfsPath(podRoot), "etc/rkt-resolv.conf")
 	_, err = os.Stat(resolvPath)
@@ -474,7 +492,7 @@ func kvmSetup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, n
 		}
 		switch n.conf.Type {
 		case "ptp":
-			link, err := setupTapDevice(podID)
+			link, err := network.setupTapDevice()
 			if err != nil {
 				return nil, err
 			}
@@ -506,6 +524,10 @@ func kvmSetup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, n
 				return nil, errwrap.Wrap(errors.New("cannot add on host direct route to pod"), err)
 			}
 
+			if err := enableProxyARP(ifName); err != nil {
+				return nil, err
+			}
+
 		case "bridge":
 			config := BridgeNetConf{
 				NetConf: NetConf{
@@ -521,15 +543,17 @@ func kvmSetup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, n
 			if err != nil {
 				return nil, errwrap.Wrap(errors.New("error in time of bridge setup"), err)
 			}
-			link, err := setupTapDevice(podID)
+			link, err := network.setupTapDevice()
 			if err != nil {
 				return nil, errwrap.Wrap(errors.New("can not setup tap device"), err)
 			}
 			err = netlink.LinkSetMaster(link, br)
 			if err != nil {
-				rErr := tuntap.RemovePersistentIface(n.runtime.IfName, tuntap.Tap)
+				rErr := tuntap.RemovePersistentIface(link.Attrs().Name, tuntap.Tap)
 				if rErr != nil {
-					stderr.PrintE("warning: could not cleanup tap interface", rErr)
+					stderr.WarnE("could not cleanup tap interface", rErr)
+				} else {
+					releaseIfName(link.Attrs().Name)
 				}
 				return nil, errwrap.Wrap(errors.New("can not add tap interface to bridge"), err)
 			}
@@ -537,6 +561,12 @@ func kvmSetup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, n
 			ifName := link.Attrs().Name
 			n.runtime.IfName = ifName
 
+			if config.Multicast != nil {
+				if err := setupBridgeMulticast(config.BrName, ifName, config.Multicast); err != nil {
+					return nil, errwrap.Wrap(fmt.Errorf("cannot set up multicast on network %q", n.conf.Name), err)
+				}
+			}
+
 			err = kvmSetupNetAddressing(&network, n, ifName)
 			if err != nil {
 				return nil, err
@@ -570,7 +600,7 @@ func kvmSetup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, n
 			if err := json.Unmarshal(n.confBytes, &config); err != nil {
 				return nil, errwrap.Wrap(fmt.Errorf("error parsing %q result", n.conf.Name), err)
 			}
-			link, err := setupMacVTapDevice(podID, config, i)
+			link, err := network.setupMacVTapDevice(config)
 			if err != nil {
 				return nil, err
 			}
@@ -609,7 +639,7 @@ func kvmSetup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, n
 				}
 				podHasResolvConf = true
 			} else {
-				stderr.Printf("Warning: network %v plugin specified DNS configuration, but DNS already supplied", n.conf.Name)
+				stderr.Warnf("network %v plugin specified DNS configuration, but DNS already supplied", n.conf.Name)
 			}
 		}
 
@@ -659,7 +689,11 @@ func (n *Networking) teardownKvmNets() {
 		switch an.conf.Type {
 		case "ptp", "bridge":
 			// remove tuntap interface
-			tuntap.RemovePersistentIface(an.runtime.IfName, tuntap.Tap)
+			if err := tuntap.RemovePersistentIface(an.runtime.IfName, tuntap.Tap); err != nil {
+				stderr.PrintE(fmt.Sprintf("cannot remove tap interface `%v`", an.runtime.IfName), err)
+			} else {
+				releaseIfName(an.runtime.IfName)
+			}
 
 		case "macvlan":
 			link, err := netlink.LinkByName(an.runtime.IfName)
@@ -672,6 +706,7 @@ func (n *Networking) teardownKvmNets() {
 					stderr.PrintE(fmt.Sprintf("cannot remove link `%v`", an.runtime.IfName), err)
 					continue
 				}
+				releaseIfName(an.runtime.IfName)
 			}
 
 		default:
@@ -747,6 +782,19 @@ func (an activeNet) Gateway() net.IP {
 func (an activeNet) Routes() []cnitypes.Route {
 	return an.runtime.IP4.Routes
 }
+func (an activeNet) ExtraRoutes() []Route {
+	return an.extraRoutes
+}
+
+// GuestIfName returns the name set with "ifName" for the interface of the
+// network in the pod, if any.
+func (an activeNet) GuestIfName() string {
+	return an.conf.IfName
+}
+
+func (an activeNet) SourceRouting() bool {
+	return an.conf.SourceRouting
+}
 
 // GetActiveNetworks returns activeNets to be used as NetDescriptors
 // by plugins, which are required for stage1 executor to run (only for KVM)
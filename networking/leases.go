@@ -0,0 +1,197 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+
+	"github.com/rkt/rkt/pkg/lock"
+)
+
+const (
+	// hostLocalDataDir is where the host-local IPAM plugin keeps its
+	// leases, in a directory per network holding a file per address,
+	// which contains the ID of the pod.
+	hostLocalDataDir = "/var/lib/cni/networks"
+	hostLocalType    = "host-local"
+
+	// LeasesDir is where rkt records the host-local leases of its pods,
+	// whatever their flavor, in a file per pod.
+	LeasesDir = "/var/lib/cni/rkt-leases"
+)
+
+// lease is an address leased by the host-local IPAM plugin.
+type lease struct {
+	Network string `json:"network"`
+	IP      net.IP `json:"ip"`
+}
+
+// path returns the file of the lease in the host-local data directory.
+func (l lease) path() string {
+	return filepath.Join(hostLocalDataDir, l.Network, l.IP.String())
+}
+
+// podLeases are the leases of a pod, recorded until `rkt gc` finds that
+// the pod does not exist anymore.
+type podLeases struct {
+	// DataDir is the rkt data directory of the pod.
+	DataDir string  `json:"dataDir"`
+	Leases  []lease `json:"leases"`
+}
+
+func loadPodLeases(path string) (*podLeases, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pl := &podLeases{}
+	if err := json.Unmarshal(b, pl); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error parsing %q", path), err)
+	}
+	return pl, nil
+}
+
+// dataDir returns the rkt data directory of the pod, which is in
+// DATADIR/pods/STATE/UUID.
+func (e *podEnv) dataDir() (string, error) {
+	podRoot, err := filepath.Abs(e.podRoot)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Dir(filepath.Dir(filepath.Dir(podRoot))), nil
+}
+
+// recordLease records the address leased to the pod on a network, if its
+// IPAM plugin is host-local.
+func (e *podEnv) recordLease(n *activeNet) error {
+	if n.conf.IPAM.Type != hostLocalType || n.runtime.IP == nil {
+		return nil
+	}
+
+	dataDir, err := e.dataDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(LeasesDir, 0700); err != nil {
+		return err
+	}
+
+	path := filepath.Join(LeasesDir, e.podID.String())
+	pl, err := loadPodLeases(path)
+	if os.IsNotExist(err) {
+		pl, err = &podLeases{DataDir: dataDir}, nil
+	}
+	if err != nil {
+		return err
+	}
+	pl.Leases = append(pl.Leases, lease{
+		Network: n.conf.Name,
+		IP:      n.runtime.IP,
+	})
+
+	b, err := json.Marshal(pl)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0600)
+}
+
+// recordLeaseOrWarn records a lease, only warning on failure as the pod
+// works without the record.
+func (e *podEnv) recordLeaseOrWarn(n *activeNet) {
+	if err := e.recordLease(n); err != nil {
+		stderr.WarnE(fmt.Sprintf("network %q: cannot record the lease of %v", n.conf.Name, n.runtime.IP), err)
+	}
+}
+
+// GCLeases releases the host-local leases of the pods of a rkt data
+// directory which do not exist anymore, like the ones which were removed
+// without tearing down their networks. It returns the released leases.
+func GCLeases(dataDir string, podExists func(uuid string) bool) ([]string, error) {
+	files, err := ioutil.ReadDir(LeasesDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error listing the recorded leases"), err)
+	}
+
+	var released []string
+	for _, f := range files {
+		uuid := f.Name()
+		path := filepath.Join(LeasesDir, uuid)
+		pl, err := loadPodLeases(path)
+		if err != nil {
+			// Not a record of ours, or a partial one.
+			continue
+		}
+		if pl.DataDir != dataDir || podExists(uuid) {
+			continue
+		}
+
+		for _, l := range pl.Leases {
+			ok, err := releaseLease(l, uuid)
+			if err != nil {
+				return released, errwrap.Wrap(fmt.Errorf("error releasing the lease of %v on network %q", l.IP, l.Network), err)
+			}
+			if ok {
+				released = append(released, fmt.Sprintf("%s:%s", l.Network, l.IP))
+			}
+		}
+		if err := os.Remove(path); err != nil {
+			return released, err
+		}
+	}
+	return released, nil
+}
+
+// releaseLease removes the file of a lease, if it still belongs to the
+// pod, under the lock the host-local plugin takes on the network.
+func releaseLease(l lease, uuid string) (bool, error) {
+	netDir := filepath.Dir(l.path())
+	nl, err := lock.ExclusiveLock(netDir, lock.Dir)
+	if err == lock.ErrNotExist {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer nl.Close()
+
+	id, err := ioutil.ReadFile(l.path())
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	// The address may have been leased to another pod since.
+	if strings.TrimSpace(string(id)) != uuid {
+		return false, nil
+	}
+	if err := os.Remove(l.path()); err != nil {
+		return false, err
+	}
+	return true, nil
+}
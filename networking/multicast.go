@@ -0,0 +1,129 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/containernetworking/cni/pkg/ns"
+	"github.com/hashicorp/errwrap"
+	"github.com/vishvananda/netlink"
+)
+
+const sysClassNet = "/sys/class/net"
+
+// MulticastConf is the multicast configuration of a bridge network.
+type MulticastConf struct {
+	// Snooping makes the bridge forward the multicast traffic only to the
+	// ports with listeners, learnt from their IGMP reports. Without it,
+	// the kernel default applies, which is on.
+	Snooping *bool `json:"snooping,omitempty"`
+	// Querier makes the bridge send the IGMP queries, for networks
+	// without a multicast router.
+	Querier bool `json:"querier"`
+	// Forward makes the bridge forward all the multicast traffic to the
+	// pods, whatever their IGMP reports.
+	Forward bool `json:"forward"`
+}
+
+func writeSysClassNet(ifName, attr, value string) error {
+	path := filepath.Join(sysClassNet, ifName, attr)
+	if err := ioutil.WriteFile(path, []byte(value), 0644); err != nil {
+		return errwrap.Wrap(fmt.Errorf("cannot write %q to %s", value, path), err)
+	}
+	return nil
+}
+
+func boolAttr(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+// setupBridgeMulticast configures multicast on a bridge and on the port of
+// the pod on it.
+func setupBridgeMulticast(brName, portName string, mc *MulticastConf) error {
+	if mc.Snooping != nil {
+		if err := writeSysClassNet(brName, "bridge/multicast_snooping", boolAttr(*mc.Snooping)); err != nil {
+			return err
+		}
+	}
+	if mc.Querier {
+		if err := writeSysClassNet(brName, "bridge/multicast_querier", "1"); err != nil {
+			return err
+		}
+	}
+	if mc.Forward {
+		// The port is then handled as a multicast router's, to which
+		// the bridge forwards all the multicast traffic.
+		if err := writeSysClassNet(portName, "brport/multicast_router", "2"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setupMulticast configures multicast on the bridges of the networks with
+// a multicast configuration. It must be called in the host network
+// namespace.
+func (e *podEnv) setupMulticast(nets []activeNet) error {
+	for _, n := range nets {
+		if n.conf.Type != "bridge" {
+			continue
+		}
+		config := BridgeNetConf{BrName: defaultBrName}
+		if err := json.Unmarshal(n.confBytes, &config); err != nil {
+			return errwrap.Wrap(fmt.Errorf("error parsing %q configuration", n.conf.Name), err)
+		}
+		if config.Multicast == nil {
+			continue
+		}
+
+		port, err := e.hostVethPeer(n.runtime.IfName)
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot find the bridge port of network %q", n.conf.Name), err)
+		}
+		if err := setupBridgeMulticast(config.BrName, port, config.Multicast); err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot set up multicast on network %q", n.conf.Name), err)
+		}
+	}
+	return nil
+}
+
+// hostVethPeer returns the name of the host end of the veth of the pod
+// named ifName.
+func (e *podEnv) hostVethPeer(ifName string) (string, error) {
+	peerIndex := 0
+	if err := e.podNS.Do(func(ns.NetNS) error {
+		link, err := netlink.LinkByName(ifName)
+		if err != nil {
+			return err
+		}
+		peerIndex = link.Attrs().ParentIndex
+		return nil
+	}); err != nil {
+		return "", err
+	}
+
+	peer, err := netlink.LinkByIndex(peerIndex)
+	if err != nil {
+		return "", err
+	}
+	return peer.Attrs().Name, nil
+}
@@ -22,6 +22,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"time"
 
 	cnitypes "github.com/containernetworking/cni/pkg/types"
 	"github.com/hashicorp/errwrap"
@@ -33,6 +34,75 @@ import (
 const UserNetPluginsPath = "/usr/lib/rkt/plugins/net"
 const BuiltinNetPluginsPath = "usr/lib/rkt/plugins/net"
 
+// Defaults of the retries of the failed network plugins.
+const (
+	defaultRetryInitialBackoff = time.Second
+	defaultRetryMaxBackoff     = 30 * time.Second
+)
+
+// RetryConf configures the retries of a network plugin failing to add a
+// network, like on DHCP timeouts.
+type RetryConf struct {
+	// Attempts is the maximum number of executions of the plugin.
+	Attempts int `json:"attempts"`
+	// InitialBackoff is the delay before the first retry, doubled after
+	// each retry up to MaxBackoff.
+	InitialBackoff string `json:"initialBackoff"`
+	MaxBackoff     string `json:"maxBackoff"`
+}
+
+// retryPolicy is a parsed RetryConf.
+type retryPolicy struct {
+	attempts       int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// policy parses the retry configuration. Without one, a plugin is
+// executed only once.
+func (r *RetryConf) policy() (*retryPolicy, error) {
+	p := &retryPolicy{
+		attempts:       1,
+		initialBackoff: defaultRetryInitialBackoff,
+		maxBackoff:     defaultRetryMaxBackoff,
+	}
+	if r == nil {
+		return p, nil
+	}
+
+	if r.Attempts < 1 {
+		return nil, fmt.Errorf("invalid number of attempts %d, expected at least 1", r.Attempts)
+	}
+	p.attempts = r.Attempts
+	if r.InitialBackoff != "" {
+		d, err := time.ParseDuration(r.InitialBackoff)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid initial backoff %q", r.InitialBackoff)
+		}
+		p.initialBackoff = d
+	}
+	if r.MaxBackoff != "" {
+		d, err := time.ParseDuration(r.MaxBackoff)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid maximum backoff %q", r.MaxBackoff)
+		}
+		p.maxBackoff = d
+	}
+	if p.initialBackoff > p.maxBackoff {
+		return nil, fmt.Errorf("initial backoff %v greater than maximum backoff %v", p.initialBackoff, p.maxBackoff)
+	}
+	return p, nil
+}
+
+// pluginError is the error reported by a network plugin which failed.
+type pluginError struct {
+	msg string
+}
+
+func (e *pluginError) Error() string {
+	return e.msg
+}
+
 func pluginErr(err error, output []byte) error {
 	if _, ok := err.(*exec.ExitError); ok {
 		emsg := cnitypes.Error{}
@@ -43,7 +113,7 @@ func pluginErr(err error, output []byte) error {
 		if emsg.Details != "" {
 			details = fmt.Sprintf("; %v", emsg.Details)
 		}
-		return fmt.Errorf("%v%v", emsg.Msg, details)
+		return &pluginError{fmt.Sprintf("%v%v", emsg.Msg, details)}
 	}
 
 	return err
@@ -72,6 +142,35 @@ func (e *podEnv) netPluginAdd(n *activeNet, netns string) error {
 	return nil
 }
 
+// netPluginAddWithRetry executes netPluginAdd, retrying the failures of the
+// plugin with an exponential backoff as configured in the network. Before
+// each retry, the plugin is executed with DEL, to clean up the interfaces
+// and addresses the failed attempt may have configured.
+func (e *podEnv) netPluginAddWithRetry(n *activeNet, netns string) error {
+	p, err := n.conf.Retry.policy()
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("invalid retry configuration of network %q", n.conf.Name), err)
+	}
+
+	backoff := p.initialBackoff
+	for attempt := 1; ; attempt++ {
+		err = e.netPluginAdd(n, netns)
+		if _, ok := err.(*pluginError); !ok || attempt >= p.attempts {
+			return err
+		}
+
+		stderr.WarnE(fmt.Sprintf("network %q: plugin %q failed (attempt %d of %d), retrying in %v", n.conf.Name, n.conf.Type, attempt, p.attempts, backoff), err)
+		if err := e.netPluginDel(n, netns); err != nil {
+			stderr.Debugf("network %q: cleanup after the failed attempt failed: %v", n.conf.Name, err)
+		}
+
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > p.maxBackoff {
+			backoff = p.maxBackoff
+		}
+	}
+}
+
 func (e *podEnv) netPluginDel(n *activeNet, netns string) error {
 	output, err := e.execNetPlugin("DEL", n, netns)
 	if err != nil {
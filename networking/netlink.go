@@ -0,0 +1,111 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/vishvananda/netlink/nl"
+)
+
+// mainTable is the main routing table, the one used without policy routing.
+const mainTable = syscall.RT_TABLE_MAIN
+
+// Routing rule attributes and actions, missing from the syscall package.
+const (
+	fraSrc      = 2
+	fraPriority = 6
+	fraTable    = 15
+	frActToTbl  = 1
+)
+
+// ipData returns the bytes of an IP address in the given family.
+func ipData(ip net.IP, family int) []byte {
+	if family == nl.FAMILY_V4 {
+		return ip.To4()
+	}
+	return ip.To16()
+}
+
+// addTableRoute adds a route through a link to a routing table, like
+// "ip route add DST [via GW] dev LINK [metric METRIC] table TABLE". The
+// vendored netlink package supports neither metrics nor tables.
+func addTableRoute(linkIndex int, r Route, table int) error {
+	family := nl.GetIPFamily(r.Dst.IP)
+	if r.GW != nil && nl.GetIPFamily(r.GW) != family {
+		return fmt.Errorf("route %q: the gateway and the destination are not the same IP family", r)
+	}
+
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWROUTE, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	msg := nl.NewRtMsg()
+	msg.Family = uint8(family)
+	dstLen, _ := r.Dst.Mask.Size()
+	msg.Dst_len = uint8(dstLen)
+	if r.GW == nil {
+		msg.Scope = syscall.RT_SCOPE_LINK
+	}
+	if table < 256 {
+		msg.Table = uint8(table)
+	} else {
+		msg.Table = syscall.RT_TABLE_UNSPEC
+	}
+	req.AddData(msg)
+
+	req.AddData(nl.NewRtAttr(syscall.RTA_DST, ipData(r.Dst.IP, family)))
+	if r.GW != nil {
+		req.AddData(nl.NewRtAttr(syscall.RTA_GATEWAY, ipData(r.GW, family)))
+	}
+	if r.Metric != 0 {
+		req.AddData(nl.NewRtAttr(syscall.RTA_PRIORITY, nl.Uint32Attr(uint32(r.Metric))))
+	}
+	if table >= 256 {
+		req.AddData(nl.NewRtAttr(syscall.RTA_TABLE, nl.Uint32Attr(uint32(table))))
+	}
+	req.AddData(nl.NewRtAttr(syscall.RTA_OIF, nl.Uint32Attr(uint32(linkIndex))))
+
+	_, err := req.Execute(syscall.NETLINK_ROUTE, 0)
+	return err
+}
+
+// addSourceRule adds a routing rule looking up a table for the packets
+// from a source, like "ip rule add from SRC table TABLE priority PRIORITY".
+func addSourceRule(src net.IPNet, table, priority int) error {
+	family := nl.GetIPFamily(src.IP)
+
+	req := nl.NewNetlinkRequest(syscall.RTM_NEWRULE, syscall.NLM_F_CREATE|syscall.NLM_F_EXCL|syscall.NLM_F_ACK)
+	// struct fib_rule_hdr has the layout of struct rtmsg.
+	msg := nl.NewRtMsg()
+	msg.Family = uint8(family)
+	srcLen, _ := src.Mask.Size()
+	msg.Src_len = uint8(srcLen)
+	msg.Protocol = 0
+	msg.Scope = 0
+	msg.Type = frActToTbl
+	if table < 256 {
+		msg.Table = uint8(table)
+	} else {
+		msg.Table = syscall.RT_TABLE_UNSPEC
+	}
+	req.AddData(msg)
+
+	req.AddData(nl.NewRtAttr(fraSrc, ipData(src.IP, family)))
+	req.AddData(nl.NewRtAttr(fraPriority, nl.Uint32Attr(uint32(priority))))
+	req.AddData(nl.NewRtAttr(fraTable, nl.Uint32Attr(uint32(table))))
+
+	_, err := req.Execute(syscall.NETLINK_ROUTE, 0)
+	return err
+}
@@ -39,6 +39,12 @@ const (
 	IfNamePattern       = "eth%d"
 	selfNetNS           = "/proc/self/ns/net"
 	mountNetnsDirectory = "/var/run/netns"
+
+	// ifNameArg is the argument of a network given with --net naming its
+	// interface in the pod.
+	ifNameArg = "ifname"
+	// maxIfNameLen is the kernel limit on the length of interface names.
+	maxIfNameLen = 15
 )
 
 // Networking describes the networking details of a pod.
@@ -52,24 +58,27 @@ type Networking struct {
 // similar to CNI plugins
 type NetConf struct {
 	cnitypes.NetConf
-	IPMasq           bool `json:"ipMasq"`
-	MTU              int  `json:"mtu"`
-	IsDefaultGateway bool `json:"isDefaultGateway"`
+	IPMasq           bool        `json:"ipMasq"`
+	MTU              int         `json:"mtu"`
+	IsDefaultGateway bool        `json:"isDefaultGateway"`
+	Retry            *RetryConf  `json:"retry,omitempty"`
+	ExtraRoutes      []RouteConf `json:"extraRoutes,omitempty"`
+	SourceRouting    bool        `json:"sourceRouting"`
+	IfName           string      `json:"ifName,omitempty"`
 }
 
-var (
-	stderr   *log.Logger
-	debuglog bool
-)
+var stderr *log.Logger
 
 // Setup creates a new networking namespace and executes network plugins to
 // set up networking. It returns in the new pod namespace
 func Setup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, netList common.NetList, localConfig, flavor string, noDNS, debug bool) (*Networking, error) {
 
 	stderr = log.New(os.Stderr, "networking", debug)
-	debuglog = debug
 
 	if flavor == "kvm" {
+		if netList.HostIfaces() {
+			return nil, errors.New("host interfaces are not supported by the kvm flavor")
+		}
 		return kvmSetup(podRoot, podID, fps, netList, localConfig, noDNS)
 	}
 
@@ -95,15 +104,34 @@ func Setup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, netL
 		return nil, err
 	}
 
+	if netList.HostIfaces() {
+		if err := n.setupHostIfaces(); err != nil {
+			return nil, err
+		}
+	}
+
 	n.nets, err = n.loadNets()
 	if err != nil {
 		return nil, errwrap.Wrap(errors.New("error loading network definitions"), err)
 	}
 
+	if err := n.ensureDHCPDaemon(n.nets); err != nil {
+		return nil, err
+	}
+
+	if err := ensureVLANs(n.nets); err != nil {
+		return nil, err
+	}
+
 	if err := n.setupNets(n.nets, noDNS); err != nil {
 		return nil, err
 	}
 
+	if err := n.setupMulticast(n.nets); err != nil {
+		n.teardownNets(n.nets)
+		return nil, err
+	}
+
 	if len(fps) > 0 {
 		if err = n.enableDefaultLocalnetRouting(); err != nil {
 			return nil, err
@@ -131,6 +159,16 @@ func Setup(podRoot string, podID types.UUID, fps []commonnet.ForwardedPort, netL
 		return nil, err
 	}
 
+	if err = n.addExtraRoutes(n.nets); err != nil {
+		return nil, err
+	}
+
+	if err = n.addSourceRoutes(n.nets); err != nil {
+		return nil, err
+	}
+
+	n.announceIPs(n.nets)
+
 	return &n, nil
 }
 
@@ -271,7 +309,6 @@ func (n *Networking) GetIfacesByIP(ifaceIP net.IP) ([]net.Interface, error) {
 func (n *Networking) Teardown(flavor string, debug bool) {
 
 	stderr = log.New(os.Stderr, "networking", debug)
-	debuglog = debug
 
 	// Teardown everything in reverse order of setup.
 	// This should be idempotent -- be tolerant of missing stuff
@@ -291,6 +328,7 @@ func (n *Networking) Teardown(flavor string, debug bool) {
 	}
 
 	n.teardownNets(n.nets)
+	n.teardownHostIfaces()
 	n.podNSDestroy()
 }
 
@@ -54,9 +54,12 @@ type podEnv struct {
 }
 
 type activeNet struct {
-	confBytes []byte
-	conf      *NetConf
-	runtime   *netinfo.NetInfo
+	confBytes   []byte
+	conf        *NetConf
+	runtime     *netinfo.NetInfo
+	extraRoutes []Route
+	// vlan is the configuration of a vlan network, set up as a bridge one
+	vlan *VLANNetConf
 }
 
 type byFilename []activeNet
@@ -95,12 +98,85 @@ func (e *podEnv) loadNets() ([]activeNet, error) {
 
 	// Add the runtime args to the network instances.
 	// We don't do this earlier because we also load networks in other contexts
-	for _, n := range nets {
-		n.runtime.Args = e.netsLoadList.SpecificArgs(n.conf.Name)
+	for i := range netSlice {
+		n := &netSlice[i]
+		args, ifName := splitIfNameArg(e.netsLoadList.SpecificArgs(n.conf.Name))
+		args, routes, err := splitRouteArgs(args)
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("invalid arguments of network %q", n.conf.Name), err)
+		}
+		n.runtime.Args = args
+		n.extraRoutes = append(n.extraRoutes, routes...)
+		if ifName != "" {
+			n.conf.IfName = ifName
+		}
+	}
+	if err := checkIfNames(netSlice); err != nil {
+		return nil, err
 	}
 	return netSlice, nil
 }
 
+// splitIfNameArg splits the arguments of a network given with --net into
+// the ones for its plugin and the name of its interface in the pod.
+func splitIfNameArg(args string) (string, string) {
+	if args == "" {
+		return "", ""
+	}
+
+	var pluginArgs []string
+	var ifName string
+	for _, arg := range strings.Split(args, ";") {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) == 2 && kv[0] == ifNameArg {
+			ifName = kv[1]
+			continue
+		}
+		pluginArgs = append(pluginArgs, arg)
+	}
+	return strings.Join(pluginArgs, ";"), ifName
+}
+
+// PodIfName returns the name of the interface in the pod of the i-th
+// network, which is the one set with "ifName", or ethN.
+func PodIfName(ifName string, i int) string {
+	if ifName != "" {
+		return ifName
+	}
+	return fmt.Sprintf(IfNamePattern, i)
+}
+
+// checkIfNames checks that the interfaces of the networks in the pod have
+// valid and distinct names.
+func checkIfNames(nets []activeNet) error {
+	seen := make(map[string]string)
+	for i, n := range nets {
+		name := PodIfName(n.conf.IfName, i)
+		if err := validateIfName(name); err != nil {
+			return errwrap.Wrap(fmt.Errorf("invalid interface name of network %q", n.conf.Name), err)
+		}
+		if other, ok := seen[name]; ok {
+			return fmt.Errorf("networks %q and %q have the same interface name %q", other, n.conf.Name, name)
+		}
+		seen[name] = n.conf.Name
+	}
+	return nil
+}
+
+// validateIfName checks that name is a valid name for a network interface
+// of the pod.
+func validateIfName(name string) error {
+	switch {
+	case len(name) > maxIfNameLen:
+		return fmt.Errorf("%q is longer than %d characters", name, maxIfNameLen)
+	case name == "." || name == ".." || name == "lo":
+		return fmt.Errorf("%q is reserved", name)
+	case strings.ContainsAny(name, "/: \t\n"):
+		return fmt.Errorf("%q contains a slash, a colon or a space", name)
+	}
+	return nil
+}
+
 // Ensure the netns directory is mounted before adding new netns like `ip netns add <netns>` command does.
 // See https://github.com/kubernetes/kubernetes/issues/48427
 // Make it possible for network namespace mounts to propagate between mount namespaces.
@@ -261,20 +337,19 @@ func (e *podEnv) setupNets(nets []activeNet, noDNS bool) error {
 	podHasResolvConf := err == nil
 
 	for i, n = range nets {
-		if debuglog {
-			stderr.Printf("loading network %v with type %v", n.conf.Name, n.conf.Type)
-		}
+		stderr.Debugf("loading network %v with type %v", n.conf.Name, n.conf.Type)
 
-		n.runtime.IfName = fmt.Sprintf(IfNamePattern, i)
+		n.runtime.IfName = PodIfName(n.conf.IfName, i)
 		if n.runtime.ConfPath, err = copyFileToDir(n.runtime.ConfPath, e.netDir()); err != nil {
 			return errwrap.Wrap(fmt.Errorf("error copying %q to %q", n.runtime.ConfPath, e.netDir()), err)
 		}
 
 		// Actually shell out to the plugin
-		err = e.netPluginAdd(&n, e.podNS.Path())
+		err = e.netPluginAddWithRetry(&n, e.podNS.Path())
 		if err != nil {
 			return errwrap.Wrap(fmt.Errorf("error adding network %q", n.conf.Name), err)
 		}
+		e.recordLeaseOrWarn(&n)
 
 		// Generate rkt-resolv.conf if it's not already there.
 		// The first network plugin that supplies a non-empty
@@ -290,7 +365,7 @@ func (e *podEnv) setupNets(nets []activeNet, noDNS bool) error {
 				}
 				podHasResolvConf = true
 			} else {
-				stderr.Printf("Warning: network %v plugin specified DNS configuration, but DNS already supplied", n.conf.Name)
+				stderr.Warnf("network %v plugin specified DNS configuration, but DNS already supplied", n.conf.Name)
 			}
 		}
 	}
@@ -299,9 +374,7 @@ func (e *podEnv) setupNets(nets []activeNet, noDNS bool) error {
 
 func (e *podEnv) teardownNets(nets []activeNet) {
 	for i := len(nets) - 1; i >= 0; i-- {
-		if debuglog {
-			stderr.Printf("teardown - executing net-plugin %v", nets[i].conf.Type)
-		}
+		stderr.Debugf("teardown - executing net-plugin %v", nets[i].conf.Type)
 
 		podNSpath := ""
 		if e.podNS != nil {
@@ -348,9 +421,7 @@ func (l *netLoader) loadNets(netsLoadList common.NetList) (map[string]activeNet,
 		parentNets = make(map[string]activeNet)
 	}
 
-	if debuglog {
-		stderr.Printf("loading networks from %v", l.configPath)
-	}
+	stderr.Debugf("loading networks from %v", l.configPath)
 
 	files, err := listFiles(l.configPath)
 	if err != nil {
@@ -396,7 +467,7 @@ func (l *netLoader) loadNets(netsLoadList common.NetList) (map[string]activeNet,
 func missingNets(defined common.NetList, loaded []activeNet) []string {
 	diff := make(map[string]struct{})
 	for _, n := range defined.StringsOnlyNames() {
-		if n != "all" {
+		if n != "all" && n != "host" {
 			diff[n] = struct{}{}
 		}
 	}
@@ -444,6 +515,27 @@ func loadNet(filepath string) (*activeNet, error) {
 	if err = json.Unmarshal(bytes, n); err != nil {
 		return nil, errwrap.Wrap(fmt.Errorf("error loading %v", filepath), err)
 	}
+	var vlan *VLANNetConf
+	if n.Type == vlanType {
+		if bytes, vlan, err = transformVLANNetwork(bytes); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("invalid vlan network in %v", filepath), err)
+		}
+		n = &NetConf{}
+		if err = json.Unmarshal(bytes, n); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("error loading %v", filepath), err)
+		}
+	}
+	if _, err = n.Retry.policy(); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("invalid retry configuration in %v", filepath), err)
+	}
+	var routes []Route
+	for _, rc := range n.ExtraRoutes {
+		r, err := rc.route()
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("invalid extra route in %v", filepath), err)
+		}
+		routes = append(routes, *r)
+	}
 
 	return &activeNet{
 		confBytes: bytes,
@@ -452,6 +544,8 @@ func loadNet(filepath string) (*activeNet, error) {
 			NetName:  n.Name,
 			ConfPath: filepath,
 		},
+		extraRoutes: routes,
+		vlan:        vlan,
 	}, nil
 }
 
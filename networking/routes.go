@@ -0,0 +1,215 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/vishvananda/netlink"
+)
+
+// The routing tables and the priorities of the rules of the networks using
+// source-based routing are numbered after these, in the order of the
+// networks.
+const (
+	SourceRoutingTableBase    = 100
+	SourceRoutingPriorityBase = 100
+)
+
+// routeArg is the key of the --net arguments declaring extra routes, which
+// are not passed to the network plugins.
+const routeArg = "ROUTE"
+
+// RouteConf is an extra route of a network, as declared in its
+// configuration.
+type RouteConf struct {
+	Dst    string `json:"dst"`
+	GW     string `json:"gw,omitempty"`
+	Metric int    `json:"metric,omitempty"`
+}
+
+// Route is an extra route of a network, added in the pod once the network
+// is set up. Without a gateway, the destination is reachable directly on
+// the interface of the network.
+type Route struct {
+	Dst    net.IPNet
+	GW     net.IP
+	Metric int
+}
+
+func (r Route) String() string {
+	s := r.Dst.String()
+	if r.GW != nil {
+		s += " via " + r.GW.String()
+	}
+	if r.Metric != 0 {
+		s += " metric " + strconv.Itoa(r.Metric)
+	}
+	return s
+}
+
+func newRoute(dst, gw string, metric int) (*Route, error) {
+	r := &Route{Metric: metric}
+	if dst == "default" {
+		dst = "0.0.0.0/0"
+	}
+	_, d, err := net.ParseCIDR(dst)
+	if err != nil {
+		return nil, fmt.Errorf("invalid route destination %q", dst)
+	}
+	r.Dst = *d
+	if gw != "" {
+		if r.GW = net.ParseIP(gw); r.GW == nil {
+			return nil, fmt.Errorf("invalid route gateway %q", gw)
+		}
+	}
+	if metric < 0 {
+		return nil, fmt.Errorf("invalid route metric %d", metric)
+	}
+	return r, nil
+}
+
+func (c RouteConf) route() (*Route, error) {
+	return newRoute(c.Dst, c.GW, c.Metric)
+}
+
+// ParseRoute parses a route in the syntax of ip-route(8), limited to
+// "DST [via GW] [metric METRIC]", where DST is a CIDR or "default".
+func ParseRoute(s string) (*Route, error) {
+	fields := strings.Fields(s)
+	if len(fields) == 0 || len(fields)%2 == 0 {
+		return nil, fmt.Errorf("invalid route %q, expected DST [via GW] [metric METRIC]", s)
+	}
+
+	gw := ""
+	metric := 0
+	for i := 1; i < len(fields); i += 2 {
+		switch fields[i] {
+		case "via":
+			gw = fields[i+1]
+		case "metric":
+			m, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid route metric %q", fields[i+1])
+			}
+			metric = m
+		default:
+			return nil, fmt.Errorf("invalid route %q, unknown keyword %q", s, fields[i])
+		}
+	}
+	return newRoute(fields[0], gw, metric)
+}
+
+// splitRouteArgs splits the arguments of a network given with --net into
+// the ones for its plugin and its extra routes.
+func splitRouteArgs(args string) (string, []Route, error) {
+	if args == "" {
+		return "", nil, nil
+	}
+
+	var pluginArgs []string
+	var routes []Route
+	for _, arg := range strings.Split(args, ";") {
+		kv := strings.SplitN(arg, "=", 2)
+		if kv[0] != routeArg {
+			pluginArgs = append(pluginArgs, arg)
+			continue
+		}
+		if len(kv) != 2 {
+			return "", nil, fmt.Errorf("missing value of %s", routeArg)
+		}
+		r, err := ParseRoute(kv[1])
+		if err != nil {
+			return "", nil, err
+		}
+		routes = append(routes, *r)
+	}
+	return strings.Join(pluginArgs, ";"), routes, nil
+}
+
+// addExtraRoutes adds the extra routes of the networks. It must be called
+// in the pod network namespace.
+func (e *podEnv) addExtraRoutes(nets []activeNet) error {
+	for _, n := range nets {
+		if len(n.extraRoutes) == 0 {
+			continue
+		}
+		link, err := netlink.LinkByName(n.runtime.IfName)
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot find the interface %q of network %q", n.runtime.IfName, n.conf.Name), err)
+		}
+		for _, r := range n.extraRoutes {
+			if err := addTableRoute(link.Attrs().Index, r, mainTable); err != nil {
+				return errwrap.Wrap(fmt.Errorf("cannot add route %q to network %q", r, n.conf.Name), err)
+			}
+			stderr.Debugf("added route %q to network %q", r, n.conf.Name)
+		}
+	}
+	return nil
+}
+
+// addSourceRoutes sets up the source-based routing of the networks which
+// use it: each one gets a routing table with the route to its subnet, its
+// default route and its extra routes, looked up for the packets from its
+// address, so the replies leave by the interface the requests came in. It
+// must be called in the pod network namespace.
+func (e *podEnv) addSourceRoutes(nets []activeNet) error {
+	for i, n := range nets {
+		if !n.conf.SourceRouting || n.runtime.IP == nil {
+			continue
+		}
+		link, err := netlink.LinkByName(n.runtime.IfName)
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot find the interface %q of network %q", n.runtime.IfName, n.conf.Name), err)
+		}
+
+		table := SourceRoutingTableBase + i
+		for _, r := range sourceRoutes(n) {
+			if err := addTableRoute(link.Attrs().Index, r, table); err != nil {
+				return errwrap.Wrap(fmt.Errorf("cannot add route %q to the table of network %q", r, n.conf.Name), err)
+			}
+		}
+
+		ip, bits := n.runtime.IP, 128
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		src := net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+		if err := addSourceRule(src, table, SourceRoutingPriorityBase+i); err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot add the source routing rule of network %q", n.conf.Name), err)
+		}
+		stderr.Debugf("network %q: routing the packets from %v with table %d", n.conf.Name, src.IP, table)
+	}
+	return nil
+}
+
+// sourceRoutes returns the routes of the source routing table of a
+// network.
+func sourceRoutes(n activeNet) []Route {
+	subnet := net.IPNet{
+		IP:   n.runtime.IP.Mask(net.IPMask(n.runtime.Mask)),
+		Mask: net.IPMask(n.runtime.Mask),
+	}
+	routes := []Route{{Dst: subnet}}
+	if n.runtime.IP4 != nil && n.runtime.IP4.Gateway != nil {
+		_, defaultNet, _ := net.ParseCIDR("0.0.0.0/0")
+		routes = append(routes, Route{Dst: *defaultNet, GW: n.runtime.IP4.Gateway})
+	}
+	return append(routes, n.extraRoutes...)
+}
@@ -0,0 +1,142 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package networking
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	vlanType  = "vlan"
+	maxVLANID = 4094
+)
+
+// VLANNetConf is the configuration of a "vlan" network, whose pods join an
+// 802.1q VLAN of a host interface. The network is set up as a bridge
+// network, on a bridge enslaving the VLAN sub-interface of the host
+// interface, which the pods of the network share.
+type VLANNetConf struct {
+	Master string `json:"master"`
+	VLANID int    `json:"vlanId"`
+	BrName string `json:"bridge"`
+	MTU    int    `json:"mtu"`
+}
+
+// ifName returns the name of the VLAN sub-interface, like eth0.100.
+func (c *VLANNetConf) ifName() string {
+	return fmt.Sprintf("%s.%d", c.Master, c.VLANID)
+}
+
+// transformVLANNetwork turns the configuration of a vlan network into the
+// one of the bridge network it is set up as.
+func transformVLANNetwork(confBytes []byte) ([]byte, *VLANNetConf, error) {
+	vc := &VLANNetConf{}
+	if err := json.Unmarshal(confBytes, vc); err != nil {
+		return nil, nil, err
+	}
+	if vc.Master == "" {
+		return nil, nil, errors.New(`missing "master" interface`)
+	}
+	if vc.VLANID < 1 || vc.VLANID > maxVLANID {
+		return nil, nil, fmt.Errorf("invalid VLAN ID %d, expected 1 to %d", vc.VLANID, maxVLANID)
+	}
+	if len(vc.ifName()) > maxIfNameLen {
+		return nil, nil, fmt.Errorf("the name of the VLAN sub-interface %q is longer than %d characters", vc.ifName(), maxIfNameLen)
+	}
+	if vc.BrName == "" {
+		vc.BrName = fmt.Sprintf("rkt-vlan%d", vc.VLANID)
+	}
+
+	var conf map[string]interface{}
+	if err := json.Unmarshal(confBytes, &conf); err != nil {
+		return nil, nil, err
+	}
+	delete(conf, "master")
+	delete(conf, "vlanId")
+	conf["type"] = "bridge"
+	conf["bridge"] = vc.BrName
+
+	b, err := json.Marshal(conf)
+	if err != nil {
+		return nil, nil, errwrap.Wrap(errors.New("error in marshaling generated network settings"), err)
+	}
+	return b, vc, nil
+}
+
+// ensureVLANBridge creates the VLAN sub-interface and the bridge of a vlan
+// network, unless they exist, and enslaves the former to the latter.
+func ensureVLANBridge(vc *VLANNetConf) error {
+	master, err := netlink.LinkByName(vc.Master)
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("cannot find master device %q", vc.Master), err)
+	}
+	mtu := vc.MTU
+	if mtu == 0 {
+		mtu = master.Attrs().MTU
+	}
+	br, err := ensureBridgeIsUp(vc.BrName, mtu)
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("cannot set up bridge %q", vc.BrName), err)
+	}
+
+	name := vc.ifName()
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		vlan := &netlink.Vlan{
+			LinkAttrs: netlink.LinkAttrs{
+				Name:        name,
+				ParentIndex: master.Attrs().Index,
+			},
+			VlanId: vc.VLANID,
+		}
+		if err := netlink.LinkAdd(vlan); err != nil && err != syscall.EEXIST {
+			return errwrap.Wrap(fmt.Errorf("cannot create VLAN sub-interface %q", name), err)
+		}
+		if link, err = netlink.LinkByName(name); err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot find link %q", name), err)
+		}
+	} else if v, ok := link.(*netlink.Vlan); !ok || v.VlanId != vc.VLANID || v.ParentIndex != master.Attrs().Index {
+		return fmt.Errorf("%q already exists but is not VLAN %d of %q", name, vc.VLANID, vc.Master)
+	}
+
+	if link.Attrs().MasterIndex != br.Attrs().Index {
+		if err := netlink.LinkSetMaster(link, br); err != nil {
+			return errwrap.Wrap(fmt.Errorf("cannot add %q to bridge %q", name, vc.BrName), err)
+		}
+	}
+	if err := netlink.LinkSetUp(link); err != nil {
+		return errwrap.Wrap(fmt.Errorf("cannot set link up %q", name), err)
+	}
+	return nil
+}
+
+// ensureVLANs sets up the host side of the vlan networks.
+func ensureVLANs(nets []activeNet) error {
+	for _, n := range nets {
+		if n.vlan == nil {
+			continue
+		}
+		if err := ensureVLANBridge(n.vlan); err != nil {
+			return errwrap.Wrap(fmt.Errorf("error setting up VLAN %d of network %q", n.vlan.VLANID, n.conf.Name), err)
+		}
+	}
+	return nil
+}
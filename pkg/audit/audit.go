@@ -0,0 +1,161 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package audit records the privileged operations done by rkt, like running
+// pods or removing images, in a file or in the journal.
+package audit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+)
+
+const (
+	// Identifier is the syslog identifier of the records logged in
+	// the journal.
+	Identifier = "rkt-audit"
+
+	// OutcomeSuccess is the outcome of the operations that succeeded,
+	// or, for the ones replacing rkt with the pod, that were started.
+	OutcomeSuccess = "success"
+	// OutcomeFailure is the outcome of the operations that failed.
+	OutcomeFailure = "failure"
+
+	journalSocket = "/run/systemd/journal/socket"
+)
+
+// Record is an audited operation.
+type Record struct {
+	Time      time.Time `json:"time"`
+	Operation string    `json:"operation"`
+	UID       int       `json:"uid"`
+	// LoginUID is the UID of the user who logged in, kept across
+	// sudo, or -1 when unknown.
+	LoginUID int      `json:"loginUID"`
+	Pods     []string `json:"pods,omitempty"`
+	Images   []string `json:"images,omitempty"`
+	Outcome  string   `json:"outcome"`
+	ExitCode int      `json:"exitCode"`
+}
+
+// NewRecord returns a record of the given operation, done by the caller.
+func NewRecord(operation string) *Record {
+	return &Record{
+		Time:      time.Now().UTC(),
+		Operation: operation,
+		UID:       os.Getuid(),
+		LoginUID:  loginUID(),
+	}
+}
+
+func loginUID() int {
+	b, err := ioutil.ReadFile("/proc/self/loginuid")
+	if err != nil {
+		return -1
+	}
+	uid, err := strconv.ParseUint(strings.TrimSpace(string(b)), 10, 32)
+	// (uint32)-1 means the process is not part of a login session.
+	if err != nil || uid == 1<<32-1 {
+		return -1
+	}
+	return int(uid)
+}
+
+// Logger records the audited operations somewhere.
+type Logger interface {
+	Log(r *Record) error
+}
+
+// FileLogger appends the records to a file, one JSON object per line.
+type FileLogger struct {
+	Path string
+}
+
+// Log implements Logger.
+func (l *FileLogger) Log(r *Record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error encoding audit record"), err)
+	}
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error opening audit log"), err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return errwrap.Wrap(errors.New("error writing audit log"), err)
+	}
+	return f.Sync()
+}
+
+// JournalLogger sends the records to the journal, with the Identifier
+// syslog identifier and a RKT_AUDIT_ field for each field of the record.
+type JournalLogger struct{}
+
+// Log implements Logger.
+func (l *JournalLogger) Log(r *Record) error {
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error connecting to the journal"), err)
+	}
+	defer conn.Close()
+	if _, err := conn.Write(journalMessage(r)); err != nil {
+		return errwrap.Wrap(errors.New("error writing to the journal"), err)
+	}
+	return nil
+}
+
+// journalMessage encodes the record with the native protocol of the
+// journal.
+func journalMessage(r *Record) []byte {
+	msg := new(bytes.Buffer)
+	field := func(name, value string) {
+		if !strings.Contains(value, "\n") {
+			fmt.Fprintf(msg, "%s=%s\n", name, value)
+			return
+		}
+		// Values with newlines are prefixed with their size.
+		fmt.Fprintf(msg, "%s\n", name)
+		binary.Write(msg, binary.LittleEndian, uint64(len(value)))
+		fmt.Fprintf(msg, "%s\n", value)
+	}
+
+	field("MESSAGE", fmt.Sprintf("%s: %s", r.Operation, r.Outcome))
+	field("SYSLOG_IDENTIFIER", Identifier)
+	// LOG_NOTICE
+	field("PRIORITY", "5")
+	field("RKT_AUDIT_OPERATION", r.Operation)
+	field("RKT_AUDIT_UID", strconv.Itoa(r.UID))
+	field("RKT_AUDIT_LOGINUID", strconv.Itoa(r.LoginUID))
+	for _, pod := range r.Pods {
+		field("RKT_AUDIT_POD", pod)
+	}
+	for _, img := range r.Images {
+		field("RKT_AUDIT_IMAGE", img)
+	}
+	field("RKT_AUDIT_OUTCOME", r.Outcome)
+	field("RKT_AUDIT_EXIT_CODE", strconv.Itoa(r.ExitCode))
+	return msg.Bytes()
+}
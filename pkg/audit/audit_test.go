@@ -0,0 +1,96 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileLogger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rkt-audit-test")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	l := &FileLogger{Path: filepath.Join(dir, "audit.log")}
+	records := []*Record{
+		{Operation: "run", UID: 1000, LoginUID: 1000, Pods: []string{"pod"}, Images: []string{"sha512-aaa"}, Outcome: OutcomeSuccess},
+		{Operation: "image rm", LoginUID: -1, Images: []string{"sha512-bbb"}, Outcome: OutcomeFailure, ExitCode: 254},
+	}
+	for _, r := range records {
+		if err := l.Log(r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	f, err := os.Open(l.Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+	var logged []*Record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		logged = append(logged, &r)
+	}
+	if !reflect.DeepEqual(logged, records) {
+		t.Errorf("expected records %v, got %v", records, logged)
+	}
+}
+
+func TestJournalMessage(t *testing.T) {
+	r := &Record{
+		Operation: "stop",
+		UID:       0,
+		LoginUID:  1000,
+		Pods:      []string{"pod1", "pod2"},
+		Outcome:   OutcomeSuccess,
+	}
+	expected := []string{
+		"MESSAGE=stop: success",
+		"SYSLOG_IDENTIFIER=rkt-audit",
+		"PRIORITY=5",
+		"RKT_AUDIT_OPERATION=stop",
+		"RKT_AUDIT_UID=0",
+		"RKT_AUDIT_LOGINUID=1000",
+		"RKT_AUDIT_POD=pod1",
+		"RKT_AUDIT_POD=pod2",
+		"RKT_AUDIT_OUTCOME=success",
+		"RKT_AUDIT_EXIT_CODE=0",
+		"",
+	}
+	if msg := string(journalMessage(r)); msg != strings.Join(expected, "\n") {
+		t.Errorf("unexpected journal message %q", msg)
+	}
+
+	r.Operation = "multi\nline"
+	msg := string(journalMessage(r))
+	binary := "RKT_AUDIT_OPERATION\n\x0a\x00\x00\x00\x00\x00\x00\x00multi\nline\n"
+	if !strings.Contains(msg, binary) {
+		t.Errorf("expected value with newline to be sent with its size, got %q", msg)
+	}
+}
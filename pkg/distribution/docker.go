@@ -88,8 +88,10 @@ func NewDockerFromString(ds string) (Distribution, error) {
 	return NewDocker(u)
 }
 
+// CIMD returns the canonical distribution URI, populated with all the
+// default values, so that equal references have the same URI.
 func (d *Docker) CIMD() *url.URL {
-	uriStr := NewCIMDString(TypeDocker, distDockerVersion, d.url)
+	uriStr := NewCIMDString(TypeDocker, distDockerVersion, d.full)
 	// Create a copy of the URL
 	u, err := url.Parse(uriStr)
 	if err != nil {
@@ -116,6 +118,12 @@ func (d *Docker) ReferenceURL() string {
 	return d.url
 }
 
+// FullReference returns the docker reference populated with all the
+// default values, like "registry-1.docker.io/library/busybox:latest".
+func (d *Docker) FullReference() string {
+	return d.full
+}
+
 // SimpleDockerRef returns a simplified docker reference. This means removing
 // the index url if it's the default docker registry (registry-1.docker.io),
 // removing the default repo (library) when using the default docker registry
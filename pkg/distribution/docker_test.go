@@ -66,6 +66,21 @@ func TestDocker(t *testing.T) {
 			"cimd:docker:v=0:registry-1.docker.io/library/busybox@sha256:a59906e33509d14c036c8678d687bd4eec81ed7c4b8ce907b888c607f6a1e0e6",
 			"busybox@sha256:a59906e33509d14c036c8678d687bd4eec81ed7c4b8ce907b888c607f6a1e0e6",
 		},
+		{
+			"docker.io/busybox:1.0",
+			"cimd:docker:v=0:registry-1.docker.io/library/busybox:1.0",
+			"busybox:1.0",
+		},
+		{
+			"docker.io/library/busybox",
+			"cimd:docker:v=0:registry-1.docker.io/library/busybox:latest",
+			"busybox",
+		},
+		{
+			"busybox:1.0@sha256:a59906e33509d14c036c8678d687bd4eec81ed7c4b8ce907b888c607f6a1e0e6",
+			"cimd:docker:v=0:registry-1.docker.io/library/busybox@sha256:a59906e33509d14c036c8678d687bd4eec81ed7c4b8ce907b888c607f6a1e0e6",
+			"busybox@sha256:a59906e33509d14c036c8678d687bd4eec81ed7c4b8ce907b888c607f6a1e0e6",
+		},
 		{
 			"myregistry.example.com:4000/busybox",
 			"cimd:docker:v=0:myregistry.example.com:4000/busybox:latest",
@@ -106,5 +121,22 @@ func TestDocker(t *testing.T) {
 		if !d.Equals(td) {
 			t.Errorf("expected identical distribution but got %q != %q", td.CIMD().String(), d.CIMD().String())
 		}
+
+		if d.CIMD().String() != tt.expectedCIMD {
+			t.Errorf("expected canonical distribution URI %q, got %q", tt.expectedCIMD, d.CIMD().String())
+		}
+		if d.String() != tt.expected {
+			t.Errorf("expected docker string %q, got %q", tt.expected, d.String())
+		}
+
+		// The canonical URI round-trips to the same docker string.
+		rd, err := Get(d.CIMD())
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			continue
+		}
+		if rd.String() != tt.expected {
+			t.Errorf("expected docker string %q after round-trip, got %q", tt.expected, rd.String())
+		}
 	}
 }
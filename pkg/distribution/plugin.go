@@ -0,0 +1,143 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distribution
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+)
+
+var validPluginType = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Plugin defines a distribution whose type is registered at runtime and
+// resolved by an external command. The format is:
+// cimd:TYPE:v=VERSION:DATA
+// The version and data are opaque to rkt, they are only interpreted by
+// the plugin.
+// Examples:
+// cimd:artifactory:v=0:docker-local%2Fapp%3A1.0
+// cimd:harbor:v=0:library%2Fnginx
+type Plugin struct {
+	cimdURL *url.URL
+	command string
+}
+
+// ValidatePluginType returns an error if distType can't be the type of a
+// plugin distribution.
+func ValidatePluginType(distType Type) error {
+	if !validPluginType.MatchString(string(distType)) {
+		return fmt.Errorf("invalid distribution type %q, it must be made of lowercase letters, digits and dashes", distType)
+	}
+	if distType == Scheme {
+		return fmt.Errorf("invalid distribution type %q", distType)
+	}
+	return nil
+}
+
+// RegisterPlugin registers the distribution type distType, resolved by
+// running command. Unlike Register, it returns an error if the type is
+// already registered, as the plugins come from the configuration.
+func RegisterPlugin(distType Type, command string) error {
+	if err := ValidatePluginType(distType); err != nil {
+		return err
+	}
+	if _, ok := distributions[distType]; ok {
+		return fmt.Errorf("distribution %q already registered", distType)
+	}
+	distributions[distType] = func(u *url.URL) (Distribution, error) {
+		return NewPlugin(u, distType, command)
+	}
+	return nil
+}
+
+// NewPlugin creates a new plugin distribution of type distType from the
+// provided distribution uri.
+func NewPlugin(u *url.URL, distType Type, command string) (Distribution, error) {
+	c, err := parseCIMD(u)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse URI: %q: %v", u.String(), err)
+	}
+	if c.Type != distType {
+		return nil, fmt.Errorf("wrong distribution type: %q", c.Type)
+	}
+	return &Plugin{
+		cimdURL: u,
+		command: command,
+	}, nil
+}
+
+func (p *Plugin) CIMD() *url.URL {
+	// Create a copy of the URL.
+	u, err := url.Parse(p.cimdURL.String())
+	if err != nil {
+		panic(err)
+	}
+	return u
+}
+
+func (p *Plugin) String() string {
+	return p.cimdURL.String()
+}
+
+func (p *Plugin) Equals(d Distribution) bool {
+	p2, ok := d.(*Plugin)
+	if !ok {
+		return false
+	}
+	return p.CIMD().String() == p2.CIMD().String()
+}
+
+// Resolve runs the plugin command with the distribution URI as argument
+// and returns the image string it prints on its standard output: a URL,
+// an image name or a distribution URI of a built-in type. The type,
+// version and data of the distribution are also passed in the
+// RKT_DISTRIBUTION_TYPE, RKT_DISTRIBUTION_VERSION and
+// RKT_DISTRIBUTION_DATA environment variables.
+func (p *Plugin) Resolve() (string, error) {
+	c, err := parseCIMD(p.cimdURL)
+	if err != nil {
+		return "", err
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(p.command, p.cimdURL.String())
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("RKT_DISTRIBUTION_TYPE=%s", c.Type),
+		fmt.Sprintf("RKT_DISTRIBUTION_VERSION=%d", c.Version),
+		fmt.Sprintf("RKT_DISTRIBUTION_DATA=%s", c.Data),
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := fmt.Sprintf("distribution plugin %q failed to resolve %q", p.command, p.cimdURL.String())
+		if out := strings.TrimSpace(stderr.String()); out != "" {
+			msg = fmt.Sprintf("%s: %s", msg, out)
+		}
+		return "", errwrap.Wrap(fmt.Errorf("%s", msg), err)
+	}
+
+	resolved := strings.TrimSpace(strings.SplitN(strings.TrimSpace(stdout.String()), "\n", 2)[0])
+	if resolved == "" {
+		return "", fmt.Errorf("distribution plugin %q resolved %q to nothing", p.command, p.cimdURL.String())
+	}
+	return resolved, nil
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package distribution
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPlugin(t *testing.T) {
+	dir, err := ioutil.TempDir("", "distribution-plugin")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	script := `#!/bin/sh
+case "$RKT_DISTRIBUTION_DATA" in
+missing)
+	echo "no such image" >&2
+	exit 1
+	;;
+empty)
+	exit 0
+	;;
+esac
+echo "https://artifacts.example.com/$RKT_DISTRIBUTION_DATA.aci"
+echo "ignored"
+`
+	command := filepath.Join(dir, "resolve")
+	if err := ioutil.WriteFile(command, []byte(script), 0755); err != nil {
+		t.Fatalf("error writing plugin: %v", err)
+	}
+
+	for _, typ := range []Type{"", "Bad", "with:colon", "cimd", TypeDocker} {
+		if err := RegisterPlugin(typ, command); err == nil {
+			t.Errorf("expected an error registering plugin type %q", typ)
+		}
+	}
+	if err := RegisterPlugin("test-plugin", command); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer delete(distributions, "test-plugin")
+
+	tests := []struct {
+		uri      string
+		resolved string
+		fail     bool
+	}{
+		{"cimd:test-plugin:v=0:app-1.0", "https://artifacts.example.com/app-1.0.aci", false},
+		{"cimd:test-plugin:v=0:missing", "", true},
+		{"cimd:test-plugin:v=0:empty", "", true},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.uri)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.uri, err)
+		}
+		p, ok := d.(*Plugin)
+		if !ok {
+			t.Fatalf("%s: expected a plugin distribution, got %T", tt.uri, d)
+		}
+		if p.String() != tt.uri {
+			t.Errorf("%s: unexpected string %q", tt.uri, p.String())
+		}
+		resolved, err := p.Resolve()
+		if tt.fail {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %q", tt.uri, resolved)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.uri, err)
+			continue
+		}
+		if resolved != tt.resolved {
+			t.Errorf("%s: got %q, want %q", tt.uri, resolved, tt.resolved)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package erase makes the data of removed files unrecoverable, for hosts
+// handling sensitive data. The files can be shredded, overwriting them
+// with random data before they are removed, or their blocks can be
+// discarded once they are removed.
+package erase
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// Mode is how the removed files are erased.
+type Mode string
+
+const (
+	// None removes the files as usual.
+	None Mode = "none"
+	// Shred overwrites the files with random data before removing
+	// them.
+	Shred Mode = "shred"
+	// Discard removes the files and discards the free blocks of the
+	// filesystem, for the thin-provisioned and solid-state disks which
+	// do not overwrite blocks in place.
+	Discard Mode = "discard"
+)
+
+// fitrim is FITRIM of linux/fs.h, the same on all the architectures.
+const fitrim = 0xc0185879
+
+// fstrimRange is struct fstrim_range of linux/fs.h.
+type fstrimRange struct {
+	start  uint64
+	len    uint64
+	minlen uint64
+}
+
+// ParseMode returns the mode named s, None if s is empty.
+func ParseMode(s string) (Mode, error) {
+	switch m := Mode(s); m {
+	case "":
+		return None, nil
+	case None, Shred, Discard:
+		return m, nil
+	default:
+		return None, fmt.Errorf("unknown erase mode %q, must be %q, %q or %q", s, None, Shred, Discard)
+	}
+}
+
+// RemoveAll removes the tree at path. With Shred, its files are overwritten
+// first. With Discard, the caller should call Trim once all the trees are
+// removed.
+func RemoveAll(path string, mode Mode) error {
+	if mode == Shred {
+		if err := Scrub(path); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(path)
+}
+
+// Scrub overwrites with random data the regular files of the tree at root,
+// without removing them. The files with several hard links are skipped,
+// as their data is still used elsewhere.
+func Scrub(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || info.Size() == 0 {
+			return nil
+		}
+		if st, ok := info.Sys().(*syscall.Stat_t); ok && st.Nlink > 1 {
+			return nil
+		}
+		if err := scrubFile(path, info.Size()); err != nil {
+			return errwrap.Wrap(fmt.Errorf("error shredding %q", path), err)
+		}
+		return nil
+	})
+}
+
+func scrubFile(path string, size int64) error {
+	f, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.CopyN(f, rand.Reader, size); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+// Trim discards the free blocks of the filesystem of path, which the
+// removed files used.
+func Trim(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := fstrimRange{len: math.MaxUint64}
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), fitrim, uintptr(unsafe.Pointer(&r)))
+	switch errno {
+	case 0:
+		return nil
+	case syscall.EOPNOTSUPP, syscall.ENOTTY:
+		return errors.New("the filesystem or its device does not support discarding blocks")
+	default:
+		return errwrap.Wrap(fmt.Errorf("error discarding the free blocks of the filesystem of %q", path), errno)
+	}
+}
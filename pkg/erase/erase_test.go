@@ -0,0 +1,94 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package erase
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		s        string
+		expected Mode
+		fail     bool
+	}{
+		{"", None, false},
+		{"none", None, false},
+		{"shred", Shred, false},
+		{"discard", Discard, false},
+		{"wipe", None, true},
+	}
+	for _, tt := range tests {
+		m, err := ParseMode(tt.s)
+		if tt.fail != (err != nil) {
+			t.Errorf("%q: expected failure %t, got %v", tt.s, tt.fail, err)
+		}
+		if m != tt.expected {
+			t.Errorf("%q: expected mode %q, got %q", tt.s, tt.expected, m)
+		}
+	}
+}
+
+func TestScrub(t *testing.T) {
+	dir, err := ioutil.TempDir("", "erase-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	secret := bytes.Repeat([]byte("secret"), 1000)
+	tree := filepath.Join(dir, "tree")
+	file := filepath.Join(tree, "sub", "file")
+	linked := filepath.Join(tree, "linked")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{file, linked} {
+		if err := ioutil.WriteFile(f, secret, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Link(linked, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Scrub(tree); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != len(secret) || bytes.Contains(b, []byte("secret")) {
+		t.Errorf("expected %q to be overwritten", file)
+	}
+	if b, err := ioutil.ReadFile(linked); err != nil || !bytes.Equal(b, secret) {
+		t.Errorf("expected the hard linked %q to be kept, got %v", linked, err)
+	}
+
+	if err := RemoveAll(tree, Shred); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(tree); !os.IsNotExist(err) {
+		t.Errorf("expected %q to be removed, got %v", tree, err)
+	}
+	if err := Scrub(tree); err != nil {
+		t.Errorf("unexpected error scrubbing a missing tree: %v", err)
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package exitcode defines the exit codes of rkt for the common classes of
+// failures, so scripts can tell them apart, and the errors carrying them.
+package exitcode
+
+import (
+	"os"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// The exit codes of rkt. Failure is used when an error is of none of the
+// other classes.
+const (
+	Success                = 0
+	InvalidArgument        = 2
+	PodNotFound            = 249
+	InsufficientPrivileges = 250
+	NetworkSetupFailed     = 251
+	SignatureInvalid       = 252
+	ImageNotFound          = 253
+	Failure                = 254
+)
+
+var descriptions = map[int]string{
+	PodNotFound:            "pod not found",
+	InsufficientPrivileges: "insufficient privileges",
+	NetworkSetupFailed:     "network setup failed",
+	SignatureInvalid:       "signature invalid",
+	ImageNotFound:          "image not found",
+}
+
+// Coder is implemented by the errors carrying an exit code.
+type Coder interface {
+	ExitCode() int
+}
+
+// Error classifies an error, so rkt exits with the code of its class. It
+// is transparent to the messages printed by rkt's loggers, which show the
+// wrapped error.
+type Error struct {
+	Code int
+	Err  error
+}
+
+// Wrap classifies err with the given exit code.
+func Wrap(code int, err error) error {
+	return &Error{
+		Code: code,
+		Err:  err,
+	}
+}
+
+func (e *Error) Error() string {
+	if d, ok := descriptions[e.Code]; ok {
+		return d
+	}
+	return e.Err.Error()
+}
+
+// WrappedErrors implements errwrap.Wrapper.
+func (e *Error) WrappedErrors() []error {
+	return []error{e.Err}
+}
+
+// ExitCode implements Coder.
+func (e *Error) ExitCode() int {
+	return e.Code
+}
+
+// FromError returns the exit code for err: the one of the outermost Coder
+// in its chain, InsufficientPrivileges if it is caused by a permission
+// error or Failure otherwise.
+func FromError(err error) int {
+	code := 0
+	permission := false
+	errwrap.Walk(err, func(e error) {
+		if c, ok := e.(Coder); ok && code == 0 {
+			code = c.ExitCode()
+		}
+		if os.IsPermission(e) {
+			permission = true
+		}
+	})
+	switch {
+	case code != 0:
+		return code
+	case permission:
+		return InsufficientPrivileges
+	default:
+		return Failure
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package exitcode
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/hashicorp/errwrap"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{
+			errors.New("some error"),
+			Failure,
+		},
+		{
+			Wrap(ImageNotFound, errors.New("no such image")),
+			ImageNotFound,
+		},
+		{
+			errwrap.Wrap(errors.New("error finding images"), Wrap(SignatureInvalid, errors.New("bad signature"))),
+			SignatureInvalid,
+		},
+		{
+			Wrap(PodNotFound, errwrap.Wrap(errors.New("outer"), Wrap(ImageNotFound, errors.New("inner")))),
+			PodNotFound,
+		},
+		{
+			errwrap.Wrap(errors.New("cannot open store"), &os.PathError{Op: "open", Path: "/var/lib/rkt", Err: os.ErrPermission}),
+			InsufficientPrivileges,
+		},
+	}
+
+	for i, tt := range tests {
+		if code := FromError(tt.err); code != tt.code {
+			t.Errorf("#%d: expected exit code %d, got %d", i, tt.code, code)
+		}
+	}
+}
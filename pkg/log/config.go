@@ -0,0 +1,185 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package log
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Level is the severity of a message.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel returns the level of the given name.
+func ParseLevel(s string) (Level, error) {
+	for l, name := range levelNames {
+		if s == name {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q, expected one of debug, info, warn or error", s)
+}
+
+// Format is the format the messages are printed in.
+type Format string
+
+const (
+	// FormatText prints the messages as lines of text, after the prefix
+	// of the logger.
+	FormatText Format = "text"
+	// FormatJSON prints each message as a JSON object on its own line,
+	// with its time, level, subsystem and message.
+	FormatJSON Format = "json"
+)
+
+// ParseFormat returns the format of the given name.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case FormatText, FormatJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown log format %q, expected text or json", s)
+}
+
+// The environment variables passing the configuration of the logging to
+// the processes started by rkt, like the stage1 ones.
+const (
+	EnvFormat = "RKT_LOG_FORMAT"
+	EnvLevels = "RKT_LOG_LEVEL"
+)
+
+// The logging configuration is shared by all the Loggers of a process.
+// Without a configured level, all the messages are printed, except the
+// debug ones of the loggers not in debug mode.
+var config = struct {
+	sync.RWMutex
+	format          Format
+	level           *Level
+	subsystemLevels map[string]Level
+}{
+	format:          FormatText,
+	subsystemLevels: make(map[string]Level),
+}
+
+func init() {
+	if f := os.Getenv(EnvFormat); f != "" {
+		if err := SetFormat(f); err != nil {
+			fmt.Fprintf(os.Stderr, "ignoring %s: %v\n", EnvFormat, err)
+		}
+	}
+	if l := os.Getenv(EnvLevels); l != "" {
+		if err := SetLevels(l); err != nil {
+			fmt.Fprintf(os.Stderr, "ignoring %s: %v\n", EnvLevels, err)
+		}
+	}
+}
+
+// SetFormat sets the format of the messages of all the Loggers.
+func SetFormat(s string) error {
+	f, err := ParseFormat(s)
+	if err != nil {
+		return err
+	}
+	config.Lock()
+	defer config.Unlock()
+	config.format = f
+	return nil
+}
+
+func currentFormat() Format {
+	config.RLock()
+	defer config.RUnlock()
+	return config.format
+}
+
+// SetLevel sets the minimum level of the messages printed by the Loggers
+// of all the subsystems without a level of their own.
+func SetLevel(l Level) {
+	config.Lock()
+	defer config.Unlock()
+	config.level = &l
+}
+
+// SetLevels sets the minimum levels of the messages printed by the
+// Loggers from a comma-separated list of levels, either for all the
+// subsystems or for one, like "info,networking=debug".
+func SetLevels(spec string) error {
+	var level *Level
+	subsystemLevels := make(map[string]Level)
+	for _, s := range strings.Split(spec, ",") {
+		kv := strings.SplitN(s, "=", 2)
+		l, err := ParseLevel(kv[len(kv)-1])
+		if err != nil {
+			return err
+		}
+		if len(kv) == 1 {
+			level = &l
+		} else {
+			subsystemLevels[kv[0]] = l
+		}
+	}
+
+	config.Lock()
+	defer config.Unlock()
+	if level != nil {
+		config.level = level
+	}
+	for subsystem, l := range subsystemLevels {
+		config.subsystemLevels[subsystem] = l
+	}
+	return nil
+}
+
+// configuredLevel returns the minimum level of the messages of a
+// subsystem, if one is configured.
+func configuredLevel(subsystem string) (Level, bool) {
+	config.RLock()
+	defer config.RUnlock()
+	if l, ok := config.subsystemLevels[subsystem]; ok {
+		return l, true
+	}
+	if config.level != nil {
+		return *config.level, true
+	}
+	return 0, false
+}
+
+// IsDebugEnabled returns whether the debug messages of a subsystem are
+// printed because of the configured levels, as opposed to the debug mode
+// of its Loggers.
+func IsDebugEnabled(subsystem string) bool {
+	l, ok := configuredLevel(subsystem)
+	return ok && l == LevelDebug
+}
@@ -16,31 +16,52 @@ package log
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 )
 
-// Logger is an extended version of the golang Logger to support structured errors.
+// Logger is an extended version of the golang Logger to support structured
+// errors, levels and the formats of the messages. The prefix of a Logger is
+// its subsystem, whose level can be configured on its own.
 type Logger struct {
-	debug bool
+	debug     bool
+	level     Level  // level of the messages printed with Print and the like
+	subsystem string // prefix of the Logger
+	raw       bool   // whether the messages are printed as is
+	out       io.Writer
 	*log.Logger
 }
 
-// New creates a new Logger with no Log flags set.
+// New creates a new Logger with no Log flags set, whose messages are at
+// the info level.
 func New(out io.Writer, prefix string, debug bool) *Logger {
 	l := &Logger{
-		debug:  debug,
-		Logger: log.New(out, prefix, 0),
+		debug:     debug,
+		level:     LevelInfo,
+		subsystem: prefix,
+		out:       out,
+		Logger:    log.New(out, prefix, 0),
 	}
 	l.SetFlags(0)
 	return l
 }
 
+// NewOutput creates a new Logger for the output of commands, which is
+// printed as is, whatever the configured levels and format.
+func NewOutput(out io.Writer) *Logger {
+	l := New(out, "", false)
+	l.raw = true
+	return l
+}
+
 // NewLogSet returns a set of Loggers for commonly used output streams: errors,
 // diagnostics, stdout. The error and stdout streams should generally never be
 // suppressed. diagnostic can be suppressed by setting the output to
@@ -49,8 +70,9 @@ func New(out io.Writer, prefix string, debug bool) *Logger {
 func NewLogSet(prefix string, debug bool) (stderr, diagnostic, stdout *Logger) {
 	stderr = New(os.Stderr, prefix, debug)
 	diagnostic = New(os.Stderr, prefix, debug)
+	diagnostic.level = LevelDebug
 	// Debug not used for stdout.
-	stdout = New(os.Stdout, prefix, false)
+	stdout = NewOutput(os.Stdout)
 
 	return stderr, diagnostic, stdout
 }
@@ -58,6 +80,12 @@ func NewLogSet(prefix string, debug bool) (stderr, diagnostic, stdout *Logger) {
 // SetDebug sets the debug flag to the value of b
 func (l *Logger) SetDebug(b bool) { l.debug = b }
 
+// SetOutput sets the output destination of the Logger.
+func (l *Logger) SetOutput(w io.Writer) {
+	l.out = w
+	l.Logger.SetOutput(w)
+}
+
 // SetFlags is a wrapper around log.SetFlags that adds and removes, ": " to and
 // from a prefix. This is needed because ": " is only added by golang's log
 // package if either of the Lshortfile or Llongfile flags are set.
@@ -77,6 +105,90 @@ func (l *Logger) SetFlags(flag int) {
 	}
 }
 
+// now is replaced in tests
+var now = time.Now
+
+// jsonMu serializes the JSON messages of all the Loggers, which may share
+// their output.
+var jsonMu sync.Mutex
+
+// output prints a message at the given level, if enabled. It is called
+// by the printing methods only, so that the Lshortfile and Llongfile
+// flags report their callers.
+func (l *Logger) output(level Level, msg string) {
+	if l.raw {
+		l.Logger.Output(3, msg)
+		return
+	}
+	if min, ok := configuredLevel(l.subsystem); ok && level < min {
+		return
+	}
+	if currentFormat() == FormatJSON {
+		entry := struct {
+			Time      string `json:"time"`
+			Level     string `json:"level"`
+			Subsystem string `json:"subsystem,omitempty"`
+			Msg       string `json:"msg"`
+		}{
+			Time:      now().UTC().Format(time.RFC3339Nano),
+			Level:     level.String(),
+			Subsystem: l.subsystem,
+			Msg:       strings.TrimSuffix(msg, "\n"),
+		}
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		jsonMu.Lock()
+		defer jsonMu.Unlock()
+		l.out.Write(append(b, '\n'))
+		return
+	}
+	if level == LevelWarn {
+		msg = "warning: " + msg
+	}
+	l.Logger.Output(3, msg)
+}
+
+// Print prints a message at the level of the Logger, like log.Print.
+func (l *Logger) Print(v ...interface{}) {
+	l.output(l.level, fmt.Sprint(v...))
+}
+
+// Printf prints a message at the level of the Logger, like log.Printf.
+func (l *Logger) Printf(format string, v ...interface{}) {
+	l.output(l.level, fmt.Sprintf(format, v...))
+}
+
+// Println prints a message at the level of the Logger, like log.Println.
+func (l *Logger) Println(v ...interface{}) {
+	l.output(l.level, fmt.Sprintln(v...))
+}
+
+// Debugf prints a debug message, if the Logger is in debug mode or the
+// debug messages of its subsystem are enabled.
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	if !l.debug && !IsDebugEnabled(l.subsystem) {
+		return
+	}
+	l.output(LevelDebug, fmt.Sprintf(format, v...))
+}
+
+// Infof prints an informational message.
+func (l *Logger) Infof(format string, v ...interface{}) {
+	l.output(LevelInfo, fmt.Sprintf(format, v...))
+}
+
+// Warnf prints a warning.
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.output(LevelWarn, fmt.Sprintf(format, v...))
+}
+
+// WarnE prints the msg and its error message(s) as a warning.
+func (l *Logger) WarnE(msg string, e error) {
+	l.output(LevelWarn, l.formatErr(e, msg))
+}
+
 func (l *Logger) formatErr(e error, msg string) string {
 	// Get a list of accumulated errors
 	var errors []error
@@ -103,32 +215,67 @@ func (l *Logger) formatErr(e error, msg string) string {
 
 // PrintE prints the msg and its error message(s).
 func (l *Logger) PrintE(msg string, e error) {
-	l.Print(l.formatErr(e, msg))
+	l.output(LevelError, l.formatErr(e, msg))
 }
 
 // Error is a convenience function for printing errors without a message.
 func (l *Logger) Error(e error) {
-	l.Print(l.formatErr(e, ""))
+	l.output(LevelError, l.formatErr(e, ""))
 }
 
 // Errorf is a convenience function for formatting and printing errors.
 func (l *Logger) Errorf(format string, a ...interface{}) {
-	l.Print(l.formatErr(fmt.Errorf(format, a...), ""))
+	l.output(LevelError, l.formatErr(fmt.Errorf(format, a...), ""))
+}
+
+// Fatal prints an error then calls os.Exit(1), like log.Fatal.
+func (l *Logger) Fatal(v ...interface{}) {
+	l.output(LevelError, fmt.Sprint(v...))
+	os.Exit(1)
+}
+
+// Fatalln prints an error then calls os.Exit(1), like log.Fatalln.
+func (l *Logger) Fatalln(v ...interface{}) {
+	l.output(LevelError, fmt.Sprintln(v...))
+	os.Exit(1)
 }
 
 // FatalE prints a string and error then calls os.Exit(254).
 func (l *Logger) FatalE(msg string, e error) {
-	l.Print(l.formatErr(e, msg))
+	l.output(LevelError, l.formatErr(e, msg))
 	os.Exit(254)
 }
 
 // Fatalf prints an error then calls os.Exit(254).
 func (l *Logger) Fatalf(format string, a ...interface{}) {
-	l.Print(l.formatErr(fmt.Errorf(format, a...), ""))
+	l.output(LevelError, l.formatErr(fmt.Errorf(format, a...), ""))
 	os.Exit(254)
 }
 
+// Panic prints an error then calls panic, like log.Panic.
+func (l *Logger) Panic(v ...interface{}) {
+	s := fmt.Sprint(v...)
+	l.output(LevelError, s)
+	panic(s)
+}
+
+// Panicf prints an error then calls panic, like log.Panicf.
+func (l *Logger) Panicf(format string, v ...interface{}) {
+	s := fmt.Sprintf(format, v...)
+	l.output(LevelError, s)
+	panic(s)
+}
+
+// Panicln prints an error then calls panic, like log.Panicln.
+func (l *Logger) Panicln(v ...interface{}) {
+	s := fmt.Sprintln(v...)
+	l.output(LevelError, s)
+	panic(s)
+}
+
 // PanicE prints a string and error then calls panic.
 func (l *Logger) PanicE(msg string, e error) {
-	l.Panic(l.formatErr(e, msg))
+	s := l.formatErr(e, msg)
+	l.output(LevelError, s)
+	panic(s)
 }
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/errwrap"
 )
@@ -77,3 +78,106 @@ func TestLogFormatting(t *testing.T) {
 		t.Errorf("expected %q, got %q", expected, logBuf.String())
 	}
 }
+
+// resetConfig restores the default logging configuration.
+func resetConfig() {
+	config.Lock()
+	defer config.Unlock()
+	config.format = FormatText
+	config.level = nil
+	config.subsystemLevels = make(map[string]Level)
+}
+
+func TestLogLevels(t *testing.T) {
+	defer resetConfig()
+
+	tests := []struct {
+		levels string
+		debug  bool
+		expect string
+	}{
+		{
+			"",
+			false,
+			"prefix: info\nprefix: warning: warn\nprefix: error\n",
+		},
+		{
+			"",
+			true,
+			"prefix: debug\nprefix: info\nprefix: warning: warn\nprefix: \n  └─error\n",
+		},
+		{
+			"warn",
+			false,
+			"prefix: warning: warn\nprefix: error\n",
+		},
+		{
+			"warn,prefix=debug",
+			false,
+			"prefix: debug\nprefix: info\nprefix: warning: warn\nprefix: error\n",
+		},
+		{
+			"debug,prefix=error",
+			true,
+			"prefix: \n  └─error\n",
+		},
+	}
+
+	for i, tt := range tests {
+		resetConfig()
+		if tt.levels != "" {
+			if err := SetLevels(tt.levels); err != nil {
+				t.Fatalf("#%d: unexpected error: %v", i, err)
+			}
+		}
+
+		var logBuf bytes.Buffer
+		l := New(&logBuf, prefix, tt.debug)
+		l.Debugf("debug")
+		l.Infof("info")
+		l.Warnf("warn")
+		l.Error(errors.New("error"))
+
+		if logBuf.String() != tt.expect {
+			t.Errorf("#%d: expected %q, got %q", i, tt.expect, logBuf.String())
+		}
+	}
+}
+
+func TestSetLevels(t *testing.T) {
+	defer resetConfig()
+
+	for _, spec := range []string{"", "verbose", "info,networking=loud", "info,=debug=info"} {
+		if err := SetLevels(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestLogJSON(t *testing.T) {
+	defer resetConfig()
+	defer func(f func() time.Time) { now = f }(now)
+	now = func() time.Time { return time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	if err := SetFormat("json"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var logBuf bytes.Buffer
+	l := New(&logBuf, prefix, false)
+	l.PrintE(accompanyingMsg, genNestedError())
+	l.Warnf("quoted %q", "value")
+
+	expected := `{"time":"2017-01-02T03:04:05Z","level":"error","subsystem":"prefix","msg":"` + accompanyingMsg + `: ` + er2Msg + `"}` + "\n" +
+		`{"time":"2017-01-02T03:04:05Z","level":"warn","subsystem":"prefix","msg":"quoted \"value\""}` + "\n"
+	if logBuf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, logBuf.String())
+	}
+
+	// The output of commands is not affected by the format.
+	logBuf.Reset()
+	NewOutput(&logBuf).Printf("name=%s\n", "value")
+	if logBuf.String() != "name=value\n" {
+		t.Errorf("expected %q, got %q", "name=value\n", logBuf.String())
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// The prepare journal records the intent of preparing a pod and its
+// completion. It's created together with the pod directory and committed,
+// after all the pod data hit the disk, right before the pod is renamed to the
+// prepared directory. A pod found in the prepared directory without a
+// committed journal (e.g. after a power loss reordered the writes) is treated
+// as an aborted prepare.
+const (
+	prepareJournalFilename = "prepare-journal"
+
+	journalBegin  = "begin"
+	journalCommit = "commit"
+)
+
+// beginPrepareJournal creates the prepare journal in the pod directory dir.
+func beginPrepareJournal(dir string) error {
+	path := filepath.Join(dir, prepareJournalFilename)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error creating prepare journal"), err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(journalBegin + "\n"); err != nil {
+		return errwrap.Wrap(errors.New("error writing prepare journal"), err)
+	}
+	if err := f.Sync(); err != nil {
+		return errwrap.Wrap(errors.New("error syncing prepare journal"), err)
+	}
+	return nil
+}
+
+// commitPrepareJournal marks the preparation of the pod as completed. The
+// pod data must already be synced.
+func (p *Pod) commitPrepareJournal() error {
+	f, err := p.openFile(prepareJournalFilename, syscall.O_WRONLY|syscall.O_APPEND)
+	if os.IsNotExist(err) {
+		// pod created by an older rkt, nothing to commit
+		return nil
+	}
+	if err != nil {
+		return errwrap.Wrap(errors.New("error opening prepare journal"), err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(journalCommit + "\n"); err != nil {
+		return errwrap.Wrap(errors.New("error writing prepare journal"), err)
+	}
+	if err := f.Sync(); err != nil {
+		return errwrap.Wrap(errors.New("error syncing prepare journal"), err)
+	}
+	return nil
+}
+
+// prepareCommitted returns whether the preparation of the pod completed.
+// Pods without a journal were prepared by an older rkt and are assumed to be
+// complete.
+func (p *Pod) prepareCommitted() (bool, error) {
+	b, err := p.readFile(prepareJournalFilename)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, errwrap.Wrap(errors.New("error reading prepare journal"), err)
+	}
+	for _, l := range bytes.Split(b, []byte("\n")) {
+		if string(l) == journalCommit {
+			return true, nil
+		}
+	}
+	return false, nil
+}
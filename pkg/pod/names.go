@@ -0,0 +1,185 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/pkg/lock"
+)
+
+// Pods can be given a unique name, usable in place of their UUID. The names
+// directory holds a file per name, containing the UUID of its pod, and each
+// named pod holds its name. A name whose pod does not exist anymore is free.
+const podNameFilename = "name"
+
+// namesDir returns where the names of the pods are recorded
+func namesDir(dataDir string) string {
+	return filepath.Join(dataDir, "pods", "names")
+}
+
+// ValidateName checks that name is usable as a pod name: a valid AC name
+// which cannot be mistaken for a UUID prefix.
+func ValidateName(name string) error {
+	if _, err := types.NewACName(name); err != nil {
+		return errwrap.Wrap(fmt.Errorf("invalid pod name %q", name), err)
+	}
+	if strings.Trim(name, "0123456789abcdef-") == "" {
+		return fmt.Errorf("invalid pod name %q: it could be mistaken for a UUID", name)
+	}
+	return nil
+}
+
+// lockNames creates the names directory if needed and locks it.
+func lockNames(dataDir string) (*lock.FileLock, error) {
+	if err := os.MkdirAll(namesDir(dataDir), 0750); err != nil {
+		return nil, err
+	}
+	return lock.ExclusiveLock(namesDir(dataDir), lock.Dir)
+}
+
+// podExists returns whether a pod with the given UUID exists in any state.
+func podExists(dataDir, uuid string) (bool, error) {
+	ls, err := listPods(dataDir, IncludeAllDirs)
+	if err != nil {
+		return false, err
+	}
+	for _, u := range ls {
+		if u == uuid {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// SetName gives the pod a name, which must not be taken by another pod of
+// the data directory.
+func (p *Pod) SetName(name string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+
+	l, err := lockNames(p.dataDir)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error locking the pod names"), err)
+	}
+	defer l.Close()
+
+	path := filepath.Join(namesDir(p.dataDir), name)
+	b, err := ioutil.ReadFile(path)
+	switch {
+	case err == nil:
+		owner := strings.TrimSpace(string(b))
+		if owner != p.UUID.String() {
+			exists, err := podExists(p.dataDir, owner)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return fmt.Errorf("pod name %q is already taken by pod %q", name, owner)
+			}
+		}
+	case !os.IsNotExist(err):
+		return errwrap.Wrap(fmt.Errorf("error reading pod name %q", name), err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(p.Path(), podNameFilename), []byte(name), 0640); err != nil {
+		return errwrap.Wrap(errors.New("error writing the pod name"), err)
+	}
+	if err := ioutil.WriteFile(path, []byte(p.UUID.String()), 0640); err != nil {
+		return errwrap.Wrap(fmt.Errorf("error recording pod name %q", name), err)
+	}
+	return nil
+}
+
+// Name returns the name of the pod, or an empty string if it has none.
+func (p *Pod) Name() (string, error) {
+	b, err := p.readFile(podNameFilename)
+	if err == syscall.ENOENT {
+		return "", nil
+	}
+	if err != nil {
+		return "", errwrap.Wrap(errors.New("error reading the pod name"), err)
+	}
+	return string(b), nil
+}
+
+// resolveName returns the UUID of the pod with the given name, or nil if no
+// existing pod has it.
+func resolveName(dataDir, name string) (*types.UUID, error) {
+	if ValidateName(name) != nil {
+		return nil, nil
+	}
+	b, err := ioutil.ReadFile(filepath.Join(namesDir(dataDir), name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error reading pod name %q", name), err)
+	}
+	uuid := strings.TrimSpace(string(b))
+	exists, err := podExists(dataDir, uuid)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return types.NewUUID(uuid)
+}
+
+// GCNames releases the names of the pods which do not exist anymore, and
+// returns them.
+func GCNames(dataDir string) ([]string, error) {
+	if _, err := os.Stat(namesDir(dataDir)); os.IsNotExist(err) {
+		return nil, nil
+	}
+	l, err := lockNames(dataDir)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error locking the pod names"), err)
+	}
+	defer l.Close()
+
+	files, err := ioutil.ReadDir(namesDir(dataDir))
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error listing the pod names"), err)
+	}
+
+	var released []string
+	for _, f := range files {
+		path := filepath.Join(namesDir(dataDir), f.Name())
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		exists, err := podExists(dataDir, strings.TrimSpace(string(b)))
+		if err != nil {
+			return released, err
+		}
+		if exists {
+			continue
+		}
+		if err := os.Remove(path); err != nil {
+			return released, err
+		}
+		released = append(released, f.Name())
+	}
+	return released, nil
+}
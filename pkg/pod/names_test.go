@@ -0,0 +1,117 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pod
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestValidateName(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"myapp", true},
+		{"my-app-2", true},
+		{"etcd", true},
+		{"", false},
+		{"MyApp", false},
+		{"my_app", false},
+		{"deadbeef", false},
+		{"0123-abcd", false},
+	}
+
+	for i, tt := range tests {
+		err := ValidateName(tt.name)
+		if tt.valid && err != nil {
+			t.Errorf("#%d: expected %q to be valid, got %v", i, tt.name, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("#%d: expected %q to be invalid", i, tt.name)
+		}
+	}
+}
+
+func TestPodNames(t *testing.T) {
+	d, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	p1, err := NewPod(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p1.Close()
+	p2, err := NewPod(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p2.Close()
+
+	if err := p1.SetName("myapp"); err != nil {
+		t.Fatalf("unexpected error naming the pod: %v", err)
+	}
+	if name, err := p1.Name(); err != nil || name != "myapp" {
+		t.Errorf("expected name %q, got %q (%v)", "myapp", name, err)
+	}
+	if name, err := p2.Name(); err != nil || name != "" {
+		t.Errorf("expected no name, got %q (%v)", name, err)
+	}
+
+	u, err := resolveUUID(d, "myapp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u == nil || *u != *p1.UUID {
+		t.Errorf("expected name to resolve to %v, got %v", p1.UUID, u)
+	}
+
+	if err := p2.SetName("myapp"); err == nil {
+		t.Errorf("expected an error when taking the name of another pod")
+	}
+
+	if released, err := GCNames(d); err != nil || len(released) != 0 {
+		t.Errorf("expected no released names, got %v (%v)", released, err)
+	}
+
+	if err := os.RemoveAll(p1.Path()); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := resolveName(d, "myapp"); err != nil || u != nil {
+		t.Errorf("expected the name of a removed pod not to resolve, got %v (%v)", u, err)
+	}
+	if err := p2.SetName("myapp"); err != nil {
+		t.Fatalf("unexpected error taking the name of a removed pod: %v", err)
+	}
+	if released, err := GCNames(d); err != nil || len(released) != 0 {
+		t.Errorf("expected no released names, got %v (%v)", released, err)
+	}
+
+	if err := os.RemoveAll(p2.Path()); err != nil {
+		t.Fatal(err)
+	}
+	released, err := GCNames(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(released, []string{"myapp"}) {
+		t.Errorf("expected %v to be released, got %v", []string{"myapp"}, released)
+	}
+}
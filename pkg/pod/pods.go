@@ -62,6 +62,7 @@ type Pod struct {
 	isPreparing      bool // when locked at pods/prepare/$uuid the pod is actively being prepared
 	isAbortedPrepare bool // when unlocked at pods/prepare/$uuid the pod never finished preparing
 	isPrepared       bool // when at pods/prepared/$uuid the pod is prepared, serves as stage for acquiring lock before rename to run/.
+	isIncomplete     bool // when at pods/prepared/$uuid without a committed prepare journal the pod never finished preparing
 	isExited         bool // when locked at pods/run/$uuid the pod is running, when unlocked it's exited.
 	isExitedGarbage  bool // when unlocked at pods/exited-garbage/$uuid the pod is exited and is garbage
 	isExitedDeleting bool // when locked at pods/exited-garbage/$uuid the pod is exited, garbage, and is being actively deleted
@@ -178,6 +179,12 @@ func NewPod(dataDir string) (*Pod, error) {
 		return nil, err
 	}
 
+	if err := beginPrepareJournal(p.embryoPath()); err != nil {
+		p.Close()
+		os.RemoveAll(p.embryoPath())
+		return nil, err
+	}
+
 	err = p.ToPreparing()
 	if err != nil {
 		return nil, err
@@ -269,6 +276,15 @@ func getPod(dataDir string, uuid *types.UUID) (*Pod, error) {
 
 	p.FileLock = l
 
+	if p.isPrepared {
+		committed, err := p.prepareCommitted()
+		if err != nil {
+			l.Close()
+			return nil, errwrap.Wrap(fmt.Errorf("error checking preparation of pod %q", uuid), err)
+		}
+		p.isIncomplete = !committed
+	}
+
 	if p.isRunning() || p.isExit() {
 		cfd, err := p.Fd()
 		if err != nil {
@@ -377,6 +393,15 @@ func (p *Pod) ToPrepared() error {
 		return fmt.Errorf("bug: only preparing pods may transition to prepared")
 	}
 
+	// The pod data must be on disk before the journal is committed,
+	// otherwise a power loss could leave a committed but broken pod.
+	if err := p.Sync(); err != nil {
+		return err
+	}
+	if err := p.commitPrepareJournal(); err != nil {
+		return err
+	}
+
 	if err := os.Rename(p.Path(), p.preparedPath()); err != nil {
 		return err
 	}
@@ -411,6 +436,10 @@ func (p *Pod) ToRun() error {
 		return fmt.Errorf("bug: only prepared or preparing pods may transition to run")
 	}
 
+	if p.isIncomplete {
+		return fmt.Errorf("pod %q did not finish preparing", p.UUID)
+	}
+
 	if err := p.ExclusiveLock(); err != nil {
 		return err
 	}
@@ -461,11 +490,12 @@ func (p *Pod) ToExitedGarbage() error {
 	return nil
 }
 
-// ToGarbage transitions a pod from abortedPrepared -> garbage or prepared -> garbage
+// ToGarbage transitions a pod from abortedPrepared -> garbage, prepared -> garbage or
+// embryo -> garbage. Embryos must be exclusively locked, to avoid racing with their creator.
 // This method refreshes the pod state.
 func (p *Pod) ToGarbage() error {
-	if !p.isAbortedPrepare && !p.isPrepared {
-		return fmt.Errorf("bug: only failed prepare or prepared pods may transition to garbage")
+	if !p.isAbortedPrepare && !p.isPrepared && !p.isEmbryo {
+		return fmt.Errorf("bug: only failed prepare, prepared or embryo pods may transition to garbage")
 	}
 
 	if err := os.Rename(p.Path(), p.garbagePath()); err != nil {
@@ -481,8 +511,10 @@ func (p *Pod) ToGarbage() error {
 		return err
 	}
 
+	p.isEmbryo = false
 	p.isAbortedPrepare = false
 	p.isPrepared = false
+	p.isIncomplete = false
 	p.isGarbage = true
 
 	return nil
@@ -567,6 +599,7 @@ func (p *Pod) refreshState() error {
 	p.isPreparing = false
 	p.isAbortedPrepare = false
 	p.isPrepared = false
+	p.isIncomplete = false
 	p.isExited = false
 	p.isExitedGarbage = false
 	p.isExitedDeleting = false
@@ -616,6 +649,14 @@ func (p *Pod) refreshState() error {
 		return nil
 	}
 
+	if p.isPrepared {
+		committed, err := p.prepareCommitted()
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("error checking preparation of pod %q", p.UUID.String()), err)
+		}
+		p.isIncomplete = !committed
+	}
+
 	if p.isPrepared || p.isEmbryo {
 		// no need to try a shared lock for these; our state is already accurate
 		return nil
@@ -1153,6 +1194,8 @@ func (p *Pod) State() string {
 		return Preparing
 	case p.isAbortedPrepare:
 		return AbortedPrepare
+	case p.isPrepared && p.isIncomplete:
+		return AbortedPrepare
 	case p.isPrepared:
 		return Prepared
 	case p.isDeleting:
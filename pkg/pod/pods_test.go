@@ -351,3 +351,61 @@ func podToStates(p *Pod) states {
 		isGone:           p.isGone,
 	}
 }
+
+func TestPrepareJournal(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "rkt-pod-journal")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// initPods only creates the pods directories once per process
+	podsInitialized = false
+	p, err := NewPod(tmpDir)
+	if err != nil {
+		t.Fatalf("unable to create pod: %v", err)
+	}
+	defer p.Close()
+
+	if committed, err := p.prepareCommitted(); err != nil || committed {
+		t.Fatalf("expected an uncommitted journal, got %v (%v)", committed, err)
+	}
+	if err := p.ToPrepared(); err != nil {
+		t.Fatalf("unable to transition to prepared: %v", err)
+	}
+	p2, err := getPod(tmpDir, p.UUID)
+	if err != nil {
+		t.Fatalf("unable to get pod: %v", err)
+	}
+	defer p2.Close()
+	if p2.State() != Prepared {
+		t.Errorf("expected state %q, got %q", Prepared, p2.State())
+	}
+
+	// A prepared pod without a committed journal never finished preparing
+	uuid, err := types.NewUUID("eeeeeeee-eeee-eeee-eeee-eeeeeeeeeeee")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	podPath := filepath.Join(preparedDir(tmpDir), uuid.String())
+	if err := os.MkdirAll(podPath, 0750); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := beginPrepareJournal(podPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	p3, err := getPod(tmpDir, uuid)
+	if err != nil {
+		t.Fatalf("unable to get pod: %v", err)
+	}
+	defer p3.Close()
+	if p3.State() != AbortedPrepare {
+		t.Errorf("expected state %q, got %q", AbortedPrepare, p3.State())
+	}
+	if err := p3.ToRun(); err == nil {
+		t.Errorf("expected an error running an incomplete pod")
+	}
+	if err := p3.ToGarbage(); err != nil {
+		t.Errorf("unable to transition to garbage: %v", err)
+	}
+}
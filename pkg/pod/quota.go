@@ -0,0 +1,125 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package pod
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/pkg/lock"
+	"github.com/rkt/rkt/pkg/quota"
+)
+
+// The disk usage of a pod can be limited with a project quota on its
+// directory, which holds the writable layers of its apps. Each limited pod
+// records the ID of its project.
+const (
+	podProjectIDFilename = "storage-project"
+
+	// firstProjectID is the first project ID given to pods, leaving the
+	// lower ones to the host.
+	firstProjectID = 1 << 16
+)
+
+// quotaDir returns where the pods quotas are managed from.
+func quotaDir(dataDir string) string {
+	return filepath.Join(dataDir, "pods")
+}
+
+// projectID returns the project ID of the pod, 0 if its disk usage is not
+// limited.
+func (p *Pod) projectID() (uint32, error) {
+	b, err := p.readFile(podProjectIDFilename)
+	if err == syscall.ENOENT {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errwrap.Wrap(errors.New("error reading the pod project ID"), err)
+	}
+	id, err := strconv.ParseUint(string(b), 10, 32)
+	if err != nil {
+		return 0, errwrap.Wrap(fmt.Errorf("invalid pod project ID %q", b), err)
+	}
+	return uint32(id), nil
+}
+
+// allocateProjectID returns a project ID no other pod uses.
+func allocateProjectID(dataDir string) (uint32, error) {
+	id := uint32(firstProjectID)
+	err := WalkPods(dataDir, IncludeAllDirs, func(p *Pod) {
+		if pid, err := p.projectID(); err == nil && pid >= id {
+			id = pid + 1
+		}
+	})
+	return id, err
+}
+
+// SetStorageLimit limits the disk usage of the pod directory to limit bytes,
+// with a project quota. The filesystem of the data directory must have
+// project quotas enabled.
+func (p *Pod) SetStorageLimit(limit uint64) error {
+	c, err := quota.NewControl(quotaDir(p.dataDir))
+	if err != nil {
+		return err
+	}
+
+	id, err := p.projectID()
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		l, err := lock.ExclusiveLock(quotaDir(p.dataDir), lock.Dir)
+		if err != nil {
+			return errwrap.Wrap(errors.New("error locking the pods quotas"), err)
+		}
+		defer l.Close()
+
+		if id, err = allocateProjectID(p.dataDir); err != nil {
+			return errwrap.Wrap(errors.New("error allocating a project ID"), err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(p.Path(), podProjectIDFilename), []byte(strconv.FormatUint(uint64(id), 10)), 0640); err != nil {
+			return errwrap.Wrap(errors.New("error writing the pod project ID"), err)
+		}
+	}
+
+	if err := c.SetLimit(id, limit); err != nil {
+		return err
+	}
+	if err := quota.SetTreeProjectID(p.Path(), id); err != nil {
+		return errwrap.Wrap(errors.New("error setting the project ID of the pod files"), err)
+	}
+	return nil
+}
+
+// StorageUsage returns the disk usage of the pod directory, or nil if it is
+// not limited.
+func (p *Pod) StorageUsage() (*quota.Usage, error) {
+	id, err := p.projectID()
+	if err != nil || id == 0 {
+		return nil, err
+	}
+	c, err := quota.NewControl(quotaDir(p.dataDir))
+	if err != nil {
+		return nil, err
+	}
+	return c.GetUsage(id)
+}
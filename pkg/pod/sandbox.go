@@ -61,7 +61,13 @@ func (p *Pod) UpdateManifest(m *schema.PodManifest, path string) error {
 	if !p.mutable {
 		return ErrImmutable
 	}
+	return p.WriteManifest(m, path)
+}
 
+// WriteManifest writes the given pod manifest in the given path atomically on the file system,
+// whether the pod is mutable or not. It is meant for the changes allowed on any running pod, like
+// its resource limits. The pod manifest has to be locked using ExclusiveLockManifest first.
+func (p *Pod) WriteManifest(m *schema.PodManifest, path string) error {
 	mpath := common.PodManifestPath(path)
 	mstat, err := os.Stat(mpath)
 	if err != nil {
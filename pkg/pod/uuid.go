@@ -23,6 +23,7 @@ import (
 
 	"github.com/appc/spec/schema/types"
 	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/pkg/exitcode"
 )
 
 // matchUUID attempts to match the uuid specified as uuid against all pods present.
@@ -48,16 +49,21 @@ func matchUUID(dataDir, uuid string) ([]string, error) {
 }
 
 // resolveUUID attempts to resolve the uuid specified as uuid against all pods present.
+// The name of a pod is accepted as well.
 // An unambiguously matched uuid or nil is returned.
 func resolveUUID(dataDir, uuid string) (*types.UUID, error) {
 	uuid = strings.ToLower(uuid)
+	if u, err := resolveName(dataDir, uuid); err != nil || u != nil {
+		return u, err
+	}
+
 	m, err := matchUUID(dataDir, uuid)
 	if err != nil {
 		return nil, err
 	}
 
 	if len(m) == 0 {
-		return nil, fmt.Errorf("no matches found for %q", uuid)
+		return nil, exitcode.Wrap(exitcode.PodNotFound, fmt.Errorf("no matches found for %q", uuid))
 	}
 
 	if len(m) > 1 {
@@ -0,0 +1,235 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+// Package quota limits the disk usage of directory trees with the project
+// quotas of XFS and ext4. The files of a tree are given a project ID,
+// inherited by the files created below it, and the project is given a
+// limit of blocks.
+package quota
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/pkg/sys"
+)
+
+// ErrNotSupported is returned when the filesystem does not have project
+// quotas enabled.
+var ErrNotSupported = errors.New("project quotas are not enabled on the filesystem")
+
+// Usage is the disk usage of a project, in bytes.
+type Usage struct {
+	Used  uint64  `json:"used"`
+	Limit *uint64 `json:"limit"`
+}
+
+const (
+	// fsXflagProjinherit makes the files created in a directory
+	// inherit its project ID.
+	fsXflagProjinherit = 0x00000200
+
+	prjQuota     = 2
+	qXGetQuota   = 0x5803
+	qXSetQLim    = 0x5804
+	fsDquotVer   = 1
+	fsProjQuota  = 2
+	fsDqBHard    = 1 << 3
+	basicBlkSize = 512
+
+	// backingDevName is the block device node created to address the
+	// filesystem in quotactl(2).
+	backingDevName = "backingFsBlockDev"
+)
+
+// fsxattr is struct fsxattr of linux/fs.h.
+type fsxattr struct {
+	xflags     uint32
+	extsize    uint32
+	nextents   uint32
+	projid     uint32
+	cowextsize uint32
+	pad        [8]byte
+}
+
+// fsDiskQuota is struct fs_disk_quota of linux/dqblk_xfs.h.
+type fsDiskQuota struct {
+	version      int8
+	flags        int8
+	fieldmask    uint16
+	id           uint32
+	blkHardlimit uint64
+	blkSoftlimit uint64
+	inoHardlimit uint64
+	inoSoftlimit uint64
+	bcount       uint64
+	icount       uint64
+	itimer       int32
+	btimer       int32
+	iwarns       uint16
+	bwarns       uint16
+	padding2     int32
+	rtbHardlimit uint64
+	rtbSoftlimit uint64
+	rtbcount     uint64
+	rtbtimer     int32
+	rtbwarns     uint16
+	padding3     int16
+	padding4     [8]byte
+}
+
+func getFsxattr(path string) (*fsxattr, *os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	var attr fsxattr
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), sys.FS_IOC_FSGETXATTR, uintptr(unsafe.Pointer(&attr))); errno != 0 {
+		f.Close()
+		return nil, nil, errwrap.Wrap(fmt.Errorf("error getting the attributes of %q", path), errno)
+	}
+	return &attr, f, nil
+}
+
+// ProjectID returns the project ID of the file at path, 0 if it has none.
+func ProjectID(path string) (uint32, error) {
+	attr, f, err := getFsxattr(path)
+	if err != nil {
+		return 0, err
+	}
+	f.Close()
+	return attr.projid, nil
+}
+
+// SetProjectID gives the file at path the project ID id. The files created
+// in a directory inherit it.
+func SetProjectID(path string, id uint32) error {
+	attr, f, err := getFsxattr(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	attr.projid = id
+	if fi, err := f.Stat(); err == nil && fi.IsDir() {
+		attr.xflags |= fsXflagProjinherit
+	}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), sys.FS_IOC_FSSETXATTR, uintptr(unsafe.Pointer(attr))); errno != 0 {
+		return errwrap.Wrap(fmt.Errorf("error setting the project ID of %q", path), errno)
+	}
+	return nil
+}
+
+// SetTreeProjectID gives the project ID id to the directories and regular
+// files of the tree at root. Other files do not use any block.
+func SetTreeProjectID(root string, id uint32) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && !info.Mode().IsRegular() {
+			return nil
+		}
+		return SetProjectID(path, id)
+	})
+}
+
+// Control sets and reads the project quotas of a filesystem.
+type Control struct {
+	dev string
+}
+
+// NewControl returns a Control for the filesystem of dir, creating in dir
+// the block device node quotactl(2) addresses it with. It returns
+// ErrNotSupported when project quotas are not enabled.
+func NewControl(dir string) (*Control, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(dir, &st); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error getting the filesystem of %q", dir), err)
+	}
+
+	dev := filepath.Join(dir, backingDevName)
+	if err := os.Remove(dev); err != nil && !os.IsNotExist(err) {
+		return nil, errwrap.Wrap(errors.New("error removing the stale block device node"), err)
+	}
+	if err := syscall.Mknod(dev, syscall.S_IFBLK|0600, int(st.Dev)); err != nil {
+		return nil, errwrap.Wrap(errors.New("error creating the block device node"), err)
+	}
+
+	c := &Control{dev: dev}
+	if _, err := c.getQuota(0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Control) quotactl(cmd int, id uint32, q *fsDiskQuota) error {
+	dev, err := syscall.BytePtrFromString(c.dev)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall6(syscall.SYS_QUOTACTL, uintptr(cmd<<8|prjQuota), uintptr(unsafe.Pointer(dev)), uintptr(id), uintptr(unsafe.Pointer(q)), 0, 0)
+	switch errno {
+	case 0:
+		return nil
+	case syscall.ENOSYS, syscall.ENOTSUP, syscall.ESRCH, syscall.ENOTTY:
+		return ErrNotSupported
+	default:
+		return errno
+	}
+}
+
+func (c *Control) getQuota(id uint32) (*fsDiskQuota, error) {
+	var q fsDiskQuota
+	if err := c.quotactl(qXGetQuota, id, &q); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+// SetLimit limits the disk usage of the project id to limit bytes.
+func (c *Control) SetLimit(id uint32, limit uint64) error {
+	q := fsDiskQuota{
+		version:      fsDquotVer,
+		flags:        fsProjQuota,
+		fieldmask:    fsDqBHard,
+		id:           id,
+		blkHardlimit: (limit + basicBlkSize - 1) / basicBlkSize,
+	}
+	if err := c.quotactl(qXSetQLim, id, &q); err != nil {
+		return errwrap.Wrap(fmt.Errorf("error setting the limit of project %d", id), err)
+	}
+	return nil
+}
+
+// GetUsage returns the disk usage of the project id.
+func (c *Control) GetUsage(id uint32) (*Usage, error) {
+	q, err := c.getQuota(id)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error getting the quota of project %d", id), err)
+	}
+	u := &Usage{Used: q.bcount * basicBlkSize}
+	if q.blkHardlimit != 0 {
+		limit := q.blkHardlimit * basicBlkSize
+		u.Limit = &limit
+	}
+	return u, nil
+}
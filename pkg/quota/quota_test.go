@@ -0,0 +1,33 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package quota
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// TestStructSizes checks that the structs passed to the kernel have the
+// size of their C counterparts.
+func TestStructSizes(t *testing.T) {
+	if s := unsafe.Sizeof(fsxattr{}); s != 28 {
+		t.Errorf("expected struct fsxattr to be 28 bytes, got %d", s)
+	}
+	if s := unsafe.Sizeof(fsDiskQuota{}); s != 112 {
+		t.Errorf("expected struct fs_disk_quota to be 112 bytes, got %d", s)
+	}
+}
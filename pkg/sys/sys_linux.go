@@ -14,7 +14,10 @@
 
 package sys
 
-import "syscall"
+import (
+	"syscall"
+	"unsafe"
+)
 
 func Syncfs(fd int) error {
 	_, _, err := syscall.RawSyscall(SYS_SYNCFS, uintptr(fd), 0, 0)
@@ -23,3 +26,20 @@ func Syncfs(fd int) error {
 	}
 	return nil
 }
+
+// SchedSetaffinity restricts the calling thread to the given CPUs. The
+// processes it executes and the threads they create inherit it.
+func SchedSetaffinity(cpus []int) error {
+	var mask [1024 / 64]uint64
+	for _, cpu := range cpus {
+		if cpu < 0 || cpu >= len(mask)*64 {
+			return syscall.EINVAL
+		}
+		mask[cpu/64] |= 1 << uint(cpu%64)
+	}
+	_, _, err := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask[0])))
+	if err != 0 {
+		return syscall.Errno(err)
+	}
+	return nil
+}
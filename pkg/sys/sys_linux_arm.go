@@ -16,4 +16,7 @@ package sys
 
 const (
 	SYS_SYNCFS = 373
+
+	FS_IOC_FSGETXATTR = 0x801c581f
+	FS_IOC_FSSETXATTR = 0x401c5820
 )
@@ -20,4 +20,7 @@ import "syscall"
 
 const (
 	SYS_SYNCFS = syscall.SYS_SYNCFS
+
+	FS_IOC_FSGETXATTR = 0x801c581f
+	FS_IOC_FSSETXATTR = 0x401c5820
 )
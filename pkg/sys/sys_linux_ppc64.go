@@ -18,4 +18,7 @@ package sys
 
 const (
 	SYS_SYNCFS = 348
+
+	FS_IOC_FSGETXATTR = 0x401c581f
+	FS_IOC_FSSETXATTR = 0x801c5820
 )
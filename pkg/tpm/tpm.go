@@ -27,7 +27,12 @@ const timeout = 10 * time.Millisecond
 
 // Extend extends the TPM log with the provided string. Returns any error.
 func Extend(description string) error {
+	return ExtendPCR(15, description)
+}
+
+// ExtendPCR extends the given PCR of the TPM with the provided string,
+// and logs it in the TPM event log. Returns any error.
+func ExtendPCR(pcr int, description string) error {
 	connection := tpmclient.New("localhost:12041", timeout)
-	err := connection.Extend(15, 0x1000, nil, description)
-	return err
+	return connection.Extend(pcr, 0x1000, nil, description)
 }
@@ -15,7 +15,10 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -42,6 +45,7 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 const (
@@ -59,7 +63,9 @@ var (
 		Long: fmt.Sprintf(`The API service listens for gRPC requests on the address and port specified by
 the --listen option, by default %s
 
-Specify the address 0.0.0.0 to listen on all interfaces.
+Specify the address 0.0.0.0 to listen on all interfaces. In that case, serve
+over TLS with --tls-cert and --tls-key, and use --tls-client-ca to only accept
+clients presenting a certificate signed by the given CA.
 
 It will also run correctly as a systemd socket-activated service, see
 systemd.socket(5).`, common.APIServiceListenAddr),
@@ -67,6 +73,9 @@ systemd.socket(5).`, common.APIServiceListenAddr),
 	}
 
 	flagAPIServiceListenAddr string
+	flagAPIServiceTLSCert    string
+	flagAPIServiceTLSKey     string
+	flagAPIServiceTLSCA      string
 	flagSocketListen         bool
 	systemdFDs               = activation.Files // for mocking
 )
@@ -74,6 +83,9 @@ systemd.socket(5).`, common.APIServiceListenAddr),
 func init() {
 	cmdRkt.AddCommand(cmdAPIService)
 	cmdAPIService.Flags().StringVar(&flagAPIServiceListenAddr, "listen", "", "address to listen for client API requests")
+	cmdAPIService.Flags().StringVar(&flagAPIServiceTLSCert, "tls-cert", "", "PEM encoded certificate to serve the API over TLS")
+	cmdAPIService.Flags().StringVar(&flagAPIServiceTLSKey, "tls-key", "", "PEM encoded private key of the certificate given with --tls-cert")
+	cmdAPIService.Flags().StringVar(&flagAPIServiceTLSCA, "tls-client-ca", "", "PEM encoded CA bundle used to verify the certificates that clients are required to present")
 }
 
 type podCacheItem struct {
@@ -128,7 +140,7 @@ type v1AlphaAPIServer struct {
 var _ v1alpha.PublicAPIServer = &v1AlphaAPIServer{}
 
 func newV1AlphaAPIServer() (*v1AlphaAPIServer, error) {
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		return nil, err
 	}
@@ -916,7 +928,16 @@ func runAPIService(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
-	publicServer := grpc.NewServer() // TODO(yifan): Add TLS credential option.
+	var opts []grpc.ServerOption
+	tlsConfig, err := apiServiceTLSConfig()
+	if err != nil {
+		stderr.PrintE("invalid TLS configuration", err)
+		return 254
+	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	publicServer := grpc.NewServer(opts...)
 
 	v1AlphaAPIServer, err := newV1AlphaAPIServer()
 	if err != nil {
@@ -976,3 +997,40 @@ func openAPISockets() ([]net.Listener, error) {
 
 	return listeners, nil
 }
+
+// apiServiceTLSConfig returns the TLS configuration of the API service, or nil
+// if the API is served in clear text.
+func apiServiceTLSConfig() (*tls.Config, error) {
+	if flagAPIServiceTLSCert == "" && flagAPIServiceTLSKey == "" {
+		if flagAPIServiceTLSCA != "" {
+			return nil, errors.New("--tls-client-ca requires --tls-cert and --tls-key")
+		}
+		return nil, nil
+	}
+	if flagAPIServiceTLSCert == "" || flagAPIServiceTLSKey == "" {
+		return nil, errors.New("both --tls-cert and --tls-key must be given")
+	}
+
+	cert, err := tls.LoadX509KeyPair(flagAPIServiceTLSCert, flagAPIServiceTLSKey)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error loading the TLS certificate"), err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if flagAPIServiceTLSCA != "" {
+		pem, err := ioutil.ReadFile(flagAPIServiceTLSCA)
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("error reading the client CA bundle"), err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %q", flagAPIServiceTLSCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return config, nil
+}
@@ -18,10 +18,10 @@ import (
 	"fmt"
 
 	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/pkg/exitcode"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/rkt/image"
 	"github.com/rkt/rkt/stage0"
-	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 
 	"github.com/spf13/cobra"
@@ -40,6 +40,7 @@ func init() {
 	cmdApp.AddCommand(cmdAppAdd)
 	addAppFlags(cmdAppAdd)
 	addIsolatorFlags(cmdAppAdd, false)
+	addSecurityProfileFlag(cmdAppAdd)
 
 	// Add per-app volume mounts only for sandbox for now
 	cmdAppAdd.Flags().Var((*appMountVolume)(&rktApps), "mnt-volume", "Configure a per-app mount and volume directly")
@@ -67,10 +68,15 @@ func runAppAdd(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
+	if err := applySecurityProfile(&rktApps); err != nil {
+		stderr.PrintE("cannot apply the security profile", err)
+		return 254
+	}
+
 	p, err := pkgPod.PodFromUUIDString(getDataDir(), args[0])
 	if err != nil {
 		stderr.PrintE("problem retrieving pod", err)
-		return 254
+		return exitcode.FromError(err)
 	}
 	defer p.Close()
 
@@ -84,7 +90,7 @@ func runAppAdd(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
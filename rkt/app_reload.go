@@ -0,0 +1,149 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+//+build linux
+
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/rkt/rkt/pkg/exitcode"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+	"github.com/rkt/rkt/stage0"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdAppReload = &cobra.Command{
+		Use:   "reload UUID --app=NAME [--signal=SIGNAL]",
+		Short: "Reload the configuration of an app in a pod",
+		Long: `Sends the reload signal, SIGHUP by default, to the main process of a running app, to reload its configuration without restarting the pod.
+
+The environment variables given with --set-env and --set-env-file are recorded for the app, which sees them from its next start.`,
+		Run: ensureSuperuser(runWrapper(runAppReload)),
+	}
+	flagReloadSignal  string
+	flagReloadEnv     kvMap
+	flagReloadEnvFile envFileMap
+)
+
+// reloadSignals are the signals accepted by name by rkt app reload.
+var reloadSignals = map[string]syscall.Signal{
+	"HUP":   syscall.SIGHUP,
+	"INT":   syscall.SIGINT,
+	"QUIT":  syscall.SIGQUIT,
+	"USR1":  syscall.SIGUSR1,
+	"USR2":  syscall.SIGUSR2,
+	"TERM":  syscall.SIGTERM,
+	"CONT":  syscall.SIGCONT,
+	"WINCH": syscall.SIGWINCH,
+}
+
+func init() {
+	cmdAppReload.Flags().StringVar(&flagAppName, "app", "", "app to reload")
+	cmdAppReload.Flags().StringVar(&flagReloadSignal, "signal", "SIGHUP", "signal to send to the app, by name or number")
+	cmdAppReload.Flags().Var(&flagReloadEnv, "set-env", "environment variable to set for the app in the form key=value")
+	cmdAppReload.Flags().Var(&flagReloadEnvFile, "set-env-file", "path to an environment variables file")
+	cmdApp.AddCommand(cmdAppReload)
+}
+
+// parseSignal parses a signal given by name, with or without the SIG prefix,
+// or by number.
+func parseSignal(s string) (syscall.Signal, error) {
+	if n, err := strconv.Atoi(s); err == nil {
+		if n <= 0 || n > 64 {
+			return 0, fmt.Errorf("invalid signal number %d", n)
+		}
+		return syscall.Signal(n), nil
+	}
+	if sig, ok := reloadSignals[strings.TrimPrefix(strings.ToUpper(s), "SIG")]; ok {
+		return sig, nil
+	}
+	return 0, fmt.Errorf("unknown signal %q", s)
+}
+
+func runAppReload(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) < 1 {
+		stderr.Print("must provide the pod UUID")
+		return 254
+	}
+
+	if flagAppName == "" {
+		stderr.Print("must provide the app to reload")
+		return 254
+	}
+
+	appName, err := types.NewACName(flagAppName)
+	if err != nil {
+		stderr.PrintE("invalid app name", err)
+		return 254
+	}
+
+	signal, err := parseSignal(flagReloadSignal)
+	if err != nil {
+		stderr.PrintE("invalid signal", err)
+		return 254
+	}
+
+	p, err := pkgPod.PodFromUUIDString(getDataDir(), args[0])
+	if err != nil {
+		stderr.PrintE("problem retrieving pod", err)
+		return exitcode.FromError(err)
+	}
+	defer p.Close()
+
+	if p.State() != pkgPod.Running {
+		stderr.Printf("pod %q isn't currently running", p.UUID)
+		return 254
+	}
+
+	podPID, err := p.ContainerPid1()
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("unable to determine the pid for pod %q", p.UUID), err)
+		return 254
+	}
+
+	cfg := stage0.CommonConfig{
+		DataDir: getDataDir(),
+		UUID:    p.UUID,
+		Debug:   globalFlags.Debug,
+	}
+
+	rcfg := stage0.ReloadConfig{
+		CommonConfig: &cfg,
+		PodPath:      p.Path(),
+		PodPID:       podPID,
+		AppName:      appName,
+		Signal:       signal,
+		// The variables given explicitly override the ones of the files
+		Env: append(flagReloadEnvFile.Strings(), flagReloadEnv.Strings()...),
+	}
+
+	if globalFlags.Debug {
+		stage0.InitDebug()
+	}
+
+	if err := stage0.ReloadApp(rcfg); err != nil {
+		stderr.PrintE("error reloading app", err)
+		return 254
+	}
+
+	return 0
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+//+build linux
+
+package main
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestParseSignal(t *testing.T) {
+	tests := []struct {
+		in  string
+		sig syscall.Signal
+		err bool
+	}{
+		{in: "SIGHUP", sig: syscall.SIGHUP},
+		{in: "usr1", sig: syscall.SIGUSR1},
+		{in: "SIGusr2", sig: syscall.SIGUSR2},
+		{in: "10", sig: syscall.Signal(10)},
+		{in: "0", err: true},
+		{in: "65", err: true},
+		{in: "SIGKILLME", err: true},
+		{in: "", err: true},
+	}
+
+	for _, tt := range tests {
+		sig, err := parseSignal(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.in, err)
+			continue
+		}
+		if sig != tt.sig {
+			t.Errorf("%q: expected signal %d, got %d", tt.in, tt.sig, sig)
+		}
+	}
+}
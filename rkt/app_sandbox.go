@@ -27,7 +27,6 @@ import (
 	"github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/pkg/user"
 	"github.com/rkt/rkt/stage0"
-	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 	"github.com/spf13/cobra"
 )
@@ -51,6 +50,7 @@ func init() {
 	addStage1ImageFlags(cmdAppSandbox.Flags())
 	cmdAppSandbox.Flags().StringVar(&flagUUIDFileSave, "uuid-file-save", "", "write out pod UUID to specified file")
 	cmdAppSandbox.Flags().Var(&flagNet, "net", "configure the pod's networking. Optionally, pass a list of user-configured networks to load and set arguments to pass to each network, respectively. Syntax: --net[=n[:args], ...]")
+	markNetFlagCompletion(cmdAppSandbox.Flags())
 	cmdAppSandbox.Flags().BoolVar(&flagNoOverlay, "no-overlay", false, "disable overlay filesystem")
 	cmdAppSandbox.Flags().Var(&flagDNS, "dns", "name servers to write in /etc/resolv.conf")
 	cmdAppSandbox.Flags().Var(&flagDNSSearch, "dns-search", "DNS search domains to write in /etc/resolv.conf")
@@ -67,7 +67,7 @@ func init() {
 }
 
 func runAppSandbox(cmd *cobra.Command, args []string) int {
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 1
@@ -207,6 +207,11 @@ func runAppSandbox(cmd *cobra.Command, args []string) int {
 		UseOverlay:           useOverlay,
 		HostsEntries:         *HostsEntries,
 	}
+	rcfg.Measurement, err = podMeasurement()
+	if err != nil {
+		stderr.PrintE("cannot get the measurement configuration", err)
+		return 1
+	}
 
 	_, manifest, err := p.PodManifest()
 	if err != nil {
@@ -17,6 +17,7 @@ package main
 import (
 	"fmt"
 
+	"github.com/rkt/rkt/pkg/exitcode"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/stage0"
 
@@ -54,7 +55,7 @@ func runAppStart(cmd *cobra.Command, args []string) (exit int) {
 	p, err := pkgPod.PodFromUUIDString(getDataDir(), args[0])
 	if err != nil {
 		stderr.PrintE("problem retrieving pod", err)
-		return 254
+		return exitcode.FromError(err)
 	}
 	defer p.Close()
 
@@ -63,7 +63,7 @@ func printApp(app *v1.App) {
 		for _, mnt := range app.Mounts {
 			mnts = append(mnts, fmt.Sprintf("%s:%s:(read_only:%v)", mnt.HostPath, mnt.ContainerPath, mnt.ReadOnly))
 		}
-		stdout.Printf(strings.Join(mnts, ","))
+		stdout.Print(strings.Join(mnts, ","))
 		stdout.Println()
 	}
 
@@ -73,7 +73,7 @@ func printApp(app *v1.App) {
 		for key, value := range app.UserAnnotations {
 			annos = append(annos, fmt.Sprintf("%s:%s", key, value))
 		}
-		stdout.Printf(strings.Join(annos, ","))
+		stdout.Print(strings.Join(annos, ","))
 		stdout.Println()
 	}
 
@@ -83,7 +83,7 @@ func printApp(app *v1.App) {
 		for key, value := range app.UserLabels {
 			labels = append(labels, fmt.Sprintf("%s:%s", key, value))
 		}
-		stdout.Printf(strings.Join(labels, ","))
+		stdout.Print(strings.Join(labels, ","))
 		stdout.Println()
 	}
 }
@@ -19,9 +19,9 @@ import (
 	"strings"
 
 	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/pkg/exitcode"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/stage0"
-	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 	"github.com/spf13/cobra"
 )
@@ -55,7 +55,7 @@ func runAttach(cmd *cobra.Command, args []string) (exit int) {
 	p, err := pkgPod.PodFromUUIDString(getDataDir(), uuid)
 	if err != nil {
 		stderr.PrintE("problem retrieving pod", err)
-		return 254
+		return exitcode.FromError(err)
 	}
 	defer p.Close()
 
@@ -76,7 +76,7 @@ func runAttach(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
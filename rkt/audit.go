@@ -0,0 +1,103 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/pkg/audit"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+	"github.com/spf13/cobra"
+)
+
+// auditedCommands are the commands recorded in the audit log, by their path
+// without the leading "rkt".
+var auditedCommands = map[string]bool{
+	"run":          true,
+	"run-prepared": true,
+	"prepare":      true,
+	"enter":        true,
+	"stop":         true,
+	"gc":           true,
+	"image rm":     true,
+	"update":       true,
+}
+
+// auditRecord is the record of the running command, nil if the command is
+// not audited or was already recorded.
+var auditRecord *audit.Record
+
+func startAudit(cmd *cobra.Command) {
+	op := strings.TrimPrefix(cmd.CommandPath(), cmdRkt.Name()+" ")
+	if auditedCommands[op] {
+		auditRecord = audit.NewRecord(op)
+	}
+}
+
+// auditPod adds the pod and the images of its apps to the record of the
+// running command.
+func auditPod(p *pkgPod.Pod) {
+	if auditRecord == nil {
+		return
+	}
+	auditRecord.Pods = append(auditRecord.Pods, p.UUID.String())
+	if _, manifest, err := p.PodManifest(); err == nil {
+		for _, app := range manifest.Apps {
+			auditImage(app.Image.ID.String())
+		}
+	}
+}
+
+// auditImage adds the image to the record of the running command.
+func auditImage(key string) {
+	if auditRecord == nil {
+		return
+	}
+	auditRecord.Images = append(auditRecord.Images, key)
+}
+
+// logAudit writes the record of the running command, with the outcome given
+// by its exit code, to the audit log configured. The commands replacing rkt
+// with the pod call it before doing so.
+func logAudit(exit int) error {
+	r := auditRecord
+	if r == nil {
+		return nil
+	}
+	auditRecord = nil
+
+	config, err := getConfig()
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot get configuration"), err)
+	}
+	var logger audit.Logger
+	switch config.Audit.Backend {
+	case "file":
+		logger = &audit.FileLogger{Path: config.Audit.Path}
+	case "journald":
+		logger = &audit.JournalLogger{}
+	default:
+		return nil
+	}
+
+	r.ExitCode = exit
+	r.Outcome = audit.OutcomeSuccess
+	if exit != 0 {
+		r.Outcome = audit.OutcomeFailure
+	}
+	return logger.Log(r)
+}
@@ -16,8 +16,13 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 
+	"github.com/rkt/rkt/pkg/exitcode"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
 	"github.com/spf13/cobra"
 )
 
@@ -25,16 +30,27 @@ var (
 	cmdCatManifest = &cobra.Command{
 		Use:   "cat-manifest --uuid-file=FILE | UUID ...",
 		Short: "Inspect and print the pod manifest",
-		Long:  `UUID should be the UUID of a pod`,
-		Run:   runWrapper(runCatManifest),
+		Long: `UUID should be the UUID of a pod.
+
+With --app, only the runtime app of the given app is printed. Adding
+--image-manifest prints the original image manifest of the app instead, as it
+was before being merged into the pod manifest.`,
+		Run: runWrapper(runCatManifest),
 	}
-	flagPMPrettyPrint bool
+	flagPMPrettyPrint        bool
+	flagPMApp                string
+	flagPMImageManifest      bool
+	flagPMResolveAnnotations bool
 )
 
 func init() {
 	cmdRkt.AddCommand(cmdCatManifest)
 	cmdCatManifest.Flags().BoolVar(&flagPMPrettyPrint, "pretty-print", true, "apply indent to format the output")
+	cmdCatManifest.Flags().BoolVar(&flagPMPrettyPrint, "pretty", true, "alias for --pretty-print")
 	cmdCatManifest.Flags().StringVar(&flagUUIDFile, "uuid-file", "", "read pod UUID from file instead of argument")
+	cmdCatManifest.Flags().StringVar(&flagPMApp, "app", "", "print only the manifest of the app with the given name")
+	cmdCatManifest.Flags().BoolVar(&flagPMImageManifest, "image-manifest", false, "print the original image manifest of the app selected with --app")
+	cmdCatManifest.Flags().BoolVar(&flagPMResolveAnnotations, "resolve-annotations", false, "print only the annotations, resolving the ones inherited from the image manifest")
 }
 
 func runCatManifest(cmd *cobra.Command, args []string) (exit int) {
@@ -55,24 +71,36 @@ func runCatManifest(cmd *cobra.Command, args []string) (exit int) {
 		podUUID = args[0]
 	}
 
+	if flagPMImageManifest && flagPMApp == "" {
+		stderr.Print("--image-manifest requires --app")
+		return 254
+	}
+
 	pod, err := pkgPod.PodFromUUIDString(getDataDir(), podUUID)
 	if err != nil {
 		stderr.PrintE("problem retrieving pod", err)
-		return 254
+		return exitcode.FromError(err)
 	}
 
 	defer pod.Close()
 
 	_, manifest, err := pod.PodManifest()
 	if err != nil {
+		stderr.PrintE("cannot read the pod manifest", err)
+		return 254
+	}
+
+	out, err := selectManifest(pod, manifest)
+	if err != nil {
+		stderr.Error(err)
 		return 254
 	}
 
 	var b []byte
 	if flagPMPrettyPrint {
-		b, err = json.MarshalIndent(manifest, "", "\t")
+		b, err = json.MarshalIndent(out, "", "\t")
 	} else {
-		b, err = json.Marshal(manifest)
+		b, err = json.Marshal(out)
 	}
 	if err != nil {
 		stderr.PrintE("cannot read the pod manifest", err)
@@ -82,3 +110,57 @@ func runCatManifest(cmd *cobra.Command, args []string) (exit int) {
 	stdout.Print(string(b))
 	return 0
 }
+
+// selectManifest returns the part of the pod manifest requested by the
+// command line flags.
+func selectManifest(pod *pkgPod.Pod, pm *schema.PodManifest) (interface{}, error) {
+	if flagPMApp == "" {
+		if flagPMResolveAnnotations {
+			return annotationsMap(pm.Annotations), nil
+		}
+		return pm, nil
+	}
+
+	appName, err := types.NewACName(flagPMApp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid app name %q: %v", flagPMApp, err)
+	}
+	ra := pm.Apps.Get(*appName)
+	if ra == nil {
+		return nil, fmt.Errorf("cannot find app %q in the pod", flagPMApp)
+	}
+
+	if !flagPMImageManifest && !flagPMResolveAnnotations {
+		return ra, nil
+	}
+
+	im, err := pod.AppImageManifest(flagPMApp)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read the image manifest of app %q: %v", flagPMApp, err)
+	}
+	if flagPMResolveAnnotations {
+		if flagPMImageManifest {
+			return annotationsMap(im.Annotations), nil
+		}
+		return resolveAppAnnotations(im, ra), nil
+	}
+	return im, nil
+}
+
+// resolveAppAnnotations returns the annotations in effect for an app: the
+// ones of its image manifest, overridden by the runtime app ones.
+func resolveAppAnnotations(im *schema.ImageManifest, ra *schema.RuntimeApp) map[string]string {
+	annotations := annotationsMap(im.Annotations)
+	for _, a := range ra.Annotations {
+		annotations[a.Name.String()] = a.Value
+	}
+	return annotations
+}
+
+func annotationsMap(annotations types.Annotations) map[string]string {
+	m := make(map[string]string, len(annotations))
+	for _, a := range annotations {
+		m[a.Name.String()] = a.Value
+	}
+	return m
+}
@@ -17,13 +17,16 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
+	"path/filepath"
 	"strconv"
 	"strings"
 
 	"github.com/hashicorp/errwrap"
 	"github.com/rkt/rkt/common/apps"
+	stage1types "github.com/rkt/rkt/stage1/common/types"
 
 	"github.com/appc/spec/schema"
 	"github.com/appc/spec/schema/types"
@@ -454,6 +457,12 @@ func (ag *appGroup) Type() string {
 	return "appGroup"
 }
 
+// errCapsRetainAndRemove is returned when both --caps-retain and
+// --caps-remove are given for the same image: they override the
+// capabilities isolators of the image with either a retain set or a
+// remove set, not both.
+var errCapsRetainAndRemove = errors.New("--caps-retain and --caps-remove cannot be used together on the same image")
+
 // appCapsRetain is for --caps-retain flags in the form of: --caps-retain=CAP_KILL,CAP_NET_ADMIN
 type appCapsRetain apps.Apps
 
@@ -462,6 +471,9 @@ func (au *appCapsRetain) Set(s string) error {
 	if app == nil {
 		return fmt.Errorf("--caps-retain must follow an image")
 	}
+	if app.CapsRemove != nil {
+		return errCapsRetainAndRemove
+	}
 	capsRetain, err := types.NewLinuxCapabilitiesRetainSet(strings.Split(s, ",")...)
 	if err != nil {
 		return err
@@ -492,7 +504,10 @@ type appCapsRemove apps.Apps
 func (au *appCapsRemove) Set(s string) error {
 	app := (*apps.Apps)(au).Last()
 	if app == nil {
-		return fmt.Errorf("--caps-retain must follow an image")
+		return fmt.Errorf("--caps-remove must follow an image")
+	}
+	if app.CapsRetain != nil {
+		return errCapsRetainAndRemove
 	}
 	capsRemove, err := types.NewLinuxCapabilitiesRevokeSet(strings.Split(s, ",")...)
 	if err != nil {
@@ -542,6 +557,301 @@ func (au *appSeccompFilter) Type() string {
 	return "appSeccompFilter"
 }
 
+// parseAbsPaths parses a comma-separated list of absolute paths.
+func parseAbsPaths(flagName, s string) ([]string, error) {
+	paths := strings.Split(s, ",")
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			return nil, fmt.Errorf("%s: path %q is not absolute", flagName, p)
+		}
+	}
+	return paths, nil
+}
+
+// appMaskedPaths is for --masked-paths flags in the form of: --masked-paths=/proc/kcore,/sys/firmware
+type appMaskedPaths apps.Apps
+
+func (au *appMaskedPaths) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--masked-paths must follow an image")
+	}
+	paths, err := parseAbsPaths("--masked-paths", s)
+	if err != nil {
+		return err
+	}
+	app.MaskedPaths = append(app.MaskedPaths, paths...)
+	return nil
+}
+
+func (au *appMaskedPaths) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return ""
+	}
+	return strings.Join(app.MaskedPaths, ",")
+}
+
+func (au *appMaskedPaths) Type() string {
+	return "appMaskedPaths"
+}
+
+// appReadOnlyPaths is for --readonly-paths flags in the form of: --readonly-paths=/proc/sys,/sys
+type appReadOnlyPaths apps.Apps
+
+func (au *appReadOnlyPaths) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--readonly-paths must follow an image")
+	}
+	paths, err := parseAbsPaths("--readonly-paths", s)
+	if err != nil {
+		return err
+	}
+	app.ReadOnlyPaths = append(app.ReadOnlyPaths, paths...)
+	return nil
+}
+
+func (au *appReadOnlyPaths) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return ""
+	}
+	return strings.Join(app.ReadOnlyPaths, ",")
+}
+
+func (au *appReadOnlyPaths) Type() string {
+	return "appReadOnlyPaths"
+}
+
+// appRLimit is for --rlimit flags in the form of: --rlimit=nofile=1024:65536
+type appRLimit apps.Apps
+
+func (au *appRLimit) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--rlimit must follow an image")
+	}
+	if _, err := stage1types.ParseRLimit(s); err != nil {
+		return err
+	}
+	app.RLimits = append(app.RLimits, s)
+	return nil
+}
+
+func (au *appRLimit) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return ""
+	}
+	return strings.Join(app.RLimits, " ")
+}
+
+func (au *appRLimit) Type() string {
+	return "appRLimit"
+}
+
+// appCPUSetCPUs is for --cpuset-cpus flags in the form of: --cpuset-cpus=0-3,8
+type appCPUSetCPUs apps.Apps
+
+func (au *appCPUSetCPUs) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--cpuset-cpus must follow an image")
+	}
+	if _, err := stage1types.ParseCPUList(s); err != nil {
+		return errwrap.Wrap(errors.New("--cpuset-cpus"), err)
+	}
+	app.CPUSetCPUs = s
+	return nil
+}
+
+func (au *appCPUSetCPUs) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return ""
+	}
+	return app.CPUSetCPUs
+}
+
+func (au *appCPUSetCPUs) Type() string {
+	return "appCPUSetCPUs"
+}
+
+// appCPUSetMems is for --cpuset-mems flags in the form of: --cpuset-mems=0,1
+type appCPUSetMems apps.Apps
+
+func (au *appCPUSetMems) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--cpuset-mems must follow an image")
+	}
+	if _, err := stage1types.ParseCPUList(s); err != nil {
+		return errwrap.Wrap(errors.New("--cpuset-mems"), err)
+	}
+	app.CPUSetMems = s
+	return nil
+}
+
+func (au *appCPUSetMems) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return ""
+	}
+	return app.CPUSetMems
+}
+
+func (au *appCPUSetMems) Type() string {
+	return "appCPUSetMems"
+}
+
+// appHugepages is for --hugepages flags in the form of: --hugepages=2MB:1Gi
+type appHugepages apps.Apps
+
+func (au *appHugepages) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--hugepages must follow an image")
+	}
+	if _, err := stage1types.ParseHugepages(s); err != nil {
+		return err
+	}
+	app.Hugepages = append(app.Hugepages, s)
+	return nil
+}
+
+func (au *appHugepages) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return ""
+	}
+	return strings.Join(app.Hugepages, " ")
+}
+
+func (au *appHugepages) Type() string {
+	return "appHugepages"
+}
+
+// appCPURTRuntime is for --cpu-rt-runtime flags in the form of: --cpu-rt-runtime=950000
+type appCPURTRuntime apps.Apps
+
+func (au *appCPURTRuntime) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--cpu-rt-runtime must follow an image")
+	}
+	runtime, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return errwrap.Wrap(errors.New("--cpu-rt-runtime"), err)
+	}
+	if err := (stage1types.Realtime{Runtime: runtime}).Validate(); err != nil {
+		return errwrap.Wrap(errors.New("--cpu-rt-runtime"), err)
+	}
+	app.CPURTRuntime = &runtime
+	return nil
+}
+
+func (au *appCPURTRuntime) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil || app.CPURTRuntime == nil {
+		return ""
+	}
+	return strconv.FormatUint(*app.CPURTRuntime, 10)
+}
+
+func (au *appCPURTRuntime) Type() string {
+	return "appCPURTRuntime"
+}
+
+// appCPURTPriority is for --cpu-rt-priority flags in the form of: --cpu-rt-priority=50
+type appCPURTPriority apps.Apps
+
+func (au *appCPURTPriority) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--cpu-rt-priority must follow an image")
+	}
+	priority, err := strconv.Atoi(s)
+	if err != nil {
+		return errwrap.Wrap(errors.New("--cpu-rt-priority"), err)
+	}
+	if err := (stage1types.Realtime{Priority: priority}).Validate(); err != nil {
+		return errwrap.Wrap(errors.New("--cpu-rt-priority"), err)
+	}
+	app.CPURTPriority = &priority
+	return nil
+}
+
+func (au *appCPURTPriority) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil || app.CPURTPriority == nil {
+		return ""
+	}
+	return strconv.Itoa(*app.CPURTPriority)
+}
+
+func (au *appCPURTPriority) Type() string {
+	return "appCPURTPriority"
+}
+
+// appMemorySwap is for --memory-swap flags in the form of: --memory-swap=512M
+type appMemorySwap apps.Apps
+
+func (au *appMemorySwap) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--memory-swap must follow an image")
+	}
+	if _, err := stage1types.NewMemorySwapIsolator(s); err != nil {
+		return err
+	}
+	app.MemorySwap = s
+	return nil
+}
+
+func (au *appMemorySwap) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return ""
+	}
+	return app.MemorySwap
+}
+
+func (au *appMemorySwap) Type() string {
+	return "appMemorySwap"
+}
+
+// appMemorySwappiness is for --memory-swappiness flags in the form of: --memory-swappiness=10
+type appMemorySwappiness apps.Apps
+
+func (au *appMemorySwappiness) Set(s string) error {
+	app := (*apps.Apps)(au).Last()
+	if app == nil {
+		return fmt.Errorf("--memory-swappiness must follow an image")
+	}
+	swappiness, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	if _, err := stage1types.NewMemorySwappinessIsolator(swappiness); err != nil {
+		return err
+	}
+	app.MemorySwappiness = &swappiness
+	return nil
+}
+
+func (au *appMemorySwappiness) String() string {
+	app := (*apps.Apps)(au).Last()
+	if app == nil || app.MemorySwappiness == nil {
+		return ""
+	}
+	return strconv.Itoa(*app.MemorySwappiness)
+}
+
+func (au *appMemorySwappiness) Type() string {
+	return "appMemorySwappiness"
+}
+
 // appOOMScoreAdj is to adjust /proc/$pid/oom_score_adj
 type appOOMScoreAdj apps.Apps
 
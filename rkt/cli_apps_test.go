@@ -147,3 +147,130 @@ func TestParsePortFlag(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCapsFlags(t *testing.T) {
+	tests := []struct {
+		in         string
+		capsRetain []types.LinuxCapability
+		capsRemove []types.LinuxCapability
+		werr       bool
+	}{
+		{
+			"example.com/foo --caps-retain=CAP_NET_ADMIN,CAP_KILL",
+			[]types.LinuxCapability{"CAP_NET_ADMIN", "CAP_KILL"},
+			nil,
+			false,
+		},
+		{
+			"example.com/foo --caps-remove=CAP_MKNOD",
+			nil,
+			[]types.LinuxCapability{"CAP_MKNOD"},
+			false,
+		},
+		{
+			"example.com/foo --caps-retain=CAP_NET_ADMIN --caps-remove=CAP_MKNOD",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"example.com/foo --caps-remove=CAP_MKNOD --caps-retain=CAP_NET_ADMIN",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"--caps-retain=CAP_NET_ADMIN example.com/foo",
+			nil,
+			nil,
+			true,
+		},
+	}
+
+	for i, tt := range tests {
+		rktApps.Reset()
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.SetInterspersed(false)
+		flags.Var((*appCapsRetain)(&rktApps), "caps-retain", "")
+		flags.Var((*appCapsRemove)(&rktApps), "caps-remove", "")
+		err := parseApps(&rktApps, strings.Split(tt.in, " "), flags, true)
+		if gerr := (err != nil); gerr != tt.werr {
+			t.Errorf("#%d: err==%v, want errstate %t", i, err, tt.werr)
+			continue
+		}
+		if tt.werr {
+			continue
+		}
+		app := rktApps.Last()
+		var capsRetain, capsRemove []types.LinuxCapability
+		if app.CapsRetain != nil {
+			capsRetain = app.CapsRetain.Set()
+		}
+		if app.CapsRemove != nil {
+			capsRemove = app.CapsRemove.Set()
+		}
+		if !reflect.DeepEqual(capsRetain, tt.capsRetain) {
+			t.Errorf("#%d: got retain set %v, want %v", i, capsRetain, tt.capsRetain)
+		}
+		if !reflect.DeepEqual(capsRemove, tt.capsRemove) {
+			t.Errorf("#%d: got remove set %v, want %v", i, capsRemove, tt.capsRemove)
+		}
+	}
+}
+
+func TestParsePathsFlags(t *testing.T) {
+	tests := []struct {
+		in       string
+		masked   []string
+		readOnly []string
+		werr     bool
+	}{
+		{
+			"example.com/foo --masked-paths=/proc/kcore,/sys/firmware --masked-paths=/proc/keys",
+			[]string{"/proc/kcore", "/sys/firmware", "/proc/keys"},
+			nil,
+			false,
+		},
+		{
+			"example.com/foo --readonly-paths=/proc/sys",
+			nil,
+			[]string{"/proc/sys"},
+			false,
+		},
+		{
+			"example.com/foo --masked-paths=proc/kcore",
+			nil,
+			nil,
+			true,
+		},
+		{
+			"--readonly-paths=/proc/sys example.com/foo",
+			nil,
+			nil,
+			true,
+		},
+	}
+
+	for i, tt := range tests {
+		rktApps.Reset()
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.SetInterspersed(false)
+		flags.Var((*appMaskedPaths)(&rktApps), "masked-paths", "")
+		flags.Var((*appReadOnlyPaths)(&rktApps), "readonly-paths", "")
+		err := parseApps(&rktApps, strings.Split(tt.in, " "), flags, true)
+		if gerr := (err != nil); gerr != tt.werr {
+			t.Errorf("#%d: err==%v, want errstate %t", i, err, tt.werr)
+			continue
+		}
+		if tt.werr {
+			continue
+		}
+		app := rktApps.Last()
+		if !reflect.DeepEqual(app.MaskedPaths, tt.masked) {
+			t.Errorf("#%d: got masked paths %v, want %v", i, app.MaskedPaths, tt.masked)
+		}
+		if !reflect.DeepEqual(app.ReadOnlyPaths, tt.readOnly) {
+			t.Errorf("#%d: got read-only paths %v, want %v", i, app.ReadOnlyPaths, tt.readOnly)
+		}
+	}
+}
@@ -15,19 +15,26 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"os"
+	"strconv"
+	"strings"
 
+	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var (
 	cmdCompletion = &cobra.Command{
 		Use:   "completion SHELL",
 		Short: "Output shell completion code for the specified shell",
-		Long: `This command outputs completion code for the specified shell. The generated
-code must be evaluated to provide interactive completion of rkt sub-commands.
-This can be done by sourcing it from the .bash_profile.
+		Long: `This command outputs completion code for the specified shell, one of bash,
+zsh or fish. The generated code must be evaluated to provide interactive
+completion of rkt sub-commands. Pod UUIDs, image names from the store and
+network names are completed by calling back into rkt.
 
 Save completion code in a home directory and then include it in .bash_profile
 script:
@@ -41,69 +48,144 @@ source "$HOME/.rkt.bash.inc"
 
 Alternatively, include the completion code directly into the launched shell:
 
-	$ source <(rkt completion bash)`,
-		ValidArgs: []string{"bash"},
+	$ source <(rkt completion bash)
+
+For zsh, save the completion code as _rkt in a directory of the $fpath:
+
+	$ rkt completion zsh > "${fpath[1]}/_rkt"
+
+For fish, save it in the completions directory:
+
+	$ rkt completion fish > $HOME/.config/fish/completions/rkt.fish`,
+		ValidArgs: []string{"bash", "zsh", "fish"},
 		Run:       runWrapper(newCompletion(os.Stdout)),
 	}
 
-	bashCompletionFunc = `__rkt_parse_image()
+	// completionArgs lists the commands whose arguments are completed
+	// dynamically, with the arguments passed to the hidden __complete
+	// command to list the candidates.
+	completionArgs = []struct {
+		command string
+		values  []string
+	}{
+		{"rkt_image_export", []string{"images"}},
+		{"rkt_image_extract", []string{"images"}},
+		{"rkt_image_cat-manifest", []string{"images"}},
+		{"rkt_image_render", []string{"images"}},
+		{"rkt_image_rm", []string{"images"}},
+		{"rkt_run", []string{"images"}},
+		{"rkt_prepare", []string{"images"}},
+		{"rkt_run-prepared", []string{"pods", pkgPod.Prepared}},
+		{"rkt_enter", []string{"pods", pkgPod.Running}},
+		{"rkt_attach", []string{"pods", pkgPod.Running}},
+		{"rkt_stop", []string{"pods", pkgPod.Running}},
+		{"rkt_export", []string{"pods", pkgPod.Exited, pkgPod.ExitedGarbage}},
+		{"rkt_rm", []string{"pods", pkgPod.Prepared, pkgPod.AbortedPrepare, pkgPod.Exited, pkgPod.ExitedGarbage}},
+		{"rkt_status", []string{"pods"}},
+		{"rkt_cat-manifest", []string{"pods"}},
+	}
+
+	bashCompletionFunc = `__rkt_complete()
+{
+	rkt __complete "$@" 2>/dev/null
+}
+
+__rkt_parse_values()
 {
 	local rkt_output
-	if rkt_output=$(rkt image list --no-legend 2>/dev/null); then
-		out=($(echo "${rkt_output}" | awk '{print $1}'))
-		COMPREPLY=( $( compgen -W "${out[*]}" -- "$cur" ) )
+	if rkt_output=$(__rkt_complete "$@"); then
+		COMPREPLY=( $( compgen -W "${rkt_output}" -- "$cur" ) )
 	fi
 }
 
-__rkt_parse_list()
+__rkt_parse_networks()
 {
-	local rkt_output
-	if rkt_output=$(rkt list --no-legend 2>/dev/null); then
-		if [[ -n "$1" ]]; then
-			out=($(echo "${rkt_output}" | grep ${1} | awk '{print $1}'))
-		else
-			out=($(echo "${rkt_output}" | awk '{print $1}'))
-		fi
-		COMPREPLY=( $( compgen -W "${out[*]}" -- "$cur" ) )
+	local rkt_output prefix=""
+	# --net takes a comma separated list of networks
+	if [[ "${cur}" == *,* ]]; then
+		prefix="${cur%,*},"
+	fi
+	if rkt_output=$(__rkt_complete networks); then
+		COMPREPLY=( $( compgen -P "${prefix}" -W "${rkt_output}" -- "${cur##*,}" ) )
 	fi
 }
 
-__custom_func() {
-	case ${last_command} in
-		rkt_image_export | \
-		rkt_image_extract | \
-		rkt_image_cat-manifest | \
-		rkt_image_render | \
-		rkt_image_rm | \
-		rkt_run | \
-		rkt_prepare)
-			__rkt_parse_image
-			return
-			;;
-		rkt_run-prepared)
-			__rkt_parse_list prepared
-			return
-			;;
-		rkt_enter)
-			__rkt_parse_list running
-			return
-			;;
-		rkt_rm)
-			__rkt_parse_list "prepare\|exited"
-			return
-			;;
-		rkt_status)
-			__rkt_parse_list
-			return
-			;;
-		*)
-			;;
-	esac
+` + bashCustomFunc()
+
+	// zshCompletionShims replace the bash-completion helpers used by the
+	// cobra generated script, which aren't available in zsh.
+	zshCompletionShims = `__rkt_get_comp_words_by_ref()
+{
+	cur="${COMP_WORDS[COMP_CWORD]}"
+	prev="${COMP_WORDS[COMP_CWORD-1]}"
+	words=("${COMP_WORDS[@]}")
+	cword=$COMP_CWORD
+}
+
+__rkt_ltrim_colon_completions()
+{
+	if [[ "$1" == *:* && "$COMP_WORDBREAKS" == *:* ]]; then
+		local colon_word=${1%"${1##*:}"}
+		local i=${#COMPREPLY[*]}
+		while [[ $((--i)) -ge 0 ]]; do
+			COMPREPLY[$i]=${COMPREPLY[$i]#"$colon_word"}
+		done
+	fi
+}
+
+__rkt_filedir()
+{
+	local w
+	if [[ "$1" == "-d" ]]; then
+		COMPREPLY=( $(compgen -d -- "$cur") )
+		return
+	fi
+	COMPREPLY=()
+	for w in $(compgen -f -- "$cur"); do
+		if [[ -d "$w" ]]; then
+			COMPREPLY+=("$w/")
+		elif [[ -z "$1" ]] || eval "[[ \"\$w\" == *.$1 ]]"; then
+			COMPREPLY+=("$w")
+		fi
+	done
 }
+
+`
+
+	zshCompletionReplacer = strings.NewReplacer(
+		`[[ $(type -t compopt) = "builtin" ]]`, "false",
+		"declare -F", "whence -w",
+		"_get_comp_words_by_ref", "__rkt_get_comp_words_by_ref",
+		"__ltrim_colon_completions", "__rkt_ltrim_colon_completions",
+		"_filedir", "__rkt_filedir",
+	)
+
+	fishCompletionFuncs = `function __rkt_command --description 'Print the rkt sub-command being completed'
+	set -l cmd rkt
+	for word in (commandline -opc)[2..-1]
+		if contains -- "$cmd"_"$word" $__rkt_commands
+			set cmd "$cmd"_"$word"
+		end
+	end
+	echo $cmd
+end
+
+function __rkt_using_command --description 'Test if the given rkt sub-command is being completed'
+	test (__rkt_command) = $argv[1]
+end
+
+function __rkt_complete --description 'List the completion candidates known by rkt'
+	rkt __complete $argv 2>/dev/null
+end
 `
 
+	// netCompletionFunc is the bash function completing the --net flag.
+	netCompletionFunc = "__rkt_parse_networks"
+
 	completionShells = map[string]func(io.Writer, *cobra.Command) error{
 		"bash": runBashCompletion,
+		"zsh":  runZshCompletion,
+		"fish": runFishCompletion,
 	}
 )
 
@@ -136,8 +218,6 @@ func runCompletion(w io.Writer, cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
-	// Right now only bash completion is supported, but zsh could be
-	// supported in a future as well.
 	completion, ok := completionShells[args[0]]
 	if !ok {
 		stderr.Printf("'%s' shell is not supported", args[0])
@@ -159,3 +239,136 @@ func runCompletion(w io.Writer, cmd *cobra.Command, args []string) (exit int) {
 func runBashCompletion(out io.Writer, cmd *cobra.Command) error {
 	return cmd.GenBashCompletion(out)
 }
+
+// runZshCompletion generates zsh completion script. It wraps the bash
+// completion script, which is loaded with bashcompinit in sh emulation.
+func runZshCompletion(out io.Writer, cmd *cobra.Command) error {
+	var bash bytes.Buffer
+	if err := cmd.GenBashCompletion(&bash); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "#compdef %s\n\n", cmd.Name())
+	fmt.Fprint(out, "autoload -U +X bashcompinit && bashcompinit\n\n")
+	fmt.Fprint(out, "emulate sh -o kshglob -o noshglob -o braceexpand -c \"$(cat <<'__RKT_BASH_COMPLETION'\n")
+	fmt.Fprint(out, zshCompletionShims)
+	fmt.Fprint(out, zshCompletionReplacer.Replace(bash.String()))
+	_, err := fmt.Fprint(out, "__RKT_BASH_COMPLETION\n)\"\n")
+	return err
+}
+
+// runFishCompletion generates fish completion script from the tree of
+// sub-commands and their flags.
+func runFishCompletion(out io.Writer, cmd *cobra.Command) error {
+	var buf bytes.Buffer
+	var commands []string
+
+	fmt.Fprintf(&buf, "complete -c %s -e\n\n", cmd.Name())
+	buf.WriteString(fishCompletionFuncs)
+	buf.WriteString("\n")
+
+	cmd.NonInheritedFlags().VisitAll(func(f *pflag.Flag) {
+		writeFishFlag(&buf, cmd.Name(), "", f)
+	})
+
+	var walk func(c *cobra.Command, path string)
+	walk = func(c *cobra.Command, path string) {
+		commands = append(commands, path)
+		for _, sub := range c.Commands() {
+			if !sub.IsAvailableCommand() {
+				continue
+			}
+			fmt.Fprintf(&buf, "complete -c %s -f -n '__rkt_using_command %s' -a %s -d %s\n",
+				cmd.Name(), path, fishQuote(sub.Name()), fishQuote(sub.Short))
+			walk(sub, path+"_"+sub.Name())
+		}
+		if c == cmd {
+			return
+		}
+		if len(c.ValidArgs) > 0 {
+			fmt.Fprintf(&buf, "complete -c %s -f -n '__rkt_using_command %s' -a %s\n",
+				cmd.Name(), path, fishQuote(strings.Join(c.ValidArgs, " ")))
+		}
+		c.NonInheritedFlags().VisitAll(func(f *pflag.Flag) {
+			writeFishFlag(&buf, cmd.Name(), path, f)
+		})
+	}
+	walk(cmd, cmd.Name())
+
+	for _, a := range completionArgs {
+		// Pods can't be referred to by a file, unlike images
+		noFiles := ""
+		if a.values[0] == "pods" {
+			noFiles = " -f"
+		}
+		fmt.Fprintf(&buf, "complete -c %s%s -n '__rkt_using_command %s' -a '(__rkt_complete %s)'\n",
+			cmd.Name(), noFiles, a.command, shellQuoteArgs(a.values))
+	}
+
+	fmt.Fprintf(out, "# fish completion for %s\n\n", cmd.Name())
+	fmt.Fprintf(out, "set -g __rkt_commands %s\n\n", strings.Join(commands, " "))
+	_, err := buf.WriteTo(out)
+	return err
+}
+
+// writeFishFlag writes the fish completion of the flag f. The flag is
+// completed for the sub-command path, or for all the sub-commands if path is
+// empty.
+func writeFishFlag(w io.Writer, name, path string, f *pflag.Flag) {
+	if f.Hidden || f.Deprecated != "" {
+		return
+	}
+
+	fmt.Fprintf(w, "complete -c %s", name)
+	if path != "" {
+		fmt.Fprintf(w, " -n '__rkt_using_command %s'", path)
+	}
+	fmt.Fprintf(w, " -l %s", f.Name)
+	if f.Shorthand != "" {
+		fmt.Fprintf(w, " -s %s", f.Shorthand)
+	}
+	if funcs, ok := f.Annotations[cobra.BashCompCustom]; ok && len(funcs) > 0 && funcs[0] == netCompletionFunc {
+		fmt.Fprint(w, " -x -a '(__rkt_complete networks)'")
+	} else if f.Value.Type() != "bool" {
+		fmt.Fprint(w, " -r")
+	}
+	fmt.Fprintf(w, " -d %s\n", fishQuote(f.Usage))
+}
+
+// bashCustomFunc generates the bash function completing the arguments of the
+// commands listed in completionArgs.
+func bashCustomFunc() string {
+	var buf bytes.Buffer
+	buf.WriteString("__custom_func() {\n\tcase ${last_command} in\n")
+	for _, a := range completionArgs {
+		fmt.Fprintf(&buf, "\t\t%s)\n", a.command)
+		fmt.Fprintf(&buf, "\t\t\t__rkt_parse_values %s\n", shellQuoteArgs(a.values))
+		buf.WriteString("\t\t\treturn\n\t\t\t;;\n")
+	}
+	buf.WriteString("\t\t*)\n\t\t\t;;\n\tesac\n}\n")
+	return buf.String()
+}
+
+// markNetFlagCompletion completes the values of the --net flag with the
+// available networks.
+func markNetFlagCompletion(flags *pflag.FlagSet) {
+	cobra.MarkFlagCustom(flags, "net", netCompletionFunc)
+}
+
+// shellQuoteArgs quotes the arguments containing spaces, like the pod
+// states, so they can be passed to __rkt_complete.
+func shellQuoteArgs(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if strings.ContainsAny(a, " \t") {
+			a = strconv.Quote(a)
+		}
+		quoted[i] = a
+	}
+	return strings.Join(quoted, " ")
+}
+
+// fishQuote quotes s as a single quoted fish string.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
@@ -32,10 +32,12 @@ func TestBashCompletion(t *testing.T) {
 		{254, []string{}},
 		// Only single argument is expected.
 		{254, []string{"two", "args"}},
-		// Fish shell is not supported.
-		{254, []string{"fish"}},
-		// Bash completion should succeed.
+		// Tcsh shell is not supported.
+		{254, []string{"tcsh"}},
+		// Bash, zsh and fish completion should succeed.
 		{0, []string{"bash"}},
+		{0, []string{"zsh"}},
+		{0, []string{"fish"}},
 	}
 
 	var buf bytes.Buffer
@@ -59,3 +61,52 @@ func TestBashCompletion(t *testing.T) {
 		t.Errorf("it is expected custom bash functions in the output")
 	}
 }
+
+func TestDynamicCompletion(t *testing.T) {
+	tests := []struct {
+		Shell    string
+		Contains []string
+	}{
+		{"bash", []string{
+			"__rkt_parse_values pods running",
+			`__rkt_parse_values pods exited "exited garbage"`,
+			"__rkt_parse_networks",
+		}},
+		{"zsh", []string{
+			"#compdef rkt",
+			"__rkt_get_comp_words_by_ref",
+			"__rkt_parse_values images",
+		}},
+		{"fish", []string{
+			"'(__rkt_complete pods running)'",
+			"'(__rkt_complete images)'",
+			"-l net -x -a '(__rkt_complete networks)'",
+		}},
+	}
+
+	var buf bytes.Buffer
+	handler := newCompletion(&buf)
+	stderr = log.New(ioutil.Discard, "", false)
+
+	for _, tt := range tests {
+		buf.Reset()
+		if code := handler(cmdCompletion, []string{tt.Shell}); code != 0 {
+			t.Fatalf("%s: got %v exit code, want 0", tt.Shell, code)
+		}
+		output := buf.String()
+		for _, c := range tt.Contains {
+			if !strings.Contains(output, c) {
+				t.Errorf("%s: expected %q in the output", tt.Shell, c)
+			}
+		}
+	}
+
+	// The zsh script must not depend on the bash-completion package.
+	buf.Reset()
+	handler(cmdCompletion, []string{"zsh"})
+	for _, f := range []string{" _get_comp_words_by_ref", " _filedir", " __ltrim_colon_completions", "declare -F"} {
+		if strings.Contains(buf.String(), f) {
+			t.Errorf("zsh: unexpected %q in the output", f)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rkt/rkt/networking"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// cmdCompleteValues is called back by the generated completion
+	// scripts to list the values which can't be known in advance.
+	cmdCompleteValues = &cobra.Command{
+		Use:    "__complete {pods [STATE...]|images|networks}",
+		Short:  "List the values for dynamic shell completion",
+		Hidden: true,
+		Run:    runWrapper(runCompleteValues),
+	}
+
+	completionValues = map[string]func([]string) ([]string, error){
+		"pods":     completePods,
+		"images":   completeImages,
+		"networks": completeNetworks,
+	}
+
+	// builtinNetworks are the network names always understood by --net.
+	builtinNetworks = []string{"all", "default", "default-restricted", "host", "none"}
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdCompleteValues)
+}
+
+func runCompleteValues(cmd *cobra.Command, args []string) int {
+	if len(args) == 0 {
+		cmd.Usage()
+		return 254
+	}
+
+	complete, ok := completionValues[args[0]]
+	if !ok {
+		stderr.Printf("unknown completion kind %q", args[0])
+		return 254
+	}
+
+	values, err := complete(args[1:])
+	if err != nil {
+		stderr.PrintE("unable to list completion values", err)
+		return 254
+	}
+
+	for _, v := range values {
+		stdout.Print(v)
+	}
+	return 0
+}
+
+// completePods lists the UUIDs of the pods in one of the given states, or of
+// all the pods if no state is given.
+func completePods(states []string) ([]string, error) {
+	wanted := make(map[string]struct{}, len(states))
+	for _, s := range states {
+		wanted[s] = struct{}{}
+	}
+
+	var uuids []string
+	err := pkgPod.WalkPods(getDataDir(), pkgPod.IncludeMostDirs, func(p *pkgPod.Pod) {
+		if _, ok := wanted[p.State()]; len(wanted) > 0 && !ok {
+			return
+		}
+		uuids = append(uuids, p.UUID.String())
+	})
+	return uuids, err
+}
+
+// completeImages lists the names and the IDs of the images in the store.
+func completeImages(_ []string) ([]string, error) {
+	s, err := openImageStore()
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	infos, err := s.GetAllACIInfos(nil, false)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, info := range infos {
+		name := info.Name
+		if im, err := s.GetImageManifest(info.BlobKey); err == nil {
+			if version, ok := im.Labels.Get("version"); ok {
+				name = fmt.Sprintf("%s:%s", name, version)
+			}
+		}
+		values = append(values, name, trimImageID(info.BlobKey))
+	}
+	return values, nil
+}
+
+// completeNetworks lists the built-in networks and the networks configured
+// in the local configuration directory.
+func completeNetworks(_ []string) ([]string, error) {
+	names := make(map[string]struct{})
+	for _, n := range builtinNetworks {
+		names[n] = struct{}{}
+	}
+
+	confs, err := filepath.Glob(filepath.Join(globalFlags.LocalConfigDir, networking.UserNetPathSuffix, "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range confs {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		var conf struct {
+			Name string `json:"name"`
+		}
+		// Broken configurations are reported when the network is loaded
+		if err := json.Unmarshal(b, &conf); err != nil || strings.TrimSpace(conf.Name) == "" {
+			continue
+		}
+		names[conf.Name] = struct{}{}
+	}
+
+	values := make([]string, 0, len(names))
+	for n := range names {
+		values = append(values, n)
+	}
+	sort.Strings(values)
+	return values, nil
+}
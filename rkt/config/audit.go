@@ -0,0 +1,70 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+type auditV1JsonParser struct{}
+
+// Audit holds where the privileged operations are recorded: in a file,
+// in the journal, or nowhere.
+type Audit struct {
+	Backend string `json:"backend"`
+	Path    string `json:"path,omitempty"`
+}
+
+func init() {
+	addParser("audit", "v1", &auditV1JsonParser{})
+	registerSubDir("audit.d", []string{"audit"})
+}
+
+// Enabled returns whether the privileged operations are recorded.
+func (a Audit) Enabled() bool {
+	return a.Backend != "" && a.Backend != "none"
+}
+
+func (p *auditV1JsonParser) parse(config *Config, raw []byte) error {
+	var a Audit
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return err
+	}
+	switch a.Backend {
+	case "none", "journald":
+		if a.Path != "" {
+			return fmt.Errorf("path cannot be specified with the %q backend", a.Backend)
+		}
+	case "file":
+		if a.Path == "" {
+			return errors.New("no path specified")
+		}
+		if !filepath.IsAbs(a.Path) {
+			return fmt.Errorf("path %q must be absolute", a.Path)
+		}
+	case "":
+		return errors.New("no backend specified")
+	default:
+		return fmt.Errorf("unknown backend %q", a.Backend)
+	}
+	if config.Audit.Backend != "" {
+		return errors.New("audit is already specified")
+	}
+	config.Audit = a
+	return nil
+}
@@ -94,6 +94,16 @@ type oAuthBearerTokenHeaderer struct {
 	auth oauthV1
 }
 
+// NewOAuthHeaderer returns a Headerer sending the passed OAuth bearer
+// token.
+func NewOAuthHeaderer(token string) Headerer {
+	return &oAuthBearerTokenHeaderer{
+		auth: oauthV1{
+			Token: token,
+		},
+	}
+}
+
 func (h *oAuthBearerTokenHeaderer) GetHeader() http.Header {
 	headers := make(http.Header)
 	headers.Add(authHeader, "Bearer "+h.auth.Token)
@@ -109,6 +119,24 @@ func (h *oAuthBearerTokenHeaderer) SignRequest(r *http.Request) *http.Request {
 
 type awsAuthHeaderer struct {
 	auth awsV1
+	// sessionToken is only set for temporary credentials, like
+	// the ones of an EC2 instance role.
+	sessionToken string
+}
+
+// NewAWSHeaderer returns a Headerer signing the requests to S3 with
+// the passed credentials. The session token is empty unless the
+// credentials are temporary. The region is guessed from the host of
+// the request if empty.
+func NewAWSHeaderer(accessKeyID, secretAccessKey, sessionToken, region string) Headerer {
+	return &awsAuthHeaderer{
+		auth: awsV1{
+			AccessKeyID:     accessKeyID,
+			SecretAccessKey: secretAccessKey,
+			Region:          region,
+		},
+		sessionToken: sessionToken,
+	}
 }
 
 func (h *awsAuthHeaderer) GetHeader() http.Header {
@@ -132,7 +160,7 @@ func (h *awsAuthHeaderer) SignRequest(r *http.Request) *http.Request {
 			SigningName:   awsS3Service,
 		},
 		Config: aws.Config{
-			Credentials: credentials.NewStaticCredentials(h.auth.AccessKeyID, h.auth.SecretAccessKey, ""),
+			Credentials: credentials.NewStaticCredentials(h.auth.AccessKeyID, h.auth.SecretAccessKey, h.sessionToken),
 		},
 		HTTPRequest: r,
 		Body:        body,
@@ -62,6 +62,14 @@ type Stage1Data struct {
 type Config struct {
 	AuthPerHost                  map[string]Headerer
 	DockerCredentialsPerRegistry map[string]BasicCredentials
+	TLSPerHost                   map[string]TLSCredentials
+	P2PPerHost                   map[string]P2PAgent
+	DistributionPlugins          map[string]string
+	StoreEncryption              StoreEncryption
+	Measurement                  Measurement
+	Audit                        Audit
+	Erase                        Erase
+	SecurityProfiles             map[string]SecurityProfile
 	Paths                        ConfigurablePaths
 	Stage1                       Stage1Data
 }
@@ -121,6 +129,126 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 		stage0 = append(stage0, dockerAuth)
 	}
 
+	for host, creds := range c.TLSPerHost {
+		tlsCreds := struct {
+			RktVersion string   `json:"rktVersion"`
+			RktKind    string   `json:"rktKind"`
+			Domains    []string `json:"domains"`
+			TLSCredentials
+		}{
+			RktVersion:     "v1",
+			RktKind:        "tls",
+			Domains:        []string{host},
+			TLSCredentials: creds,
+		}
+
+		stage0 = append(stage0, tlsCreds)
+	}
+
+	for host, agent := range c.P2PPerHost {
+		p2p := struct {
+			RktVersion string   `json:"rktVersion"`
+			RktKind    string   `json:"rktKind"`
+			Domains    []string `json:"domains"`
+			P2PAgent
+		}{
+			RktVersion: "v1",
+			RktKind:    "p2p",
+			Domains:    []string{host},
+			P2PAgent:   agent,
+		}
+
+		stage0 = append(stage0, p2p)
+	}
+
+	for typ, command := range c.DistributionPlugins {
+		plugin := struct {
+			RktVersion string `json:"rktVersion"`
+			RktKind    string `json:"rktKind"`
+			Type       string `json:"type"`
+			Command    string `json:"command"`
+		}{
+			RktVersion: "v1",
+			RktKind:    "distribution",
+			Type:       typ,
+			Command:    command,
+		}
+
+		stage0 = append(stage0, plugin)
+	}
+
+	for name, profile := range c.SecurityProfiles {
+		sp := struct {
+			RktVersion string `json:"rktVersion"`
+			RktKind    string `json:"rktKind"`
+			Name       string `json:"name"`
+			SecurityProfile
+		}{
+			RktVersion:      "v1",
+			RktKind:         "securityProfile",
+			Name:            name,
+			SecurityProfile: profile,
+		}
+
+		stage0 = append(stage0, sp)
+	}
+
+	if c.StoreEncryption.Enabled() {
+		encryption := struct {
+			RktVersion string `json:"rktVersion"`
+			RktKind    string `json:"rktKind"`
+			StoreEncryption
+		}{
+			RktVersion:      "v1",
+			RktKind:         "encryption",
+			StoreEncryption: c.StoreEncryption,
+		}
+
+		stage0 = append(stage0, encryption)
+	}
+
+	if c.Measurement.Enabled() {
+		measurement := struct {
+			RktVersion string `json:"rktVersion"`
+			RktKind    string `json:"rktKind"`
+			Measurement
+		}{
+			RktVersion:  "v1",
+			RktKind:     "measurement",
+			Measurement: c.Measurement,
+		}
+
+		stage0 = append(stage0, measurement)
+	}
+
+	if c.Audit.Backend != "" {
+		audit := struct {
+			RktVersion string `json:"rktVersion"`
+			RktKind    string `json:"rktKind"`
+			Audit
+		}{
+			RktVersion: "v1",
+			RktKind:    "audit",
+			Audit:      c.Audit,
+		}
+
+		stage0 = append(stage0, audit)
+	}
+
+	if c.Erase.Mode != "" {
+		erase := struct {
+			RktVersion string `json:"rktVersion"`
+			RktKind    string `json:"rktKind"`
+			Erase
+		}{
+			RktVersion: "v1",
+			RktKind:    "erase",
+			Erase:      c.Erase,
+		}
+
+		stage0 = append(stage0, erase)
+	}
+
 	paths := struct {
 		RktVersion   string `json:"rktVersion"`
 		RktKind      string `json:"rktKind"`
@@ -263,6 +391,10 @@ func newConfig() *Config {
 	return &Config{
 		AuthPerHost:                  make(map[string]Headerer),
 		DockerCredentialsPerRegistry: make(map[string]BasicCredentials),
+		TLSPerHost:                   make(map[string]TLSCredentials),
+		P2PPerHost:                   make(map[string]P2PAgent),
+		DistributionPlugins:          make(map[string]string),
+		SecurityProfiles:             make(map[string]SecurityProfile),
 		Paths: ConfigurablePaths{
 			DataDir: "",
 		},
@@ -400,6 +532,30 @@ func mergeConfigs(config *Config, subconfig *Config) {
 	for registry, creds := range subconfig.DockerCredentialsPerRegistry {
 		config.DockerCredentialsPerRegistry[registry] = creds
 	}
+	for host, creds := range subconfig.TLSPerHost {
+		config.TLSPerHost[host] = creds
+	}
+	for host, agent := range subconfig.P2PPerHost {
+		config.P2PPerHost[host] = agent
+	}
+	for typ, command := range subconfig.DistributionPlugins {
+		config.DistributionPlugins[typ] = command
+	}
+	for name, profile := range subconfig.SecurityProfiles {
+		config.SecurityProfiles[name] = profile
+	}
+	if subconfig.StoreEncryption.Enabled() {
+		config.StoreEncryption = subconfig.StoreEncryption
+	}
+	if subconfig.Measurement.Enabled() {
+		config.Measurement = subconfig.Measurement
+	}
+	if subconfig.Audit.Backend != "" {
+		config.Audit = subconfig.Audit
+	}
+	if subconfig.Erase.Mode != "" {
+		config.Erase = subconfig.Erase
+	}
 	if subconfig.Paths.DataDir != "" {
 		config.Paths.DataDir = subconfig.Paths.DataDir
 	}
@@ -129,6 +129,249 @@ func TestDockerAuthConfigFormat(t *testing.T) {
 	}
 }
 
+func TestTLSConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected map[string]TLSCredentials
+		fail     bool
+	}{
+		{`{"rktKind": "tls", "rktVersion": "v1"}`, nil, true},
+		{`{"rktKind": "tls", "rktVersion": "v1", "domains": []}`, nil, true},
+		{`{"rktKind": "tls", "rktVersion": "v1", "domains": ["example.com"]}`, nil, true},
+		{`{"rktKind": "tls", "rktVersion": "v1", "domains": ["example.com"], "caBundle": "ca.pem"}`, nil, true},
+		{`{"rktKind": "tls", "rktVersion": "v1", "domains": ["example.com"], "clientCert": "/cert.pem"}`, nil, true},
+		{`{"rktKind": "tls", "rktVersion": "v1", "domains": ["example.com"], "clientKey": "/key.pem"}`, nil, true},
+		{`{"rktKind": "tls", "rktVersion": "v1", "domains": ["example.com"], "caBundle": "/ca.pem"}`, map[string]TLSCredentials{"example.com": {CABundle: "/ca.pem"}}, false},
+		{`{"rktKind": "tls", "rktVersion": "v1", "domains": ["example.com", "example.org:8443"], "clientCert": "/cert.pem", "clientKey": "/key.pem"}`, map[string]TLSCredentials{"example.com": {ClientCert: "/cert.pem", ClientKey: "/key.pem"}, "example.org:8443": {ClientCert: "/cert.pem", ClientKey: "/key.pem"}}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "tls")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.TLSPerHost
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Error("Got unexpected results\nResult:\n", result, "\n\nExpected:\n", tt.expected)
+			}
+		}
+
+		if _, err := json.Marshal(cfg); err != nil {
+			t.Errorf("error marshaling config %v", err)
+		}
+	}
+}
+
+func TestP2PConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected map[string]P2PAgent
+		fail     bool
+	}{
+		{`{"rktKind": "p2p", "rktVersion": "v1"}`, nil, true},
+		{`{"rktKind": "p2p", "rktVersion": "v1", "domains": ["example.com"]}`, nil, true},
+		{`{"rktKind": "p2p", "rktVersion": "v1", "domains": ["example.com"], "agent": "127.0.0.1:65001"}`, nil, true},
+		{`{"rktKind": "p2p", "rktVersion": "v1", "domains": ["example.com"], "backend": "torrent", "agent": "http://127.0.0.1:65001"}`, nil, true},
+		{`{"rktKind": "p2p", "rktVersion": "v1", "domains": ["example.com"], "agent": "http://127.0.0.1:65001"}`, map[string]P2PAgent{"example.com": {Backend: "proxy", Agent: "http://127.0.0.1:65001"}}, false},
+		{`{"rktKind": "p2p", "rktVersion": "v1", "domains": ["example.com", "example.org"], "backend": "proxy", "agent": "http://127.0.0.1:65001", "fallback": true}`, map[string]P2PAgent{"example.com": {Backend: "proxy", Agent: "http://127.0.0.1:65001", Fallback: true}, "example.org": {Backend: "proxy", Agent: "http://127.0.0.1:65001", Fallback: true}}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "p2p")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.P2PPerHost
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Error("Got unexpected results\nResult:\n", result, "\n\nExpected:\n", tt.expected)
+			}
+		}
+
+		if _, err := json.Marshal(cfg); err != nil {
+			t.Errorf("error marshaling config %v", err)
+		}
+	}
+}
+
+func TestDistributionConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected map[string]string
+		fail     bool
+	}{
+		{`{"rktKind": "distribution", "rktVersion": "v1"}`, nil, true},
+		{`{"rktKind": "distribution", "rktVersion": "v1", "type": "artifactory"}`, nil, true},
+		{`{"rktKind": "distribution", "rktVersion": "v1", "type": "artifactory", "command": "resolve"}`, nil, true},
+		{`{"rktKind": "distribution", "rktVersion": "v1", "type": "Artifactory", "command": "/usr/bin/resolve"}`, nil, true},
+		{`{"rktKind": "distribution", "rktVersion": "v1", "type": "artifactory", "command": "/usr/bin/resolve"}`, map[string]string{"artifactory": "/usr/bin/resolve"}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "distribution")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.DistributionPlugins
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Error("Got unexpected results\nResult:\n", result, "\n\nExpected:\n", tt.expected)
+			}
+		}
+
+		if _, err := json.Marshal(cfg); err != nil {
+			t.Errorf("error marshaling config %v", err)
+		}
+	}
+}
+
+func TestEncryptionConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected StoreEncryption
+		fail     bool
+	}{
+		{`{"rktKind": "encryption", "rktVersion": "v1"}`, StoreEncryption{}, true},
+		{`{"rktKind": "encryption", "rktVersion": "v1", "keyFile": "store.key"}`, StoreEncryption{}, true},
+		{`{"rktKind": "encryption", "rktVersion": "v1", "keyCommand": ["tpm2_unseal"]}`, StoreEncryption{}, true},
+		{`{"rktKind": "encryption", "rktVersion": "v1", "keyFile": "/etc/rkt/store.key", "keyCommand": ["/usr/bin/tpm2_unseal"]}`, StoreEncryption{}, true},
+		{`{"rktKind": "encryption", "rktVersion": "v1", "keyFile": "/etc/rkt/store.key"}`, StoreEncryption{KeyFile: "/etc/rkt/store.key"}, false},
+		{`{"rktKind": "encryption", "rktVersion": "v1", "keyCommand": ["/usr/bin/tpm2_unseal", "-c", "0x81000001"]}`, StoreEncryption{KeyCommand: []string{"/usr/bin/tpm2_unseal", "-c", "0x81000001"}}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "encryption")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.StoreEncryption
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Got unexpected results\nResult:\n%#v\n\nExpected:\n%#v", result, tt.expected)
+			}
+		}
+
+		if _, err := json.Marshal(cfg); err != nil {
+			t.Errorf("error marshaling config %v", err)
+		}
+	}
+}
+
+func TestMeasurementConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected Measurement
+		fail     bool
+	}{
+		{`{"rktKind": "measurement", "rktVersion": "v1"}`, Measurement{}, true},
+		{`{"rktKind": "measurement", "rktVersion": "v1", "pcr": 7}`, Measurement{}, true},
+		{`{"rktKind": "measurement", "rktVersion": "v1", "pcr": 24}`, Measurement{}, true},
+		{`{"rktKind": "measurement", "rktVersion": "v1", "eventLog": "measurements.log"}`, Measurement{}, true},
+		{`{"rktKind": "measurement", "rktVersion": "v1", "pcr": 15}`, Measurement{PCR: 15}, false},
+		{`{"rktKind": "measurement", "rktVersion": "v1", "eventLog": "/var/log/rkt/measurements.log"}`, Measurement{EventLog: "/var/log/rkt/measurements.log"}, false},
+		{`{"rktKind": "measurement", "rktVersion": "v1", "pcr": 16, "eventLog": "/var/log/rkt/measurements.log"}`, Measurement{PCR: 16, EventLog: "/var/log/rkt/measurements.log"}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "measurement")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.Measurement
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Got unexpected results\nResult:\n%#v\n\nExpected:\n%#v", result, tt.expected)
+			}
+		}
+	}
+}
+
+func TestAuditConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected Audit
+		fail     bool
+	}{
+		{`{"rktKind": "audit", "rktVersion": "v1"}`, Audit{}, true},
+		{`{"rktKind": "audit", "rktVersion": "v1", "backend": "syslog"}`, Audit{}, true},
+		{`{"rktKind": "audit", "rktVersion": "v1", "backend": "file"}`, Audit{}, true},
+		{`{"rktKind": "audit", "rktVersion": "v1", "backend": "file", "path": "audit.log"}`, Audit{}, true},
+		{`{"rktKind": "audit", "rktVersion": "v1", "backend": "journald", "path": "/var/log/rkt/audit.log"}`, Audit{}, true},
+		{`{"rktKind": "audit", "rktVersion": "v1", "backend": "file", "path": "/var/log/rkt/audit.log"}`, Audit{Backend: "file", Path: "/var/log/rkt/audit.log"}, false},
+		{`{"rktKind": "audit", "rktVersion": "v1", "backend": "journald"}`, Audit{Backend: "journald"}, false},
+		{`{"rktKind": "audit", "rktVersion": "v1", "backend": "none"}`, Audit{Backend: "none"}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "audit")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.Audit
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Got unexpected results\nResult:\n%#v\n\nExpected:\n%#v", result, tt.expected)
+			}
+		}
+	}
+}
+
+func TestEraseConfigFormat(t *testing.T) {
+	tests := []struct {
+		contents string
+		expected Erase
+		fail     bool
+	}{
+		{`{"rktKind": "erase", "rktVersion": "v1"}`, Erase{}, true},
+		{`{"rktKind": "erase", "rktVersion": "v1", "mode": "wipe"}`, Erase{}, true},
+		{`{"rktKind": "erase", "rktVersion": "v1", "mode": "shred"}`, Erase{Mode: "shred"}, false},
+		{`{"rktKind": "erase", "rktVersion": "v1", "mode": "discard"}`, Erase{Mode: "discard"}, false},
+		{`{"rktKind": "erase", "rktVersion": "v1", "mode": "none"}`, Erase{Mode: "none"}, false},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "erase")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.Erase
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Got unexpected results\nResult:\n%#v\n\nExpected:\n%#v", result, tt.expected)
+			}
+		}
+	}
+}
+
+func TestSecurityProfileConfigFormat(t *testing.T) {
+	yes := true
+	tests := []struct {
+		contents string
+		expected map[string]SecurityProfile
+		fail     bool
+	}{
+		{`{"rktKind": "securityProfile", "rktVersion": "v1"}`, nil, true},
+		{`{"rktKind": "securityProfile", "rktVersion": "v1", "name": "web", "capsRetain": ["CAP_NET_BIND_SERVICE"], "capsRemove": ["CAP_MKNOD"]}`, nil, true},
+		{`{"rktKind": "securityProfile", "rktVersion": "v1", "name": "web", "capsRetain": ["NET_BIND_SERVICE"]}`, nil, true},
+		{`{"rktKind": "securityProfile", "rktVersion": "v1", "name": "web", "maskedPaths": ["proc/kcore"]}`, nil, true},
+		{`{"rktKind": "securityProfile", "rktVersion": "v1", "name": "web"}`, map[string]SecurityProfile{"web": {}}, false},
+		{
+			`{"rktKind": "securityProfile", "rktVersion": "v1", "name": "web", "capsRetain": ["CAP_NET_BIND_SERVICE"], "seccomp": "mode=retain,@rkt/default-whitelist", "noNewPrivileges": true, "maskedPaths": ["/proc/keys"]}`,
+			map[string]SecurityProfile{
+				"web": {
+					CapsRetain:      []string{"CAP_NET_BIND_SERVICE"},
+					Seccomp:         "mode=retain,@rkt/default-whitelist",
+					NoNewPrivileges: &yes,
+					MaskedPaths:     []string{"/proc/keys"},
+				},
+			},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		cfg, err := getConfigFromContents(tt.contents, "securityProfile")
+		if vErr := verifyFailure(tt.fail, tt.contents, err); vErr != nil {
+			t.Errorf("%v", vErr)
+		} else if !tt.fail {
+			result := cfg.SecurityProfiles
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Got unexpected results\nResult:\n%#v\n\nExpected:\n%#v", result, tt.expected)
+			}
+		}
+
+		if _, err := json.Marshal(cfg); err != nil {
+			t.Errorf("error marshaling config %v", err)
+		}
+	}
+}
+
 func TestPathsConfigFormat(t *testing.T) {
 	tests := []struct {
 		contents string
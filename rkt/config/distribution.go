@@ -0,0 +1,57 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	dist "github.com/rkt/rkt/pkg/distribution"
+)
+
+type distributionV1JsonParser struct{}
+
+type distributionV1 struct {
+	Type    string `json:"type"`
+	Command string `json:"command"`
+}
+
+func init() {
+	addParser("distribution", "v1", &distributionV1JsonParser{})
+	registerSubDir("distribution.d", []string{"distribution"})
+}
+
+func (p *distributionV1JsonParser) parse(config *Config, raw []byte) error {
+	var d distributionV1
+	if err := json.Unmarshal(raw, &d); err != nil {
+		return err
+	}
+	if err := dist.ValidatePluginType(dist.Type(d.Type)); err != nil {
+		return err
+	}
+	if d.Command == "" {
+		return errors.New("no command specified")
+	}
+	if !filepath.IsAbs(d.Command) {
+		return fmt.Errorf("command %q must be an absolute path", d.Command)
+	}
+	if _, ok := config.DistributionPlugins[d.Type]; ok {
+		return fmt.Errorf("plugin for distribution type %q is already specified", d.Type)
+	}
+	config.DistributionPlugins[d.Type] = d.Command
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/hashicorp/errwrap"
+)
+
+type encryptionV1JsonParser struct{}
+
+// StoreEncryption holds where the key encrypting the images of the store
+// comes from: either a key file, or the output of a command, like the
+// one unsealing a key with the TPM. The encryption is disabled when
+// neither is set.
+type StoreEncryption struct {
+	KeyFile    string   `json:"keyFile,omitempty"`
+	KeyCommand []string `json:"keyCommand,omitempty"`
+}
+
+func init() {
+	addParser("encryption", "v1", &encryptionV1JsonParser{})
+	registerSubDir("encryption.d", []string{"encryption"})
+}
+
+// Enabled returns whether the encryption of the store is configured.
+func (e StoreEncryption) Enabled() bool {
+	return e.KeyFile != "" || len(e.KeyCommand) != 0
+}
+
+// Key reads the key from the key file or from the output of the key
+// command.
+func (e StoreEncryption) Key() ([]byte, error) {
+	if e.KeyFile != "" {
+		key, err := ioutil.ReadFile(e.KeyFile)
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("error reading key file %q", e.KeyFile), err)
+		}
+		return key, nil
+	}
+	key, err := exec.Command(e.KeyCommand[0], e.KeyCommand[1:]...).Output()
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error running key command %q", e.KeyCommand[0]), err)
+	}
+	return key, nil
+}
+
+func (p *encryptionV1JsonParser) parse(config *Config, raw []byte) error {
+	var e StoreEncryption
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return err
+	}
+	if e.KeyFile != "" && len(e.KeyCommand) != 0 {
+		return errors.New("key file and key command cannot be specified together")
+	}
+	if !e.Enabled() {
+		return errors.New("neither key file nor key command specified")
+	}
+	if e.KeyFile != "" && !filepath.IsAbs(e.KeyFile) {
+		return fmt.Errorf("key file %q must be an absolute path", e.KeyFile)
+	}
+	if len(e.KeyCommand) != 0 && !filepath.IsAbs(e.KeyCommand[0]) {
+		return fmt.Errorf("key command %q must be an absolute path", e.KeyCommand[0])
+	}
+	if config.StoreEncryption.Enabled() {
+		return errors.New("store encryption is already specified")
+	}
+	config.StoreEncryption = e
+	return nil
+}
@@ -0,0 +1,53 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+type eraseV1JsonParser struct{}
+
+// Erase holds how the data of the removed pods and images is erased:
+// shredded, discarded, or just removed.
+type Erase struct {
+	Mode string `json:"mode"`
+}
+
+func init() {
+	addParser("erase", "v1", &eraseV1JsonParser{})
+	registerSubDir("erase.d", []string{"erase"})
+}
+
+func (p *eraseV1JsonParser) parse(config *Config, raw []byte) error {
+	var e Erase
+	if err := json.Unmarshal(raw, &e); err != nil {
+		return err
+	}
+	switch e.Mode {
+	case "none", "shred", "discard":
+	case "":
+		return errors.New("no mode specified")
+	default:
+		return fmt.Errorf("unknown mode %q", e.Mode)
+	}
+	if config.Erase.Mode != "" {
+		return errors.New("erase is already specified")
+	}
+	config.Erase = e
+	return nil
+}
@@ -0,0 +1,64 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+)
+
+type measurementV1JsonParser struct{}
+
+// Measurement holds where the images of the pods are measured when
+// they start: the TPM PCR to extend, the event log to append to, or
+// both. The measurement is disabled when neither is set.
+type Measurement struct {
+	PCR      int    `json:"pcr,omitempty"`
+	EventLog string `json:"eventLog,omitempty"`
+}
+
+func init() {
+	addParser("measurement", "v1", &measurementV1JsonParser{})
+	registerSubDir("measurement.d", []string{"measurement"})
+}
+
+// Enabled returns whether the measurement of the pods is configured.
+func (m Measurement) Enabled() bool {
+	return m.PCR != 0 || m.EventLog != ""
+}
+
+func (p *measurementV1JsonParser) parse(config *Config, raw []byte) error {
+	var m Measurement
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return err
+	}
+	if !m.Enabled() {
+		return errors.New("neither pcr nor event log specified")
+	}
+	// PCRs 0 to 7 are extended by the firmware and the boot loader.
+	if m.PCR != 0 && (m.PCR < 8 || m.PCR > 23) {
+		return fmt.Errorf("invalid pcr %d, must be between 8 and 23", m.PCR)
+	}
+	if m.EventLog != "" && !filepath.IsAbs(m.EventLog) {
+		return fmt.Errorf("event log %q must be an absolute path", m.EventLog)
+	}
+	if config.Measurement.Enabled() {
+		return errors.New("measurement is already specified")
+	}
+	config.Measurement = m
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+const (
+	// P2PBackendProxy is a P2P agent acting as an HTTP proxy, like
+	// the Dragonfly daemon.
+	P2PBackendProxy = "proxy"
+)
+
+type p2pV1JsonParser struct{}
+
+type p2pV1 struct {
+	Domains  []string `json:"domains"`
+	Backend  string   `json:"backend"`
+	Agent    string   `json:"agent"`
+	Fallback bool     `json:"fallback"`
+}
+
+// P2PAgent describes the peer-to-peer agent the images of a domain are
+// downloaded through.
+type P2PAgent struct {
+	Backend string `json:"backend"`
+	// Agent is the URL of the agent.
+	Agent string `json:"agent"`
+	// Fallback tells whether the images are downloaded directly
+	// when the agent can't be reached.
+	Fallback bool `json:"fallback"`
+}
+
+func init() {
+	addParser("p2p", "v1", &p2pV1JsonParser{})
+	registerSubDir("p2p.d", []string{"p2p"})
+}
+
+func (p *p2pV1JsonParser) parse(config *Config, raw []byte) error {
+	var p2p p2pV1
+	if err := json.Unmarshal(raw, &p2p); err != nil {
+		return err
+	}
+	if len(p2p.Domains) == 0 {
+		return errors.New("no domains specified")
+	}
+	if p2p.Backend == "" {
+		p2p.Backend = P2PBackendProxy
+	}
+	if p2p.Backend != P2PBackendProxy {
+		return fmt.Errorf("unsupported P2P backend %q, only %q is supported", p2p.Backend, P2PBackendProxy)
+	}
+	if p2p.Agent == "" {
+		return errors.New("no P2P agent specified")
+	}
+	u, err := url.Parse(p2p.Agent)
+	if err != nil {
+		return fmt.Errorf("invalid P2P agent URL %q: %v", p2p.Agent, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid P2P agent URL %q, expected http://HOST:PORT", p2p.Agent)
+	}
+	agent := P2PAgent{
+		Backend:  p2p.Backend,
+		Agent:    p2p.Agent,
+		Fallback: p2p.Fallback,
+	}
+	for _, domain := range p2p.Domains {
+		if _, ok := config.P2PPerHost[domain]; ok {
+			return fmt.Errorf("P2P agent for domain %q is already specified", domain)
+		}
+		config.P2PPerHost[domain] = agent
+	}
+	return nil
+}
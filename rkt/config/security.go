@@ -0,0 +1,81 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+type securityProfileV1JsonParser struct{}
+
+// SecurityProfile is a named set of restrictions applied to all the apps of
+// a pod, unless overridden for an app on the command line.
+type SecurityProfile struct {
+	// CapsRetain and CapsRemove replace the capabilities isolators
+	// of the images, only one of them can be set.
+	CapsRetain []string `json:"capsRetain,omitempty"`
+	CapsRemove []string `json:"capsRemove,omitempty"`
+	// Seccomp replaces the seccomp isolators of the images, with the
+	// syntax of the --seccomp flag.
+	Seccomp string `json:"seccomp,omitempty"`
+	// NoNewPrivileges replaces the no-new-privileges isolator of the
+	// images, when set.
+	NoNewPrivileges *bool `json:"noNewPrivileges,omitempty"`
+	// MaskedPaths are made inaccessible to the apps, in addition to
+	// the paths masked by stage1.
+	MaskedPaths []string `json:"maskedPaths,omitempty"`
+}
+
+type securityProfileV1 struct {
+	Name string `json:"name"`
+	SecurityProfile
+}
+
+func init() {
+	addParser("securityProfile", "v1", &securityProfileV1JsonParser{})
+	registerSubDir("security.d", []string{"securityProfile"})
+}
+
+func (p *securityProfileV1JsonParser) parse(config *Config, raw []byte) error {
+	var sp securityProfileV1
+	if err := json.Unmarshal(raw, &sp); err != nil {
+		return err
+	}
+	if sp.Name == "" {
+		return errors.New("no name specified")
+	}
+	if len(sp.CapsRetain) > 0 && len(sp.CapsRemove) > 0 {
+		return errors.New("capsRetain and capsRemove cannot be specified together")
+	}
+	for _, c := range append(sp.CapsRetain, sp.CapsRemove...) {
+		if !strings.HasPrefix(c, "CAP_") {
+			return fmt.Errorf("invalid capability %q", c)
+		}
+	}
+	for _, path := range sp.MaskedPaths {
+		if !filepath.IsAbs(path) {
+			return fmt.Errorf("masked path %q must be absolute", path)
+		}
+	}
+	if _, ok := config.SecurityProfiles[sp.Name]; ok {
+		return fmt.Errorf("security profile %q is already specified", sp.Name)
+	}
+	config.SecurityProfiles[sp.Name] = sp.SecurityProfile
+	return nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"github.com/hashicorp/errwrap"
+)
+
+type tlsV1JsonParser struct{}
+
+type tlsV1 struct {
+	Domains    []string `json:"domains"`
+	CABundle   string   `json:"caBundle"`
+	ClientCert string   `json:"clientCert"`
+	ClientKey  string   `json:"clientKey"`
+}
+
+// TLSCredentials holds the TLS settings used when downloading data
+// from a host: the CA bundle the server certificate must be signed by
+// and the certificate to authenticate with. All of them are optional.
+type TLSCredentials struct {
+	CABundle   string `json:"caBundle,omitempty"`
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+}
+
+func init() {
+	addParser("tls", "v1", &tlsV1JsonParser{})
+	registerSubDir("auth.d", []string{"tls"})
+}
+
+// TLSConfig loads the CA bundle and the client certificate and returns
+// the TLS configuration using them.
+func (c TLSCredentials) TLSConfig() (*tls.Config, error) {
+	config := &tls.Config{}
+	if c.CABundle != "" {
+		pem, err := ioutil.ReadFile(c.CABundle)
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("error reading CA bundle %q", c.CABundle), err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in CA bundle %q", c.CABundle)
+		}
+		config.RootCAs = pool
+	}
+	if c.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(c.ClientCert, c.ClientKey)
+		if err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("error loading client certificate %q", c.ClientCert), err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func (p *tlsV1JsonParser) parse(config *Config, raw []byte) error {
+	var t tlsV1
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return err
+	}
+	if len(t.Domains) == 0 {
+		return errors.New("no domains specified")
+	}
+	if t.CABundle == "" && t.ClientCert == "" && t.ClientKey == "" {
+		return errors.New("neither CA bundle nor client certificate specified")
+	}
+	if (t.ClientCert == "") != (t.ClientKey == "") {
+		return errors.New("client certificate and client key must be specified together")
+	}
+	for _, path := range []string{t.CABundle, t.ClientCert, t.ClientKey} {
+		if path != "" && !filepath.IsAbs(path) {
+			return fmt.Errorf("%q must be an absolute path", path)
+		}
+	}
+	creds := TLSCredentials{
+		CABundle:   t.CABundle,
+		ClientCert: t.ClientCert,
+		ClientKey:  t.ClientKey,
+	}
+	for _, domain := range t.Domains {
+		if _, ok := config.TLSPerHost[domain]; ok {
+			return fmt.Errorf("TLS configuration for domain %q is already specified", domain)
+		}
+		config.TLSPerHost[domain] = creds
+	}
+	return nil
+}
@@ -0,0 +1,460 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/common/cgroup"
+	"github.com/rkt/rkt/store/treestore"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdDoctor = &cobra.Command{
+		Use:   "doctor [--flavor=FLAVOR]",
+		Short: "Check whether the host is able to run pods",
+		Long: `Checks the kernel features, the devices, the tools, the data directory and the
+store used by rkt, and prints for each stage1 flavor whether it's ready to run
+pods.
+
+With --flavor, the exit status tells whether the given flavor is ready.`,
+		Run: runWrapper(runDoctor),
+	}
+	flagDoctorFlavor string
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdDoctor)
+	cmdDoctor.Flags().StringVar(&flagDoctorFlavor, "flavor", "", "exit with an error if the given stage1 flavor (coreos, fly, host, kvm, src) is not ready")
+}
+
+// Names of the host checks
+const (
+	checkOverlay     = "overlay"
+	checkUserNS      = "user-namespaces"
+	checkCgroups     = "cgroup-controllers"
+	checkKVM         = "kvm-device"
+	checkTun         = "tun-device"
+	checkFirewall    = "firewall"
+	checkDataDir     = "data-dir"
+	checkStore       = "store"
+	checkHostSystemd = "host-systemd"
+)
+
+// minHostSystemdVersion is the oldest systemd the host flavor works with.
+const minHostSystemdVersion = 220
+
+// doctorCheck is the result of a single host check.
+type doctorCheck struct {
+	name    string
+	ok      bool
+	details string
+	// hint tells how to fix a failed check
+	hint string
+}
+
+// flavorRequirements lists the checks needed by a stage1 flavor. A failed
+// required check makes the flavor not ready, a failed recommended check only
+// disables some features.
+type flavorRequirements struct {
+	required    []string
+	recommended []string
+}
+
+var doctorFlavors = []struct {
+	name string
+	reqs flavorRequirements
+}{
+	{"coreos", flavorRequirements{
+		required:    []string{checkStore},
+		recommended: []string{checkDataDir, checkOverlay, checkUserNS, checkCgroups, checkFirewall},
+	}},
+	{"fly", flavorRequirements{
+		required:    []string{checkStore},
+		recommended: []string{checkDataDir, checkOverlay},
+	}},
+	{"host", flavorRequirements{
+		required:    []string{checkStore, checkHostSystemd},
+		recommended: []string{checkDataDir, checkOverlay, checkUserNS, checkCgroups, checkFirewall},
+	}},
+	{"kvm", flavorRequirements{
+		required:    []string{checkStore, checkKVM, checkTun},
+		recommended: []string{checkDataDir, checkOverlay, checkFirewall},
+	}},
+	{"src", flavorRequirements{
+		required:    []string{checkStore},
+		recommended: []string{checkDataDir, checkOverlay, checkUserNS, checkCgroups, checkFirewall},
+	}},
+}
+
+func runDoctor(cmd *cobra.Command, args []string) int {
+	if len(args) > 0 {
+		cmd.Usage()
+		return 254
+	}
+	if flagDoctorFlavor != "" && findDoctorFlavor(flagDoctorFlavor) == nil {
+		stderr.Printf("unknown stage1 flavor %q", flagDoctorFlavor)
+		return 254
+	}
+
+	checks := []doctorCheck{
+		doctorCheckOverlay(),
+		doctorCheckUserNS(),
+		doctorCheckCgroups(),
+		doctorCheckKVM(),
+		doctorCheckTun(),
+		doctorCheckFirewall(),
+		doctorCheckDataDir(getDataDir()),
+		doctorCheckStore(),
+		doctorCheckHostSystemd(),
+	}
+	results := make(map[string]doctorCheck)
+
+	tabBuffer := new(bytes.Buffer)
+	tabOut := getTabOutWithWriter(tabBuffer)
+	fmt.Fprintf(tabOut, "CHECK\tSTATUS\tDETAILS\n")
+	for _, c := range checks {
+		results[c.name] = c
+		status := "ok"
+		if !c.ok {
+			status = "failed"
+		}
+		fmt.Fprintf(tabOut, "%s\t%s\t%s\n", c.name, status, c.details)
+	}
+	tabOut.Flush()
+	stdout.Print(tabBuffer)
+	tabBuffer.Reset()
+
+	fmt.Fprintf(tabOut, "\nFLAVOR\tREADINESS\tMISSING\n")
+	ready := true
+	for _, f := range doctorFlavors {
+		readiness, missing := flavorReadiness(f.reqs, results)
+		fmt.Fprintf(tabOut, "%s\t%s\t%s\n", f.name, readiness, strings.Join(missing, ","))
+		if f.name == flagDoctorFlavor && readiness == "not ready" {
+			ready = false
+		}
+	}
+	tabOut.Flush()
+	stdout.Print(tabBuffer)
+
+	var hints []string
+	for _, c := range checks {
+		if !c.ok && c.hint != "" {
+			hints = append(hints, fmt.Sprintf("%s: %s", c.name, c.hint))
+		}
+	}
+	if len(hints) > 0 {
+		stdout.Print("")
+		stdout.Print("Suggested fixes:")
+		for _, h := range hints {
+			stdout.Printf("  %s", h)
+		}
+	}
+
+	if !ready {
+		return 254
+	}
+	return 0
+}
+
+func findDoctorFlavor(name string) *flavorRequirements {
+	for _, f := range doctorFlavors {
+		if f.name == name {
+			return &f.reqs
+		}
+	}
+	return nil
+}
+
+// flavorReadiness returns "ready", "degraded" (some recommended checks
+// failed) or "not ready" (some required checks failed), together with the
+// names of the failed checks.
+func flavorReadiness(reqs flavorRequirements, results map[string]doctorCheck) (string, []string) {
+	var missing []string
+	readiness := "ready"
+	for _, name := range reqs.recommended {
+		if !results[name].ok {
+			missing = append(missing, name)
+			readiness = "degraded"
+		}
+	}
+	for _, name := range reqs.required {
+		if !results[name].ok {
+			missing = append(missing, name)
+			readiness = "not ready"
+		}
+	}
+	return readiness, missing
+}
+
+func doctorCheckOverlay() doctorCheck {
+	c := doctorCheck{name: checkOverlay}
+	if err := common.SupportsOverlay(); err != nil {
+		c.details = err.Error()
+		c.hint = "load the overlay kernel module, otherwise pods run with --no-overlay and take more disk space"
+		return c
+	}
+	c.ok = true
+	c.details = "overlay filesystem available"
+	return c
+}
+
+func doctorCheckUserNS() doctorCheck {
+	c := doctorCheck{name: checkUserNS}
+	if !common.SupportsUserNS() {
+		c.details = "kernel compiled without user namespace support"
+		c.hint = "use a kernel with CONFIG_USER_NS to be able to use --private-users"
+		return c
+	}
+	c.ok = true
+	c.details = "user namespaces available"
+	return c
+}
+
+func doctorCheckCgroups() doctorCheck {
+	c := doctorCheck{name: checkCgroups}
+	var missing []string
+	for _, controller := range []string{"cpu", "memory"} {
+		ok, err := cgroup.IsIsolatorSupported(controller)
+		if err != nil {
+			c.details = fmt.Sprintf("cannot check cgroup controllers: %v", err)
+			c.hint = "make sure cgroups are mounted in /sys/fs/cgroup"
+			return c
+		}
+		if !ok {
+			missing = append(missing, controller)
+		}
+	}
+	if len(missing) > 0 {
+		c.details = fmt.Sprintf("missing controllers: %s", strings.Join(missing, ", "))
+		c.hint = "enable the missing cgroup controllers, otherwise the resource isolators are ignored"
+		return c
+	}
+	c.ok = true
+	c.details = "cpu and memory controllers available"
+	return c
+}
+
+func doctorCheckKVM() doctorCheck {
+	c := doctorCheck{name: checkKVM}
+	f, err := os.OpenFile("/dev/kvm", os.O_RDWR, 0)
+	if err != nil {
+		c.details = fmt.Sprintf("cannot open /dev/kvm: %v", err)
+		c.hint = "enable virtualization in the firmware and load the kvm_intel or kvm_amd kernel module"
+		return c
+	}
+	f.Close()
+	c.ok = true
+	c.details = "/dev/kvm available"
+	return c
+}
+
+func doctorCheckTun() doctorCheck {
+	c := doctorCheck{name: checkTun}
+	fi, err := os.Stat("/dev/net/tun")
+	if err != nil {
+		c.details = fmt.Sprintf("cannot find /dev/net/tun: %v", err)
+		c.hint = "load the tun kernel module"
+		return c
+	}
+	if fi.Mode()&os.ModeCharDevice == 0 {
+		c.details = "/dev/net/tun is not a character device"
+		c.hint = "load the tun kernel module"
+		return c
+	}
+	c.ok = true
+	c.details = "/dev/net/tun available"
+	return c
+}
+
+func doctorCheckFirewall() doctorCheck {
+	c := doctorCheck{name: checkFirewall}
+	if p, err := exec.LookPath("iptables"); err == nil {
+		c.ok = true
+		c.details = fmt.Sprintf("iptables found at %s", p)
+		return c
+	}
+	if p, err := exec.LookPath("nft"); err == nil {
+		c.details = fmt.Sprintf("only nft found at %s, the default networks need iptables", p)
+	} else {
+		c.details = "neither iptables nor nft found"
+	}
+	c.hint = "install iptables (or the iptables-nft compatibility layer), otherwise only --net=host and networks without IP masquerading work"
+	return c
+}
+
+// doctorCheckDataDir checks that the data directory was set up as done by
+// dist/scripts/setup-data-dir.sh: directories owned by the rkt group, with
+// the setgid bit set. This is only needed to use rkt as a non-root user, root
+// creates the missing directories on demand.
+func doctorCheckDataDir(dataDir string) doctorCheck {
+	c := doctorCheck{name: checkDataDir}
+	hint := "run dist/scripts/setup-data-dir.sh, or systemd-tmpfiles with dist/init/systemd/tmpfiles.d/rkt.conf"
+
+	gid := -1
+	if g, err := user.LookupGroup("rkt"); err == nil {
+		gid, _ = strconv.Atoi(g.Gid)
+	}
+
+	var problems []string
+	for _, d := range []string{dataDir, storeDir(), filepath.Join(dataDir, "pods")} {
+		fi, err := os.Stat(d)
+		if os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("%s does not exist", d))
+			continue
+		}
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", d, err))
+			continue
+		}
+		if !fi.IsDir() {
+			problems = append(problems, fmt.Sprintf("%s is not a directory", d))
+			continue
+		}
+		st, ok := fi.Sys().(*syscall.Stat_t)
+		if gid >= 0 && ok && int(st.Gid) != gid {
+			problems = append(problems, fmt.Sprintf("%s is not owned by the rkt group", d))
+		}
+		if fi.Mode()&os.ModeSetgid == 0 {
+			problems = append(problems, fmt.Sprintf("%s has no setgid bit", d))
+		}
+	}
+	if gid < 0 {
+		problems = append(problems, "rkt group not found")
+	}
+	if len(problems) > 0 {
+		c.details = strings.Join(problems, "; ")
+		c.hint = hint
+		return c
+	}
+	c.ok = true
+	c.details = fmt.Sprintf("%s set up", dataDir)
+	return c
+}
+
+// doctorCheckStore checks that the store can be opened, that every image has
+// its blob and that every rendered tree belongs to an image in the store.
+// It does not verify the content of the rendered trees, which is what
+// "rkt image verify" is for.
+func doctorCheckStore() doctorCheck {
+	c := doctorCheck{name: checkStore}
+	hint := "remove the broken images with \"rkt image rm\" and run \"rkt image gc\""
+
+	// don't create the store as a side effect
+	if _, err := os.Stat(storeDir()); os.IsNotExist(err) {
+		c.ok = true
+		c.details = "store not created yet"
+		return c
+	}
+
+	s, err := openImageStore()
+	if err != nil {
+		c.details = fmt.Sprintf("cannot open store: %v", err)
+		c.hint = "check the permissions of the data directory, or run as root"
+		return c
+	}
+	defer s.Close()
+
+	ts, err := treestore.NewStore(treeStoreDir(), s)
+	if err != nil {
+		c.details = fmt.Sprintf("cannot open treestore: %v", err)
+		c.hint = "check the permissions of the data directory, or run as root"
+		return c
+	}
+
+	var problems []string
+	infos, err := s.GetAllACIInfos(nil, false)
+	if err != nil {
+		c.details = fmt.Sprintf("cannot list images: %v", err)
+		c.hint = hint
+		return c
+	}
+	for _, info := range infos {
+		rc, err := s.ReadStream(info.BlobKey)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("image %s has no blob", info.BlobKey))
+			continue
+		}
+		rc.Close()
+	}
+
+	ids, err := ts.GetIDs()
+	if err != nil {
+		c.details = fmt.Sprintf("cannot list rendered images: %v", err)
+		c.hint = hint
+		return c
+	}
+	orphans := 0
+	for _, id := range ids {
+		key, err := ts.GetImageHash(id)
+		if err != nil || !s.HasFullKey(key) {
+			orphans++
+		}
+	}
+	if orphans > 0 {
+		problems = append(problems, fmt.Sprintf("%d rendered images not matching any image in the store", orphans))
+	}
+
+	if len(problems) > 0 {
+		c.details = strings.Join(problems, "; ")
+		c.hint = hint
+		return c
+	}
+	c.ok = true
+	c.details = fmt.Sprintf("%d images, %d rendered images", len(infos), len(ids))
+	return c
+}
+
+func doctorCheckHostSystemd() doctorCheck {
+	c := doctorCheck{name: checkHostSystemd}
+	hint := fmt.Sprintf("the host flavor needs the host to be managed by systemd v%d or later", minHostSystemdVersion)
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		c.details = "the host is not managed by systemd"
+		c.hint = hint
+		return c
+	}
+	systemdBin, err := common.LookupPath("systemd", "/usr/lib/systemd:/lib/systemd")
+	if err != nil {
+		c.details = err.Error()
+		c.hint = hint
+		return c
+	}
+	version, err := common.SystemdVersion(systemdBin)
+	if err != nil {
+		c.details = err.Error()
+		c.hint = hint
+		return c
+	}
+	if version < minHostSystemdVersion {
+		c.details = fmt.Sprintf("systemd v%d is too old", version)
+		c.hint = hint
+		return c
+	}
+	c.ok = true
+	c.details = fmt.Sprintf("systemd v%d", version)
+	return c
+}
@@ -0,0 +1,57 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFlavorReadiness(t *testing.T) {
+	reqs := flavorRequirements{
+		required:    []string{checkStore, checkKVM},
+		recommended: []string{checkOverlay},
+	}
+	tests := []struct {
+		failed    []string
+		readiness string
+		missing   []string
+	}{
+		{nil, "ready", nil},
+		{[]string{checkOverlay}, "degraded", []string{checkOverlay}},
+		{[]string{checkKVM}, "not ready", []string{checkKVM}},
+		{[]string{checkKVM, checkOverlay}, "not ready", []string{checkOverlay, checkKVM}},
+		// checks not used by the flavor don't matter
+		{[]string{checkHostSystemd}, "ready", nil},
+	}
+	for i, tt := range tests {
+		results := make(map[string]doctorCheck)
+		for _, name := range []string{checkStore, checkKVM, checkOverlay, checkHostSystemd} {
+			results[name] = doctorCheck{name: name, ok: true}
+		}
+		for _, name := range tt.failed {
+			results[name] = doctorCheck{name: name}
+		}
+		readiness, missing := flavorReadiness(reqs, results)
+		if readiness != tt.readiness {
+			t.Errorf("#%d: expected readiness %q, got %q", i, tt.readiness, readiness)
+		}
+		if !reflect.DeepEqual(missing, tt.missing) {
+			t.Errorf("#%d: expected missing %v, got %v", i, tt.missing, missing)
+		}
+	}
+}
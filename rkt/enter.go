@@ -22,9 +22,9 @@ import (
 
 	"github.com/appc/spec/schema/types"
 	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/pkg/exitcode"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/stage0"
-	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 	"github.com/spf13/cobra"
 )
@@ -66,7 +66,7 @@ func runEnter(cmd *cobra.Command, args []string) (exit int) {
 	p, err := pkgPod.PodFromUUIDString(getDataDir(), args[0])
 	if err != nil {
 		stderr.PrintE("problem retrieving pod", err)
-		return 254
+		return exitcode.FromError(err)
 	}
 	defer p.Close()
 
@@ -93,7 +93,7 @@ func runEnter(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -113,6 +113,12 @@ func runEnter(cmd *cobra.Command, args []string) (exit int) {
 
 	stage1RootFS := ts.GetRootFS(stage1TreeStoreID)
 
+	auditPod(p)
+	if err := logAudit(0); err != nil {
+		stderr.PrintE("cannot write the audit record", err)
+		return 254
+	}
+
 	if err = stage0.Enter(p.Path(), podPID, *appName, stage1RootFS, argv); err != nil {
 		stderr.PrintE("enter failed", err)
 		return 254
@@ -30,6 +30,7 @@ import (
 	"github.com/hashicorp/errwrap"
 	"github.com/rkt/rkt/common"
 	"github.com/rkt/rkt/common/overlay"
+	"github.com/rkt/rkt/pkg/exitcode"
 	"github.com/rkt/rkt/pkg/mountinfo"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/pkg/user"
@@ -70,7 +71,7 @@ func runExport(cmd *cobra.Command, args []string) (exit int) {
 	p, err := pkgPod.PodFromUUIDString(getDataDir(), args[0])
 	if err != nil {
 		stderr.PrintE("problem retrieving pod", err)
-		return 254
+		return exitcode.FromError(err)
 	}
 	defer p.Close()
 
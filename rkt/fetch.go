@@ -18,8 +18,8 @@ import (
 	"github.com/appc/spec/schema/types"
 	"github.com/rkt/rkt/common"
 	"github.com/rkt/rkt/common/apps"
+	"github.com/rkt/rkt/pkg/exitcode"
 	"github.com/rkt/rkt/rkt/image"
-	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 
 	"github.com/spf13/cobra"
@@ -55,7 +55,7 @@ func init() {
 	cmdFetch.Flags().BoolVar(&flagNoStore, "no-store", false, "fetch images ignoring the local store")
 	cmdFetch.Flags().MarkDeprecated("no-store", "please use --pull-policy=update")
 	cmdFetch.Flags().BoolVar(&flagFullHash, "full", false, "print the full image hash after fetching")
-	cmdFetch.Flags().StringVar(&flagPullPolicyDefaultUpdate, "pull-policy", image.PullPolicyUpdate, "when to pull an image")
+	cmdFetch.Flags().StringVar(&flagPullPolicyDefaultUpdate, "pull-policy", image.PullPolicyUpdate, "when to pull an image: never, if-not-present (alias new), update or always")
 
 	cmdRkt.AddCommand(cmdFetch)
 
@@ -90,8 +90,12 @@ func runFetch(cmd *cobra.Command, args []string) (exit int) {
 	if flagNoStore {
 		flagPullPolicy = image.PullPolicyUpdate
 	}
+	if err := image.ValidatePullPolicy(flagPullPolicy); err != nil {
+		stderr.Error(err)
+		return 254
+	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -114,10 +118,13 @@ func runFetch(cmd *cobra.Command, args []string) (exit int) {
 		Ts:                 ts,
 		Ks:                 ks,
 		Headers:            config.AuthPerHost,
+		TLSPerHost:         config.TLSPerHost,
+		P2PPerHost:         config.P2PPerHost,
 		DockerAuth:         config.DockerCredentialsPerRegistry,
 		InsecureFlags:      globalFlags.InsecureFlags,
 		Debug:              globalFlags.Debug,
 		TrustKeysFromHTTPS: globalFlags.TrustKeysFromHTTPS,
+		Offline:            globalFlags.Offline,
 
 		PullPolicy: flagPullPolicy,
 		WithDeps:   true,
@@ -126,7 +133,7 @@ func runFetch(cmd *cobra.Command, args []string) (exit int) {
 	err = ft.FetchImages(&rktApps)
 	if err != nil {
 		stderr.Error(err)
-		return 254
+		return exitcode.FromError(err)
 	}
 	err = rktApps.Walk(func(app *apps.App) error {
 		hash := app.ImageID.String()
@@ -26,10 +26,11 @@ import (
 
 	"github.com/hashicorp/errwrap"
 	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/networking"
+	"github.com/rkt/rkt/pkg/erase"
 	"github.com/rkt/rkt/pkg/mountinfo"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/stage0"
-	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 	"github.com/spf13/cobra"
 )
@@ -37,11 +38,14 @@ import (
 const (
 	defaultGracePeriod        = 30 * time.Minute
 	defaultPreparedExpiration = 24 * time.Hour
+	// embryoExpiration is the time after which an embryo pod not locked by
+	// its creator is considered left behind by an interrupted rkt.
+	embryoExpiration = time.Hour
 )
 
 var (
 	cmdGC = &cobra.Command{
-		Use:   "gc [--grace-period=duration] [--expire-prepared=duration]",
+		Use:   "gc [--grace-period=duration] [--expire-prepared=duration] [--archive-dir=dir] [--archive-hook=path]",
 		Short: "Garbage collect rkt pods no longer in use",
 		Long: `This is intended to be run periodically from a timer or cron job.
 
@@ -50,7 +54,10 @@ garbage by one invocation of the gc command. A subsequent invocation will clean
 up the pod, assuming the pod has been in the garbage for more time than the
 specified grace period.
 
-Use --grace-period=0s to effectively disable the grace-period.`,
+Use --grace-period=0s to effectively disable the grace-period.
+
+Use --archive-dir and/or --archive-hook to keep the exit statuses, the pod
+manifest and the last logs of exited pods before they are removed.`,
 		Run: ensureSuperuser(runWrapper(runGC)),
 	}
 	flagGracePeriod        time.Duration
@@ -62,10 +69,17 @@ func init() {
 	cmdRkt.AddCommand(cmdGC)
 	cmdGC.Flags().DurationVar(&flagGracePeriod, "grace-period", defaultGracePeriod, "duration to wait before discarding inactive pods from garbage")
 	cmdGC.Flags().DurationVar(&flagPreparedExpiration, "expire-prepared", defaultPreparedExpiration, "duration to wait before expiring prepared pods")
+	addSecureEraseFlag(cmdGC.Flags())
 	cmdGC.Flags().BoolVar(&flagMarkOnly, "mark-only", false, "if set to true, then the exited/aborted pods will be moved to the garbage directories without actually deleting them, this is useful for marking the exit time of a pod")
 }
 
 func runGC(cmd *cobra.Command, args []string) (exit int) {
+	mode, err := getEraseMode()
+	if err != nil {
+		stderr.PrintE("invalid erase mode", err)
+		return 254
+	}
+
 	if err := renameExited(); err != nil {
 		stderr.PrintE("failed to rename exited pods", err)
 		return 254
@@ -76,6 +90,11 @@ func runGC(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
+	if err := renameEmbryos(embryoExpiration); err != nil {
+		stderr.PrintE("failed to rename abandoned embryo pods", err)
+		return 254
+	}
+
 	if flagMarkOnly {
 		return
 	}
@@ -94,10 +113,45 @@ func runGC(cmd *cobra.Command, args []string) (exit int) {
 		stderr.PrintE("failed to empty garbage", err)
 		return 254
 	}
+	trimErased(getDataDir(), mode)
+
+	if err := gcIfNames(); err != nil {
+		stderr.PrintE("failed to remove the interfaces of removed pods", err)
+		return 254
+	}
+
+	if err := gcLeases(); err != nil {
+		stderr.PrintE("failed to release the leases of removed pods", err)
+		return 254
+	}
+
+	if err := gcNames(); err != nil {
+		stderr.PrintE("failed to release the names of removed pods", err)
+		return 254
+	}
 
 	return
 }
 
+// gcNames releases the names of the pods which do not exist anymore.
+func gcNames() error {
+	released, err := pkgPod.GCNames(getDataDir())
+	for _, n := range released {
+		stderr.Printf("released pod name %q", n)
+	}
+	return err
+}
+
+// gcLeases releases the host-local IPAM leases of the pods which do not
+// exist anymore.
+func gcLeases() error {
+	released, err := networking.GCLeases(getDataDir(), podExists)
+	for _, l := range released {
+		stderr.Printf("released lease %s", l)
+	}
+	return err
+}
+
 // renameExited renames exited pods to the exitedGarbage directory
 func renameExited() error {
 	if err := pkgPod.WalkPods(getDataDir(), pkgPod.IncludeRunDir, func(p *pkgPod.Pod) {
@@ -130,7 +184,16 @@ func emptyExitedGarbage(gracePeriod time.Duration) error {
 			if err := p.ExclusiveLock(); err != nil {
 				return
 			}
+			if archiveEnabled() {
+				// keep the pod around on failure, it will be retried on
+				// the next gc pass
+				if err := archivePod(p); err != nil {
+					stderr.PrintE(fmt.Sprintf("unable to archive pod %q, not removing it", p.UUID), err)
+					return
+				}
+			}
 			stdout.Printf("Garbage collecting pod %q", p.UUID)
+			auditPod(p)
 
 			deletePod(p)
 		} else {
@@ -143,9 +206,10 @@ func emptyExitedGarbage(gracePeriod time.Duration) error {
 	return nil
 }
 
-// renameAborted renames failed prepares to the garbage directory
+// renameAborted renames failed prepares to the garbage directory, including
+// the prepared pods whose preparation never completed
 func renameAborted() error {
-	if err := pkgPod.WalkPods(getDataDir(), pkgPod.IncludePrepareDir, func(p *pkgPod.Pod) {
+	if err := pkgPod.WalkPods(getDataDir(), pkgPod.IncludePrepareDir|pkgPod.IncludePreparedDir, func(p *pkgPod.Pod) {
 		if p.State() == pkgPod.AbortedPrepare {
 			stderr.Printf("moving failed prepare %q to garbage", p.UUID)
 			if err := p.ToGarbage(); err != nil && err != os.ErrNotExist {
@@ -158,6 +222,38 @@ func renameAborted() error {
 	return nil
 }
 
+// renameEmbryos renames embryo pods left behind by an interrupted rkt to the
+// garbage directory
+func renameEmbryos(expiration time.Duration) error {
+	if err := pkgPod.WalkPods(getDataDir(), pkgPod.IncludeEmbryoDir, func(p *pkgPod.Pod) {
+		if p.State() != pkgPod.Embryo {
+			return
+		}
+		st := &syscall.Stat_t{}
+		ep := p.Path()
+		if err := syscall.Lstat(ep, st); err != nil {
+			if err != syscall.ENOENT {
+				stderr.PrintE(fmt.Sprintf("unable to stat %q, ignoring", ep), err)
+			}
+			return
+		}
+		if time.Now().Before(time.Unix(st.Ctim.Unix()).Add(expiration)) {
+			return
+		}
+		// a locked embryo is still being created
+		if err := p.TryExclusiveLock(); err != nil {
+			return
+		}
+		stderr.Printf("moving abandoned embryo %q to garbage", p.UUID)
+		if err := p.ToGarbage(); err != nil && err != os.ErrNotExist {
+			stderr.PrintE("rename error", err)
+		}
+	}); err != nil {
+		return err
+	}
+	return nil
+}
+
 // renameExpired renames expired prepared pods to the garbage directory
 func renameExpired(preparedExpiration time.Duration) error {
 	if err := pkgPod.WalkPods(getDataDir(), pkgPod.IncludePreparedDir, func(p *pkgPod.Pod) {
@@ -189,6 +285,7 @@ func emptyGarbage() error {
 			return
 		}
 		stdout.Printf("Garbage collecting pod %q", p.UUID)
+		auditPod(p)
 
 		deletePod(p)
 	}); err != nil {
@@ -256,7 +353,7 @@ func deletePod(p *pkgPod.Pod) bool {
 	}
 
 	if podState == pkgPod.ExitedGarbage {
-		s, err := imagestore.NewStore(storeDir())
+		s, err := openImageStore()
 		if err != nil {
 			stderr.PrintE("cannot open store", err)
 			return false
@@ -296,18 +393,24 @@ func deletePod(p *pkgPod.Pod) bool {
 		}
 	}
 
+	mode, err := getEraseMode()
+	if err != nil {
+		stderr.PrintE("invalid erase mode", err)
+		return false
+	}
+
 	// remove the rootfs first; if this fails (eg. due to busy mountpoints), pod manifest
 	// is left in place and clean-up can be re-tried later.
 	rootfsPath, err := p.Stage1RootfsPath()
 	if err == nil {
-		if e := os.RemoveAll(rootfsPath); e != nil {
+		if e := erase.RemoveAll(rootfsPath, mode); e != nil {
 			stderr.PrintE(fmt.Sprintf("unable to remove pod rootfs %q", p.UUID), e)
 			return false
 		}
 	}
 
 	// finally remove all remaining pieces
-	if err := os.RemoveAll(p.Path()); err != nil {
+	if err := erase.RemoveAll(p.Path(), mode); err != nil {
 		stderr.PrintE(fmt.Sprintf("unable to remove pod %q", p.UUID), err)
 		return false
 	}
@@ -0,0 +1,170 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/hashicorp/errwrap"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+)
+
+const (
+	defaultArchiveLogSize = 64 // KiB
+
+	archiveManifestFilename = "pod-manifest.json"
+	archiveStatusFilename   = "exit-statuses.json"
+	archiveLogFilename      = "journal.log"
+)
+
+var (
+	flagArchiveDir     string
+	flagArchiveHook    string
+	flagArchiveLogSize int
+)
+
+func init() {
+	cmdGC.Flags().StringVar(&flagArchiveDir, "archive-dir", "", "directory where the exit statuses, the pod manifest and the last logs of exited pods are archived before removal")
+	cmdGC.Flags().StringVar(&flagArchiveHook, "archive-hook", "", "executable invoked with the pod UUID and the paths of the archived files before removing an exited pod")
+	cmdGC.Flags().IntVar(&flagArchiveLogSize, "archive-log-size", defaultArchiveLogSize, "amount of logs to archive, in KiB")
+}
+
+// appExitStatus is the archived exit status of an app.
+type appExitStatus struct {
+	Name     string `json:"name"`
+	ExitCode *int   `json:"exitCode"`
+}
+
+// archiveEnabled returns whether exited pods should be archived before
+// removal.
+func archiveEnabled() bool {
+	return flagArchiveDir != "" || flagArchiveHook != ""
+}
+
+// archivePod saves the post-mortem data of an exited pod: the exit statuses
+// of its apps, its final pod manifest and the tail of its logs. The files are
+// written in a per pod directory inside the archive directory, or in a
+// temporary directory if only a hook is configured. The hook, if any, is then
+// invoked with the pod UUID and the paths of the archived files.
+// The pod must be under exclusive lock.
+func archivePod(p *pkgPod.Pod) error {
+	var dir string
+	if flagArchiveDir != "" {
+		dir = filepath.Join(flagArchiveDir, p.UUID.String())
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return errwrap.Wrap(errors.New("error creating archive directory"), err)
+		}
+	} else {
+		tmp, err := ioutil.TempDir("", "rkt-gc-archive-")
+		if err != nil {
+			return errwrap.Wrap(errors.New("error creating temporary archive directory"), err)
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	}
+
+	pmb, pm, err := p.PodManifest()
+	if err != nil {
+		return err
+	}
+	manifestPath := filepath.Join(dir, archiveManifestFilename)
+	if err := ioutil.WriteFile(manifestPath, pmb, 0600); err != nil {
+		return errwrap.Wrap(errors.New("error archiving pod manifest"), err)
+	}
+
+	statuses := make([]appExitStatus, 0, len(pm.Apps))
+	for _, ra := range pm.Apps {
+		s := appExitStatus{Name: ra.Name.String()}
+		if code, err := p.AppExitCode(ra.Name.String()); err == nil {
+			s.ExitCode = &code
+		}
+		statuses = append(statuses, s)
+	}
+	sb, err := json.Marshal(statuses)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error marshalling exit statuses"), err)
+	}
+	statusPath := filepath.Join(dir, archiveStatusFilename)
+	if err := ioutil.WriteFile(statusPath, sb, 0600); err != nil {
+		return errwrap.Wrap(errors.New("error archiving exit statuses"), err)
+	}
+
+	logPath := ""
+	if flagArchiveLogSize > 0 {
+		logPath = filepath.Join(dir, archiveLogFilename)
+		if err := archivePodLogs(p, logPath, flagArchiveLogSize*1024); err != nil {
+			// Missing logs should not prevent archiving the rest
+			stderr.PrintE(fmt.Sprintf("unable to archive logs of pod %q", p.UUID), err)
+			logPath = ""
+		}
+	}
+
+	if flagArchiveHook != "" {
+		cmd := exec.Command(flagArchiveHook, p.UUID.String(), manifestPath, statusPath, logPath)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errwrap.Wrap(fmt.Errorf("archive hook %q failed", flagArchiveHook), err)
+		}
+	}
+	return nil
+}
+
+// archivePodLogs writes at most size bytes from the end of the pod journal to
+// path. The journal is exported with journalctl, so it's available only if the
+// stage1 keeps the logs inside the pod.
+func archivePodLogs(p *pkgPod.Pod, path string, size int) error {
+	journalPath, err := p.JournalLogPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(journalPath); err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command("journalctl", "--directory", journalPath, "--no-pager", "--output", "short-iso")
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return errwrap.Wrap(errors.New("error reading pod journal"), err)
+	}
+	return ioutil.WriteFile(path, tailLines(out.Bytes(), size), 0600)
+}
+
+// tailLines returns at most size bytes from the end of b, without starting
+// in the middle of a line.
+func tailLines(b []byte, size int) []byte {
+	if len(b) <= size {
+		return b
+	}
+	cut := len(b) - size
+	if b[cut-1] == '\n' {
+		return b[cut:]
+	}
+	b = b[cut:]
+	if i := bytes.IndexByte(b, '\n'); i >= 0 {
+		return b[i+1:]
+	}
+	return b
+}
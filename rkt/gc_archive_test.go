@@ -0,0 +1,39 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import "testing"
+
+func TestTailLines(t *testing.T) {
+	tests := []struct {
+		in   string
+		size int
+		out  string
+	}{
+		{"", 10, ""},
+		{"short\n", 10, "short\n"},
+		{"first line\nsecond line\n", 15, "second line\n"},
+		{"first line\nsecond line\n", 12, "second line\n"},
+		{"first line\nsecond line\n", 11, ""},
+		{"a very long line without newline", 4, "line"},
+	}
+	for i, tt := range tests {
+		if out := string(tailLines([]byte(tt.in), tt.size)); out != tt.out {
+			t.Errorf("#%d: expected %q, got %q", i, tt.out, out)
+		}
+	}
+}
@@ -20,11 +20,13 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/hashicorp/errwrap"
 	dist "github.com/rkt/rkt/pkg/distribution"
+	"github.com/rkt/rkt/pkg/exitcode"
 	"github.com/rkt/rkt/pkg/keystore"
 	rktlog "github.com/rkt/rkt/pkg/log"
 	"github.com/rkt/rkt/rkt/config"
@@ -43,11 +45,22 @@ const (
 	imageStringName imageStringType = iota // image type to be guessed
 	imageStringPath                        // absolute or relative path
 
-	PullPolicyNever  = "never"
-	PullPolicyNew    = "new"
-	PullPolicyUpdate = "update"
+	PullPolicyNever        = "never"
+	PullPolicyNew          = "new"
+	PullPolicyIfNotPresent = "if-not-present" // alias of PullPolicyNew
+	PullPolicyUpdate       = "update"
+	PullPolicyAlways       = "always"
 )
 
+// ValidatePullPolicy returns an error if p is not a known pull policy.
+func ValidatePullPolicy(p string) error {
+	switch p {
+	case PullPolicyNever, PullPolicyNew, PullPolicyIfNotPresent, PullPolicyUpdate, PullPolicyAlways:
+		return nil
+	}
+	return fmt.Errorf("invalid pull policy %q, it must be one of %q, %q, %q, %q or %q", p, PullPolicyNever, PullPolicyIfNotPresent, PullPolicyNew, PullPolicyUpdate, PullPolicyAlways)
+}
+
 // action is a common type for Finder and Fetcher
 type action struct {
 	// S is an aci store where images will be looked for or stored.
@@ -59,6 +72,12 @@ type action struct {
 	// Headers is a map of headers which might be used for
 	// downloading via https protocol.
 	Headers map[string]config.Headerer
+	// TLSPerHost is a map of TLS settings (CA bundles and client
+	// certificates) used for downloading via https protocol.
+	TLSPerHost map[string]config.TLSCredentials
+	// P2PPerHost is a map of P2P agents the downloads via http
+	// and https protocols go through.
+	P2PPerHost map[string]config.P2PAgent
 	// DockerAuth is used for authenticating when fetching docker
 	// images.
 	DockerAuth map[string]config.BasicCredentials
@@ -81,6 +100,29 @@ type action struct {
 	// WithDeps tells whether image dependencies should be
 	// downloaded too.
 	WithDeps bool
+	// Offline forbids any network access, only images already in
+	// the store or in local files can be used. It overrides
+	// PullPolicy.
+	Offline bool
+}
+
+// MissingImagesError is returned in offline mode when some of the requested
+// images are not available locally.
+type MissingImagesError struct {
+	Images []string
+}
+
+func (e *MissingImagesError) Error() string {
+	lines := []string{"offline mode: the following images are not in the store:"}
+	for _, img := range e.Images {
+		lines = append(lines, fmt.Sprintf("  %s", img))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ExitCode implements exitcode.Coder.
+func (e *MissingImagesError) ExitCode() int {
+	return exitcode.ImageNotFound
 }
 
 var (
@@ -93,7 +135,7 @@ func ensureLogger(debug bool) {
 	if log == nil || diag == nil || stdout == nil {
 		log, diag, stdout = rktlog.NewLogSet("image", debug)
 	}
-	if !debug {
+	if !debug && !rktlog.IsDebugEnabled("image") {
 		diag.SetOutput(ioutil.Discard)
 	}
 }
@@ -131,6 +173,20 @@ func printIdentities(entity *openpgp.Entity) {
 	log.Print(strings.Join(lines, "\n"))
 }
 
+// verificationResult describes the result of the verification of an
+// image signature, as recorded in the store.
+func verificationResult(entity *openpgp.Entity) string {
+	if entity == nil {
+		return "not verified"
+	}
+	var names []string
+	for _, v := range entity.Identities {
+		names = append(names, v.Name)
+	}
+	sort.Strings(names)
+	return fmt.Sprintf("signed by %X (%s)", entity.PrimaryKey.Fingerprint, strings.Join(names, ", "))
+}
+
 // DistFromImageString return the distribution for the given input image string
 func DistFromImageString(is string) (dist.Distribution, error) {
 	u, err := url.Parse(is)
@@ -164,7 +220,7 @@ func DistFromImageString(is string) (dist.Distribution, error) {
 		default:
 			return nil, fmt.Errorf("invalid image string type %q", appImageType)
 		}
-	case "file", "http", "https":
+	case "file", "http", "https", "s3", "gs":
 		// An ACI archive with any transport type (file, http, s3 etc...) and final aci extension
 		if filepath.Ext(u.Path) == schema.ACIExtension {
 			dist, err := dist.NewACIArchiveFromTransportURL(u)
@@ -259,6 +315,14 @@ func eTag(rem *imagestore.Remote) string {
 	return ""
 }
 
+// cacheETag returns the ETag the server sent with the image, if any.
+func cacheETag(cd *cacheData) string {
+	if cd != nil {
+		return cd.ETag
+	}
+	return ""
+}
+
 func maybeUseCached(rem *imagestore.Remote, cd *cacheData) string {
 	if rem == nil || cd == nil {
 		return ""
@@ -269,6 +333,22 @@ func maybeUseCached(rem *imagestore.Remote, cd *cacheData) string {
 	return ""
 }
 
+// refreshRemote updates the caching information of a remote after the
+// server told us that the stored image is still valid, so the next
+// fetches can rely on the new max age and ETag instead of asking the
+// server again.
+func refreshRemote(s *imagestore.Store, rem *imagestore.Remote, cd *cacheData) error {
+	rem.DownloadTime = time.Now()
+	rem.CacheMaxAge = cd.MaxAge
+	if cd.ETag != "" {
+		rem.ETag = cd.ETag
+	}
+	if err := s.WriteRemote(rem); err != nil {
+		return errwrap.Wrap(fmt.Errorf("failed to update remote for URL %q", rem.ACIURL), err)
+	}
+	return nil
+}
+
 func remoteForURL(s *imagestore.Store, u *url.URL) (*imagestore.Remote, error) {
 	urlStr := u.String()
 	rem, err := s.GetRemote(urlStr)
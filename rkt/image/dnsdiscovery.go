@@ -0,0 +1,116 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/appc/spec/discovery"
+)
+
+// dnsDiscoveryPrefix is prepended to the domain of an image name to get
+// the name holding the TXT records used for its discovery.
+const dnsDiscoveryPrefix = "_appc-discovery."
+
+var (
+	// lookupTXT is overridden in the tests.
+	lookupTXT = net.LookupTXT
+
+	dnsTemplateExpression = regexp.MustCompile(`{.*?}`)
+)
+
+// discoverACIEndpointsDNS resolves the endpoints of the app from the TXT
+// records of the domain of its name. The records have the same content
+// as the ac-discovery meta tags, prefixed with the tag name:
+//
+//	ac-discovery example.com/app https://example.com/{name}-{version}-{os}-{arch}.{ext}
+//
+// Only the records with the longest prefix matching the name are used.
+// DNS is not authenticated, so the templates must use https unless
+// insecure allows http.
+func discoverACIEndpointsDNS(app discovery.App, insecure discovery.InsecureOption) (discovery.ACIEndpoints, error) {
+	name := app.Name.String()
+	domain := strings.SplitN(name, "/", 2)[0]
+	records, err := lookupTXT(dnsDiscoveryPrefix + domain)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		templates []string
+		prefixLen int
+	)
+	for _, r := range records {
+		fields := strings.Fields(r)
+		if len(fields) != 3 || fields[0] != "ac-discovery" {
+			continue
+		}
+		prefix, tpl := fields[1], fields[2]
+		if !strings.HasPrefix(name, prefix) || len(prefix) < prefixLen {
+			continue
+		}
+		if len(prefix) > prefixLen {
+			templates = nil
+			prefixLen = len(prefix)
+		}
+		templates = append(templates, tpl)
+	}
+
+	app = *app.Copy()
+	if app.Labels["version"] == "" {
+		app.Labels["version"] = "latest"
+	}
+	vars := []string{"{name}", name}
+	for n, v := range app.Labels {
+		vars = append(vars, fmt.Sprintf("{%s}", n), v)
+	}
+
+	var ep discovery.ACIEndpoints
+	for _, tpl := range templates {
+		uri := renderDNSTemplate(tpl, vars...)
+		aci, ok := renderDNSTemplateFully(uri, "{ext}", "aci")
+		if !ok {
+			continue
+		}
+		asc, _ := renderDNSTemplateFully(uri, "{ext}", "aci.asc")
+		u, err := url.Parse(aci)
+		if err != nil {
+			continue
+		}
+		if u.Scheme != "https" && (u.Scheme != "http" || insecure&discovery.InsecureHTTP == 0) {
+			continue
+		}
+		ep = append(ep, discovery.ACIEndpoint{ACI: aci, ASC: asc})
+	}
+	return ep, nil
+}
+
+func renderDNSTemplate(tpl string, kvs ...string) string {
+	for i := 0; i < len(kvs); i += 2 {
+		tpl = strings.Replace(tpl, kvs[i], kvs[i+1], -1)
+	}
+	return tpl
+}
+
+// renderDNSTemplateFully renders the template and returns whether no
+// variable is left in it.
+func renderDNSTemplateFully(tpl string, kvs ...string) (string, bool) {
+	tpl = renderDNSTemplate(tpl, kvs...)
+	return tpl, !dnsTemplateExpression.MatchString(tpl)
+}
@@ -0,0 +1,110 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/appc/spec/discovery"
+)
+
+func TestDiscoverACIEndpointsDNS(t *testing.T) {
+	records := map[string][]string{
+		"_appc-discovery.example.com": {
+			"v=spf1 -all",
+			"ac-discovery example.com https://example.com/{name}-{version}-{os}-{arch}.{ext}",
+			"ac-discovery example.com/app https://cdn.example.com/app/{version}/{os}-{arch}.{ext}",
+			"ac-discovery example.com/plain http://example.com/plain-{version}.{ext}",
+			"ac-discovery example.com/unknown https://example.com/unknown-{flavor}.{ext}",
+		},
+	}
+	defer func(f func(string) ([]string, error)) { lookupTXT = f }(lookupTXT)
+	lookupTXT = func(name string) ([]string, error) {
+		if r, ok := records[name]; ok {
+			return r, nil
+		}
+		return nil, fmt.Errorf("no such host %s", name)
+	}
+
+	tests := []struct {
+		image    string
+		insecure discovery.InsecureOption
+		expected discovery.ACIEndpoints
+		err      bool
+	}{
+		{
+			"example.com/app:1.0,os=linux,arch=amd64",
+			discovery.InsecureNone,
+			discovery.ACIEndpoints{{
+				ACI: "https://cdn.example.com/app/1.0/linux-amd64.aci",
+				ASC: "https://cdn.example.com/app/1.0/linux-amd64.aci.asc",
+			}},
+			false,
+		},
+		{
+			"example.com/other,os=linux,arch=amd64",
+			discovery.InsecureNone,
+			discovery.ACIEndpoints{{
+				ACI: "https://example.com/example.com/other-latest-linux-amd64.aci",
+				ASC: "https://example.com/example.com/other-latest-linux-amd64.aci.asc",
+			}},
+			false,
+		},
+		{
+			"example.com/plain:1.0",
+			discovery.InsecureNone,
+			nil,
+			false,
+		},
+		{
+			"example.com/plain:1.0",
+			discovery.InsecureHTTP,
+			discovery.ACIEndpoints{{
+				ACI: "http://example.com/plain-1.0.aci",
+				ASC: "http://example.com/plain-1.0.aci.asc",
+			}},
+			false,
+		},
+		{
+			"example.com/unknown:1.0",
+			discovery.InsecureNone,
+			nil,
+			false,
+		},
+		{
+			"example.org/app:1.0",
+			discovery.InsecureNone,
+			nil,
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		app, err := discovery.NewAppFromString(tt.image)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.image, err)
+		}
+		ep, err := discoverACIEndpointsDNS(*app, tt.insecure)
+		if tt.err != (err != nil) {
+			t.Errorf("%s: expected error %t, got %v", tt.image, tt.err, err)
+			continue
+		}
+		if !reflect.DeepEqual(ep, tt.expected) {
+			t.Errorf("%s: expected endpoints %v, got %v", tt.image, tt.expected, ep)
+		}
+	}
+}
@@ -44,6 +44,9 @@ type dockerFetcher struct {
 	DockerAuth    map[string]config.BasicCredentials
 	S             *imagestore.Store
 	Debug         bool
+	// Source is the distribution URI recorded as the source of the
+	// image in the store.
+	Source string
 }
 
 // Hash uses docker2aci to download the image and convert it to
@@ -74,7 +77,10 @@ func (f *dockerFetcher) fetchImageFrom(u *url.URL, latest bool) (string, error)
 	defer aciFile.Close()
 
 	key, err := f.S.WriteACI(aciFile, imagestore.ACIFetchInfo{
-		Latest: latest,
+		Latest:       latest,
+		Source:       f.Source,
+		Digest:       dockerDigest(u),
+		Verification: verificationResult(nil),
 	})
 	if err != nil {
 		return "", err
@@ -92,6 +98,16 @@ func (f *dockerFetcher) fetchImageFrom(u *url.URL, latest bool) (string, error)
 	return key, nil
 }
 
+// dockerDigest returns the digest of the image the docker URL refers to,
+// if the reference is done by digest.
+func dockerDigest(u *url.URL) string {
+	ref := strings.TrimPrefix(u.String(), "docker://")
+	if i := strings.LastIndex(ref, "@"); i != -1 {
+		return ref[i+1:]
+	}
+	return ""
+}
+
 func (f *dockerFetcher) fetch(u *url.URL) (*os.File, error) {
 	tmpDir, err := f.getTmpDir()
 	if err != nil {
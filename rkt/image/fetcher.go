@@ -25,6 +25,7 @@ import (
 	"github.com/rkt/rkt/common"
 	"github.com/rkt/rkt/common/apps"
 	dist "github.com/rkt/rkt/pkg/distribution"
+	"github.com/rkt/rkt/pkg/exitcode"
 	"github.com/rkt/rkt/stage0"
 	"github.com/rkt/rkt/store/imagestore"
 
@@ -37,25 +38,42 @@ import (
 type distBundle struct {
 	dist  dist.Distribution
 	image string
+	// source is the distribution URI recorded in the store as the
+	// source of the image. It defaults to the URI of dist.
+	source string
 }
 
 // Fetcher will try to fetch images into the store.
 type Fetcher action
 
-// FetchImages uses FetchImage to attain a list of image hashes
+// FetchImages uses FetchImage to attain a list of image hashes.
+// In offline mode, all the images missing from the store are reported
+// together in a MissingImagesError.
 func (f *Fetcher) FetchImages(al *apps.Apps) error {
-	return al.Walk(func(app *apps.App) error {
+	missing := &MissingImagesError{}
+	err := al.Walk(func(app *apps.App) error {
 		d, err := DistFromImageString(app.Image)
 		if err != nil {
 			return err
 		}
 		h, err := f.FetchImage(d, app.Image, app.Asc)
+		if merr, ok := err.(*MissingImagesError); ok {
+			missing.Images = append(missing.Images, merr.Images...)
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 		app.ImageID = *h
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	if len(missing.Images) > 0 {
+		return missing
+	}
+	return nil
 }
 
 // FetchImage will take an image as either a path, a URL or a name
@@ -98,6 +116,40 @@ func (f *Fetcher) FetchImage(d dist.Distribution, image, ascPath string) (*types
 	return h, nil
 }
 
+// pullPolicy returns the pull policy in effect, taking offline mode and
+// aliases into account.
+func (f *Fetcher) pullPolicy() string {
+	if f.Offline {
+		return PullPolicyNever
+	}
+	if f.PullPolicy == PullPolicyIfNotPresent {
+		return PullPolicyNew
+	}
+	return f.PullPolicy
+}
+
+// useStore returns whether an image already in the store can be used
+// without consulting the remote.
+func (f *Fetcher) useStore() bool {
+	p := f.pullPolicy()
+	return p != PullPolicyUpdate && p != PullPolicyAlways
+}
+
+// noCache returns whether the transport caching information (ETag and
+// Cache-Control) must be ignored.
+func (f *Fetcher) noCache() bool {
+	return f.NoCache || f.pullPolicy() == PullPolicyAlways
+}
+
+// notFoundError returns the error to report when an image could be found
+// neither in the store nor remotely.
+func (f *Fetcher) notFoundError(image string, err error) error {
+	if f.Offline {
+		return &MissingImagesError{Images: []string{image}}
+	}
+	return exitcode.Wrap(exitcode.ImageNotFound, err)
+}
+
 func (f *Fetcher) getAsc(ascPath string) *asc {
 	if ascPath != "" {
 		return &asc{
@@ -168,13 +220,18 @@ func (f *Fetcher) getImageDeps(hash string) (types.Dependencies, error) {
 }
 
 func (f *Fetcher) fetchSingleImage(db *distBundle, a *asc) (string, error) {
+	if db.source == "" {
+		db.source = db.dist.CIMD().String()
+	}
 	switch v := db.dist.(type) {
 	case *dist.ACIArchive:
 		return f.fetchACIArchive(db, a)
 	case *dist.Appc:
 		return f.fetchSingleImageByName(db, a)
 	case *dist.Docker:
-		return f.fetchSingleImageByDockerURL(v)
+		return f.fetchSingleImageByDockerURL(v, db.source)
+	case *dist.Plugin:
+		return f.fetchSingleImageByPlugin(v, a)
 	default:
 		return "", fmt.Errorf("unknown distribution type %T", v)
 	}
@@ -185,17 +242,19 @@ func (f *Fetcher) fetchACIArchive(db *distBundle, a *asc) (string, error) {
 
 	switch u.Scheme {
 	case "http", "https":
-		return f.fetchSingleImageByHTTPURL(u, a)
+		return f.fetchSingleImageByHTTPURL(u, a, db.source)
+	case "s3", "gs":
+		return f.fetchSingleImageByObjectURL(u, a, db.source)
 	case "file":
-		return f.fetchSingleImageByPath(u.Path, a)
+		return f.fetchSingleImageByPath(u.Path, a, db.source)
 	case "":
 		return "", fmt.Errorf("expected image URL %q to contain a scheme", u.String())
 	default:
-		return "", fmt.Errorf("an unsupported URL scheme %q - the only URL schemes supported by rkt for an archive are http, https and file", u.Scheme)
+		return "", fmt.Errorf("an unsupported URL scheme %q - the only URL schemes supported by rkt for an archive are http, https, s3, gs and file", u.Scheme)
 	}
 }
 
-func (f *Fetcher) fetchSingleImageByHTTPURL(u *url.URL, a *asc) (string, error) {
+func (f *Fetcher) fetchSingleImageByHTTPURL(u *url.URL, a *asc, source string) (string, error) {
 	rem, err := remoteForURL(f.S, u)
 	if err != nil {
 		return "", err
@@ -203,14 +262,48 @@ func (f *Fetcher) fetchSingleImageByHTTPURL(u *url.URL, a *asc) (string, error)
 	if h := f.maybeCheckRemoteFromStore(rem); h != "" {
 		return h, nil
 	}
-	if h, err := f.maybeFetchHTTPURLFromRemote(rem, u, a); h != "" || err != nil {
+	if h, err := f.maybeFetchHTTPURLFromRemote(rem, u, a, source); h != "" || err != nil {
 		return h, err
 	}
-	return "", fmt.Errorf("unable to fetch image from URL %q: either image was not found in the store or store was disabled and fetching from remote yielded nothing or it was disabled", u.String())
+	return "", f.notFoundError(u.String(), fmt.Errorf("unable to fetch image from URL %q: either image was not found in the store or store was disabled and fetching from remote yielded nothing or it was disabled", u.String()))
+}
+
+// fetchSingleImageByObjectURL fetches an image from S3 or GCS through the
+// HTTPS endpoint of the object store, the signature being the object next
+// to the image.
+func (f *Fetcher) fetchSingleImageByObjectURL(u *url.URL, a *asc, source string) (string, error) {
+	hu, err := objectStoreURL(u)
+	if err != nil {
+		return "", err
+	}
+	diag.Printf("using URL %q for %q", hu.String(), u.String())
+	of := *f
+	of.Headers = objectStoreHeaderers(f.Headers, u.Scheme, hu.Host)
+	return of.fetchSingleImageByHTTPURL(hu, a, source)
+}
+
+// fetchSingleImageByPlugin resolves the distribution with its plugin and
+// fetches the image it resolves to.
+func (f *Fetcher) fetchSingleImageByPlugin(p *dist.Plugin, a *asc) (string, error) {
+	is, err := p.Resolve()
+	if err != nil {
+		return "", err
+	}
+	diag.Printf("distribution %s resolved to %q", p.String(), is)
+	d, err := DistFromImageString(is)
+	if err != nil {
+		return "", errwrap.Wrap(fmt.Errorf("invalid image string %q returned by the distribution plugin", is), err)
+	}
+	if _, ok := d.(*dist.Plugin); ok {
+		return "", fmt.Errorf("distribution %s resolved to another plugin distribution %q", p.String(), is)
+	}
+	return f.fetchSingleImage(&distBundle{dist: d, image: is, source: p.CIMD().String()}, a)
 }
 
-func (f *Fetcher) fetchSingleImageByDockerURL(d *dist.Docker) (string, error) {
-	ds := d.ReferenceURL()
+func (f *Fetcher) fetchSingleImageByDockerURL(d *dist.Docker, source string) (string, error) {
+	// Use the canonical reference, so that the references differing
+	// only by their default values share the same remote.
+	ds := d.FullReference()
 	// Convert to the docker2aci URL format
 	urlStr := "docker://" + ds
 	u, err := url.Parse(urlStr)
@@ -225,57 +318,63 @@ func (f *Fetcher) fetchSingleImageByDockerURL(d *dist.Docker) (string, error) {
 	if h := f.maybeCheckRemoteFromStore(rem); h != "" {
 		return h, nil
 	}
-	if h, err := f.maybeFetchDockerURLFromRemote(u); h != "" || err != nil {
+	if h, err := f.maybeFetchDockerURLFromRemote(u, source); h != "" || err != nil {
 		return h, err
 	}
-	return "", fmt.Errorf("unable to fetch docker image from URL %q: either image was not found in the store or store was disabled and fetching from remote yielded nothing or it was disabled", u.String())
+	return "", f.notFoundError(u.String(), fmt.Errorf("unable to fetch docker image from URL %q: either image was not found in the store or store was disabled and fetching from remote yielded nothing or it was disabled", u.String()))
 }
 
 func (f *Fetcher) maybeCheckRemoteFromStore(rem *imagestore.Remote) string {
-	if f.PullPolicy == PullPolicyUpdate || rem == nil {
+	if !f.useStore() || rem == nil {
 		return ""
 	}
 	diag.Printf("using image from local store for url %s", rem.ACIURL)
 	return rem.BlobKey
 }
 
-func (f *Fetcher) maybeFetchHTTPURLFromRemote(rem *imagestore.Remote, u *url.URL, a *asc) (string, error) {
-	if f.PullPolicy != PullPolicyNever {
+func (f *Fetcher) maybeFetchHTTPURLFromRemote(rem *imagestore.Remote, u *url.URL, a *asc, source string) (string, error) {
+	if f.pullPolicy() != PullPolicyNever {
 		diag.Printf("remote fetching from URL %q", u.String())
 		hf := &httpFetcher{
 			InsecureFlags: f.InsecureFlags,
 			S:             f.S,
 			Ks:            f.Ks,
 			Rem:           rem,
+			NoCache:       f.noCache(),
 			Debug:         f.Debug,
 			Headers:       f.Headers,
+			TLSPerHost:    f.TLSPerHost,
+			P2PPerHost:    f.P2PPerHost,
+			Source:        source,
 		}
 		return hf.Hash(u, a)
 	}
 	return "", nil
 }
 
-func (f *Fetcher) maybeFetchDockerURLFromRemote(u *url.URL) (string, error) {
-	if f.PullPolicy != PullPolicyNever {
+func (f *Fetcher) maybeFetchDockerURLFromRemote(u *url.URL, source string) (string, error) {
+	if f.pullPolicy() != PullPolicyNever {
 		diag.Printf("remote fetching from URL %q", u.String())
 		df := &dockerFetcher{
 			InsecureFlags: f.InsecureFlags,
 			DockerAuth:    f.DockerAuth,
 			S:             f.S,
 			Debug:         f.Debug,
+			Source:        source,
 		}
 		return df.Hash(u)
 	}
 	return "", nil
 }
 
-func (f *Fetcher) fetchSingleImageByPath(path string, a *asc) (string, error) {
+func (f *Fetcher) fetchSingleImageByPath(path string, a *asc, source string) (string, error) {
 	diag.Printf("using image from file %s", path)
 	ff := &fileFetcher{
 		InsecureFlags: f.InsecureFlags,
 		S:             f.S,
 		Ks:            f.Ks,
 		Debug:         f.Debug,
+		Source:        source,
 	}
 	return ff.Hash(path, a)
 }
@@ -308,11 +407,11 @@ func (f *Fetcher) fetchSingleImageByName(db *distBundle, a *asc) (string, error)
 	if h, err := f.maybeFetchImageFromRemote(db, a); h != "" || err != nil {
 		return h, err
 	}
-	return "", fmt.Errorf("unable to fetch image from image name %q: either image was not found in the store or store was disabled and fetching from remote yielded nothing or it was disabled", db.image)
+	return "", f.notFoundError(db.image, fmt.Errorf("unable to fetch image from image name %q: either image was not found in the store or store was disabled and fetching from remote yielded nothing or it was disabled", db.image))
 }
 
 func (f *Fetcher) maybeCheckStoreForApp(db *distBundle) (string, error) {
-	if f.PullPolicy != PullPolicyUpdate {
+	if f.useStore() {
 		key, err := f.getStoreKeyFromApp(db)
 		if err == nil {
 			diag.Printf("using image from local store for image name %s", db.image)
@@ -347,16 +446,19 @@ func (f *Fetcher) getStoreKeyFromApp(db *distBundle) (string, error) {
 }
 
 func (f *Fetcher) maybeFetchImageFromRemote(db *distBundle, a *asc) (string, error) {
-	if f.PullPolicy != PullPolicyNever {
+	if f.pullPolicy() != PullPolicyNever {
 		app := db.dist.(*dist.Appc).App()
 		nf := &nameFetcher{
 			InsecureFlags:      f.InsecureFlags,
 			S:                  f.S,
 			Ks:                 f.Ks,
-			NoCache:            f.NoCache,
+			NoCache:            f.noCache(),
 			Debug:              f.Debug,
 			Headers:            f.Headers,
+			TLSPerHost:         f.TLSPerHost,
+			P2PPerHost:         f.P2PPerHost,
 			TrustKeysFromHTTPS: f.TrustKeysFromHTTPS,
+			Source:             db.source,
 		}
 		return nf.Hash(app, a)
 	}
@@ -24,6 +24,7 @@ import (
 	"github.com/rkt/rkt/pkg/keystore"
 	rktflag "github.com/rkt/rkt/rkt/flag"
 	"github.com/rkt/rkt/store/imagestore"
+	"golang.org/x/crypto/openpgp"
 )
 
 // fileFetcher is used to fetch files from a local filesystem
@@ -32,6 +33,13 @@ type fileFetcher struct {
 	S             *imagestore.Store
 	Ks            *keystore.Keystore
 	Debug         bool
+	// Source is the distribution URI recorded as the source of the
+	// image in the store.
+	Source string
+
+	// signer is the key the signature of the image was verified
+	// with, if it was.
+	signer *openpgp.Entity
 }
 
 // Hash opens a file, optionally verifies it against passed asc,
@@ -51,7 +59,9 @@ func (f *fileFetcher) Hash(aciPath string, a *asc) (string, error) {
 	defer aciFile.Close()
 
 	key, err := f.S.WriteACI(aciFile, imagestore.ACIFetchInfo{
-		Latest: false,
+		Latest:       false,
+		Source:       f.Source,
+		Verification: verificationResult(f.signer),
 	})
 	if err != nil {
 		return "", err
@@ -62,7 +72,7 @@ func (f *fileFetcher) Hash(aciPath string, a *asc) (string, error) {
 
 func (f *fileFetcher) getFile(aciPath string, a *asc) (*os.File, error) {
 	if f.InsecureFlags.SkipImageCheck() && f.Ks != nil {
-		log.Printf("warning: image signature verification has been disabled")
+		log.Warnf("image signature verification has been disabled")
 	}
 	if f.InsecureFlags.SkipImageCheck() || f.Ks == nil {
 		aciFile, err := os.Open(aciPath)
@@ -111,6 +121,7 @@ func (f *fileFetcher) getVerifiedFile(aciPath string, a *asc) (*os.File, error)
 		return nil, errwrap.Wrap(fmt.Errorf("image %q verification failed", validator.ImageName()), errClose)
 	}
 	printIdentities(entity)
+	f.signer = entity
 
 	return aciFile, nil
 }
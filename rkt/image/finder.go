@@ -19,6 +19,8 @@ import (
 
 	"github.com/hashicorp/errwrap"
 	"github.com/rkt/rkt/common/apps"
+	"github.com/rkt/rkt/pkg/exitcode"
+	"github.com/rkt/rkt/store/imagestore"
 
 	"github.com/appc/spec/schema/types"
 )
@@ -27,16 +29,30 @@ import (
 // try to fetch them.
 type Finder action
 
-// FindImages uses FindImage to attain a list of image hashes
+// FindImages uses FindImage to attain a list of image hashes.
+// In offline mode, all the images missing from the store are reported
+// together in a MissingImagesError.
 func (f *Finder) FindImages(al *apps.Apps) error {
-	return al.Walk(func(app *apps.App) error {
+	missing := &MissingImagesError{}
+	err := al.Walk(func(app *apps.App) error {
 		h, err := f.FindImage(app.Image, app.Asc)
+		if merr, ok := err.(*MissingImagesError); ok {
+			missing.Images = append(missing.Images, merr.Images...)
+			return nil
+		}
 		if err != nil {
 			return err
 		}
 		app.ImageID = *h
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	if len(missing.Images) > 0 {
+		return missing
+	}
+	return nil
 }
 
 // FindImage tries to get a hash of a passed image, ideally from
@@ -74,6 +90,12 @@ func (f *Finder) getHashFromStore(img string) (*types.Hash, error) {
 		return nil, errwrap.Wrap(fmt.Errorf("%q is not a valid hash", img), err)
 	}
 	fullKey, err := f.S.ResolveKey(img)
+	if err == imagestore.ErrKeyNotFound && f.Offline {
+		return nil, &MissingImagesError{Images: []string{img}}
+	}
+	if err == imagestore.ErrKeyNotFound {
+		return nil, errwrap.Wrap(fmt.Errorf("could not resolve image %q", img), exitcode.Wrap(exitcode.ImageNotFound, err))
+	}
 	if err != nil {
 		return nil, errwrap.Wrap(fmt.Errorf("could not resolve image %q", img), err)
 	}
@@ -0,0 +1,62 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/rkt/rkt/common/apps"
+	"github.com/rkt/rkt/store/imagestore"
+)
+
+func TestFindImagesOffline(t *testing.T) {
+	dir, err := ioutil.TempDir("", "finder-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := imagestore.NewStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	images := []string{
+		"example.com/app1:v1.0.0",
+		"sha512-0123456789abcdef",
+		"docker://example.com/app2:latest",
+	}
+	al := &apps.Apps{}
+	for _, img := range images {
+		al.Create(img)
+	}
+
+	f := &Finder{
+		S:       s,
+		Offline: true,
+	}
+	err = f.FindImages(al)
+	merr, ok := err.(*MissingImagesError)
+	if !ok {
+		t.Fatalf("expected a MissingImagesError, got %v", err)
+	}
+	if !reflect.DeepEqual(merr.Images, images) {
+		t.Fatalf("expected missing images %v, got %v", images, merr.Images)
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/rkt/rkt/pkg/keystore"
 	"github.com/rkt/rkt/rkt/config"
 	"github.com/rkt/rkt/store/imagestore"
+	"golang.org/x/crypto/openpgp"
 )
 
 // httpFetcher is used to download images from http or https URLs.
@@ -37,6 +38,15 @@ type httpFetcher struct {
 	NoCache       bool
 	Debug         bool
 	Headers       map[string]config.Headerer
+	TLSPerHost    map[string]config.TLSCredentials
+	P2PPerHost    map[string]config.P2PAgent
+	// Source is the distribution URI recorded as the source of the
+	// image in the store.
+	Source string
+
+	// signer is the key the signature of the image was verified
+	// with, if it was.
+	signer *openpgp.Entity
 }
 
 // Hash fetches the URL, optionally verifies it against passed asc,
@@ -54,21 +64,27 @@ func (f *httpFetcher) Hash(u *url.URL, a *asc) (string, error) {
 
 	diag.Printf("fetching image from %s", urlStr)
 
-	aciFile, cd, err := f.fetchURL(u, a, eTag(f.Rem))
+	etag := ""
+	if !f.NoCache {
+		etag = eTag(f.Rem)
+	}
+	aciFile, cd, err := f.fetchURL(u, a, etag)
 	if err != nil {
 		return "", err
 	}
 	defer aciFile.Close()
 
 	if key := maybeUseCached(f.Rem, cd); key != "" {
-		// TODO(krnowak): that does not update the store with
-		// the new CacheMaxAge and Download Time, so it will
-		// query the server every time after initial
-		// CacheMaxAge is exceeded
+		if err := refreshRemote(f.S, f.Rem, cd); err != nil {
+			return "", err
+		}
 		return key, nil
 	}
 	key, err := f.S.WriteACI(aciFile, imagestore.ACIFetchInfo{
-		Latest: false,
+		Latest:       false,
+		Source:       f.Source,
+		Digest:       cacheETag(cd),
+		Verification: verificationResult(f.signer),
 	})
 	if err != nil {
 		return "", err
@@ -94,10 +110,10 @@ func (f *httpFetcher) Hash(u *url.URL, a *asc) (string, error) {
 
 func (f *httpFetcher) fetchURL(u *url.URL, a *asc, etag string) (readSeekCloser, *cacheData, error) {
 	if f.InsecureFlags.SkipTLSCheck() && f.Ks != nil {
-		log.Printf("warning: TLS verification has been disabled")
+		log.Warnf("TLS verification has been disabled")
 	}
 	if f.InsecureFlags.SkipImageCheck() && f.Ks != nil {
-		log.Printf("warning: image signature verification has been disabled")
+		log.Warnf("image signature verification has been disabled")
 	}
 
 	if f.InsecureFlags.SkipImageCheck() || f.Ks == nil {
@@ -160,9 +176,11 @@ func (f *httpFetcher) fetchVerifiedURL(u *url.URL, a *asc, etag string) (readSee
 func (f *httpFetcher) httpOps() *httpOps {
 	return &httpOps{
 		InsecureSkipTLSVerify: f.InsecureFlags.SkipTLSCheck(),
-		S:       f.S,
-		Headers: f.Headers,
-		Debug:   f.Debug,
+		S:                     f.S,
+		Headers:               f.Headers,
+		TLSPerHost:            f.TLSPerHost,
+		P2PPerHost:            f.P2PPerHost,
+		Debug:                 f.Debug,
 	}
 }
 
@@ -180,6 +198,7 @@ func (f *httpFetcher) validate(aciFile, ascFile io.ReadSeeker) error {
 	}
 
 	printIdentities(entity)
+	f.signer = entity
 	return nil
 }
 
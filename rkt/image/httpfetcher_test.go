@@ -0,0 +1,113 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	rktflag "github.com/rkt/rkt/rkt/flag"
+	"github.com/rkt/rkt/store/imagestore"
+)
+
+// TestHTTPFetcherRevalidation checks that an image which the server reports
+// as not modified is taken from the store and that its caching information is
+// refreshed.
+func TestHTTPFetcherRevalidation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpfetcher-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s, err := imagestore.NewStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != `"v1"` {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL + "/app.aci")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rem := imagestore.NewRemote(u.String(), "")
+	rem.BlobKey = "sha512-0123456789abcdef"
+	rem.ETag = `"v1"`
+	rem.DownloadTime = time.Now().Add(-time.Hour)
+	if err := s.WriteRemote(rem); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	insecureFlags, err := rktflag.NewSecFlags("image")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	f := &Fetcher{
+		S:             s,
+		InsecureFlags: insecureFlags,
+		PullPolicy:    PullPolicyUpdate,
+	}
+	key, err := f.fetchSingleImageByHTTPURL(u, &asc{}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key != rem.BlobKey {
+		t.Fatalf("expected key %q, got %q", rem.BlobKey, key)
+	}
+
+	newRem, err := s.GetRemote(u.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if newRem.CacheMaxAge != 60 {
+		t.Errorf("expected max age 60, got %d", newRem.CacheMaxAge)
+	}
+	if !useCached(newRem.DownloadTime, newRem.CacheMaxAge) {
+		t.Errorf("expected the refreshed remote to be fresh")
+	}
+
+	// The always policy must not revalidate with the saved ETag
+	f.PullPolicy = PullPolicyAlways
+	if _, err := f.fetchSingleImageByHTTPURL(u, &asc{}, ""); err == nil {
+		t.Fatalf("expected an error when fetching without ETag")
+	}
+}
+
+func TestValidatePullPolicy(t *testing.T) {
+	for _, p := range []string{"never", "new", "if-not-present", "update", "always"} {
+		if err := ValidatePullPolicy(p); err != nil {
+			t.Errorf("unexpected error for %q: %v", p, err)
+		}
+	}
+	if err := ValidatePullPolicy("sometimes"); err == nil {
+		t.Errorf("expected an error for an invalid pull policy")
+	}
+}
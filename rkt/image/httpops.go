@@ -34,6 +34,8 @@ type httpOps struct {
 	InsecureSkipTLSVerify bool
 	S                     *imagestore.Store
 	Headers               map[string]config.Headerer
+	TLSPerHost            map[string]config.TLSCredentials
+	P2PPerHost            map[string]config.P2PAgent
 	Debug                 bool
 }
 
@@ -148,6 +150,8 @@ func (o *httpOps) getSession(u *url.URL, file *os.File, label, etag string) *res
 		InsecureSkipTLSVerify: o.InsecureSkipTLSVerify,
 		Headers:               o.getHeaders(u, etag),
 		Headerers:             o.Headers,
+		TLSPerHost:            o.TLSPerHost,
+		P2PPerHost:            o.P2PPerHost,
 		File:                  file,
 		ETagFilePath:          eTagFilePath,
 		Label:                 label,
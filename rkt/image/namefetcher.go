@@ -22,6 +22,7 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/rkt/rkt/pkg/exitcode"
 	rktflag "github.com/rkt/rkt/rkt/flag"
 
 	"github.com/hashicorp/errwrap"
@@ -31,6 +32,7 @@ import (
 	"github.com/rkt/rkt/store/imagestore"
 
 	"github.com/appc/spec/discovery"
+	"golang.org/x/crypto/openpgp"
 	pgperrors "golang.org/x/crypto/openpgp/errors"
 )
 
@@ -42,7 +44,16 @@ type nameFetcher struct {
 	NoCache            bool
 	Debug              bool
 	Headers            map[string]config.Headerer
+	TLSPerHost         map[string]config.TLSCredentials
+	P2PPerHost         map[string]config.P2PAgent
 	TrustKeysFromHTTPS bool
+	// Source is the distribution URI recorded as the source of the
+	// image in the store.
+	Source string
+
+	// signer is the key the signature of the image was verified
+	// with, if it was.
+	signer *openpgp.Entity
 }
 
 // Hash runs the discovery, fetches the image, optionally verifies
@@ -71,8 +82,22 @@ func (f *nameFetcher) discoverApp(app *discovery.App) (discovery.ACIEndpoints, e
 	if f.InsecureFlags.AllowHTTP() {
 		insecure = insecure | discovery.InsecureHTTP
 	}
+	dnsEp, dnsErr := discoverACIEndpointsDNS(*app, insecure)
+	if dnsErr != nil && f.Debug {
+		log.PrintE(fmt.Sprintf("no 'ac-discovery' TXT record found for %s", app.Name), dnsErr)
+	}
+	if len(dnsEp) != 0 {
+		return dnsEp, nil
+	}
 	hostHeaders := config.ResolveAuthPerHost(f.Headers)
-	ep, attempts, err := discovery.DiscoverACIEndpoints(*app, hostHeaders, insecure, 0)
+	var (
+		ep       discovery.ACIEndpoints
+		attempts []discovery.FailedAttempt
+		err      error
+	)
+	withDiscoveryTLS(f.TLSPerHost, func() {
+		ep, attempts, err = discovery.DiscoverACIEndpoints(*app, hostHeaders, insecure, 0)
+	})
 	if f.Debug {
 		for _, a := range attempts {
 			log.PrintE(fmt.Sprintf("meta tag 'ac-discovery' not found on %s", a.Prefix), a.Error)
@@ -117,17 +142,27 @@ func (f *nameFetcher) fetchImageFromSingleEndpoint(app *discovery.App, aciURL st
 		}
 	}
 
-	aciFile, cd, err := f.fetch(app, aciURL, a, eTag(rem))
+	etag := ""
+	if !f.NoCache {
+		etag = eTag(rem)
+	}
+	aciFile, cd, err := f.fetch(app, aciURL, a, etag)
 	if err != nil {
 		return "", err
 	}
 	defer aciFile.Close()
 
 	if key := maybeUseCached(rem, cd); key != "" {
+		if err := refreshRemote(f.S, rem, cd); err != nil {
+			return "", err
+		}
 		return key, nil
 	}
 	key, err := f.S.WriteACI(aciFile, imagestore.ACIFetchInfo{
-		Latest: latest,
+		Latest:       latest,
+		Source:       f.Source,
+		Digest:       cacheETag(cd),
+		Verification: verificationResult(f.signer),
 	})
 	if err != nil {
 		return "", err
@@ -150,10 +185,10 @@ func (f *nameFetcher) fetchImageFromSingleEndpoint(app *discovery.App, aciURL st
 
 func (f *nameFetcher) fetch(app *discovery.App, aciURL string, a *asc, etag string) (readSeekCloser, *cacheData, error) {
 	if f.InsecureFlags.SkipTLSCheck() && f.Ks != nil {
-		log.Print("warning: TLS verification has been disabled")
+		log.Warnf("TLS verification has been disabled")
 	}
 	if f.InsecureFlags.SkipImageCheck() && f.Ks != nil {
-		log.Print("warning: image signature verification has been disabled")
+		log.Warnf("image signature verification has been disabled")
 	}
 
 	u, err := url.Parse(aciURL)
@@ -280,7 +315,7 @@ func (f *nameFetcher) checkIdentity(appName string, ascFile io.ReadSeeker) error
 			log.Printf("    rkt trust --prefix %q", appName)
 		}
 		if _, ok := err.(pgperrors.SignatureError); !ok {
-			return err
+			return exitcode.Wrap(exitcode.SignatureInvalid, err)
 		}
 	}
 	return nil
@@ -310,6 +345,7 @@ func (f *nameFetcher) validate(app *discovery.App, aciFile, ascFile io.ReadSeeke
 	}
 
 	printIdentities(entity)
+	f.signer = entity
 	return nil
 }
 
@@ -324,8 +360,10 @@ func (f *nameFetcher) maybeOverrideAscFetcherWithRemote(ascURL string, a *asc) {
 func (f *nameFetcher) httpOps() *httpOps {
 	return &httpOps{
 		InsecureSkipTLSVerify: f.InsecureFlags.SkipTLSCheck(),
-		S:       f.S,
-		Headers: f.Headers,
-		Debug:   f.Debug,
+		S:                     f.S,
+		Headers:               f.Headers,
+		TLSPerHost:            f.TLSPerHost,
+		P2PPerHost:            f.P2PPerHost,
+		Debug:                 f.Debug,
 	}
 }
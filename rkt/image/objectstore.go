@@ -0,0 +1,214 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/rkt/config"
+)
+
+const (
+	s3Host  = "s3.amazonaws.com"
+	gcsHost = "storage.googleapis.com"
+
+	metadataTimeout = 2 * time.Second
+)
+
+var (
+	// awsMetadataURL and gceMetadataURL are the base URLs of the
+	// instance metadata services, variables for the tests.
+	awsMetadataURL = "http://169.254.169.254/latest"
+	gceMetadataURL = "http://metadata.google.internal/computeMetadata/v1"
+)
+
+// objectStoreURL returns the HTTPS URL of the object pointed by an s3://
+// or a gs:// URL.
+func objectStoreURL(u *url.URL) (*url.URL, error) {
+	bucket := u.Host
+	object := strings.TrimPrefix(u.Path, "/")
+	if bucket == "" || object == "" {
+		return nil, fmt.Errorf("invalid object URL %q, expected %s://BUCKET/OBJECT", u.String(), u.Scheme)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		// The names of buckets containing dots do not match the
+		// wildcard certificate of S3, so the path-style URL is used
+		// for them.
+		if strings.Contains(bucket, ".") {
+			return &url.URL{Scheme: "https", Host: s3Host, Path: "/" + bucket + "/" + object}, nil
+		}
+		return &url.URL{Scheme: "https", Host: bucket + "." + s3Host, Path: "/" + object}, nil
+	case "gs":
+		return &url.URL{Scheme: "https", Host: gcsHost, Path: "/" + bucket + "/" + object}, nil
+	}
+	return nil, fmt.Errorf("unsupported object store scheme %q", u.Scheme)
+}
+
+// objectStoreHeaderers returns the headerers used to download from the
+// object store host. The credentials from the auth configuration of the
+// host are used if there are some, the ones of the instance otherwise.
+func objectStoreHeaderers(headers map[string]config.Headerer, scheme, host string) map[string]config.Headerer {
+	if _, ok := headers[host]; ok {
+		return headers
+	}
+
+	hs := make(map[string]config.Headerer, len(headers)+1)
+	for h, headerer := range headers {
+		hs[h] = headerer
+	}
+	switch scheme {
+	case "s3":
+		hs[host] = &instanceHeaderer{get: awsInstanceHeaderer}
+	case "gs":
+		hs[host] = &instanceHeaderer{get: gceInstanceHeaderer}
+	}
+	return hs
+}
+
+// instanceHeaderer is a config.Headerer using the credentials of the
+// cloud instance rkt runs on. They are taken from the metadata service of
+// the instance on first use; the requests are sent anonymously when there
+// are none, for public buckets.
+type instanceHeaderer struct {
+	get func() (config.Headerer, error)
+
+	once     sync.Once
+	headerer config.Headerer
+}
+
+func (h *instanceHeaderer) instanceHeaderer() config.Headerer {
+	h.once.Do(func() {
+		headerer, err := h.get()
+		if err != nil {
+			diag.PrintE("no instance credentials, downloading anonymously", err)
+			return
+		}
+		h.headerer = headerer
+	})
+	return h.headerer
+}
+
+func (h *instanceHeaderer) GetHeader() http.Header {
+	if headerer := h.instanceHeaderer(); headerer != nil {
+		return headerer.GetHeader()
+	}
+	return make(http.Header)
+}
+
+func (h *instanceHeaderer) SignRequest(r *http.Request) *http.Request {
+	if headerer := h.instanceHeaderer(); headerer != nil {
+		return headerer.SignRequest(r)
+	}
+	return r
+}
+
+// awsInstanceHeaderer returns a headerer signing the requests with the
+// credentials of the IAM role of the EC2 instance.
+func awsInstanceHeaderer() (config.Headerer, error) {
+	header := make(http.Header)
+	// IMDSv2 session token, older metadata services don't know about
+	// it and accept requests without it.
+	if token, err := metadataRequest("PUT", awsMetadataURL+"/api/token", http.Header{"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"300"}}); err == nil {
+		header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+	}
+
+	roles, err := metadataRequest("GET", awsMetadataURL+"/meta-data/iam/security-credentials/", header)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error getting the IAM role of the instance"), err)
+	}
+	role := strings.TrimSpace(strings.SplitN(string(roles), "\n", 2)[0])
+	if role == "" {
+		return nil, errors.New("no IAM role attached to the instance")
+	}
+	raw, err := metadataRequest("GET", awsMetadataURL+"/meta-data/iam/security-credentials/"+role, header)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error getting the credentials of IAM role %q", role), err)
+	}
+	var creds struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string `json:"SecretAccessKey"`
+		Token           string `json:"Token"`
+	}
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error decoding the credentials of IAM role %q", role), err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, fmt.Errorf("no credentials for IAM role %q", role)
+	}
+
+	// Buckets are usually in the region of the instances using them,
+	// the region is guessed from the host otherwise.
+	region := ""
+	if az, err := metadataRequest("GET", awsMetadataURL+"/meta-data/placement/availability-zone", header); err == nil && len(az) > 1 {
+		region = string(az[:len(az)-1])
+	}
+	return config.NewAWSHeaderer(creds.AccessKeyID, creds.SecretAccessKey, creds.Token, region), nil
+}
+
+// gceInstanceHeaderer returns a headerer sending an access token of the
+// default service account of the GCE instance.
+func gceInstanceHeaderer() (config.Headerer, error) {
+	raw, err := metadataRequest("GET", gceMetadataURL+"/instance/service-accounts/default/token", http.Header{"Metadata-Flavor": {"Google"}})
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error getting an access token of the instance service account"), err)
+	}
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(raw, &token); err != nil {
+		return nil, errwrap.Wrap(errors.New("error decoding the access token of the instance service account"), err)
+	}
+	if token.AccessToken == "" {
+		return nil, errors.New("no access token for the instance service account")
+	}
+	return config.NewOAuthHeaderer(token.AccessToken), nil
+}
+
+// metadataRequest queries an instance metadata service. These are only
+// reachable from the instance, so it never goes through a proxy and
+// gives up quickly elsewhere.
+func metadataRequest(method, urlStr string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequest(method, urlStr, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	client := &http.Client{
+		Transport: &http.Transport{},
+		Timeout:   metadataTimeout,
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad HTTP status code: %d", res.StatusCode)
+	}
+	return ioutil.ReadAll(res.Body)
+}
@@ -0,0 +1,140 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/rkt/rkt/rkt/config"
+)
+
+func TestObjectStoreURL(t *testing.T) {
+	tests := []struct {
+		in   string
+		out  string
+		fail bool
+	}{
+		{"s3://bucket/images/app.aci", "https://bucket.s3.amazonaws.com/images/app.aci", false},
+		{"s3://my.bucket/app.aci", "https://s3.amazonaws.com/my.bucket/app.aci", false},
+		{"gs://bucket/images/app.aci", "https://storage.googleapis.com/bucket/images/app.aci", false},
+		{"s3://bucket/", "", true},
+		{"gs:///app.aci", "", true},
+	}
+
+	for i, tt := range tests {
+		u, err := url.Parse(tt.in)
+		if err != nil {
+			t.Fatalf("#%d: error parsing %q: %v", i, tt.in, err)
+		}
+		out, err := objectStoreURL(u)
+		if tt.fail {
+			if err == nil {
+				t.Errorf("#%d: expected an error for %q, got %q", i, tt.in, out)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error for %q: %v", i, tt.in, err)
+			continue
+		}
+		if out.String() != tt.out {
+			t.Errorf("#%d: got %q, want %q", i, out.String(), tt.out)
+		}
+		if got := ascURLFromImgURL(out).String(); got != strings.TrimSuffix(tt.out, ".aci")+".aci.asc" {
+			t.Errorf("#%d: unexpected signature URL %q", i, got)
+		}
+	}
+}
+
+func TestObjectStoreHeaderers(t *testing.T) {
+	configured := config.NewOAuthHeaderer("configured")
+	headers := map[string]config.Headerer{gcsHost: configured}
+
+	if hs := objectStoreHeaderers(headers, "gs", gcsHost); hs[gcsHost] != configured {
+		t.Errorf("expected the configured headerer to be used")
+	}
+	hs := objectStoreHeaderers(headers, "s3", "bucket."+s3Host)
+	if _, ok := hs["bucket."+s3Host].(*instanceHeaderer); !ok {
+		t.Errorf("expected the instance headerer to be used, got %T", hs["bucket."+s3Host])
+	}
+	if hs[gcsHost] != configured {
+		t.Errorf("expected the other headerers to be kept")
+	}
+	if _, ok := headers["bucket."+s3Host]; ok {
+		t.Errorf("expected the passed headerers to be left untouched")
+	}
+}
+
+func TestInstanceHeaderer(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/aws/api/token":
+			w.Write([]byte("imds-token"))
+		case "/aws/meta-data/iam/security-credentials/":
+			w.Write([]byte("rkt-role\n"))
+		case "/aws/meta-data/iam/security-credentials/rkt-role":
+			if r.Header.Get("X-Aws-Ec2-Metadata-Token") != "imds-token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte(`{"Code": "Success", "AccessKeyId": "AKID", "SecretAccessKey": "secret", "Token": "session"}`))
+		case "/aws/meta-data/placement/availability-zone":
+			w.Write([]byte("eu-west-1b"))
+		case "/gce/instance/service-accounts/default/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"access_token": "gce-token", "expires_in": 3599, "token_type": "Bearer"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	ensureLogger(false)
+	defer func(aws, gce string) {
+		awsMetadataURL, gceMetadataURL = aws, gce
+	}(awsMetadataURL, gceMetadataURL)
+	awsMetadataURL = ts.URL + "/aws"
+	gceMetadataURL = ts.URL + "/gce"
+
+	req, _ := http.NewRequest("GET", "https://bucket.s3.amazonaws.com/app.aci", nil)
+	req = (&instanceHeaderer{get: awsInstanceHeaderer}).SignRequest(req)
+	if auth := req.Header.Get("Authorization"); !strings.Contains(auth, "Credential=AKID/") || !strings.Contains(auth, "/eu-west-1/s3/") {
+		t.Errorf("unexpected AWS authorization header %q", auth)
+	}
+	if token := req.Header.Get("X-Amz-Security-Token"); token != "session" {
+		t.Errorf("unexpected AWS security token %q", token)
+	}
+
+	req, _ = http.NewRequest("GET", "https://storage.googleapis.com/bucket/app.aci", nil)
+	req = (&instanceHeaderer{get: gceInstanceHeaderer}).SignRequest(req)
+	if auth := req.Header.Get("Authorization"); auth != "Bearer gce-token" {
+		t.Errorf("unexpected GCE authorization header %q", auth)
+	}
+
+	// no metadata service, the request is sent anonymously
+	gceMetadataURL = ts.URL + "/none"
+	req, _ = http.NewRequest("GET", "https://storage.googleapis.com/bucket/app.aci", nil)
+	req = (&instanceHeaderer{get: gceInstanceHeaderer}).SignRequest(req)
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		t.Errorf("unexpected authorization header %q", auth)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/rkt/config"
+)
+
+// p2pTransport is an http.RoundTripper sending the requests to the hosts
+// having a P2P agent through it, and the other requests through a
+// fallback transport. The agent is used as an HTTP proxy, it is expected
+// to share the downloaded data with the agents of the other hosts.
+type p2pTransport struct {
+	perHost    map[string]config.P2PAgent
+	tlsPerHost map[string]config.TLSCredentials
+	insecure   bool
+	fallback   http.RoundTripper
+
+	lock       sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func newP2PTransport(perHost map[string]config.P2PAgent, tlsPerHost map[string]config.TLSCredentials, insecure bool, fallback http.RoundTripper) *p2pTransport {
+	return &p2pTransport{
+		perHost:    perHost,
+		tlsPerHost: tlsPerHost,
+		insecure:   insecure,
+		fallback:   fallback,
+		transports: make(map[string]*http.Transport),
+	}
+}
+
+func (t *p2pTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	agent, ok := lookupP2PAgent(t.perHost, req.URL)
+	if !ok {
+		return t.fallback.RoundTrip(req)
+	}
+	tr, err := t.transport(agent, req.URL)
+	if err != nil {
+		return nil, err
+	}
+	res, err := tr.RoundTrip(req)
+	if err != nil && agent.Fallback {
+		diag.PrintE(fmt.Sprintf("P2P agent %s failed, downloading %s directly", agent.Agent, req.URL.String()), err)
+		return t.fallback.RoundTrip(req)
+	}
+	return res, err
+}
+
+// transport returns the transport going through the agent for the host
+// of the URL. The host keeps its TLS configuration, the agent being
+// expected to pass the TLS connections through or to be trusted with a
+// CA bundle.
+func (t *p2pTransport) transport(agent config.P2PAgent, u *url.URL) (*http.Transport, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	key := agent.Agent + " " + u.Host
+	if tr, ok := t.transports[key]; ok {
+		return tr, nil
+	}
+	proxy, err := url.Parse(agent.Agent)
+	if err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("invalid P2P agent URL %q", agent.Agent), err)
+	}
+	tlsConfig := &tls.Config{}
+	if _, creds, ok := lookupTLSCredentials(t.tlsPerHost, u); ok {
+		if tlsConfig, err = creds.TLSConfig(); err != nil {
+			return nil, err
+		}
+	}
+	tlsConfig.InsecureSkipVerify = t.insecure
+	tr := &http.Transport{
+		Proxy:           http.ProxyURL(proxy),
+		TLSClientConfig: tlsConfig,
+	}
+	t.transports[key] = tr
+	return tr, nil
+}
+
+// lookupP2PAgent returns the P2P agent for the host of the URL. The host
+// may be configured with or without the port.
+func lookupP2PAgent(perHost map[string]config.P2PAgent, u *url.URL) (config.P2PAgent, bool) {
+	for _, host := range []string{u.Host, u.Hostname()} {
+		if agent, ok := perHost[host]; ok {
+			return agent, true
+		}
+	}
+	return config.P2PAgent{}, false
+}
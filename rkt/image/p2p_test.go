@@ -0,0 +1,86 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/rkt/rkt/rkt/config"
+)
+
+func TestP2PTransport(t *testing.T) {
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("origin"))
+	}))
+	defer origin.Close()
+	// The agent answers the proxied requests itself, as if it got
+	// the data from its peers.
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !r.URL.IsAbs() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("agent"))
+	}))
+	defer agent.Close()
+	// An address nothing listens on.
+	down := httptest.NewServer(http.NotFoundHandler())
+	downURL := down.URL
+	down.Close()
+
+	u, err := url.Parse(origin.URL)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", origin.URL, err)
+	}
+
+	ensureLogger(false)
+	tests := []struct {
+		perHost map[string]config.P2PAgent
+		body    string
+		fail    bool
+	}{
+		{nil, "origin", false},
+		{map[string]config.P2PAgent{"other.example.com": {Agent: agent.URL}}, "origin", false},
+		{map[string]config.P2PAgent{u.Host: {Agent: agent.URL}}, "agent", false},
+		{map[string]config.P2PAgent{u.Hostname(): {Agent: agent.URL}}, "agent", false},
+		{map[string]config.P2PAgent{u.Host: {Agent: downURL}}, "", true},
+		{map[string]config.P2PAgent{u.Host: {Agent: downURL, Fallback: true}}, "origin", false},
+	}
+
+	for i, tt := range tests {
+		client := &http.Client{Transport: newP2PTransport(tt.perHost, nil, false, &http.Transport{})}
+		res, err := client.Get(origin.URL)
+		if tt.fail {
+			if err == nil {
+				res.Body.Close()
+				t.Errorf("#%d: expected an error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+			continue
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if string(body) != tt.body {
+			t.Errorf("#%d: got body %q, want %q", i, body, tt.body)
+		}
+	}
+}
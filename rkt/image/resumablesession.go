@@ -15,7 +15,6 @@
 package image
 
 import (
-	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -63,6 +62,12 @@ type resumableSession struct {
 	Headers http.Header
 	// Headerers used for authentication.
 	Headerers map[string]config.Headerer
+	// TLSPerHost holds the CA bundles and the client certificates
+	// used for the hosts requiring them.
+	TLSPerHost map[string]config.TLSCredentials
+	// P2PPerHost holds the P2P agents the downloads from the
+	// hosts go through.
+	P2PPerHost map[string]config.P2PAgent
 	// File possibly holds the downloaded data - it is used for
 	// resuming interrupted downloads.
 	File *os.File
@@ -277,10 +282,13 @@ func (s *resumableSession) reset() error {
 func (s *resumableSession) getClient() *http.Client {
 	transport := http.DefaultTransport
 	if s.InsecureSkipTLSVerify {
-		transport = &http.Transport{
-			Proxy:           http.ProxyFromEnvironment,
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-		}
+		transport = insecureTransport()
+	}
+	if len(s.TLSPerHost) > 0 {
+		transport = newPerHostTLSTransport(s.TLSPerHost, s.InsecureSkipTLSVerify, transport)
+	}
+	if len(s.P2PPerHost) > 0 {
+		transport = newP2PTransport(s.P2PPerHost, s.TLSPerHost, s.InsecureSkipTLSVerify, transport)
 	}
 
 	return &http.Client{
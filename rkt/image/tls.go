@@ -0,0 +1,124 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/appc/spec/discovery"
+	"github.com/rkt/rkt/rkt/config"
+)
+
+// perHostTLSTransport is an http.RoundTripper using the TLS
+// configuration set up for the host of each request, if any, and a
+// fallback transport for the other hosts.
+type perHostTLSTransport struct {
+	perHost  map[string]config.TLSCredentials
+	insecure bool
+	fallback http.RoundTripper
+
+	lock       sync.Mutex
+	transports map[string]*http.Transport
+}
+
+func newPerHostTLSTransport(perHost map[string]config.TLSCredentials, insecure bool, fallback http.RoundTripper) *perHostTLSTransport {
+	return &perHostTLSTransport{
+		perHost:    perHost,
+		insecure:   insecure,
+		fallback:   fallback,
+		transports: make(map[string]*http.Transport),
+	}
+}
+
+func (t *perHostTLSTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host, creds, ok := t.lookup(req)
+	if !ok {
+		return t.fallback.RoundTrip(req)
+	}
+	tr, err := t.transport(host, creds)
+	if err != nil {
+		return nil, err
+	}
+	return tr.RoundTrip(req)
+}
+
+func (t *perHostTLSTransport) lookup(req *http.Request) (string, config.TLSCredentials, bool) {
+	return lookupTLSCredentials(t.perHost, req.URL)
+}
+
+// lookupTLSCredentials returns the TLS credentials for the host of the
+// URL. The host may be configured with or without the port.
+func lookupTLSCredentials(perHost map[string]config.TLSCredentials, u *url.URL) (string, config.TLSCredentials, bool) {
+	if u.Scheme != "https" {
+		return "", config.TLSCredentials{}, false
+	}
+	for _, host := range []string{u.Host, u.Hostname()} {
+		if creds, ok := perHost[host]; ok {
+			return host, creds, true
+		}
+	}
+	return "", config.TLSCredentials{}, false
+}
+
+func (t *perHostTLSTransport) transport(host string, creds config.TLSCredentials) (*http.Transport, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if tr, ok := t.transports[host]; ok {
+		return tr, nil
+	}
+	tlsConfig, err := creds.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	// The client certificate is still sent when the verification
+	// of the server is disabled.
+	tlsConfig.InsecureSkipVerify = t.insecure
+	tr := &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: tlsConfig,
+	}
+	t.transports[host] = tr
+	return tr, nil
+}
+
+// insecureTransport returns the transport used when TLS verification
+// is disabled.
+func insecureTransport() http.RoundTripper {
+	return &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+}
+
+// withDiscoveryTLS makes the appc discovery use the TLS configuration
+// of the hosts while f runs. The discovery package only provides
+// global HTTP clients.
+func withDiscoveryTLS(perHost map[string]config.TLSCredentials, f func()) {
+	if len(perHost) == 0 {
+		f()
+		return
+	}
+	secure, insecure := discovery.Client.Transport, discovery.ClientInsecureTLS.Transport
+	defer func() {
+		discovery.Client.Transport, discovery.ClientInsecureTLS.Transport = secure, insecure
+	}()
+	discovery.Client.Transport = newPerHostTLSTransport(perHost, false, secure)
+	discovery.ClientInsecureTLS.Transport = newPerHostTLSTransport(perHost, true, insecure)
+	f()
+}
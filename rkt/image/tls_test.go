@@ -0,0 +1,142 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package image
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rkt/rkt/rkt/config"
+)
+
+// writeClientCert generates a self-signed client certificate and
+// writes it with its key in dir.
+func writeClientCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("error generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "rkt-test-client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("error marshalling key: %v", err)
+	}
+
+	certPath := filepath.Join(dir, "client.pem")
+	keyPath := filepath.Join(dir, "client.key")
+	if err := ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatalf("error writing certificate: %v", err)
+	}
+	if err := ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatalf("error writing key: %v", err)
+	}
+	return certPath, keyPath
+}
+
+func TestPerHostTLSTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rkt-tls-test-")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) == 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	ts.TLS = &tls.Config{ClientAuth: tls.RequestClientCert}
+	ts.StartTLS()
+	defer ts.Close()
+
+	caPath := filepath.Join(dir, "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.TLS.Certificates[0].Certificate[0]})
+	if err := ioutil.WriteFile(caPath, caPEM, 0600); err != nil {
+		t.Fatalf("error writing CA bundle: %v", err)
+	}
+	certPath, keyPath := writeClientCert(t, dir)
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatalf("error parsing %q: %v", ts.URL, err)
+	}
+
+	tests := []struct {
+		perHost map[string]config.TLSCredentials
+		status  int
+		body    string
+		fail    bool
+	}{
+		// the server certificate is not trusted
+		{nil, 0, "", true},
+		{map[string]config.TLSCredentials{"other.example.com": {CABundle: caPath}}, 0, "", true},
+		// trusted server, no client certificate
+		{map[string]config.TLSCredentials{u.Host: {CABundle: caPath}}, http.StatusUnauthorized, "", false},
+		// the host can be configured without the port
+		{map[string]config.TLSCredentials{u.Hostname(): {CABundle: caPath}}, http.StatusUnauthorized, "", false},
+		// trusted server and client certificate
+		{map[string]config.TLSCredentials{u.Host: {CABundle: caPath, ClientCert: certPath, ClientKey: keyPath}}, http.StatusOK, "rkt-test-client", false},
+	}
+
+	for i, tt := range tests {
+		client := &http.Client{Transport: newPerHostTLSTransport(tt.perHost, false, &http.Transport{})}
+		res, err := client.Get(ts.URL)
+		if tt.fail {
+			if err == nil {
+				res.Body.Close()
+				t.Errorf("#%d: expected an error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+			continue
+		}
+		body, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != tt.status {
+			t.Errorf("#%d: got status %d, want %d", i, res.StatusCode, tt.status)
+		}
+		if string(body) != tt.body {
+			t.Errorf("#%d: got body %q, want %q", i, body, tt.body)
+		}
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"io"
 
 	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/pkg/exitcode"
 	"github.com/rkt/rkt/pkg/keystore"
 
 	"github.com/appc/spec/aci"
@@ -98,7 +99,7 @@ func (v *validator) ValidateWithSignature(ks *keystore.Keystore, sig io.ReadSeek
 		log.Print("    rkt trust --prefix <image>")
 	}
 	if err != nil {
-		return nil, err
+		return nil, exitcode.Wrap(exitcode.SignatureInvalid, err)
 	}
 	return entity, nil
 }
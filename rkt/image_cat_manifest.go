@@ -17,7 +17,8 @@ package main
 import (
 	"encoding/json"
 
-	"github.com/rkt/rkt/store/imagestore"
+	"github.com/appc/spec/schema"
+	"github.com/rkt/rkt/api/v1"
 
 	"github.com/spf13/cobra"
 )
@@ -26,15 +27,27 @@ var (
 	cmdImageCatManifest = &cobra.Command{
 		Use:   "cat-manifest IMAGE",
 		Short: "Inspect and print the image manifest",
-		Long:  `IMAGE should be a string referencing an image; either a ID or an image name.`,
-		Run:   runWrapper(runImageCatManifest),
+		Long: `IMAGE should be a string referencing an image; either a ID or an image name.
+
+With --provenance, the manifest is printed along with the provenance of the
+image recorded when it was fetched: its source, the fetch time, the digest
+announced by the remote and the result of the signature verification.`,
+		Run: runWrapper(runImageCatManifest),
 	}
 	flagPrettyPrint bool
+	flagProvenance  bool
 )
 
+// manifestWithProvenance is printed by cat-manifest with --provenance.
+type manifestWithProvenance struct {
+	Manifest   *schema.ImageManifest `json:"manifest"`
+	Provenance v1.ImageProvenance    `json:"provenance"`
+}
+
 func init() {
 	cmdImage.AddCommand(cmdImageCatManifest)
 	cmdImageCatManifest.Flags().BoolVar(&flagPrettyPrint, "pretty-print", true, "apply indent to format the output")
+	cmdImageCatManifest.Flags().BoolVar(&flagProvenance, "provenance", false, "print the provenance of the image along with the manifest")
 }
 
 func runImageCatManifest(cmd *cobra.Command, args []string) (exit int) {
@@ -43,7 +56,7 @@ func runImageCatManifest(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -61,11 +74,29 @@ func runImageCatManifest(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
+	var out interface{} = manifest
+	if flagProvenance {
+		info, err := s.GetACIInfoWithBlobKey(key)
+		if err != nil {
+			stderr.PrintE("cannot get image info", err)
+			return 254
+		}
+		out = manifestWithProvenance{
+			Manifest: manifest,
+			Provenance: v1.ImageProvenance{
+				Source:       info.Source,
+				FetchTime:    info.ImportTime.UnixNano(),
+				Digest:       info.Digest,
+				Verification: info.Verification,
+			},
+		}
+	}
+
 	var b []byte
 	if flagPrettyPrint {
-		b, err = json.MarshalIndent(manifest, "", "\t")
+		b, err = json.MarshalIndent(out, "", "\t")
 	} else {
-		b, err = json.Marshal(manifest)
+		b, err = json.Marshal(out)
 	}
 	if err != nil {
 		stderr.PrintE("cannot read the image manifest", err)
@@ -19,8 +19,6 @@ import (
 	"io"
 	"os"
 
-	"github.com/rkt/rkt/store/imagestore"
-
 	"github.com/spf13/cobra"
 )
 
@@ -48,7 +46,7 @@ func runImageExport(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -23,7 +23,6 @@ import (
 	"github.com/rkt/rkt/pkg/fileutil"
 	"github.com/rkt/rkt/pkg/tar"
 	"github.com/rkt/rkt/pkg/user"
-	"github.com/rkt/rkt/store/imagestore"
 
 	"github.com/spf13/cobra"
 )
@@ -55,7 +54,7 @@ func runImageExtract(cmd *cobra.Command, args []string) (exit int) {
 	}
 	outputDir := args[1]
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
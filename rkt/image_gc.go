@@ -48,11 +48,12 @@ the grace-period.`,
 
 func init() {
 	cmdImage.AddCommand(cmdImageGC)
+	addSecureEraseFlag(cmdImageGC.Flags())
 	cmdImageGC.Flags().DurationVar(&flagImageGracePeriod, "grace-period", defaultImageGracePeriod, "duration to wait since an image was last used before removing it")
 }
 
 func runGCImage(cmd *cobra.Command, args []string) (exit int) {
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -74,6 +75,15 @@ func runGCImage(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
+	if err := s.GCManifestCache(); err != nil {
+		stderr.PrintE("failed to garbage collect the manifest cache", err)
+		return 254
+	}
+
+	if mode, err := getEraseMode(); err == nil {
+		trimErased(storeDir(), mode)
+	}
+
 	return 0
 }
 
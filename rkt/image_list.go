@@ -25,18 +25,22 @@ import (
 	"github.com/appc/spec/schema/lastditch"
 	"github.com/dustin/go-humanize"
 	"github.com/rkt/rkt/api/v1"
+	dist "github.com/rkt/rkt/pkg/distribution"
 	rktflag "github.com/rkt/rkt/pkg/flag"
 	"github.com/rkt/rkt/store/imagestore"
 	"github.com/spf13/cobra"
 )
 
 const (
-	id         = "id"
-	name       = "name"
-	importTime = "import time"
-	lastUsed   = "last used"
-	size       = "size"
-	latest     = "latest"
+	id           = "id"
+	name         = "name"
+	importTime   = "import time"
+	lastUsed     = "last used"
+	size         = "size"
+	latest       = "latest"
+	source       = "source"
+	digest       = "digest"
+	verification = "verification"
 )
 
 const defaultTimeLayout = "2006-01-02 15:04:05.999 -0700 MST"
@@ -52,12 +56,15 @@ func u(s string) string {
 var (
 	// map of valid fields and related header name
 	ImagesFieldHeaderMap = map[string]string{
-		l(id):         u(id),
-		l(name):       u(name),
-		l(importTime): u(importTime),
-		l(lastUsed):   u(lastUsed),
-		l(latest):     u(latest),
-		l(size):       u(size),
+		l(id):           u(id),
+		l(name):         u(name),
+		l(importTime):   u(importTime),
+		l(lastUsed):     u(lastUsed),
+		l(latest):       u(latest),
+		l(size):         u(size),
+		l(source):       u(source),
+		l(digest):       u(digest),
+		l(verification): u(verification),
 	}
 
 	// map of valid sort fields containing the mapping between the provided field name
@@ -121,16 +128,28 @@ func (pi *printableImage) lastUsedTime() string {
 	return humanize.Time(t)
 }
 
+// orDash returns the value of an optional attribute, or "-" when it is
+// not set.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
 func (pi *printableImage) attributes(fields *rktflag.OptionList) []string {
 	if fields == nil {
 		return []string{pi.ID, pi.Name, pi.imageSize(), pi.importTime(), pi.lastUsedTime()}
 	}
 	optionMapping := map[string]string{
-		l(id):         pi.ID,
-		l(name):       pi.Name,
-		l(size):       pi.imageSize(),
-		l(importTime): pi.importTime(),
-		l(lastUsed):   pi.lastUsedTime(),
+		l(id):           pi.ID,
+		l(name):         pi.Name,
+		l(size):         pi.imageSize(),
+		l(importTime):   pi.importTime(),
+		l(lastUsed):     pi.lastUsedTime(),
+		l(source):       orDash(pi.Source),
+		l(digest):       orDash(pi.Digest),
+		l(verification): orDash(pi.Verification),
 	}
 	attrs := []string{}
 	for _, f := range fields.Options {
@@ -210,16 +229,28 @@ var (
 	flagImagesSortFields *rktflag.OptionList
 	flagImagesSortAsc    ImagesSortAsc
 	flagImageFormat      outputFormat
+
+	// imagesFullFields are the fields displayed with --full, unless
+	// --fields is given.
+	imagesFullFields *rktflag.OptionList
 )
 
 func init() {
 	sortFields := []string{l(name), l(importTime), l(lastUsed), l(size)}
 
 	fields := []string{l(id), l(name), l(size), l(importTime), l(lastUsed)}
+	// The provenance of the images is only displayed on request, or
+	// with --full.
+	provenanceFields := []string{l(source), l(digest), l(verification)}
+	allFields := append(fields, provenanceFields...)
 
 	// Set defaults
 	var err error
-	flagImagesFields, err = rktflag.NewOptionList(fields, strings.Join(fields, ","))
+	flagImagesFields, err = rktflag.NewOptionList(allFields, strings.Join(fields, ","))
+	if err != nil {
+		stderr.FatalE("", err)
+	}
+	imagesFullFields, err = rktflag.NewOptionList(allFields, strings.Join(allFields, ","))
 	if err != nil {
 		stderr.FatalE("", err)
 	}
@@ -236,7 +267,7 @@ func init() {
 		flagImagesSortFields.PermissibleString()))
 	cmdImageList.Flags().Var(&flagImagesSortAsc, "order", `choose the sorting order if at least one sort field is provided (--sort). Accepted values: "asc", "desc"`)
 	cmdImageList.Flags().BoolVar(&flagNoLegend, "no-legend", false, "suppress a legend with the list")
-	cmdImageList.Flags().BoolVar(&flagFullOutput, "full", false, "use long output format")
+	cmdImageList.Flags().BoolVar(&flagFullOutput, "full", false, "use long output format, with the provenance of the images")
 	cmdImageList.Flags().Var(&flagImageFormat, "format", fmt.Sprintf("choose the output format, allowed format includes 'json', 'json-pretty'. If empty, then the result is printed as key value pairs"))
 }
 
@@ -245,7 +276,7 @@ func runImages(cmd *cobra.Command, args []string) int {
 	tabBuffer := new(bytes.Buffer)
 	tabOut := getTabOutWithWriter(tabBuffer)
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -306,23 +337,31 @@ func runImages(cmd *cobra.Command, args []string) int {
 				ImportTime:   aciInfo.ImportTime.UnixNano(),
 				LastUsedTime: aciInfo.LastUsed.UnixNano(),
 				Size:         totalSize,
+				Source:       imageSource(aciInfo, remoteMap[aciInfo.BlobKey], flagFullOutput),
+				Digest:       aciInfo.Digest,
+				Verification: aciInfo.Verification,
 			},
 			format: flagImageFormat,
 			full:   flagFullOutput,
 		})
 	}
 
+	fields := flagImagesFields
+	if flagFullOutput && !cmd.Flags().Changed("fields") {
+		fields = imagesFullFields
+	}
+
 	switch flagImageFormat {
 	case outputFormatTabbed:
 		if !flagNoLegend {
 			var headerFields []string
-			for _, f := range flagImagesFields.Options {
+			for _, f := range fields.Options {
 				headerFields = append(headerFields, ImagesFieldHeaderMap[f])
 			}
 			fmt.Fprintf(tabOut, "%s\n", strings.Join(headerFields, "\t"))
 		}
 		for _, image := range imagesToPrint {
-			fmt.Fprintf(tabOut, "%s\n", image.printableString(flagImagesFields))
+			fmt.Fprintf(tabOut, "%s\n", image.printableString(fields))
 		}
 	case outputFormatJSON:
 		result, err := json.Marshal(imagesToPrint)
@@ -377,3 +416,31 @@ func newImgListLoadError(err error, imj []byte, blobKey string) error {
 	lines = append(lines, fmt.Sprintf("  %s", blobKey))
 	return fmt.Errorf("%s", strings.Join(lines, "\n"))
 }
+
+// imageSource returns the location the image was fetched from, the images
+// from Docker registries being shown with their user friendly docker
+// reference, like "docker://busybox". The full output shows the
+// distribution URI recorded in the store instead. The images fetched by
+// older versions of rkt only have their remote.
+func imageSource(info *imagestore.ACIInfo, rem *imagestore.Remote, full bool) string {
+	if info.Source != "" {
+		if full {
+			return info.Source
+		}
+		if d, err := dist.Parse(info.Source); err == nil {
+			if _, ok := d.(*dist.Docker); ok {
+				return "docker://" + d.String()
+			}
+			return d.String()
+		}
+	}
+	if rem == nil {
+		return ""
+	}
+	if strings.HasPrefix(rem.ACIURL, "docker://") {
+		if d, err := dist.NewDockerFromString(strings.TrimPrefix(rem.ACIURL, "docker://")); err == nil {
+			return "docker://" + d.String()
+		}
+	}
+	return rem.ACIURL
+}
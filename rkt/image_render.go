@@ -22,7 +22,6 @@ import (
 
 	"github.com/rkt/rkt/pkg/fileutil"
 	"github.com/rkt/rkt/pkg/user"
-	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 
 	"github.com/spf13/cobra"
@@ -58,7 +57,7 @@ func runImageRender(cmd *cobra.Command, args []string) (exit int) {
 	}
 	outputDir := args[1]
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -82,7 +81,7 @@ func runImageRender(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 	if _, err := ts.Check(id); err != nil {
-		stderr.Print("warning: tree cache is in a bad state. Rebuilding...")
+		stderr.Warnf("tree cache is in a bad state. Rebuilding...")
 		var err error
 		if id, _, err = ts.Render(key, true); err != nil {
 			stderr.PrintE("error rendering ACI", err)
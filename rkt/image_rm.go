@@ -32,6 +32,7 @@ var (
 
 func init() {
 	cmdImage.AddCommand(cmdImageRm)
+	addSecureEraseFlag(cmdImageRm.Flags())
 }
 
 func rmImages(s *imagestore.Store, images []string) error {
@@ -58,6 +59,7 @@ func rmImages(s *imagestore.Store, images []string) error {
 	staleErrors := 0
 
 	for key, name := range imageMap {
+		auditImage(key)
 		if err := s.RemoveACI(key); err != nil {
 			if serr, ok := err.(*imagestore.StoreRemovalError); ok {
 				staleErrors++
@@ -96,7 +98,7 @@ func runRmImage(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -107,5 +109,9 @@ func runRmImage(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
+	if mode, err := getEraseMode(); err == nil {
+		trimErased(storeDir(), mode)
+	}
+
 	return 0
 }
@@ -0,0 +1,286 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/gorilla/mux"
+	"github.com/rkt/rkt/store/imagestore"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdImageServe = &cobra.Command{
+		Use:   "serve [--listen=ADDRESS]",
+		Short: "Serve the images of the local store to the other hosts over HTTP",
+		Long: `Serve the images of the local store read-only over HTTP, so that the
+neighboring hosts can fetch them from this host instead of upstream.
+
+The images are available at digest-addressed URLs:
+
+	http://HOST:7678/blobs/sha512-....aci
+
+and through appc discovery, for the names of the images in the store:
+
+	http://HOST:7678/NAME?ac-discovery=1
+
+The list of the served images is available in JSON at http://HOST:7678/images.
+
+The signatures are not kept in the store, so the images must be fetched with
+--insecure-options=image, or with a signature file given with --signature.`,
+		Run: runWrapper(runImageServe),
+	}
+	flagImageServeListen string
+)
+
+const (
+	defaultImageServeListen = ":7678"
+
+	// blobMaxAge is the cache lifetime announced for the blobs, they
+	// never change as they are addressed by their digest.
+	blobMaxAge = 365 * 24 * time.Hour
+)
+
+func init() {
+	cmdImage.AddCommand(cmdImageServe)
+	cmdImageServe.Flags().StringVar(&flagImageServeListen, "listen", defaultImageServeListen, "address to listen on")
+}
+
+func runImageServe(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 0 {
+		cmd.Usage()
+		return 254
+	}
+
+	s, err := openImageStore()
+	if err != nil {
+		stderr.PrintE("cannot open store", err)
+		return 254
+	}
+
+	l, err := net.Listen("tcp", flagImageServeListen)
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("error listening on %s", flagImageServeListen), err)
+		return 254
+	}
+	defer l.Close()
+
+	stderr.Printf("serving the images of the store on %s", l.Addr())
+	if err := http.Serve(l, newImageServer(s)); err != nil {
+		stderr.PrintE("error serving images", err)
+		return 254
+	}
+	return 0
+}
+
+// imageServer serves the images of a store.
+type imageServer struct {
+	s *imagestore.Store
+}
+
+// servedImage is an entry of the JSON list of the served images.
+type servedImage struct {
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Labels     map[string]string `json:"labels"`
+	Size       int64             `json:"size"`
+	ImportTime time.Time         `json:"importTime"`
+	URL        string            `json:"url"`
+}
+
+var discoveryTemplate = template.Must(template.New("discovery").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta name="ac-discovery" content="{{.Name}} http://{{.Host}}/aci/{{.Name}}/{version}/{os}/{arch}.{ext}">
+</head>
+</html>
+`))
+
+func newImageServer(s *imagestore.Store) http.Handler {
+	is := &imageServer{s: s}
+
+	r := mux.NewRouter()
+	mr := r.Methods("GET", "HEAD").Subrouter()
+	mr.HandleFunc("/images", logReq(is.handleImages))
+	mr.HandleFunc("/blobs/{id:[a-z0-9]+-[0-9a-f]+}.aci", logReq(is.handleBlob))
+	mr.HandleFunc("/blobs/{id:[a-z0-9]+-[0-9a-f]+}.aci.asc", logReq(handleNoSignature))
+	mr.HandleFunc("/aci/{name:.+}/{version:[^/]+}/{os:[^/]+}/{arch:[^/]+}.aci", logReq(is.handleACI))
+	mr.HandleFunc("/aci/{name:.+}/{version:[^/]+}/{os:[^/]+}/{arch:[^/]+}.aci.asc", logReq(handleNoSignature))
+	mr.PathPrefix("/").Queries("ac-discovery", "1").HandlerFunc(logReq(is.handleDiscovery))
+	return r
+}
+
+func (is *imageServer) handleImages(w http.ResponseWriter, r *http.Request) {
+	infos, err := is.s.GetAllACIInfos(nil, false)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "error listing images: %v", err)
+		return
+	}
+
+	images := []servedImage{}
+	for _, info := range infos {
+		im, err := is.s.GetImageManifest(info.BlobKey)
+		if err != nil {
+			stderr.PrintE(fmt.Sprintf("error getting the manifest of image %q", info.BlobKey), err)
+			continue
+		}
+		labels := make(map[string]string)
+		for _, l := range im.Labels {
+			labels[l.Name.String()] = l.Value
+		}
+		images = append(images, servedImage{
+			ID:         info.BlobKey,
+			Name:       info.Name,
+			Labels:     labels,
+			Size:       info.Size,
+			ImportTime: info.ImportTime,
+			URL:        fmt.Sprintf("/blobs/%s.aci", info.BlobKey),
+		})
+	}
+
+	out, err := json.MarshalIndent(images, "", "\t")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "JSON encoding error: %v", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(out)
+}
+
+func (is *imageServer) handleBlob(w http.ResponseWriter, r *http.Request) {
+	key, err := is.s.ResolveKey(mux.Vars(r)["id"])
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, err)
+		return
+	}
+	is.serveBlob(w, r, key)
+}
+
+func (is *imageServer) handleACI(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	name, err := types.NewACIdentifier(vars["name"])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "invalid image name %q: %v", vars["name"], err)
+		return
+	}
+
+	// appc discovery uses "latest" when no version is requested.
+	var versionLabels types.Labels
+	if v := vars["version"]; v != "latest" {
+		versionLabels = append(versionLabels, types.Label{Name: "version", Value: v})
+	}
+	labels := append(types.Labels{
+		{Name: "os", Value: vars["os"]},
+		{Name: "arch", Value: vars["arch"]},
+	}, versionLabels...)
+
+	key, err := is.s.GetACI(*name, labels)
+	if _, ok := err.(imagestore.ACINotFoundError); ok {
+		// Some images don't have the os and arch labels the
+		// fetching rkt fills in with its defaults.
+		key, err = is.s.GetACI(*name, versionLabels)
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, err)
+		return
+	}
+	is.serveBlob(w, r, key)
+}
+
+func (is *imageServer) serveBlob(w http.ResponseWriter, r *http.Request, key string) {
+	info, err := is.s.GetACIInfoWithBlobKey(key)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintln(w, err)
+		return
+	}
+
+	etag := strconv.Quote(key)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("max-age=%d", int(blobMaxAge.Seconds())))
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	aci, err := is.s.ReadStream(key)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "error reading image: %v", err)
+		return
+	}
+	defer aci.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == "HEAD" {
+		return
+	}
+	if _, err := io.Copy(w, aci); err != nil {
+		stderr.PrintE(fmt.Sprintf("error sending image %q", key), err)
+	}
+}
+
+func (is *imageServer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(r.URL.Path, "/")
+	if _, err := types.NewACIdentifier(name); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, "invalid image name %q: %v", name, err)
+		return
+	}
+	keys, found, err := is.s.ResolveName(name)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprintf(w, "error looking up image %q: %v", name, err)
+		return
+	}
+	if !found || len(keys) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, "no image named %q", name)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	discoveryTemplate.Execute(w, struct {
+		Name string
+		Host string
+	}{
+		Name: name,
+		Host: r.Host,
+	})
+}
+
+func handleNoSignature(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprintln(w, "signatures are not kept in the store")
+}
@@ -0,0 +1,128 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/rkt/rkt/pkg/aci"
+	"github.com/rkt/rkt/pkg/log"
+	"github.com/rkt/rkt/store/imagestore"
+)
+
+func TestImageServe(t *testing.T) {
+	stderr = log.New(ioutil.Discard, "", false)
+
+	dir, err := ioutil.TempDir("", "image-serve")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := imagestore.NewStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer s.Close()
+
+	imj := `{
+		"acKind": "ImageManifest",
+		"acVersion": "0.8.11",
+		"name": "example.com/app",
+		"labels": [
+			{"name": "version", "value": "1.0.0"},
+			{"name": "os", "value": "linux"},
+			{"name": "arch", "value": "amd64"}
+		]
+	}`
+	a, err := aci.NewACI(dir, imj, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer a.Close()
+	if _, err := a.Seek(0, 0); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	key, err := s.WriteACI(a, imagestore.ACIFetchInfo{Latest: true})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	blob, err := s.ReadStream(key)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	aciBody, err := ioutil.ReadAll(blob)
+	blob.Close()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	ts := httptest.NewServer(newImageServer(s))
+	defer ts.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/blobs/" + key + ".aci", http.StatusOK, string(aciBody)},
+		{"/blobs/" + key[:len("sha512-")+12] + ".aci", http.StatusOK, string(aciBody)},
+		{"/blobs/sha512-0123456789ab.aci", http.StatusNotFound, ""},
+		{"/blobs/" + key + ".aci.asc", http.StatusNotFound, ""},
+		{"/aci/example.com/app/1.0.0/linux/amd64.aci", http.StatusOK, string(aciBody)},
+		{"/aci/example.com/app/latest/linux/amd64.aci", http.StatusOK, string(aciBody)},
+		{"/aci/example.com/app/2.0.0/linux/amd64.aci", http.StatusNotFound, ""},
+		{"/aci/example.com/other/latest/linux/amd64.aci", http.StatusNotFound, ""},
+		{"/example.com/app?ac-discovery=1", http.StatusOK, `content="example.com/app http://` + strings.TrimPrefix(ts.URL, "http://") + `/aci/example.com/app/{version}/{os}/{arch}.{ext}"`},
+		{"/example.com/other?ac-discovery=1", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		res, err := http.Get(ts.URL + tt.path)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.path, err)
+		}
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", tt.path, err)
+		}
+		if res.StatusCode != tt.status {
+			t.Errorf("%s: got status %d, want %d", tt.path, res.StatusCode, tt.status)
+			continue
+		}
+		if tt.status == http.StatusOK && !strings.Contains(string(body), tt.body) {
+			t.Errorf("%s: unexpected body %q", tt.path, body)
+		}
+	}
+
+	res, err := http.Get(ts.URL + "/images")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	defer res.Body.Close()
+	var images []servedImage
+	if err := json.NewDecoder(res.Body).Decode(&images); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(images) != 1 || images[0].ID != key || images[0].Name != "example.com/app" || images[0].Labels["version"] != "1.0.0" {
+		t.Errorf("unexpected images %+v", images)
+	}
+}
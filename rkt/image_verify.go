@@ -18,7 +18,6 @@ import (
 	"os"
 
 	"github.com/hashicorp/errwrap"
-	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 	"github.com/spf13/cobra"
 )
@@ -44,7 +43,7 @@ func runVerifyImage(cmd *cobra.Command, args []string) int {
 		return 254
 	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -0,0 +1,73 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"github.com/rkt/rkt/networking"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdNetwork = &cobra.Command{
+		Use:   "network [command]",
+		Short: "Operate on the host resources of pod networks",
+	}
+	cmdNetworkGC = &cobra.Command{
+		Use:   "gc",
+		Short: "Release the network resources of pods no longer in use",
+		Long: `The interfaces created for the pods which do not exist anymore, like the
+ones removed without tearing down their networks, are removed, and their
+host-local IPAM leases are released.`,
+		Run: ensureSuperuser(runWrapper(runNetworkGC)),
+	}
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdNetwork)
+	cmdNetwork.AddCommand(cmdNetworkGC)
+}
+
+func runNetworkGC(cmd *cobra.Command, args []string) (exit int) {
+	if err := gcIfNames(); err != nil {
+		stderr.PrintE("failed to remove the interfaces of removed pods", err)
+		return 254
+	}
+
+	if err := gcLeases(); err != nil {
+		stderr.PrintE("failed to release the leases of removed pods", err)
+		return 254
+	}
+
+	return
+}
+
+// podExists tells whether a pod exists in the data directory.
+func podExists(uuid string) bool {
+	_, err := pkgPod.PodFromUUIDString(getDataDir(), uuid)
+	return err == nil
+}
+
+// gcIfNames removes the interfaces recorded for the pods which do not
+// exist anymore.
+func gcIfNames() error {
+	removed, err := networking.GCIfNames(getDataDir(), podExists)
+	for _, name := range removed {
+		stderr.Printf("removed interface %s", name)
+	}
+	return err
+}
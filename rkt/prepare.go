@@ -21,12 +21,13 @@ import (
 
 	"github.com/appc/spec/schema/types"
 	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/pkg/exitcode"
 	"github.com/rkt/rkt/pkg/lock"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/pkg/user"
 	"github.com/rkt/rkt/rkt/image"
 	"github.com/rkt/rkt/stage0"
-	"github.com/rkt/rkt/store/imagestore"
+	stage1types "github.com/rkt/rkt/stage1/common/types"
 	"github.com/rkt/rkt/store/treestore"
 	"github.com/spf13/cobra"
 )
@@ -58,6 +59,8 @@ func init() {
 	addStage1ImageFlags(cmdPrepare.Flags())
 	cmdPrepare.Flags().Var(&flagPorts, "port", "ports to expose on the host (requires contained network). Syntax: --port=NAME:HOSTPORT")
 	cmdPrepare.Flags().BoolVar(&flagQuiet, "quiet", false, "suppress superfluous output on stdout, print only the UUID on success")
+	cmdPrepare.Flags().StringVar(&flagPodName, "pod-name", "", "unique name of the pod, usable in place of its UUID")
+	cmdPrepare.Flags().StringVar(&flagStorageLimit, "storage-limit", "", "disk usage limit of the pod writable layers, enforced with a project quota (example: '--storage-limit=10G')")
 	cmdPrepare.Flags().BoolVar(&flagInheritEnv, "inherit-env", false, "inherit all environment variables not set by apps")
 	cmdPrepare.Flags().BoolVar(&flagNoOverlay, "no-overlay", false, "disable overlay filesystem")
 	cmdPrepare.Flags().BoolVar(&flagPrivateUsers, "private-users", false, "run within user namespaces.")
@@ -67,7 +70,7 @@ func init() {
 	cmdPrepare.Flags().MarkDeprecated("store-only", "please use --pull-policy=never")
 	cmdPrepare.Flags().BoolVar(&flagNoStore, "no-store", false, "fetch images ignoring the local store")
 	cmdPrepare.Flags().MarkDeprecated("no-store", "please use --pull-policy=update")
-	cmdPrepare.Flags().StringVar(&flagPullPolicy, "pull-policy", image.PullPolicyNew, "when to pull an image")
+	cmdPrepare.Flags().StringVar(&flagPullPolicy, "pull-policy", image.PullPolicyNew, "when to pull an image: never, if-not-present (alias new), update or always")
 	cmdPrepare.Flags().StringVar(&flagPodManifest, "pod-manifest", "", "the path to the pod manifest. If it's non-empty, then only '--quiet' and '--no-overlay' will have effect")
 	cmdPrepare.Flags().Var((*appsVolume)(&rktApps), "volume", "volumes to make available in the pod")
 
@@ -75,6 +78,7 @@ func init() {
 	cmdPrepare.Flags().Var((*appAsc)(&rktApps), "signature", "local signature file to use in validating the preceding image")
 	addAppFlags(cmdPrepare)
 	addIsolatorFlags(cmdPrepare, true)
+	addSecurityProfileFlag(cmdPrepare)
 
 	// Disable interspersed flags to stop parsing after the first non flag
 	// argument. This is need to permit to correctly handle
@@ -103,6 +107,22 @@ func runPrepare(cmd *cobra.Command, args []string) (exit int) {
 	if flagNoStore {
 		flagPullPolicy = image.PullPolicyUpdate
 	}
+	if err := image.ValidatePullPolicy(flagPullPolicy); err != nil {
+		stderr.Error(err)
+		return 254
+	}
+	if flagPodName != "" {
+		if err := pkgPod.ValidateName(flagPodName); err != nil {
+			stderr.Error(err)
+			return 254
+		}
+	}
+	if flagStorageLimit != "" {
+		if _, err := stage1types.ParseStorageLimit(flagStorageLimit); err != nil {
+			stderr.Error(err)
+			return 254
+		}
+	}
 
 	if flagPrivateUsers {
 		if !common.SupportsUserNS() {
@@ -119,19 +139,27 @@ func runPrepare(cmd *cobra.Command, args []string) (exit int) {
 
 	if len(flagPodManifest) > 0 && (rktApps.Count() > 0 ||
 		(*appsVolume)(&rktApps).String() != "" || (*appMount)(&rktApps).String() != "" ||
-		len(flagPorts) > 0 || flagPullPolicy == image.PullPolicyNever ||
-		flagPullPolicy == image.PullPolicyUpdate || flagInheritEnv ||
-		!flagExplicitEnv.IsEmpty() || !flagEnvFromFile.IsEmpty()) {
+		len(flagPorts) > 0 || (flagPullPolicy != image.PullPolicyNew &&
+		flagPullPolicy != image.PullPolicyIfNotPresent) || flagInheritEnv ||
+		!flagExplicitEnv.IsEmpty() || !flagEnvFromFile.IsEmpty() ||
+		cmd.Flags().Changed("security-profile")) {
 		stderr.Print("conflicting flags set with --pod-manifest (see --help)")
 		return 254
 	}
 
+	if len(flagPodManifest) == 0 {
+		if err := applySecurityProfile(&rktApps); err != nil {
+			stderr.PrintE("cannot apply the security profile", err)
+			return 254
+		}
+	}
+
 	if rktApps.Count() < 1 && len(flagPodManifest) == 0 {
 		stderr.Print("must provide at least one image or specify the pod manifest")
 		return 254
 	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -160,17 +188,20 @@ func runPrepare(cmd *cobra.Command, args []string) (exit int) {
 		Ts:                 ts,
 		Ks:                 getKeystore(),
 		Headers:            config.AuthPerHost,
+		TLSPerHost:         config.TLSPerHost,
+		P2PPerHost:         config.P2PPerHost,
 		DockerAuth:         config.DockerCredentialsPerRegistry,
 		InsecureFlags:      globalFlags.InsecureFlags,
 		Debug:              globalFlags.Debug,
 		TrustKeysFromHTTPS: globalFlags.TrustKeysFromHTTPS,
+		Offline:            globalFlags.Offline,
 
 		PullPolicy: flagPullPolicy,
 		WithDeps:   true,
 	}
 	if err := fn.FindImages(&rktApps); err != nil {
 		stderr.PrintE("error finding images", err)
-		return 254
+		return exitcode.FromError(err)
 	}
 
 	p, err := pkgPod.NewPod(getDataDir())
@@ -179,6 +210,13 @@ func runPrepare(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
+	if flagPodName != "" {
+		if err := p.SetName(flagPodName); err != nil {
+			stderr.PrintE("error naming the pod", err)
+			return 254
+		}
+	}
+
 	cfg := stage0.CommonConfig{
 		DataDir:     getDataDir(),
 		Store:       s,
@@ -231,11 +269,13 @@ func runPrepare(cmd *cobra.Command, args []string) (exit int) {
 	}
 	keyLock.Close()
 
-	if err := p.Sync(); err != nil {
-		stderr.PrintE("error syncing pod data", err)
+	if err := setPodStorageLimit(p); err != nil {
+		stderr.PrintE("error limiting the pod storage", err)
 		return 254
 	}
 
+	auditPod(p)
+
 	if err := p.ToPrepared(); err != nil {
 		stderr.PrintE("error transitioning to prepared", err)
 		return 254
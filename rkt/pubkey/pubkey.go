@@ -56,7 +56,7 @@ const (
 
 var (
 	log    *rktlog.Logger
-	stdout *rktlog.Logger = rktlog.New(os.Stdout, "", false)
+	stdout *rktlog.Logger = rktlog.NewOutput(os.Stdout)
 
 	secureClient   = newClient(false)
 	insecureClient = newClient(true)
@@ -0,0 +1,88 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rkt/rkt/api/v1alpha"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	cmdImage = &cobra.Command{
+		Use:   "image [command]",
+		Short: "Operate on the images in the store of the host",
+	}
+	cmdImageList = &cobra.Command{
+		Use:   "list",
+		Short: "List the images in the store of the host",
+		Run:   runWrapper(withClient(runImageList)),
+	}
+)
+
+func init() {
+	cmdRemote.AddCommand(cmdImage)
+	cmdImage.AddCommand(cmdImageList)
+
+	cmdImageList.Flags().BoolVar(&flagNoLegend, "no-legend", false, "suppress a legend with the list")
+	cmdImageList.Flags().BoolVar(&flagFullOutput, "full", false, "use long output format")
+}
+
+func runImageList(c v1alpha.PublicAPIClient, cmd *cobra.Command, args []string) int {
+	resp, err := c.ListImages(context.Background(), &v1alpha.ListImagesRequest{})
+	if err != nil {
+		stderr.PrintE("unable to list images", err)
+		return 254
+	}
+
+	tabBuffer := new(bytes.Buffer)
+	tabOut := getTabOutWithWriter(tabBuffer)
+	if !flagNoLegend {
+		fmt.Fprintf(tabOut, "ID\tNAME\tSIZE\tIMPORT TIME\n")
+	}
+	for _, img := range resp.Images {
+		id := img.Id
+		if !flagFullOutput {
+			id = trimImageID(id)
+		}
+		name := img.Name
+		if img.Version != "" {
+			name = fmt.Sprintf("%s:%s", name, img.Version)
+		}
+		imported := humanize.Time(time.Unix(img.ImportTimestamp, 0))
+		fmt.Fprintf(tabOut, "%s\t%s\t%s\t%s\n", id, name, humanize.IBytes(uint64(img.Size)), imported)
+	}
+	tabOut.Flush()
+	stdout.Print(tabBuffer)
+	return 0
+}
+
+// trimImageID returns the short form of the image ID printed by rkt.
+func trimImageID(imageID string) string {
+	// The short hash form is [HASH_ALGO]-[FIRST 12 CHAR]
+	// For example, sha512-123456789012
+	pos := strings.Index(imageID, "-")
+	trimLength := pos + 13
+	if pos > 0 && trimLength < len(imageID) {
+		imageID = imageID[:trimLength]
+	}
+	return imageID
+}
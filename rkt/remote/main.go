@@ -0,0 +1,217 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// rkt-remote is a thin client of the rkt API service. It only depends on the
+// public API, so it builds on every platform supported by Go and lets
+// operators inspect rkt hosts from non-Linux workstations. Pods are still
+// executed by rkt on the Linux host.
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/api/v1alpha"
+	"github.com/rkt/rkt/pkg/log"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+const (
+	// defaultEndpoint is the default address of rkt api-service.
+	defaultEndpoint = "localhost:15441"
+
+	defaultDialTimeout = 10 * time.Second
+)
+
+var (
+	cmdRemote = &cobra.Command{
+		Use:   "rkt-remote [command]",
+		Short: "Inspect a rkt host through its API service",
+		Long: `A thin client of the rkt API service, started on the host with
+"rkt api-service". It talks gRPC over TLS to the address given with
+--endpoint, verifying the server certificate with --tls-ca or the system
+roots, and authenticates with --tls-cert and --tls-key when the service
+requires client certificates.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Usage()
+			cmdExitCode = 254
+		},
+	}
+
+	flagEndpoint  string
+	flagTLSCA     string
+	flagTLSCert   string
+	flagTLSKey    string
+	flagTLSServer string
+	flagPlaintext bool
+	flagTimeout   time.Duration
+	flagDebug     bool
+
+	stderr *log.Logger
+	stdout *log.Logger
+
+	cmdExitCode int
+)
+
+func init() {
+	cmdRemote.PersistentFlags().StringVar(&flagEndpoint, "endpoint", defaultEndpoint, "address of the rkt API service")
+	cmdRemote.PersistentFlags().StringVar(&flagTLSCA, "tls-ca", "", "PEM encoded CA bundle to verify the API service certificate, instead of the system roots")
+	cmdRemote.PersistentFlags().StringVar(&flagTLSCert, "tls-cert", "", "PEM encoded client certificate")
+	cmdRemote.PersistentFlags().StringVar(&flagTLSKey, "tls-key", "", "PEM encoded private key of the client certificate")
+	cmdRemote.PersistentFlags().StringVar(&flagTLSServer, "tls-server-name", "", "name to verify in the API service certificate, instead of the endpoint host")
+	cmdRemote.PersistentFlags().BoolVar(&flagPlaintext, "plaintext", false, "talk to the API service without TLS, e.g. through an SSH tunnel")
+	cmdRemote.PersistentFlags().DurationVar(&flagTimeout, "timeout", defaultDialTimeout, "timeout to connect to the API service")
+	cmdRemote.PersistentFlags().BoolVar(&flagDebug, "debug", false, "print out more debug information to stderr")
+
+	cmdRemote.PersistentPreRun = func(cmd *cobra.Command, args []string) {
+		stderr = log.New(os.Stderr, cmd.Name(), flagDebug)
+		stdout = log.NewOutput(os.Stdout)
+	}
+
+	cobra.EnablePrefixMatching = true
+}
+
+func main() {
+	if err := cmdRemote.Execute(); err != nil && cmdExitCode == 0 {
+		// err already printed by cobra on stderr
+		cmdExitCode = 254
+	}
+	os.Exit(cmdExitCode)
+}
+
+// runWrapper adapts a command handler returning an exit code to cobra.
+func runWrapper(cf func(cmd *cobra.Command, args []string) (exit int)) func(cmd *cobra.Command, args []string) {
+	return func(cmd *cobra.Command, args []string) {
+		cmdExitCode = cf(cmd, args)
+	}
+}
+
+// clientTLSConfig returns the TLS configuration used to connect to the API
+// service.
+func clientTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		ServerName: flagTLSServer,
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if flagTLSCA != "" {
+		pem, err := ioutil.ReadFile(flagTLSCA)
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("error reading the CA bundle"), err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate found in %q", flagTLSCA)
+		}
+		config.RootCAs = pool
+	}
+
+	switch {
+	case flagTLSCert != "" && flagTLSKey != "":
+		cert, err := tls.LoadX509KeyPair(flagTLSCert, flagTLSKey)
+		if err != nil {
+			return nil, errwrap.Wrap(errors.New("error loading the client certificate"), err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	case flagTLSCert != "" || flagTLSKey != "":
+		return nil, errors.New("both --tls-cert and --tls-key must be given")
+	}
+
+	return config, nil
+}
+
+// dialAPIService connects to the API service. The returned connection must be
+// closed by the caller.
+func dialAPIService() (*grpc.ClientConn, v1alpha.PublicAPIClient, error) {
+	opts := []grpc.DialOption{grpc.WithBlock(), grpc.WithTimeout(flagTimeout)}
+	if flagPlaintext {
+		if flagTLSCA != "" || flagTLSCert != "" || flagTLSKey != "" {
+			return nil, nil, errors.New("TLS options can't be used with --plaintext")
+		}
+		opts = append(opts, grpc.WithInsecure())
+	} else {
+		config, err := clientTLSConfig()
+		if err != nil {
+			return nil, nil, err
+		}
+		opts = append(opts, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	}
+
+	if flagDebug {
+		stderr.Printf("connecting to %s", flagEndpoint)
+	}
+	conn, err := grpc.Dial(flagEndpoint, opts...)
+	if err != nil {
+		return nil, nil, errwrap.Wrap(fmt.Errorf("unable to connect to %s", flagEndpoint), err)
+	}
+	return conn, v1alpha.NewPublicAPIClient(conn), nil
+}
+
+// withClient runs f with a client connected to the API service.
+func withClient(f func(c v1alpha.PublicAPIClient, cmd *cobra.Command, args []string) int) func(cmd *cobra.Command, args []string) int {
+	return func(cmd *cobra.Command, args []string) int {
+		conn, c, err := dialAPIService()
+		if err != nil {
+			stderr.Error(err)
+			return 254
+		}
+		defer conn.Close()
+		return f(c, cmd, args)
+	}
+}
+
+func getTabOutWithWriter(writer io.Writer) *tabwriter.Writer {
+	aTabOut := new(tabwriter.Writer)
+
+	aTabOut.Init(writer, 0, 8, 1, '\t', 0)
+
+	return aTabOut
+}
+
+var cmdInfo = &cobra.Command{
+	Use:   "info",
+	Short: "Print the versions of rkt and of the API on the host",
+	Run:   runWrapper(withClient(runInfo)),
+}
+
+func init() {
+	cmdRemote.AddCommand(cmdInfo)
+}
+
+func runInfo(c v1alpha.PublicAPIClient, cmd *cobra.Command, args []string) int {
+	resp, err := c.GetInfo(context.Background(), &v1alpha.GetInfoRequest{})
+	if err != nil {
+		stderr.PrintE("unable to get info", err)
+		return 254
+	}
+
+	stdout.Printf("rkt Version: %s", resp.Info.RktVersion)
+	stdout.Printf("appc Version: %s", resp.Info.AppcVersion)
+	stdout.Printf("API Version: %s", resp.Info.ApiVersion)
+	if gf := resp.Info.GlobalFlags; gf != nil {
+		stdout.Printf("Data Directory: %s", gf.Dir)
+	}
+	return 0
+}
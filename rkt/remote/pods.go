@@ -0,0 +1,212 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/rkt/rkt/api/v1alpha"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/context"
+)
+
+var (
+	cmdList = &cobra.Command{
+		Use:   "list",
+		Short: "List the pods of the host",
+		Run:   runWrapper(withClient(runList)),
+	}
+	cmdStatus = &cobra.Command{
+		Use:   "status UUID",
+		Short: "Check the status of a pod",
+		Run:   runWrapper(withClient(runStatus)),
+	}
+	cmdLogs = &cobra.Command{
+		Use:   "logs [--app=APPNAME] [--lines=N] [--follow] UUID",
+		Short: "Print the logs of a pod",
+		Run:   runWrapper(withClient(runLogs)),
+	}
+
+	flagNoLegend   bool
+	flagFullOutput bool
+	flagLogsApp    string
+	flagLogsLines  int32
+	flagLogsFollow bool
+)
+
+func init() {
+	cmdRemote.AddCommand(cmdList)
+	cmdRemote.AddCommand(cmdStatus)
+	cmdRemote.AddCommand(cmdLogs)
+
+	cmdList.Flags().BoolVar(&flagNoLegend, "no-legend", false, "suppress a legend with the list")
+	cmdList.Flags().BoolVar(&flagFullOutput, "full", false, "use long output format")
+	cmdLogs.Flags().StringVar(&flagLogsApp, "app", "", "name of the app whose logs are printed, all the apps if empty")
+	cmdLogs.Flags().Int32Var(&flagLogsLines, "lines", 0, "number of most recent lines to print, all the lines if 0")
+	cmdLogs.Flags().BoolVar(&flagLogsFollow, "follow", false, "keep printing the new lines")
+}
+
+func runList(c v1alpha.PublicAPIClient, cmd *cobra.Command, args []string) int {
+	resp, err := c.ListPods(context.Background(), &v1alpha.ListPodsRequest{Detail: true})
+	if err != nil {
+		stderr.PrintE("unable to list pods", err)
+		return 254
+	}
+
+	tabBuffer := new(bytes.Buffer)
+	tabOut := getTabOutWithWriter(tabBuffer)
+	if !flagNoLegend {
+		fmt.Fprintf(tabOut, "UUID\tAPPS\tSTATE\tCREATED\tNETWORKS\n")
+	}
+	for _, p := range resp.Pods {
+		id := p.Id
+		if !flagFullOutput && len(id) > 8 {
+			id = id[:8]
+		}
+		var apps, nets []string
+		for _, a := range p.Apps {
+			apps = append(apps, a.Name)
+		}
+		for _, n := range p.Networks {
+			nets = append(nets, fmt.Sprintf("%s:ip4=%s", n.Name, n.Ipv4))
+		}
+		fmt.Fprintf(tabOut, "%s\t%s\t%s\t%s\t%s\n", id, strings.Join(apps, ","), podStateString(p.State), formatTimestamp(p.CreatedAt), strings.Join(nets, ","))
+	}
+	tabOut.Flush()
+	stdout.Print(tabBuffer)
+	return 0
+}
+
+func runStatus(c v1alpha.PublicAPIClient, cmd *cobra.Command, args []string) int {
+	if len(args) != 1 {
+		cmd.Usage()
+		return 254
+	}
+
+	id, err := resolvePodID(c, args[0])
+	if err != nil {
+		stderr.Error(err)
+		return 254
+	}
+	resp, err := c.InspectPod(context.Background(), &v1alpha.InspectPodRequest{Id: id})
+	if err != nil {
+		stderr.PrintE("unable to inspect pod", err)
+		return 254
+	}
+
+	p := resp.Pod
+	stdout.Printf("state=%s", podStateString(p.State))
+	stdout.Printf("created=%s", formatTimestamp(p.CreatedAt))
+	stdout.Printf("started=%s", formatTimestamp(p.StartedAt))
+	if p.Pid > 0 {
+		stdout.Printf("pid=%d", p.Pid)
+	}
+	for _, n := range p.Networks {
+		stdout.Printf("network %s: ip4=%s ip6=%s", n.Name, n.Ipv4, n.Ipv6)
+	}
+	for _, a := range p.Apps {
+		if a.State == v1alpha.AppState_APP_STATE_EXITED {
+			stdout.Printf("app-%s=%d", a.Name, a.ExitCode)
+		}
+	}
+	return 0
+}
+
+func runLogs(c v1alpha.PublicAPIClient, cmd *cobra.Command, args []string) int {
+	if len(args) != 1 {
+		cmd.Usage()
+		return 254
+	}
+
+	id, err := resolvePodID(c, args[0])
+	if err != nil {
+		stderr.Error(err)
+		return 254
+	}
+	stream, err := c.GetLogs(context.Background(), &v1alpha.GetLogsRequest{
+		PodId:   id,
+		AppName: flagLogsApp,
+		Lines:   flagLogsLines,
+		Follow:  flagLogsFollow,
+	})
+	if err != nil {
+		stderr.PrintE("unable to get logs", err)
+		return 254
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return 0
+		}
+		if err != nil {
+			stderr.PrintE("unable to get logs", err)
+			return 254
+		}
+		for _, l := range resp.Lines {
+			stdout.Print(l)
+		}
+	}
+}
+
+// resolvePodID returns the full UUID of the only pod whose UUID starts with
+// prefix.
+func resolvePodID(c v1alpha.PublicAPIClient, prefix string) (string, error) {
+	resp, err := c.ListPods(context.Background(), &v1alpha.ListPodsRequest{})
+	if err != nil {
+		return "", err
+	}
+	return matchPodID(resp.Pods, prefix)
+}
+
+// matchPodID returns the UUID of the only pod of pods whose UUID starts with
+// prefix.
+func matchPodID(pods []*v1alpha.Pod, prefix string) (string, error) {
+	var matches []string
+	for _, p := range pods {
+		if p.Id == prefix {
+			return p.Id, nil
+		}
+		if strings.HasPrefix(p.Id, prefix) {
+			matches = append(matches, p.Id)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no pod matches %q", prefix)
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("UUID prefix %q is ambiguous", prefix)
+}
+
+// podStateString returns the state as printed by rkt, e.g. "aborted prepare".
+func podStateString(s v1alpha.PodState) string {
+	name := strings.TrimPrefix(s.String(), "POD_STATE_")
+	return strings.Replace(strings.ToLower(name), "_", " ", -1)
+}
+
+// formatTimestamp formats a timestamp in nanoseconds since epoch, the zero
+// value meaning the event didn't happen yet.
+func formatTimestamp(ns int64) string {
+	if ns == 0 {
+		return "-"
+	}
+	return time.Unix(0, ns).Format("2006-01-02 15:04:05.000 -0700 MST")
+}
@@ -0,0 +1,69 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/rkt/rkt/api/v1alpha"
+)
+
+func TestMatchPodID(t *testing.T) {
+	pods := []*v1alpha.Pod{
+		{Id: "6ac2b4c6-6d8d-4e6e-9b3a-5a2e1e1e1e1e"},
+		{Id: "6ac2f3a1-1b2c-4d5e-8f90-123456789abc"},
+		{Id: "9f1e2d3c-aaaa-bbbb-cccc-dddddddddddd"},
+	}
+
+	tests := []struct {
+		prefix string
+		want   string
+		err    bool
+	}{
+		{"9f1e", "9f1e2d3c-aaaa-bbbb-cccc-dddddddddddd", false},
+		{"6ac2f", "6ac2f3a1-1b2c-4d5e-8f90-123456789abc", false},
+		{"6ac2b4c6-6d8d-4e6e-9b3a-5a2e1e1e1e1e", "6ac2b4c6-6d8d-4e6e-9b3a-5a2e1e1e1e1e", false},
+		// ambiguous
+		{"6ac2", "", true},
+		// unknown
+		{"0000", "", true},
+	}
+
+	for i, tt := range tests {
+		got, err := matchPodID(pods, tt.prefix)
+		if (err != nil) != tt.err {
+			t.Errorf("#%d: got error %v, want error: %t", i, err, tt.err)
+		}
+		if got != tt.want {
+			t.Errorf("#%d: got %q, want %q", i, got, tt.want)
+		}
+	}
+}
+
+func TestPodStateString(t *testing.T) {
+	tests := []struct {
+		state v1alpha.PodState
+		want  string
+	}{
+		{v1alpha.PodState_POD_STATE_RUNNING, "running"},
+		{v1alpha.PodState_POD_STATE_ABORTED_PREPARE, "aborted prepare"},
+	}
+
+	for _, tt := range tests {
+		if got := podStateString(tt.state); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -22,11 +23,16 @@ import (
 	"runtime/pprof"
 	"text/tabwriter"
 
+	"github.com/hashicorp/errwrap"
 	"github.com/rkt/rkt/common"
+	dist "github.com/rkt/rkt/pkg/distribution"
+	"github.com/rkt/rkt/pkg/erase"
+	"github.com/rkt/rkt/pkg/exitcode"
 	"github.com/rkt/rkt/pkg/keystore"
 	"github.com/rkt/rkt/pkg/log"
 	"github.com/rkt/rkt/rkt/config"
 	rktflag "github.com/rkt/rkt/rkt/flag"
+	"github.com/rkt/rkt/store/imagestore"
 	"github.com/spf13/cobra"
 )
 
@@ -62,6 +68,46 @@ func (d *absDir) Type() string {
 	return "absolute-directory"
 }
 
+// logFormat is the value of the --log-format flag. It is exported to the
+// environment, so the processes started by rkt, like stage1, use it too.
+type logFormat string
+
+func (f *logFormat) Set(s string) error {
+	if err := log.SetFormat(s); err != nil {
+		return err
+	}
+	*f = logFormat(s)
+	return os.Setenv(log.EnvFormat, s)
+}
+
+func (f *logFormat) String() string {
+	return string(*f)
+}
+
+func (f *logFormat) Type() string {
+	return "format"
+}
+
+// logLevels is the value of the --log-level flag. Like logFormat, it is
+// exported to the environment.
+type logLevels string
+
+func (l *logLevels) Set(s string) error {
+	if err := log.SetLevels(s); err != nil {
+		return err
+	}
+	*l = logLevels(s)
+	return os.Setenv(log.EnvLevels, s)
+}
+
+func (l *logLevels) String() string {
+	return string(*l)
+}
+
+func (l *logLevels) Type() string {
+	return "levels"
+}
+
 var (
 	tabOut      *tabwriter.Writer
 	globalFlags = struct {
@@ -70,9 +116,12 @@ var (
 		LocalConfigDir     string
 		UserConfigDir      string
 		Debug              bool
+		LogFormat          logFormat
+		LogLevels          logLevels
 		Help               bool
 		InsecureFlags      *rktflag.SecFlags
 		TrustKeysFromHTTPS bool
+		Offline            bool
 
 		// Hidden flags for profiling.
 		CPUProfile string
@@ -81,6 +130,7 @@ var (
 		Dir:             defaultDataDir,
 		SystemConfigDir: common.DefaultSystemConfigDir,
 		LocalConfigDir:  common.DefaultLocalConfigDir,
+		LogFormat:       logFormat(log.FormatText),
 	}
 
 	cachedConfig  *config.Config
@@ -111,6 +161,8 @@ func init() {
 	globalFlags.InsecureFlags = sf
 
 	cmdRkt.PersistentFlags().BoolVar(&globalFlags.Debug, "debug", false, "print out more debug information to stderr")
+	cmdRkt.PersistentFlags().Var(&globalFlags.LogFormat, "log-format", "format of the log messages. Allowed values: text, json")
+	cmdRkt.PersistentFlags().Var(&globalFlags.LogLevels, "log-level", "comma-separated minimum levels of the log messages, for all the subsystems or per subsystem, e.g. info,networking=debug")
 	cmdRkt.PersistentFlags().Var((*absDir)(&globalFlags.Dir), "dir", "rkt data directory")
 	cmdRkt.PersistentFlags().Var((*absDir)(&globalFlags.SystemConfigDir), "system-config", "system configuration directory")
 	cmdRkt.PersistentFlags().Var((*absDir)(&globalFlags.LocalConfigDir), "local-config", "local configuration directory")
@@ -120,6 +172,8 @@ func init() {
 			globalFlags.InsecureFlags.PermissibleString()))
 	cmdRkt.PersistentFlags().BoolVar(&globalFlags.TrustKeysFromHTTPS, "trust-keys-from-https",
 		false, "automatically trust gpg keys fetched from https")
+	cmdRkt.PersistentFlags().BoolVar(&globalFlags.Offline, "offline",
+		false, "forbid any network access when finding and fetching images, only use the store and local files")
 	cmdRkt.PersistentFlags().StringVar(&globalFlags.CPUProfile, "cpuprofile", "", "write CPU profile to the file")
 	cmdRkt.PersistentFlags().MarkHidden("cpuprofile")
 	cmdRkt.PersistentFlags().StringVar(&globalFlags.MemProfile, "memprofile", "", "write memory profile to the file")
@@ -128,7 +182,7 @@ func init() {
 	// Run this before the execution of each subcommand to set up output
 	cmdRkt.PersistentPreRun = func(cmd *cobra.Command, args []string) {
 		stderr = log.New(os.Stderr, cmd.Name(), globalFlags.Debug)
-		stdout = log.New(os.Stdout, "", false)
+		stdout = log.NewOutput(os.Stdout)
 	}
 
 	cobra.EnablePrefixMatching = true
@@ -183,7 +237,11 @@ func runWrapper(cf func(cmd *cobra.Command, args []string) (exit int)) func(cmd
 		}
 		defer stopProfile(cpufile, memfile)
 
+		startAudit(cmd)
 		cmdExitCode = cf(cmd, args)
+		if err := logAudit(cmdExitCode); err != nil {
+			stderr.PrintE("cannot write the audit record", err)
+		}
 	}
 }
 
@@ -193,7 +251,7 @@ func ensureSuperuser(cf func(cmd *cobra.Command, args []string)) func(cmd *cobra
 	return func(cmd *cobra.Command, args []string) {
 		if os.Geteuid() != 0 {
 			stderr.Print("cannot run as unprivileged user")
-			cmdExitCode = 254
+			cmdExitCode = exitcode.InsufficientPrivileges
 			return
 		}
 
@@ -204,7 +262,7 @@ func ensureSuperuser(cf func(cmd *cobra.Command, args []string)) func(cmd *cobra
 func runMissingCommand(cmd *cobra.Command, args []string) {
 	stderr.Print("missing command")
 	cmd.HelpFunc()(cmd, args)
-	cmdExitCode = 2 // invalid argument
+	cmdExitCode = exitcode.InvalidArgument
 }
 
 // where pod directories are created and locked before moving to prepared
@@ -241,6 +299,38 @@ func storeDir() string {
 	return filepath.Join(getDataDir(), "cas")
 }
 
+// openImageStore opens the image store, encrypting the images with the
+// key of the configuration, if any.
+func openImageStore() (*imagestore.Store, error) {
+	s, err := imagestore.NewStore(storeDir())
+	if err != nil {
+		return nil, err
+	}
+	config, err := getConfig()
+	if err != nil {
+		s.Close()
+		return nil, errwrap.Wrap(errors.New("cannot get configuration"), err)
+	}
+	mode, err := getEraseMode()
+	if err != nil {
+		s.Close()
+		return nil, errwrap.Wrap(errors.New("invalid erase mode"), err)
+	}
+	s.SetScrubOnRemove(mode == erase.Shred)
+	if !config.StoreEncryption.Enabled() {
+		return s, nil
+	}
+	key, err := config.StoreEncryption.Key()
+	if err == nil {
+		err = s.SetEncryptionKey(key)
+	}
+	if err != nil {
+		s.Close()
+		return nil, errwrap.Wrap(errors.New("cannot set up the encryption of the store"), err)
+	}
+	return s, nil
+}
+
 // TODO(sgotti) backward compatibility with the current tree store paths. Needs a migration path to better paths.
 func treeStoreDir() string {
 	return filepath.Join(getDataDir(), "cas")
@@ -325,6 +415,12 @@ func getConfig() (*config.Config, error) {
 		return nil, err
 	}
 
+	for typ, command := range cfg.DistributionPlugins {
+		if err := dist.RegisterPlugin(dist.Type(typ), command); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("cannot register the plugin of distribution type %q", typ), err)
+		}
+	}
+
 	cachedConfig = cfg
 
 	return cfg, nil
@@ -17,33 +17,185 @@
 package main
 
 import (
+	"bytes"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/appc/spec/schema"
+	"github.com/dustin/go-humanize"
+	"github.com/rkt/rkt/pkg/exitcode"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/spf13/cobra"
 )
 
 var (
 	cmdRm = &cobra.Command{
-		Use:   "rm --uuid-file=FILE | UUID ...",
+		Use:   "rm --uuid-file=FILE | --filter=FILTER ... | --all-exited | UUID ...",
 		Short: "Remove all files and resources associated with an exited pod",
-		Long:  `Unlike gc, rm allows users to remove specific pods.`,
-		Run:   ensureSuperuser(runWrapper(runRm)),
+		Long: `Unlike gc, rm allows users to remove specific pods.
+
+The pods can be selected by UUID, or with filters matching all the pods which
+are not running. Filters are ANDed and have the form:
+	state=exited|prepared|aborted-prepare|garbage
+	label=NAME=VALUE, a user label of the pod or of one of its apps
+	older-than=DURATION, like 24h, since the creation of the pod`,
+		Run: ensureSuperuser(runWrapper(runRm)),
 	}
-	flagUUIDFile string
+	flagUUIDFile  string
+	flagRmFilters []string
+	flagAllExited bool
+	flagRmDryRun  bool
 )
 
+// rmStates maps the states of the state filter to the states of the pods
+// they match.
+var rmStates = map[string][]string{
+	"exited":          {pkgPod.Exited, pkgPod.ExitedGarbage},
+	"prepared":        {pkgPod.Prepared},
+	"aborted-prepare": {pkgPod.AbortedPrepare},
+	"garbage":         {pkgPod.Garbage, pkgPod.ExitedGarbage},
+}
+
+// podFilter selects the pods to remove.
+type podFilter struct {
+	// states are the accepted states, any removable one if empty
+	states    map[string]bool
+	labels    map[string]string
+	olderThan time.Duration
+}
+
 func init() {
 	cmdRkt.AddCommand(cmdRm)
 	cmdRm.Flags().StringVar(&flagUUIDFile, "uuid-file", "", "read pod UUID from file instead of argument")
+	cmdRm.Flags().StringSliceVar(&flagRmFilters, "filter", nil, "remove the pods matching the filter, like 'state=exited', 'label=app=web' or 'older-than=24h'")
+	cmdRm.Flags().BoolVar(&flagAllExited, "all-exited", false, "remove all the exited pods, same as --filter=state=exited")
+	addSecureEraseFlag(cmdRm.Flags())
+	cmdRm.Flags().BoolVar(&flagRmDryRun, "dry-run", false, "list the pods which would be removed, without removing them")
+}
+
+// parseRmFilters parses the --filter flags into a podFilter.
+func parseRmFilters(filters []string) (*podFilter, error) {
+	f := &podFilter{
+		states: make(map[string]bool),
+		labels: make(map[string]string),
+	}
+	for _, filter := range filters {
+		kv := strings.SplitN(filter, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("invalid filter %q, expected NAME=VALUE", filter)
+		}
+		switch kv[0] {
+		case "state":
+			states, ok := rmStates[kv[1]]
+			if !ok {
+				return nil, fmt.Errorf("unknown state %q, expected exited, prepared, aborted-prepare or garbage", kv[1])
+			}
+			for _, s := range states {
+				f.states[s] = true
+			}
+		case "label":
+			label := strings.SplitN(kv[1], "=", 2)
+			if len(label) != 2 {
+				return nil, fmt.Errorf("invalid label filter %q, expected label=NAME=VALUE", filter)
+			}
+			f.labels[label[0]] = label[1]
+		case "older-than":
+			d, err := time.ParseDuration(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid duration %q", kv[1])
+			}
+			f.olderThan = d
+		default:
+			return nil, fmt.Errorf("unknown filter %q", kv[0])
+		}
+	}
+	return f, nil
+}
+
+// match returns whether the pod passes the filter. The running pods and the
+// ones being prepared or deleted never do.
+func (f *podFilter) match(p *pkgPod.Pod) bool {
+	state := p.State()
+	if len(f.states) > 0 {
+		if !f.states[state] {
+			return false
+		}
+	} else if !removableState(state) {
+		return false
+	}
+
+	if f.olderThan > 0 {
+		created, err := p.CreationTime()
+		if err != nil || time.Since(created) < f.olderThan {
+			return false
+		}
+	}
+
+	if len(f.labels) > 0 {
+		_, pm, err := p.PodManifest()
+		if err != nil {
+			return false
+		}
+		for name, value := range f.labels {
+			if !podHasLabel(pm, name, value) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func removableState(state string) bool {
+	for _, states := range rmStates {
+		for _, s := range states {
+			if s == state {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// podHasLabel returns whether the pod or one of its apps has the user label.
+func podHasLabel(pm *schema.PodManifest, name, value string) bool {
+	if v, ok := pm.UserLabels[name]; ok && v == value {
+		return true
+	}
+	for _, ra := range pm.Apps {
+		if ra.App == nil {
+			continue
+		}
+		if v, ok := ra.App.UserLabels[name]; ok && v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// filterPods returns the UUIDs of the pods passing the filter.
+func filterPods(f *podFilter) ([]string, error) {
+	var uuids []string
+	err := pkgPod.WalkPods(getDataDir(), pkgPod.IncludeMostDirs|pkgPod.IncludeGarbageDir, func(p *pkgPod.Pod) {
+		if f.match(p) {
+			uuids = append(uuids, p.UUID.String())
+		}
+	})
+	return uuids, err
 }
 
 func runRm(cmd *cobra.Command, args []string) (exit int) {
 	var podUUIDs []string
 	var ret int
 
+	filters := flagRmFilters
+	if flagAllExited {
+		filters = append(filters, "state=exited")
+	}
+
 	switch {
-	case len(args) == 0 && flagUUIDFile != "":
+	case len(args) == 0 && flagUUIDFile != "" && len(filters) == 0:
 		podUUID, err := pkgPod.ReadUUIDFromFile(flagUUIDFile)
 		if err != nil {
 			stderr.PrintE("unable to resolve UUID from file", err)
@@ -52,18 +204,40 @@ func runRm(cmd *cobra.Command, args []string) (exit int) {
 			podUUIDs = append(podUUIDs, podUUID)
 		}
 
-	case len(args) > 0 && flagUUIDFile == "":
+	case len(args) > 0 && flagUUIDFile == "" && len(filters) == 0:
 		podUUIDs = args
 
+	case len(args) == 0 && flagUUIDFile == "" && len(filters) > 0:
+		f, err := parseRmFilters(filters)
+		if err != nil {
+			stderr.PrintE("invalid filter", err)
+			return 254
+		}
+		podUUIDs, err = filterPods(f)
+		if err != nil {
+			stderr.PrintE("failed to get pods", err)
+			return 254
+		}
+
 	default:
 		cmd.Usage()
 		return 254
 	}
 
+	if flagRmDryRun {
+		return printRmDryRun(podUUIDs)
+	}
+
+	mode, err := getEraseMode()
+	if err != nil {
+		stderr.PrintE("invalid erase mode", err)
+		return 254
+	}
+
 	for _, podUUID := range podUUIDs {
 		p, err := pkgPod.PodFromUUIDString(getDataDir(), podUUID)
 		if err != nil {
-			ret = 254
+			ret = exitcode.FromError(err)
 			stderr.PrintE("cannot get pod", err)
 			continue
 		}
@@ -76,6 +250,8 @@ func runRm(cmd *cobra.Command, args []string) (exit int) {
 		}
 	}
 
+	trimErased(getDataDir(), mode)
+
 	if ret == 254 {
 		stderr.Print("failed to remove one or more pods")
 	}
@@ -83,6 +259,37 @@ func runRm(cmd *cobra.Command, args []string) (exit int) {
 	return ret
 }
 
+// printRmDryRun lists the pods which rm would remove.
+func printRmDryRun(podUUIDs []string) (exit int) {
+	tabBuffer := new(bytes.Buffer)
+	tabOut := getTabOutWithWriter(tabBuffer)
+	fmt.Fprintf(tabOut, "UUID\tSTATE\tCREATED\n")
+	for _, podUUID := range podUUIDs {
+		p, err := pkgPod.PodFromUUIDString(getDataDir(), podUUID)
+		if err != nil {
+			exit = exitcode.FromError(err)
+			stderr.PrintE("cannot get pod", err)
+			continue
+		}
+		state := p.State()
+		if !removableState(state) {
+			stderr.Printf("pod %q cannot be removed in state %q", p.UUID, state)
+			exit = 254
+			p.Close()
+			continue
+		}
+		var createdStr string
+		if created, err := p.CreationTime(); err == nil {
+			createdStr = humanize.Time(created)
+		}
+		fmt.Fprintf(tabOut, "%s\t%s\t%s\n", p.UUID, state, createdStr)
+		p.Close()
+	}
+	tabOut.Flush()
+	stdout.Print(tabBuffer)
+	return exit
+}
+
 func removePod(p *pkgPod.Pod) bool {
 	switch p.State() {
 	case pkgPod.Running:
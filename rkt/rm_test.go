@@ -0,0 +1,81 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+//+build linux
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+)
+
+func TestParseRmFilters(t *testing.T) {
+	f, err := parseRmFilters([]string{"state=exited", "label=tier=web", "older-than=24h"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !f.states[pkgPod.Exited] || !f.states[pkgPod.ExitedGarbage] || f.states[pkgPod.Prepared] {
+		t.Errorf("unexpected states %v", f.states)
+	}
+	if f.labels["tier"] != "web" {
+		t.Errorf("unexpected labels %v", f.labels)
+	}
+	if f.olderThan != 24*time.Hour {
+		t.Errorf("unexpected duration %v", f.olderThan)
+	}
+
+	for _, filter := range []string{
+		"state=running",
+		"label=tier",
+		"older-than=yesterday",
+		"color=red",
+		"state",
+		"state=",
+	} {
+		if _, err := parseRmFilters([]string{filter}); err == nil {
+			t.Errorf("%q: expected error", filter)
+		}
+	}
+}
+
+func TestPodHasLabel(t *testing.T) {
+	pm := &schema.PodManifest{
+		UserLabels: map[string]string{"team": "infra"},
+		Apps: schema.AppList{
+			{Name: "db"},
+			{Name: "web", App: &types.App{UserLabels: map[string]string{"tier": "web"}}},
+		},
+	}
+
+	tests := []struct {
+		name, value string
+		match       bool
+	}{
+		{"team", "infra", true},
+		{"tier", "web", true},
+		{"tier", "db", false},
+		{"zone", "eu", false},
+	}
+	for _, tt := range tests {
+		if match := podHasLabel(pm, tt.name, tt.value); match != tt.match {
+			t.Errorf("%s=%s: expected %t, got %t", tt.name, tt.value, tt.match, match)
+		}
+	}
+}
@@ -22,6 +22,8 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"os/exec"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -30,12 +32,14 @@ import (
 	"github.com/hashicorp/errwrap"
 	"github.com/opencontainers/selinux/go-selinux/label"
 	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/common/apps"
+	"github.com/rkt/rkt/pkg/exitcode"
 	"github.com/rkt/rkt/pkg/lock"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/pkg/user"
 	"github.com/rkt/rkt/rkt/image"
 	"github.com/rkt/rkt/stage0"
-	"github.com/rkt/rkt/store/imagestore"
+	stage1types "github.com/rkt/rkt/stage1/common/types"
 	"github.com/rkt/rkt/store/treestore"
 	"github.com/spf13/cobra"
 )
@@ -76,20 +80,36 @@ image arguments with a lone "---" to resume argument parsing.`,
 	flagPodManifest  string
 	flagMDSRegister  bool
 	flagUUIDFileSave string
+	flagPodName      string
+	flagStorageLimit string
 	flagHostname     string
 	flagHostsEntries flagStringList
 	flagPullPolicy   string
 	flagIPCMode      string
+	flagSecretsDir   string
+	flagSecretsExec  string
+	flagTimeOffsets  common.TimeOffsets
+	flagHypervisor   string
 )
 
 func addIsolatorFlags(cmd *cobra.Command, compat bool) {
 	cmd.Flags().Var((*appMemoryLimit)(&rktApps), "memory", "memory limit for the preceding image (example: '--memory=16Mi', '--memory=50M', '--memory=1G')")
+	cmd.Flags().Var((*appMemorySwap)(&rktApps), "memory-swap", "swap limit for the preceding image, on top of its memory limit (example: '--memory-swap=512M')")
+	cmd.Flags().Var((*appMemorySwappiness)(&rktApps), "memory-swappiness", "memory swappiness for the preceding image, from 0 to 100 (example: '--memory-swappiness=10')")
+	cmd.Flags().Var((*appHugepages)(&rktApps), "hugepages", "hugetlb pages granted to the preceding image, can be given several times (example: '--hugepages=2MB:1Gi')")
 	cmd.Flags().Var((*appCPULimit)(&rktApps), "cpu", "cpu limit for the preceding image (example: '--cpu=500m')")
 	cmd.Flags().Var((*appCPUShares)(&rktApps), "cpu-shares", "cpu-shares assigns the specified CPU time share weight (example: '--cpu-shares=2048')")
+	cmd.Flags().Var((*appCPUSetCPUs)(&rktApps), "cpuset-cpus", "CPUs the preceding image is pinned to (example: '--cpuset-cpus=0-3,8')")
+	cmd.Flags().Var((*appCPUSetMems)(&rktApps), "cpuset-mems", "NUMA memory nodes the preceding image is pinned to (example: '--cpuset-mems=0')")
+	cmd.Flags().Var((*appCPURTRuntime)(&rktApps), "cpu-rt-runtime", "real-time runtime of the preceding image, in microseconds per second (example: '--cpu-rt-runtime=950000')")
+	cmd.Flags().Var((*appCPURTPriority)(&rktApps), "cpu-rt-priority", "maximum real-time priority of the preceding image, from 1 to 99 (example: '--cpu-rt-priority=50')")
 	cmd.Flags().Var((*appCapsRetain)(&rktApps), "caps-retain", "capability to retain (example: '--caps-retain=CAP_SYS_ADMIN')")
 	cmd.Flags().Var((*appCapsRemove)(&rktApps), "caps-remove", "capability to remove (example: '--caps-remove=CAP_MKNOD')")
 	cmd.Flags().Var((*appSeccompFilter)(&rktApps), "seccomp", "seccomp filter override (example: '--seccomp mode=retain,errno=EPERM,chmod,chown')")
 	cmd.Flags().Var((*appOOMScoreAdj)(&rktApps), "oom-score-adj", "oom-score-adj isolator override")
+	cmd.Flags().Var((*appMaskedPaths)(&rktApps), "masked-paths", "paths made inaccessible to the preceding image (example: '--masked-paths=/proc/kcore,/sys/firmware')")
+	cmd.Flags().Var((*appReadOnlyPaths)(&rktApps), "readonly-paths", "paths made read-only for the preceding image (example: '--readonly-paths=/proc/sys')")
+	cmd.Flags().Var((*appRLimit)(&rktApps), "rlimit", "resource limit for the preceding image, can be given several times (example: '--rlimit=nofile=1024:65536')")
 
 	// For backwards compatibility
 	if compat {
@@ -123,6 +143,54 @@ func addAppFlags(cmd *cobra.Command) {
 	}
 }
 
+func addSecretsFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagSecretsDir, "secrets-dir", "", "host directory whose files are made available to all the apps in "+common.SecretsMountPath)
+	cmd.Flags().StringVar(&flagSecretsExec, "secrets-helper", "", "executable writing the secrets to make available to all the apps in "+common.SecretsMountPath+" into the directory passed as its argument")
+}
+
+// secretsFromFlags returns the secrets configuration from the command line
+// flags, with absolute paths since stage0 changes its working directory.
+func secretsFromFlags() (stage0.Secrets, error) {
+	var secrets stage0.Secrets
+	if flagSecretsDir != "" {
+		dir, err := filepath.Abs(flagSecretsDir)
+		if err != nil {
+			return secrets, err
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return secrets, err
+		}
+		if !fi.IsDir() {
+			return secrets, fmt.Errorf("%q is not a directory", flagSecretsDir)
+		}
+		secrets.Dir = dir
+	}
+	if flagSecretsExec != "" {
+		helper, err := exec.LookPath(flagSecretsExec)
+		if err != nil {
+			return secrets, err
+		}
+		if secrets.Helper, err = filepath.Abs(helper); err != nil {
+			return secrets, err
+		}
+	}
+	return secrets, nil
+}
+
+// podMeasurement returns where the images of the pods are measured when they
+// start, from the measurement configuration.
+func podMeasurement() (stage0.Measurement, error) {
+	config, err := getConfig()
+	if err != nil {
+		return stage0.Measurement{}, err
+	}
+	return stage0.Measurement{
+		PCR:      config.Measurement.PCR,
+		EventLog: config.Measurement.EventLog,
+	}, nil
+}
+
 func init() {
 	cmdRkt.AddCommand(cmdRun)
 
@@ -135,12 +203,13 @@ func init() {
 	cmdRun.Flags().Var(&flagPorts, "port", "ports to expose on the host (requires contained network). Syntax: --port=NAME:[HOSTIP:]HOSTPORT")
 	cmdRun.Flags().Var(&flagNet, "net", "configure the pod's networking. Optionally, pass a list of user-configured networks to load and set arguments to pass to each network, respectively. Syntax: --net[=n[:args], ...]")
 	cmdRun.Flags().Lookup("net").NoOptDefVal = "default"
+	markNetFlagCompletion(cmdRun.Flags())
 	cmdRun.Flags().BoolVar(&flagInheritEnv, "inherit-env", false, "inherit all environment variables not set by apps")
 	cmdRun.Flags().BoolVar(&flagNoOverlay, "no-overlay", false, "disable overlay filesystem")
 	cmdRun.Flags().BoolVar(&flagPrivateUsers, "private-users", false, "run within user namespaces.")
 	cmdRun.Flags().Var(&flagExplicitEnv, "set-env", "environment variable to set for all the apps in the form key=value, this will be overridden by --environment")
 	cmdRun.Flags().Var(&flagEnvFromFile, "set-env-file", "path to an environment variables file")
-	cmdRun.Flags().BoolVar(&flagInteractive, "interactive", false, "run pod interactively. If true, only one image may be supplied.")
+	cmdRun.Flags().BoolVar(&flagInteractive, "interactive", false, "run pod interactively. If several images are supplied, each app gets its own TTY reachable with 'rkt attach' (requires the attach experiment)")
 	cmdRun.Flags().Var(&flagDNS, "dns", "name servers to write in /etc/resolv.conf. Pass 'host' to use host's resolv.conf. Pass 'none' to ignore CNI DNS config")
 	cmdRun.Flags().Var(&flagDNSSearch, "dns-search", "DNS search domains to write in /etc/resolv.conf")
 	cmdRun.Flags().Var(&flagDNSOpt, "dns-opt", "DNS options to write in /etc/resolv.conf")
@@ -150,18 +219,24 @@ func init() {
 	cmdRun.Flags().MarkDeprecated("store-only", "please use --pull-policy=never")
 	cmdRun.Flags().BoolVar(&flagNoStore, "no-store", false, "fetch images ignoring the local store")
 	cmdRun.Flags().MarkDeprecated("no-store", "please use --pull-policy=update")
-	cmdRun.Flags().StringVar(&flagPullPolicy, "pull-policy", image.PullPolicyNew, "when to pull an image")
+	cmdRun.Flags().StringVar(&flagPullPolicy, "pull-policy", image.PullPolicyNew, "when to pull an image: never, if-not-present (alias new), update or always")
 	cmdRun.Flags().StringVar(&flagPodManifest, "pod-manifest", "", "the path to the pod manifest. If it's non-empty, then only '--net', '--no-overlay' and '--interactive' will have effect")
 	cmdRun.Flags().BoolVar(&flagMDSRegister, "mds-register", false, "register pod with metadata service. needs network connectivity to the host (--net=(default|default-restricted|host)")
 	cmdRun.Flags().StringVar(&flagUUIDFileSave, "uuid-file-save", "", "write out pod UUID to specified file")
+	cmdRun.Flags().StringVar(&flagPodName, "pod-name", "", "unique name of the pod, usable in place of its UUID")
+	cmdRun.Flags().StringVar(&flagStorageLimit, "storage-limit", "", "disk usage limit of the pod writable layers, enforced with a project quota (example: '--storage-limit=10G')")
 	cmdRun.Flags().StringVar(&flagHostname, "hostname", "", `pod's hostname. If empty, it will be "rkt-$PODUUID"`)
 	cmdRun.Flags().Var((*appsVolume)(&rktApps), "volume", "volumes to make available in the pod")
 	cmdRun.Flags().StringVar(&flagIPCMode, "ipc", "", `whether to stay in the host IPC namespace. Syntax: --ipc=[auto|private|parent]`)
+	addSecretsFlags(cmdRun)
+	cmdRun.Flags().Var(&flagTimeOffsets, "time-offset", "run the pod in its own time namespace, with the given clock offsets. Syntax: --time-offset=monotonic=DURATION,boottime=DURATION")
+	cmdRun.Flags().StringVar(&flagHypervisor, "stage1-kvm-hypervisor", "", "hypervisor of the kvm stage1, among the ones it ships. Syntax: --stage1-kvm-hypervisor=[lkvm|qemu]")
 
 	// per-app flags
 	cmdRun.Flags().Var((*appAsc)(&rktApps), "signature", "local signature file to use in validating the preceding image")
 	addAppFlags(cmdRun)
 	addIsolatorFlags(cmdRun, true)
+	addSecurityProfileFlag(cmdRun)
 
 	flagPorts = portList{}
 	flagDNS = flagStringList{}
@@ -193,6 +268,22 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 	if flagNoStore {
 		flagPullPolicy = image.PullPolicyUpdate
 	}
+	if err := image.ValidatePullPolicy(flagPullPolicy); err != nil {
+		stderr.Error(err)
+		return 254
+	}
+	if flagPodName != "" {
+		if err := pkgPod.ValidateName(flagPodName); err != nil {
+			stderr.Error(err)
+			return 254
+		}
+	}
+	if flagStorageLimit != "" {
+		if _, err := stage1types.ParseStorageLimit(flagStorageLimit); err != nil {
+			stderr.Error(err)
+			return 254
+		}
+	}
 
 	if flagPrivateUsers {
 		if !common.SupportsUserNS() {
@@ -218,15 +309,41 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 
 	if len(flagPodManifest) > 0 && (rktApps.Count() > 0 ||
 		(*appsVolume)(&rktApps).String() != "" || (*appMount)(&rktApps).String() != "" ||
-		len(flagPorts) > 0 || flagPullPolicy == image.PullPolicyNever ||
-		flagPullPolicy == image.PullPolicyUpdate || flagInheritEnv ||
-		!flagExplicitEnv.IsEmpty() || !flagEnvFromFile.IsEmpty()) {
+		len(flagPorts) > 0 || (flagPullPolicy != image.PullPolicyNew &&
+		flagPullPolicy != image.PullPolicyIfNotPresent) || flagInheritEnv ||
+		!flagExplicitEnv.IsEmpty() || !flagEnvFromFile.IsEmpty() ||
+		cmd.Flags().Changed("security-profile")) {
 		stderr.Print("conflicting flags set with --pod-manifest (see --help)")
 		return 254
 	}
 
-	if flagInteractive && rktApps.Count() > 1 {
-		stderr.Print("interactive option only supports one image")
+	if len(flagPodManifest) == 0 {
+		if err := applySecurityProfile(&rktApps); err != nil {
+			stderr.PrintE("cannot apply the security profile", err)
+			return 254
+		}
+	}
+
+	// Several apps can't share the console, so each one gets its own TTY,
+	// reachable with rkt attach.
+	multiplexTTY := flagInteractive && rktApps.Count() > 1
+	if multiplexTTY {
+		if !common.IsExperimentEnabled("attach") {
+			stderr.Print("interactive option with several images requires the attach experiment (RKT_EXPERIMENT_ATTACH=true)")
+			return 254
+		}
+		setAppsTTY(&rktApps)
+	}
+
+	secrets, err := secretsFromFlags()
+	if err != nil {
+		stderr.PrintE("invalid secrets", err)
+		return 254
+	}
+
+	measurement, err := podMeasurement()
+	if err != nil {
+		stderr.PrintE("cannot get the measurement configuration", err)
 		return 254
 	}
 
@@ -235,7 +352,7 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -264,17 +381,20 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 		Ts:                 ts,
 		Ks:                 getKeystore(),
 		Headers:            config.AuthPerHost,
+		TLSPerHost:         config.TLSPerHost,
+		P2PPerHost:         config.P2PPerHost,
 		DockerAuth:         config.DockerCredentialsPerRegistry,
 		InsecureFlags:      globalFlags.InsecureFlags,
 		Debug:              globalFlags.Debug,
 		TrustKeysFromHTTPS: globalFlags.TrustKeysFromHTTPS,
+		Offline:            globalFlags.Offline,
 
 		PullPolicy: flagPullPolicy,
 		WithDeps:   true,
 	}
 	if err := fn.FindImages(&rktApps); err != nil {
 		stderr.Error(err)
-		return 254
+		return exitcode.FromError(err)
 	}
 
 	p, err := pkgPod.NewPod(getDataDir())
@@ -283,6 +403,13 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 
+	if flagPodName != "" {
+		if err := p.SetName(flagPodName); err != nil {
+			stderr.PrintE("error naming the pod", err)
+			return 254
+		}
+	}
+
 	// if requested, write out pod UUID early so "rkt rm" can
 	// clean it up even if something goes wrong
 	if flagUUIDFileSave != "" {
@@ -357,6 +484,11 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 	}
 	keyLock.Close()
 
+	if err := setPodStorageLimit(p); err != nil {
+		stderr.PrintE("error limiting the pod storage", err)
+		return 254
+	}
+
 	// get the lock fd for run
 	lfd, err := p.Fd()
 	if err != nil {
@@ -386,7 +518,7 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 		CommonConfig:         &cfg,
 		Net:                  flagNet,
 		LockFd:               lfd,
-		Interactive:          flagInteractive,
+		Interactive:          flagInteractive && !multiplexTTY,
 		DNSConfMode:          DNSConfMode,
 		DNSConfig:            DNSConfig,
 		MDSRegister:          flagMDSRegister,
@@ -399,6 +531,10 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 		UseOverlay:           useOverlay,
 		HostsEntries:         *HostsEntries,
 		IPCMode:              flagIPCMode,
+		Secrets:              secrets,
+		TimeOffsets:          flagTimeOffsets,
+		Hypervisor:           flagHypervisor,
+		Measurement:          measurement,
 	}
 
 	_, manifest, err := p.PodManifest()
@@ -412,6 +548,19 @@ func runRun(cmd *cobra.Command, args []string) (exit int) {
 		return 254
 	}
 	rcfg.Apps = manifest.Apps
+
+	if multiplexTTY {
+		for _, ra := range manifest.Apps {
+			stderr.Printf("app %q runs on its own TTY, attach to it with 'rkt attach --app=%s %s'", ra.Name, ra.Name, p.UUID)
+		}
+	}
+
+	auditPod(p)
+	if err := logAudit(0); err != nil {
+		stderr.PrintE("cannot write the audit record", err)
+		return 254
+	}
+
 	stage0.Run(rcfg, p.Path(), getDataDir()) // execs, never returns
 
 	return 254
@@ -682,3 +831,20 @@ func parseDNSFlags(flagHostsEntries, flagDNS, flagDNSSearch, flagDNSOpt []string
 
 	return DNSConfMode, DNSConfig, &HostsEntries, nil
 }
+
+// setAppsTTY sets the streams of the apps to the TTY mode, unless a mode was
+// explicitly requested.
+func setAppsTTY(al *apps.Apps) {
+	al.Walk(func(app *apps.App) error {
+		if app.Stdin == "" {
+			app.Stdin = apps.AppIOTTY
+		}
+		if app.Stdout == "" {
+			app.Stdout = apps.AppIOTTY
+		}
+		if app.Stderr == "" {
+			app.Stderr = apps.AppIOTTY
+		}
+		return nil
+	})
+}
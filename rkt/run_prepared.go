@@ -18,9 +18,9 @@ package main
 
 import (
 	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/pkg/exitcode"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/rkt/rkt/stage0"
-	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 	"github.com/spf13/cobra"
 )
@@ -43,6 +43,7 @@ func init() {
 
 	cmdRunPrepared.Flags().Var(&flagNet, "net", "configure the pod's networking. Optionally, pass a list of user-configured networks to load and set arguments to pass to each network, respectively. Syntax: --net[=n[:args]][,]")
 	cmdRunPrepared.Flags().Lookup("net").NoOptDefVal = "default"
+	markNetFlagCompletion(cmdRunPrepared.Flags())
 	cmdRunPrepared.Flags().Var(&flagDNS, "dns", "name servers to write in /etc/resolv.conf. Pass 'host' to use host's resolv.conf. Pass 'none' to ignore CNI DNS config")
 	cmdRunPrepared.Flags().Var(&flagDNSSearch, "dns-search", "DNS search domains to write in /etc/resolv.conf")
 	cmdRunPrepared.Flags().Var(&flagDNSOpt, "dns-opt", "DNS options to write in /etc/resolv.conf")
@@ -53,6 +54,9 @@ func init() {
 	cmdRunPrepared.Flags().BoolVar(&flagMDSRegister, "mds-register", false, "register pod with metadata service")
 	cmdRunPrepared.Flags().StringVar(&flagHostname, "hostname", "", `pod's hostname. If empty, it will be "rkt-$PODUUID"`)
 	cmdRunPrepared.Flags().StringVar(&flagIPCMode, "ipc", "", `whether to stay in the host IPC namespace. Syntax: --ipc=[auto|private|parent]`)
+	addSecretsFlags(cmdRunPrepared)
+	cmdRunPrepared.Flags().Var(&flagTimeOffsets, "time-offset", "run the pod in its own time namespace, with the given clock offsets. Syntax: --time-offset=monotonic=DURATION,boottime=DURATION")
+	cmdRunPrepared.Flags().StringVar(&flagHypervisor, "stage1-kvm-hypervisor", "", "hypervisor of the kvm stage1, among the ones it ships. Syntax: --stage1-kvm-hypervisor=[lkvm|qemu]")
 }
 
 func runRunPrepared(cmd *cobra.Command, args []string) (exit int) {
@@ -64,11 +68,11 @@ func runRunPrepared(cmd *cobra.Command, args []string) (exit int) {
 	p, err := pkgPod.PodFromUUIDString(getDataDir(), args[0])
 	if err != nil {
 		stderr.PrintE("problem retrieving pod", err)
-		return 254
+		return exitcode.FromError(err)
 	}
 	defer p.Close()
 
-	s, err := imagestore.NewStore(storeDir())
+	s, err := openImageStore()
 	if err != nil {
 		stderr.PrintE("cannot open store", err)
 		return 254
@@ -173,10 +177,27 @@ func runRunPrepared(cmd *cobra.Command, args []string) (exit int) {
 		InsecurePaths:        globalFlags.InsecureFlags.SkipPaths(),
 		InsecureSeccomp:      globalFlags.InsecureFlags.SkipSeccomp(),
 		UseOverlay:           ovlPrep && ovlOk,
+		TimeOffsets:          flagTimeOffsets,
+		Hypervisor:           flagHypervisor,
+	}
+	rcfg.Secrets, err = secretsFromFlags()
+	if err != nil {
+		stderr.PrintE("invalid secrets", err)
+		return 254
+	}
+	rcfg.Measurement, err = podMeasurement()
+	if err != nil {
+		stderr.PrintE("cannot get the measurement configuration", err)
+		return 254
 	}
 	if globalFlags.Debug {
 		stage0.InitDebug()
 	}
+	auditPod(p)
+	if err := logAudit(0); err != nil {
+		stderr.PrintE("cannot write the audit record", err)
+		return 254
+	}
 	stage0.Run(rcfg, p.Path(), getDataDir()) // execs, never returns
 	return 254
 }
@@ -0,0 +1,56 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"errors"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/pkg/erase"
+	"github.com/spf13/pflag"
+)
+
+var flagSecureErase string
+
+// addSecureEraseFlag adds --secure-erase to the commands removing pods or
+// images. Without a value, it shreds them.
+func addSecureEraseFlag(flags *pflag.FlagSet) {
+	flags.StringVar(&flagSecureErase, "secure-erase", "", "erase the data of the removed pods or images: 'shred', 'discard' or 'none'. If not set, the erase configuration is used")
+	flags.Lookup("secure-erase").NoOptDefVal = string(erase.Shred)
+}
+
+// getEraseMode returns how the data of the removed pods and images is
+// erased, from --secure-erase or else from the configuration.
+func getEraseMode() (erase.Mode, error) {
+	if flagSecureErase != "" {
+		return erase.ParseMode(flagSecureErase)
+	}
+	config, err := getConfig()
+	if err != nil {
+		return erase.None, errwrap.Wrap(errors.New("cannot get configuration"), err)
+	}
+	return erase.ParseMode(config.Erase.Mode)
+}
+
+// trimErased discards the blocks freed by the removal of files under path,
+// in the discard mode.
+func trimErased(path string, mode erase.Mode) {
+	if mode != erase.Discard {
+		return
+	}
+	if err := erase.Trim(path); err != nil {
+		stderr.PrintE("unable to discard the erased data", err)
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/rkt/rkt/common/apps"
+	"github.com/rkt/rkt/rkt/config"
+	"github.com/spf13/cobra"
+	"github.com/syndtr/gocapability/capability"
+)
+
+const defaultSecurityProfile = "default"
+
+var flagSecurityProfile string
+
+// builtinSecurityProfiles are the security profiles available without any
+// configuration. A profile of the configuration with the same name replaces
+// the built-in one.
+var builtinSecurityProfiles = map[string]config.SecurityProfile{
+	// The restrictions of the images and of stage1.
+	defaultSecurityProfile: {},
+	// The capabilities needed by the usual services, the default
+	// seccomp filter of rkt, no privilege escalation and some more
+	// kernel information masked.
+	"restricted": {
+		CapsRetain: []string{
+			"CAP_CHOWN",
+			"CAP_DAC_OVERRIDE",
+			"CAP_FOWNER",
+			"CAP_FSETID",
+			"CAP_KILL",
+			"CAP_NET_BIND_SERVICE",
+			"CAP_SETGID",
+			"CAP_SETUID",
+		},
+		Seccomp:         "mode=retain,@rkt/default-whitelist",
+		NoNewPrivileges: boolPtr(true),
+		MaskedPaths: []string{
+			"/proc/acpi",
+			"/proc/keys",
+			"/proc/latency_stats",
+			"/proc/scsi",
+			"/proc/timer_list",
+			"/proc/timer_stats",
+		},
+	},
+	// All the capabilities and no seccomp filter.
+	"privileged": {
+		CapsRetain:      allCapabilities(),
+		Seccomp:         "mode=retain,@appc.io/all",
+		NoNewPrivileges: boolPtr(false),
+	},
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func allCapabilities() []string {
+	var caps []string
+	for _, c := range capability.List() {
+		caps = append(caps, "CAP_"+strings.ToUpper(c.String()))
+	}
+	return caps
+}
+
+func addSecurityProfileFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&flagSecurityProfile, "security-profile", defaultSecurityProfile, "security profile applied to the apps: default, restricted, privileged or a profile of the configuration")
+}
+
+// applySecurityProfile applies the selected security profile to the apps.
+// The capabilities, seccomp filter and no-new-privileges settings given for
+// an app on the command line take precedence over the profile, which takes
+// precedence over the isolators of the image.
+func applySecurityProfile(al *apps.Apps) error {
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	profile, ok := cfg.SecurityProfiles[flagSecurityProfile]
+	if !ok {
+		if profile, ok = builtinSecurityProfiles[flagSecurityProfile]; !ok {
+			return fmt.Errorf("unknown security profile %q", flagSecurityProfile)
+		}
+	}
+
+	return al.Walk(func(app *apps.App) error {
+		if app.CapsRetain == nil && app.CapsRemove == nil {
+			var err error
+			if len(profile.CapsRetain) > 0 {
+				app.CapsRetain, err = types.NewLinuxCapabilitiesRetainSet(profile.CapsRetain...)
+			} else if len(profile.CapsRemove) > 0 {
+				app.CapsRemove, err = types.NewLinuxCapabilitiesRevokeSet(profile.CapsRemove...)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if app.SeccompFilter == "" {
+			app.SeccompFilter = profile.Seccomp
+		}
+		if app.NoNewPrivileges == nil {
+			app.NoNewPrivileges = profile.NoNewPrivileges
+		}
+		app.MaskedPaths = append(app.MaskedPaths, profile.MaskedPaths...)
+		return nil
+	})
+}
@@ -0,0 +1,92 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/rkt/rkt/common/apps"
+	"github.com/rkt/rkt/rkt/config"
+)
+
+func TestApplySecurityProfile(t *testing.T) {
+	defer func(c *config.Config, p string) {
+		cachedConfig, flagSecurityProfile = c, p
+	}(cachedConfig, flagSecurityProfile)
+	cachedConfig = &config.Config{
+		SecurityProfiles: map[string]config.SecurityProfile{
+			"web": {
+				CapsRemove:  []string{"CAP_MKNOD"},
+				MaskedPaths: []string{"/proc/keys"},
+			},
+		},
+	}
+
+	newApps := func() *apps.Apps {
+		var al apps.Apps
+		al.Reset()
+		al.Create("example.com/plain")
+		al.Create("example.com/overridden")
+		app := al.Last()
+		app.CapsRetain, _ = types.NewLinuxCapabilitiesRetainSet("CAP_NET_ADMIN")
+		app.SeccompFilter = "mode=remove,reboot"
+		no := false
+		app.NoNewPrivileges = &no
+		return &al
+	}
+
+	flagSecurityProfile = "web"
+	al := newApps()
+	if err := applySecurityProfile(al); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var plain, overridden *apps.App
+	al.Walk(func(app *apps.App) error {
+		if plain == nil {
+			plain = app
+		} else {
+			overridden = app
+		}
+		return nil
+	})
+	if plain.CapsRemove == nil || !reflect.DeepEqual(plain.CapsRemove.Set(), []types.LinuxCapability{"CAP_MKNOD"}) {
+		t.Errorf("expected the caps of the profile, got %v", plain.CapsRemove)
+	}
+	if !reflect.DeepEqual(plain.MaskedPaths, []string{"/proc/keys"}) {
+		t.Errorf("expected the masked paths of the profile, got %v", plain.MaskedPaths)
+	}
+	if overridden.CapsRemove != nil || !reflect.DeepEqual(overridden.CapsRetain.Set(), []types.LinuxCapability{"CAP_NET_ADMIN"}) {
+		t.Errorf("expected the caps of the command line to be kept")
+	}
+	if overridden.SeccompFilter != "mode=remove,reboot" || *overridden.NoNewPrivileges {
+		t.Errorf("expected the seccomp and no-new-privileges settings of the command line to be kept")
+	}
+
+	flagSecurityProfile = "restricted"
+	al = newApps()
+	if err := applySecurityProfile(al); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p := al.Last(); p.SeccompFilter != "mode=remove,reboot" || len(p.MaskedPaths) == 0 {
+		t.Errorf("unexpected restricted app %+v", p)
+	}
+
+	flagSecurityProfile = "unknown"
+	if err := applySecurityProfile(newApps()); err == nil {
+		t.Errorf("expected error with an unknown profile")
+	}
+}
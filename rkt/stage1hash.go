@@ -327,6 +327,7 @@ func getStage1Finder(s *imagestore.Store, ts *treestore.Store, withKeystore bool
 		Debug:              globalFlags.Debug,
 		InsecureFlags:      globalFlags.InsecureFlags,
 		TrustKeysFromHTTPS: globalFlags.TrustKeysFromHTTPS,
+		Offline:            globalFlags.Offline,
 
 		PullPolicy: image.PullPolicyNew,
 		WithDeps:   false,
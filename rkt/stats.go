@@ -0,0 +1,193 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/dustin/go-humanize"
+	"github.com/rkt/rkt/common/cgroup"
+	"github.com/rkt/rkt/pkg/exitcode"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+	"github.com/rkt/rkt/pkg/quota"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdStats = &cobra.Command{
+		Use:   "stats [--format=json] UUID",
+		Short: "Print the resource usage of a running pod",
+		Long: `Prints the memory and swap usage and limits of a running pod, and of each of its apps.
+
+The swap usage requires swap accounting in the kernel. The storage usage is
+only reported for the pods with a storage limit.`,
+		Run: runWrapper(runStats),
+	}
+)
+
+// podStats is the resource usage of a pod. The stats of the apps are
+// only available with the stage1 flavors running them in their own
+// cgroups.
+type podStats struct {
+	Memory  *cgroup.MemoryStats `json:"memory"`
+	Storage *quota.Usage        `json:"storage,omitempty"`
+	Apps    []appStats          `json:"apps,omitempty"`
+}
+
+type appStats struct {
+	Name   string              `json:"name"`
+	Memory *cgroup.MemoryStats `json:"memory"`
+}
+
+func init() {
+	cmdRkt.AddCommand(cmdStats)
+	cmdStats.Flags().Var(&flagFormat, "format", "choose the output format, allowed format includes 'json', 'json-pretty'. If empty, then the result is printed as a table")
+}
+
+func runStats(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return 254
+	}
+
+	p, err := pkgPod.PodFromUUIDString(getDataDir(), args[0])
+	if err != nil {
+		stderr.PrintE("problem retrieving pod", err)
+		return exitcode.FromError(err)
+	}
+	defer p.Close()
+
+	if p.State() != pkgPod.Running {
+		stderr.Printf("pod %q isn't running", p.UUID)
+		return 254
+	}
+
+	stats, err := getPodStats(p)
+	switch {
+	case err == cgroup.ErrNoSwapAccounting:
+		stderr.PrintE("cannot report the swap usage", err)
+	case err != nil:
+		stderr.PrintE("unable to get the pod stats", err)
+		return 254
+	}
+
+	switch flagFormat {
+	case outputFormatJSON:
+		result, err := json.Marshal(stats)
+		if err != nil {
+			stderr.PrintE("error marshaling the pod stats", err)
+			return 254
+		}
+		stdout.Print(string(result))
+	case outputFormatPrettyJSON:
+		result, err := json.MarshalIndent(stats, "", "\t")
+		if err != nil {
+			stderr.PrintE("error marshaling the pod stats", err)
+			return 254
+		}
+		stdout.Print(string(result))
+	default:
+		printPodStats(stats, err != cgroup.ErrNoSwapAccounting)
+	}
+
+	return 0
+}
+
+// getPodStats returns the stats of the pod, along with
+// cgroup.ErrNoSwapAccounting when the swap usage is missing.
+func getPodStats(p *pkgPod.Pod) (*podStats, error) {
+	pid, err := p.ContainerPid1()
+	if err != nil {
+		return nil, err
+	}
+	podCgroup, err := getPodCgroup(p, pid)
+	if err != nil {
+		return nil, err
+	}
+	_, manifest, err := p.PodManifest()
+	if err != nil {
+		return nil, err
+	}
+
+	var swapErr error
+	getMemoryStats := func(cg string) (*cgroup.MemoryStats, error) {
+		stats, err := cgroup.GetMemoryStats(cg)
+		if err == cgroup.ErrNoSwapAccounting {
+			swapErr = err
+			err = nil
+		}
+		return stats, err
+	}
+
+	var stats podStats
+	if stats.Memory, err = getMemoryStats(podCgroup); err != nil {
+		return nil, err
+	}
+	if stats.Storage, err = p.StorageUsage(); err != nil {
+		return nil, err
+	}
+	for _, ra := range manifest.Apps {
+		appCgroup := filepath.Join(podCgroup, "system.slice", ra.Name.String()+".service")
+		memory, err := getMemoryStats(appCgroup)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		stats.Apps = append(stats.Apps, appStats{Name: ra.Name.String(), Memory: memory})
+	}
+	return &stats, swapErr
+}
+
+func printPodStats(stats *podStats, withSwap bool) {
+	var tabBuffer bytes.Buffer
+	tabOut := getTabOutWithWriter(&tabBuffer)
+	if withSwap {
+		fmt.Fprintf(tabOut, "NAME\tMEMORY\tMEMORY LIMIT\tSWAP\tSWAP LIMIT\n")
+	} else {
+		fmt.Fprintf(tabOut, "NAME\tMEMORY\tMEMORY LIMIT\n")
+	}
+	printMemoryStats := func(name string, m *cgroup.MemoryStats) {
+		if withSwap {
+			fmt.Fprintf(tabOut, "%s\t%s\t%s\t%s\t%s\n", name, humanize.IBytes(m.Usage), formatLimit(m.Limit), humanize.IBytes(m.SwapUsage), formatLimit(m.SwapLimit))
+		} else {
+			fmt.Fprintf(tabOut, "%s\t%s\t%s\n", name, humanize.IBytes(m.Usage), formatLimit(m.Limit))
+		}
+	}
+	printMemoryStats("(pod)", stats.Memory)
+	for _, app := range stats.Apps {
+		printMemoryStats(app.Name, app.Memory)
+	}
+	if s := stats.Storage; s != nil {
+		fmt.Fprintf(tabOut, "\nSTORAGE\tSTORAGE LIMIT\n")
+		fmt.Fprintf(tabOut, "%s\t%s\n", humanize.IBytes(s.Used), formatLimit(s.Limit))
+	}
+	tabOut.Flush()
+	stdout.Print(tabBuffer.String())
+}
+
+func formatLimit(limit *uint64) string {
+	if limit == nil {
+		return "-"
+	}
+	return humanize.IBytes(*limit)
+}
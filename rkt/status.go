@@ -23,6 +23,7 @@ import (
 	"time"
 
 	lib "github.com/rkt/rkt/lib"
+	"github.com/rkt/rkt/pkg/exitcode"
 	pkgPod "github.com/rkt/rkt/pkg/pod"
 	"github.com/spf13/cobra"
 	"golang.org/x/net/context"
@@ -92,7 +93,7 @@ func runStatus(cmd *cobra.Command, args []string) (exit int) {
 	p, err := pkgPod.PodFromUUIDString(getDataDir(), podUUID)
 	if err != nil {
 		stderr.PrintE("problem retrieving pod", err)
-		return 254
+		return exitcode.FromError(err)
 	}
 	defer p.Close()
 
@@ -194,6 +195,14 @@ func printStatus(p *pkgPod.Pod) error {
 	state := p.State()
 	stdout.Printf("state=%s", state)
 
+	name, err := p.Name()
+	if err != nil {
+		return fmt.Errorf("unable to get name for pod %q: %v", p.UUID, err)
+	}
+	if name != "" {
+		stdout.Printf("name=%s", name)
+	}
+
 	created, err := p.CreationTime()
 	if err != nil {
 		return fmt.Errorf("unable to get creation time for pod %q: %v", p.UUID, err)
@@ -71,6 +71,7 @@ func runStop(cmd *cobra.Command, args []string) (exit int) {
 			continue
 		}
 		defer p.Close()
+		auditPod(p)
 
 		if p.IsAfterRun() {
 			stdout.Printf("pod %q is already stopped", p.UUID)
@@ -0,0 +1,65 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"errors"
+
+	"github.com/hashicorp/errwrap"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+	stage1types "github.com/rkt/rkt/stage1/common/types"
+)
+
+// podStorageLimit returns the disk usage limit in bytes of the pod, the one
+// of --storage-limit or else the sum of the storage isolators of its apps.
+// It is 0 when the pod is not limited.
+func podStorageLimit(p *pkgPod.Pod) (uint64, error) {
+	if flagStorageLimit != "" {
+		return stage1types.ParseStorageLimit(flagStorageLimit)
+	}
+
+	_, manifest, err := p.PodManifest()
+	if err != nil {
+		return 0, err
+	}
+	var total uint64
+	for _, ra := range manifest.Apps {
+		if ra.App == nil {
+			continue
+		}
+		limit, err := stage1types.IsolatorStorage(ra.App.Isolators)
+		if err != nil {
+			return 0, err
+		}
+		if limit != nil {
+			total += *limit
+		}
+	}
+	return total, nil
+}
+
+// setPodStorageLimit limits the disk usage of a prepared pod, if requested.
+func setPodStorageLimit(p *pkgPod.Pod) error {
+	limit, err := podStorageLimit(p)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error getting the pod storage limit"), err)
+	}
+	if limit == 0 {
+		return nil
+	}
+	return p.SetStorageLimit(limit)
+}
@@ -0,0 +1,133 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+//+build linux
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/rkt/rkt/pkg/exitcode"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+	"github.com/rkt/rkt/stage0"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cmdUpdate = &cobra.Command{
+		Use:   "update [--app=NAME] [--memory=LIMIT] [--cpus=LIMIT] UUID",
+		Short: "Change the resource limits of a running pod",
+		Long: `Changes the memory and CPU limits of the apps of a running pod, or of the given app.
+
+The new limits are recorded in the pod manifest and apply to the restarted apps as well.
+With the kvm flavor, they apply within the virtual machine, whose memory and vCPUs are set when it starts.`,
+		Run: ensureSuperuser(runWrapper(runUpdate)),
+	}
+	flagUpdateMemory string
+	flagUpdateCPUs   string
+)
+
+func init() {
+	cmdRkt.AddCommand(cmdUpdate)
+	cmdUpdate.Flags().StringVar(&flagAppName, "app", "", "app to update, all the apps of the pod if empty")
+	cmdUpdate.Flags().StringVar(&flagUpdateMemory, "memory", "", "memory limit (example: '--memory=16Mi', '--memory=50M', '--memory=1G')")
+	cmdUpdate.Flags().StringVar(&flagUpdateCPUs, "cpus", "", "CPU limit, in cores (example: '--cpus=2', '--cpus=500m')")
+}
+
+func runUpdate(cmd *cobra.Command, args []string) (exit int) {
+	if len(args) != 1 {
+		cmd.Usage()
+		return 254
+	}
+	if flagUpdateMemory == "" && flagUpdateCPUs == "" {
+		stderr.Print("must provide --memory or --cpus")
+		return 254
+	}
+
+	cfg := stage0.UpdateConfig{}
+	if flagAppName != "" {
+		appName, err := types.NewACName(flagAppName)
+		if err != nil {
+			stderr.PrintE("invalid app name", err)
+			return 254
+		}
+		cfg.AppName = appName
+	}
+	if flagUpdateMemory != "" {
+		memory, err := types.NewResourceMemoryIsolator(flagUpdateMemory, flagUpdateMemory)
+		if err != nil {
+			stderr.PrintE("invalid memory limit", err)
+			return 254
+		}
+		// Same lower bound as --memory of rkt run, see appMemoryLimit
+		if memory.Limit().Value() < 4096 {
+			stderr.Printf("memory limit of %d bytes too low. Try a reasonable value, such as --memory=16M", memory.Limit().Value())
+			return 254
+		}
+		cfg.Memory = memory
+	}
+	if flagUpdateCPUs != "" {
+		cpu, err := types.NewResourceCPUIsolator(flagUpdateCPUs, flagUpdateCPUs)
+		if err != nil {
+			stderr.PrintE("invalid CPU limit", err)
+			return 254
+		}
+		if cpu.Limit().MilliValue() < 10 {
+			stderr.Printf("CPU limit %s too low, the minimum is 10m", flagUpdateCPUs)
+			return 254
+		}
+		cfg.CPU = cpu
+	}
+
+	p, err := pkgPod.PodFromUUIDString(getDataDir(), args[0])
+	if err != nil {
+		stderr.PrintE("problem retrieving pod", err)
+		return exitcode.FromError(err)
+	}
+	defer p.Close()
+	auditPod(p)
+
+	if p.State() != pkgPod.Running {
+		stderr.Printf("pod %q isn't currently running", p.UUID)
+		return 254
+	}
+
+	podPID, err := p.ContainerPid1()
+	if err != nil {
+		stderr.PrintE(fmt.Sprintf("unable to determine the pid for pod %q", p.UUID), err)
+		return 254
+	}
+
+	cfg.CommonConfig = &stage0.CommonConfig{
+		DataDir: getDataDir(),
+		UUID:    p.UUID,
+		Debug:   globalFlags.Debug,
+	}
+	cfg.PodPath = p.Path()
+	cfg.PodPID = podPID
+
+	if globalFlags.Debug {
+		stage0.InitDebug()
+	}
+
+	if err := stage0.UpdateResources(cfg); err != nil {
+		stderr.PrintE("error updating the pod resources", err)
+		return 254
+	}
+
+	return 0
+}
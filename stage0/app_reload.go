@@ -0,0 +1,106 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+//+build linux
+
+package stage0
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/hashicorp/errwrap"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+)
+
+type ReloadConfig struct {
+	*CommonConfig
+	PodPath string
+	PodPID  int
+	AppName *types.ACName
+	Signal  syscall.Signal
+	// Env are the environment variables to set for the app, in the form
+	// name=value
+	Env []string
+}
+
+// ReloadApp sends the reload signal to a running app, after updating its
+// environment if requested. The environment is recorded in the pod manifest,
+// and seen by the app from its next start.
+func ReloadApp(cfg ReloadConfig) error {
+	pod, err := pkgPod.PodFromUUIDString(cfg.DataDir, cfg.UUID.String())
+	if err != nil {
+		return errwrap.Wrap(errors.New("error loading pod"), err)
+	}
+	defer pod.Close()
+
+	if pod.State() != pkgPod.Running {
+		return errors.New("pod is not running")
+	}
+
+	debug("locking pod manifest")
+	if err := pod.ExclusiveLockManifest(); err != nil {
+		return errwrap.Wrap(errors.New("failed to lock pod manifest"), err)
+	}
+	defer pod.UnlockManifest()
+
+	_, pm, err := pod.PodManifest()
+	if err != nil {
+		return errwrap.Wrap(errors.New("error loading pod manifest"), err)
+	}
+
+	ra := pm.Apps.Get(*cfg.AppName)
+	if ra == nil {
+		return fmt.Errorf("error: nonexistent app %q", *cfg.AppName)
+	}
+	if ra.App == nil {
+		return fmt.Errorf("error: app %q has no app section", ra.Name)
+	}
+
+	args := []string{
+		fmt.Sprintf("--debug=%t", cfg.Debug),
+		fmt.Sprintf("--uuid=%s", cfg.UUID),
+		fmt.Sprintf("--app=%s", cfg.AppName),
+		fmt.Sprintf("--signal=%d", cfg.Signal),
+	}
+	for _, e := range cfg.Env {
+		args = append(args, "--env="+e)
+	}
+
+	ce := CrossingEntrypoint{
+		PodPath:        cfg.PodPath,
+		PodPID:         cfg.PodPID,
+		AppName:        cfg.AppName.String(),
+		EntrypointName: appReloadEntrypoint,
+		EntrypointArgs: args,
+		Interactive:    false,
+	}
+	if err := ce.Run(); err != nil {
+		return err
+	}
+
+	if len(cfg.Env) == 0 {
+		return nil
+	}
+	mergeEnvs(&ra.App.Environment, cfg.Env, true)
+	debug("recording the environment in the pod manifest")
+	if err := pod.WriteManifest(pm, cfg.PodPath); err != nil {
+		return errwrap.Wrap(errors.New("error recording the environment in the pod manifest"), err)
+	}
+
+	return nil
+}
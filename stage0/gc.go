@@ -34,7 +34,7 @@ func GC(pdir string, uuid *types.UUID, localConfig string) error {
 	err := unregisterPod(pdir, uuid)
 	if err != nil {
 		// Probably not worth abandoning the rest
-		log.PrintE("warning: could not unregister pod with metadata service", err)
+		log.WarnE("could not unregister pod with metadata service", err)
 	}
 
 	stage1Path := common.Stage1RootfsPath(pdir)
@@ -35,10 +35,12 @@ const (
 	stopEntrypoint   = "coreos.com/rkt/stage1/stop"
 	attachEntrypoint = "coreos.com/rkt/stage1/attach"
 
-	appAddEntrypoint   = "coreos.com/rkt/stage1/app/add"
-	appRmEntrypoint    = "coreos.com/rkt/stage1/app/rm"
-	appStartEntrypoint = "coreos.com/rkt/stage1/app/start"
-	appStopEntrypoint  = "coreos.com/rkt/stage1/app/stop"
+	appAddEntrypoint    = "coreos.com/rkt/stage1/app/add"
+	appRmEntrypoint     = "coreos.com/rkt/stage1/app/rm"
+	appStartEntrypoint  = "coreos.com/rkt/stage1/app/start"
+	appStopEntrypoint   = "coreos.com/rkt/stage1/app/stop"
+	appReloadEntrypoint = "coreos.com/rkt/stage1/app/reload"
+	appUpdateEntrypoint = "coreos.com/rkt/stage1/app/update"
 )
 
 const (
@@ -129,3 +131,15 @@ func interfaceVersionSupportsGCLocalConfig(version int) bool {
 func interfaceVersionSupportsIPCMode(version int) bool {
 	return version >= 6
 }
+
+func interfaceVersionSupportsSecrets(version int) bool {
+	return version >= 7
+}
+
+func interfaceVersionSupportsTimeOffsets(version int) bool {
+	return version >= 8
+}
+
+func interfaceVersionSupportsHypervisor(version int) bool {
+	return version >= 9
+}
@@ -0,0 +1,118 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package stage0
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/pkg/tpm"
+)
+
+// Measurement describes where the images of a pod are measured when it
+// starts, so that remote attestation systems can verify which containers a
+// host executed.
+type Measurement struct {
+	PCR      int    // TPM PCR extended with the measurements, 0 to not use the TPM
+	EventLog string // file the measurements are appended to, empty to not log them
+}
+
+// Enabled returns whether the pods are measured.
+func (m Measurement) Enabled() bool {
+	return m.PCR != 0 || m.EventLog != ""
+}
+
+// measurementEvent is an entry of the event log. Event is the string the PCR
+// is extended with, so that the PCR value can be replayed from the log.
+type measurementEvent struct {
+	Time  time.Time `json:"time"`
+	PCR   int       `json:"pcr,omitempty"`
+	Pod   string    `json:"pod"`
+	App   string    `json:"app,omitempty"`
+	Image string    `json:"image"`
+	Event string    `json:"event"`
+}
+
+// measurePod measures the stage1 image and the image of each app of the pod,
+// in this order. Any failure is returned, as a pod whose start was not
+// measured must not start.
+func measurePod(cfg RunConfig, dir string) error {
+	treeStoreID, err := ioutil.ReadFile(filepath.Join(dir, common.Stage1TreeStoreIDFilename))
+	if err != nil {
+		return errwrap.Wrap(errors.New("error reading stage1 treeStoreID"), err)
+	}
+	stage1Hash, err := cfg.TreeStore.GetImageHash(string(treeStoreID))
+	if err != nil {
+		return errwrap.Wrap(errors.New("error getting the stage1 image hash"), err)
+	}
+
+	pod := cfg.UUID.String()
+	events := []measurementEvent{{
+		Pod:   pod,
+		Image: stage1Hash,
+		Event: fmt.Sprintf("rkt: Pod: %s Stage1: %s", pod, stage1Hash),
+	}}
+	for _, app := range cfg.Apps {
+		events = append(events, measurementEvent{
+			Pod:   pod,
+			App:   app.Name.String(),
+			Image: app.Image.ID.String(),
+			Event: fmt.Sprintf("rkt: Pod: %s App: %s Image: %s", pod, app.Name, app.Image.ID),
+		})
+	}
+
+	var log *os.File
+	if cfg.Measurement.EventLog != "" {
+		log, err = os.OpenFile(cfg.Measurement.EventLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+		if err != nil {
+			return errwrap.Wrap(errors.New("error opening the measurement event log"), err)
+		}
+		defer log.Close()
+	}
+
+	for _, e := range events {
+		if cfg.Measurement.PCR != 0 {
+			if err := tpm.ExtendPCR(cfg.Measurement.PCR, e.Event); err != nil {
+				return errwrap.Wrap(fmt.Errorf("error extending PCR %d", cfg.Measurement.PCR), err)
+			}
+			e.PCR = cfg.Measurement.PCR
+		}
+		if log != nil {
+			e.Time = time.Now().UTC()
+			line, err := json.Marshal(e)
+			if err != nil {
+				return errwrap.Wrap(errors.New("error encoding measurement event"), err)
+			}
+			if _, err := log.Write(append(line, '\n')); err != nil {
+				return errwrap.Wrap(errors.New("error writing the measurement event log"), err)
+			}
+		}
+	}
+	if log != nil {
+		if err := log.Sync(); err != nil {
+			return errwrap.Wrap(errors.New("error syncing the measurement event log"), err)
+		}
+	}
+	return nil
+}
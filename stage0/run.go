@@ -51,6 +51,7 @@ import (
 	"github.com/rkt/rkt/pkg/sys"
 	"github.com/rkt/rkt/pkg/tpm"
 	"github.com/rkt/rkt/pkg/user"
+	stage1types "github.com/rkt/rkt/stage1/common/types"
 	"github.com/rkt/rkt/store/imagestore"
 	"github.com/rkt/rkt/store/treestore"
 	"github.com/rkt/rkt/version"
@@ -76,22 +77,26 @@ type PrepareConfig struct {
 // RunConfig defines the configuration parameters needed by Run
 type RunConfig struct {
 	*CommonConfig
-	Net                  common.NetList // pod should have its own network stack
-	LockFd               int            // lock file descriptor
-	Interactive          bool           // whether the pod is interactive or not
-	MDSRegister          bool           // whether to register with metadata service or not
-	Apps                 schema.AppList // applications (prepare gets them via Apps)
-	LocalConfig          string         // Path to local configuration
-	Hostname             string         // hostname of the pod
-	RktGid               int            // group id of the 'rkt' group, -1 ere's no rkt group.
-	DNSConfMode          DNSConfMode    // dns configuration file mode - for stAage1
-	DNSConfig            cnitypes.DNS   // the DNS configuration (nameservers, search, options)
-	InsecureCapabilities bool           // Do not restrict capabilities
-	InsecurePaths        bool           // Do not restrict access to files in sysfs or procfs
-	InsecureSeccomp      bool           // Do not add seccomp restrictions
-	UseOverlay           bool           // run pod with overlay fs
-	HostsEntries         HostsEntries   // The entries in /etc/hosts
-	IPCMode              string         // whether to stay in the host IPC namespace
+	Net                  common.NetList     // pod should have its own network stack
+	LockFd               int                // lock file descriptor
+	Interactive          bool               // whether the pod is interactive or not
+	MDSRegister          bool               // whether to register with metadata service or not
+	Apps                 schema.AppList     // applications (prepare gets them via Apps)
+	LocalConfig          string             // Path to local configuration
+	Hostname             string             // hostname of the pod
+	RktGid               int                // group id of the 'rkt' group, -1 ere's no rkt group.
+	DNSConfMode          DNSConfMode        // dns configuration file mode - for stAage1
+	DNSConfig            cnitypes.DNS       // the DNS configuration (nameservers, search, options)
+	InsecureCapabilities bool               // Do not restrict capabilities
+	InsecurePaths        bool               // Do not restrict access to files in sysfs or procfs
+	InsecureSeccomp      bool               // Do not add seccomp restrictions
+	UseOverlay           bool               // run pod with overlay fs
+	HostsEntries         HostsEntries       // The entries in /etc/hosts
+	IPCMode              string             // whether to stay in the host IPC namespace
+	Secrets              Secrets            // where to get the pod secrets from
+	Measurement          Measurement        // where to measure the pod images to
+	TimeOffsets          common.TimeOffsets // clock offsets of the pod in its own time namespace
+	Hypervisor           string             // hypervisor of the kvm stage1, the default one if empty
 }
 
 // CommonConfig defines the configuration shared by both Run and Prepare
@@ -139,9 +144,7 @@ func InitDebug() {
 }
 
 func debug(format string, i ...interface{}) {
-	if debugEnabled {
-		log.Printf(format, i...)
-	}
+	log.Debugf(format, i...)
 }
 
 // mergeEnvs merges environment variables from env into the current appEnv
@@ -284,11 +287,85 @@ func prepareIsolators(setup *apps.App, app *types.App) error {
 		app.Isolators = append(app.Isolators, isolator)
 	}
 
+	if swap := setup.MemorySwap; swap != "" {
+		isolator, err := stage1types.NewMemorySwapIsolator(swap)
+		if err != nil {
+			return err
+		}
+		app.Isolators.ReplaceIsolatorsByName(*isolator, []types.ACIdentifier{stage1types.MemorySwapIsolatorName})
+	}
+
+	if swappiness := setup.MemorySwappiness; swappiness != nil {
+		isolator, err := stage1types.NewMemorySwappinessIsolator(*swappiness)
+		if err != nil {
+			return err
+		}
+		app.Isolators.ReplaceIsolatorsByName(*isolator, []types.ACIdentifier{stage1types.MemorySwappinessIsolatorName})
+	}
+
+	if len(setup.Hugepages) > 0 {
+		var pages []stage1types.Hugepages
+		for _, s := range setup.Hugepages {
+			h, err := stage1types.ParseHugepages(s)
+			if err != nil {
+				return err
+			}
+			pages = append(pages, h)
+		}
+		if err := stage1types.MergeHugepagesIsolator(&app.Isolators, pages); err != nil {
+			return err
+		}
+	}
+
 	if cpuOverride := setup.CPULimit; cpuOverride != nil {
 		isolator := cpuOverride.AsIsolator()
 		app.Isolators = append(app.Isolators, isolator)
 	}
 
+	if setup.CPUSetCPUs != "" || setup.CPUSetMems != "" {
+		var cpuset stage1types.CPUSet
+		existing, err := stage1types.IsolatorCPUSet(app.Isolators)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			cpuset = *existing
+		}
+		if setup.CPUSetCPUs != "" {
+			cpuset.CPUs = setup.CPUSetCPUs
+		}
+		if setup.CPUSetMems != "" {
+			cpuset.Mems = setup.CPUSetMems
+		}
+		isolator, err := stage1types.NewCPUSetIsolator(cpuset)
+		if err != nil {
+			return err
+		}
+		app.Isolators.ReplaceIsolatorsByName(*isolator, []types.ACIdentifier{stage1types.CPUSetIsolatorName})
+	}
+
+	if setup.CPURTRuntime != nil || setup.CPURTPriority != nil {
+		var rt stage1types.Realtime
+		existing, err := stage1types.IsolatorRealtime(app.Isolators)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			rt = *existing
+		}
+		if setup.CPURTRuntime != nil {
+			rt.Runtime = *setup.CPURTRuntime
+		}
+		if setup.CPURTPriority != nil {
+			rt.Priority = *setup.CPURTPriority
+		}
+		isolator, err := stage1types.NewRealtimeIsolator(rt)
+		if err != nil {
+			return err
+		}
+		app.Isolators.ReplaceIsolatorsByName(*isolator, []types.ACIdentifier{stage1types.RealtimeIsolatorName})
+	}
+
 	if cpuSharesOverride := setup.CPUShares; cpuSharesOverride != nil {
 		isolator := cpuSharesOverride.AsIsolator()
 		app.Isolators.ReplaceIsolatorsByName(isolator, []types.ACIdentifier{types.LinuxCPUSharesName})
@@ -298,6 +375,41 @@ func prepareIsolators(setup *apps.App, app *types.App) error {
 		app.Isolators.ReplaceIsolatorsByName(oomAdjOverride.AsIsolator(), []types.ACIdentifier{types.LinuxOOMScoreAdjName})
 	}
 
+	if nnp := setup.NoNewPrivileges; nnp != nil {
+		var isolator types.Isolator
+		raw := fmt.Sprintf(`{"name": %q, "value": %t}`, types.LinuxNoNewPrivilegesName, *nnp)
+		if err := json.Unmarshal([]byte(raw), &isolator); err != nil {
+			return err
+		}
+		app.Isolators.ReplaceIsolatorsByName(isolator, []types.ACIdentifier{types.LinuxNoNewPrivilegesName})
+	}
+
+	if len(setup.MaskedPaths) > 0 {
+		if err := stage1types.MergePathsIsolator(&app.Isolators, stage1types.MaskedPathsIsolatorName, setup.MaskedPaths); err != nil {
+			return err
+		}
+	}
+
+	if len(setup.ReadOnlyPaths) > 0 {
+		if err := stage1types.MergePathsIsolator(&app.Isolators, stage1types.ReadOnlyPathsIsolatorName, setup.ReadOnlyPaths); err != nil {
+			return err
+		}
+	}
+
+	if len(setup.RLimits) > 0 {
+		var limits []stage1types.RLimit
+		for _, s := range setup.RLimits {
+			l, err := stage1types.ParseRLimit(s)
+			if err != nil {
+				return err
+			}
+			limits = append(limits, l)
+		}
+		if err := stage1types.MergeRLimitsIsolator(&app.Isolators, limits); err != nil {
+			return err
+		}
+	}
+
 	if setup.CapsRetain != nil && setup.CapsRemove != nil {
 		return fmt.Errorf("error: cannot use both --caps-retain and --caps-remove on the same image")
 	}
@@ -629,7 +741,7 @@ func Run(cfg RunConfig, dir string, dataDir string) {
 		if interfaceVersionSupportsHostname(s1v) {
 			args = append(args, "--hostname="+cfg.Hostname)
 		} else {
-			log.Printf("warning: --hostname option is not supported by stage1")
+			log.Warnf("--hostname option is not supported by stage1")
 		}
 	}
 
@@ -637,7 +749,7 @@ func Run(cfg RunConfig, dir string, dataDir string) {
 		if interfaceVersionSupportsDNSConfMode(s1v) {
 			args = append(args, fmt.Sprintf("--dns-conf-mode=resolv=%s,hosts=%s", cfg.DNSConfMode.Resolv, cfg.DNSConfMode.Hosts))
 		} else {
-			log.Printf("warning: --dns-conf-mode option not supported by stage1")
+			log.Warnf("--dns-conf-mode option not supported by stage1")
 		}
 	}
 
@@ -670,8 +782,35 @@ func Run(cfg RunConfig, dir string, dataDir string) {
 		if interfaceVersionSupportsIPCMode(s1v) {
 			args = append(args, "--ipc="+cfg.IPCMode)
 		} else {
-			log.Printf("warning: --ipc option is not supported by stage1")
+			log.Warnf("--ipc option is not supported by stage1")
+		}
+	}
+
+	if !cfg.Secrets.Empty() {
+		// Secrets are never silently dropped, better to fail
+		if !interfaceVersionSupportsSecrets(s1v) {
+			log.Fatalln("stage1 does not support secrets")
+		}
+		debug("Setting up secrets")
+		if err := setupSecrets(cfg.Secrets, dir, privateUsers); err != nil {
+			log.FatalE("error setting up secrets", err)
 		}
+		args = append(args, "--secrets")
+	}
+
+	if !cfg.TimeOffsets.Empty() {
+		// Running with the clocks of the host instead could go unnoticed
+		if !interfaceVersionSupportsTimeOffsets(s1v) {
+			log.Fatalln("stage1 does not support time namespaces")
+		}
+		args = append(args, "--time-offset="+cfg.TimeOffsets.String())
+	}
+
+	if cfg.Hypervisor != "" {
+		if !interfaceVersionSupportsHypervisor(s1v) {
+			log.Fatalln("stage1 does not support choosing a hypervisor")
+		}
+		args = append(args, "--hypervisor="+cfg.Hypervisor)
 	}
 
 	args = append(args, cfg.UUID.String())
@@ -681,6 +820,13 @@ func Run(cfg RunConfig, dir string, dataDir string) {
 		log.Fatalf("error clearing FD_CLOEXEC on lock fd")
 	}
 
+	if cfg.Measurement.Enabled() {
+		debug("Measuring the pod images")
+		if err := measurePod(cfg, dir); err != nil {
+			log.FatalE("error measuring the pod", err)
+		}
+	}
+
 	tpmEvent := fmt.Sprintf("rkt: Rootfs: %s Manifest: %s Stage1 args: %s", cfg.CommonConfig.RootHash, cfg.CommonConfig.ManifestData, strings.Join(args, " "))
 	// If there's no TPM available or there's a failure for some other
 	// reason, ignore it and continue anyway. Long term we'll want policy
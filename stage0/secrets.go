@@ -0,0 +1,148 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package stage0
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"syscall"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/pkg/user"
+)
+
+// maxSecretsSize is the size of the tmpfs holding the pod secrets.
+const maxSecretsSize = "16m"
+
+// Secrets describes where the secrets of a pod come from. Secrets are only
+// kept in memory, in a tmpfs mounted in the pod directory, and never end up in
+// the pod manifest.
+type Secrets struct {
+	Dir    string // host directory whose regular files are copied as secrets
+	Helper string // executable invoked with the secrets directory as argument, it's expected to write the secrets there
+}
+
+// Empty returns whether no secrets source is configured.
+func (s Secrets) Empty() bool {
+	return s.Dir == "" && s.Helper == ""
+}
+
+// setupSecrets mounts a tmpfs in the pod directory and populates it with the
+// configured secrets. On error the tmpfs is unmounted.
+func setupSecrets(s Secrets, dir string, privateUsers string) (err error) {
+	uidRange := user.NewBlankUidRange()
+	if privateUsers != "" {
+		if err := uidRange.Deserialize([]byte(privateUsers)); err != nil {
+			return errwrap.Wrap(errors.New("error parsing private users range"), err)
+		}
+	}
+	uid, gid, err := uidRange.ShiftRange(0, 0)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error shifting secrets owner"), err)
+	}
+
+	secretsDir := common.SecretsPath(dir)
+	if err := os.MkdirAll(secretsDir, 0700); err != nil {
+		return errwrap.Wrap(errors.New("error creating secrets directory"), err)
+	}
+	opts := fmt.Sprintf("mode=0755,size=%s,uid=%d,gid=%d", maxSecretsSize, uid, gid)
+	if err := syscall.Mount("tmpfs", secretsDir, "tmpfs", syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, opts); err != nil {
+		return errwrap.Wrap(errors.New("error mounting secrets tmpfs"), err)
+	}
+	defer func() {
+		if err != nil {
+			syscall.Unmount(secretsDir, syscall.MNT_DETACH)
+		}
+	}()
+
+	if s.Dir != "" {
+		if err := copySecrets(s.Dir, secretsDir, int(uid), int(gid)); err != nil {
+			return err
+		}
+	}
+	if s.Helper != "" {
+		debug("Executing secrets helper %q", s.Helper)
+		cmd := exec.Command(s.Helper, secretsDir)
+		cmd.Stdout = os.Stderr
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return errwrap.Wrap(fmt.Errorf("secrets helper %q failed", s.Helper), err)
+		}
+		if uid != 0 || gid != 0 {
+			if err := chownSecrets(secretsDir, int(uid), int(gid)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// copySecrets copies the regular files in src to dest, keeping their
+// permissions. Subdirectories and special files are ignored.
+func copySecrets(src, dest string, uid, gid int) error {
+	fis, err := ioutil.ReadDir(src)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error reading secrets directory"), err)
+	}
+	for _, fi := range fis {
+		if !fi.Mode().IsRegular() {
+			debug("Skipping non regular secret file %q", fi.Name())
+			continue
+		}
+		if err := copySecret(filepath.Join(src, fi.Name()), filepath.Join(dest, fi.Name()), fi.Mode().Perm(), uid, gid); err != nil {
+			return errwrap.Wrap(fmt.Errorf("error copying secret %q", fi.Name()), err)
+		}
+	}
+	return nil
+}
+
+func copySecret(src, dest string, perm os.FileMode, uid, gid int) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	if err := out.Chmod(perm); err != nil {
+		return err
+	}
+	return out.Chown(uid, gid)
+}
+
+func chownSecrets(dir string, uid, gid int) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		return os.Lchown(path, uid, gid)
+	})
+}
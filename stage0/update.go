@@ -0,0 +1,123 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package stage0
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/appc/spec/schema"
+	"github.com/appc/spec/schema/types"
+	"github.com/hashicorp/errwrap"
+	pkgPod "github.com/rkt/rkt/pkg/pod"
+)
+
+type UpdateConfig struct {
+	*CommonConfig
+	PodPath string
+	PodPID  int
+	// AppName is the app to update, or nil for all the apps of the pod
+	AppName *types.ACName
+	Memory  *types.ResourceMemory
+	CPU     *types.ResourceCPU
+}
+
+// UpdateResources changes the resource limits of the apps of a running pod,
+// then records them in the pod manifest, so that they survive the restart
+// of the apps.
+func UpdateResources(cfg UpdateConfig) error {
+	if cfg.Memory == nil && cfg.CPU == nil {
+		return errors.New("no resource limit to update")
+	}
+
+	pod, err := pkgPod.PodFromUUIDString(cfg.DataDir, cfg.UUID.String())
+	if err != nil {
+		return errwrap.Wrap(errors.New("error loading pod"), err)
+	}
+	defer pod.Close()
+
+	if pod.State() != pkgPod.Running {
+		return errors.New("pod is not running")
+	}
+
+	debug("locking pod manifest")
+	if err := pod.ExclusiveLockManifest(); err != nil {
+		return errwrap.Wrap(errors.New("failed to lock pod manifest"), err)
+	}
+	defer pod.UnlockManifest()
+
+	_, pm, err := pod.PodManifest()
+	if err != nil {
+		return errwrap.Wrap(errors.New("error loading pod manifest"), err)
+	}
+
+	var ras []*schema.RuntimeApp
+	if cfg.AppName != nil {
+		ra := pm.Apps.Get(*cfg.AppName)
+		if ra == nil {
+			return fmt.Errorf("error: nonexistent app %q", *cfg.AppName)
+		}
+		ras = append(ras, ra)
+	} else {
+		for i := range pm.Apps {
+			ras = append(ras, &pm.Apps[i])
+		}
+	}
+
+	for _, ra := range ras {
+		if ra.App == nil {
+			return fmt.Errorf("error: app %q has no app section", ra.Name)
+		}
+
+		args := []string{
+			fmt.Sprintf("--debug=%t", cfg.Debug),
+			fmt.Sprintf("--app=%s", ra.Name),
+		}
+		if cfg.Memory != nil {
+			args = append(args, fmt.Sprintf("--memory=%d", cfg.Memory.Limit().Value()))
+		}
+		if cfg.CPU != nil {
+			args = append(args, fmt.Sprintf("--cpu=%d", cfg.CPU.Limit().MilliValue()))
+		}
+
+		ce := CrossingEntrypoint{
+			PodPath:        cfg.PodPath,
+			PodPID:         cfg.PodPID,
+			AppName:        ra.Name.String(),
+			EntrypointName: appUpdateEntrypoint,
+			EntrypointArgs: args,
+			Interactive:    false,
+		}
+		if err := ce.Run(); err != nil {
+			return errwrap.Wrap(fmt.Errorf("error updating the resources of app %q", ra.Name), err)
+		}
+
+		if cfg.Memory != nil {
+			ra.App.Isolators.ReplaceIsolatorsByName(cfg.Memory.AsIsolator(), []types.ACIdentifier{types.ResourceMemoryName})
+		}
+		if cfg.CPU != nil {
+			ra.App.Isolators.ReplaceIsolatorsByName(cfg.CPU.AsIsolator(), []types.ACIdentifier{types.ResourceCPUName})
+		}
+	}
+
+	debug("recording the resources in the pod manifest")
+	if err := pod.WriteManifest(pm, cfg.PodPath); err != nil {
+		return errwrap.Wrap(errors.New("error recording the resources in the pod manifest"), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+//+build linux
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/common"
+	rktlog "github.com/rkt/rkt/pkg/log"
+	stage1common "github.com/rkt/rkt/stage1/common"
+	stage1types "github.com/rkt/rkt/stage1/common/types"
+	stage1initcommon "github.com/rkt/rkt/stage1/init/common"
+
+	"github.com/appc/spec/schema/types"
+)
+
+// envList is the list of the environment variables given with --env
+type envList []string
+
+func (e *envList) String() string {
+	return strings.Join(*e, " ")
+}
+
+func (e *envList) Set(s string) error {
+	if !strings.Contains(s, "=") {
+		return fmt.Errorf("environment variable %q must be specified as name=value", s)
+	}
+	*e = append(*e, s)
+	return nil
+}
+
+var (
+	flagApp    string
+	flagUUID   string
+	flagSignal int
+	flagEnv    envList
+	debug      bool
+	log        *rktlog.Logger
+	diag       *rktlog.Logger
+)
+
+func init() {
+	flag.StringVar(&flagApp, "app", "", "Application name")
+	flag.StringVar(&flagUUID, "uuid", "", "Pod UUID")
+	flag.IntVar(&flagSignal, "signal", 1, "Signal to send to the main process of the app")
+	flag.Var(&flagEnv, "env", "Environment variable to set for the app, in the form name=value")
+	flag.BoolVar(&debug, "debug", false, "Run in debug mode")
+}
+
+func main() {
+	flag.Parse()
+
+	stage1initcommon.InitDebug(debug)
+
+	log, diag, _ = rktlog.NewLogSet("app-reload", debug)
+	if !debug {
+		diag.SetOutput(ioutil.Discard)
+	}
+
+	uuid, err := types.NewUUID(flagUUID)
+	if err != nil {
+		log.FatalE("UUID is missing or malformed", err)
+	}
+
+	appName, err := types.NewACName(flagApp)
+	if err != nil {
+		log.FatalE("invalid app name", err)
+	}
+
+	if len(flagEnv) > 0 {
+		if err := updateEnvFile(uuid, appName, flagEnv); err != nil {
+			log.FatalE("cannot update the environment of the app", err)
+		}
+	}
+
+	// Only the main process gets the signal, like with ExecReload=kill -HUP $MAINPID
+	enterCmd := stage1common.PrepareEnterCmd(false)
+
+	args := enterCmd
+	args = append(args, "/usr/bin/systemctl")
+	args = append(args, "kill")
+	args = append(args, "--kill-who=main")
+	args = append(args, "--signal="+strconv.Itoa(flagSignal))
+	args = append(args, appName.String())
+
+	cmd := exec.Cmd{
+		Path: args[0],
+		Args: args,
+	}
+
+	if err := cmd.Run(); err != nil {
+		status, err := common.GetExitStatus(err)
+		if err != nil {
+			os.Exit(254)
+		}
+		os.Exit(status)
+	}
+
+	os.Exit(0)
+}
+
+// updateEnvFile sets the variables in the environment file of the app, which
+// its unit reads when it starts. The file is updated rather than generated
+// again, to keep the values expanded from the host facts when the pod started.
+func updateEnvFile(uuid *types.UUID, appName *types.ACName, vars []string) error {
+	root, err := os.Getwd()
+	if err != nil {
+		return errwrap.Wrapf("failed to determine current directory", err)
+	}
+	p, err := stage1types.LoadPod(root, uuid, nil)
+	if err != nil {
+		return errwrap.Wrapf("failed to load pod", err)
+	}
+
+	path := stage1initcommon.EnvFilePath(p.Root, *appName)
+	raw, err := common.ReadEnvFileRaw(path)
+	if err != nil {
+		return errwrap.Wrapf("failed to read the environment file", err)
+	}
+
+	var env types.Environment
+	for _, v := range append(raw, vars...) {
+		pair := strings.SplitN(v, "=", 2)
+		if len(pair) != 2 {
+			continue
+		}
+		env.Set(pair[0], pair[1])
+	}
+
+	diag.Printf("setting %v in the environment of app %q", vars, appName)
+	return common.WriteEnvFile(common.ComposeEnviron(env), &p.UidRange, path)
+}
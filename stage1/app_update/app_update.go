@@ -0,0 +1,107 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+
+//+build linux
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/rkt/rkt/common"
+	rktlog "github.com/rkt/rkt/pkg/log"
+	stage1common "github.com/rkt/rkt/stage1/common"
+	stage1initcommon "github.com/rkt/rkt/stage1/init/common"
+
+	"github.com/appc/spec/schema/types"
+)
+
+var (
+	flagApp    string
+	flagMemory int64
+	flagCPU    int64
+	debug      bool
+	log        *rktlog.Logger
+	diag       *rktlog.Logger
+)
+
+func init() {
+	flag.StringVar(&flagApp, "app", "", "Application name")
+	flag.Int64Var(&flagMemory, "memory", 0, "Memory limit in bytes")
+	flag.Int64Var(&flagCPU, "cpu", 0, "CPU limit in millicores")
+	flag.BoolVar(&debug, "debug", false, "Run in debug mode")
+}
+
+func main() {
+	flag.Parse()
+
+	stage1initcommon.InitDebug(debug)
+
+	log, diag, _ = rktlog.NewLogSet("app-update", debug)
+	if !debug {
+		diag.SetOutput(ioutil.Discard)
+	}
+
+	appName, err := types.NewACName(flagApp)
+	if err != nil {
+		log.FatalE("invalid app name", err)
+	}
+
+	// The properties are the ones of the unit of the app, see
+	// stage1/init/common/units.go. They are changed at runtime only, the
+	// unit files being generated again from the pod manifest.
+	var props []string
+	if flagMemory > 0 {
+		props = append(props, "MemoryLimit="+strconv.FormatInt(flagMemory, 10))
+	}
+	if flagCPU > 0 {
+		if flagCPU > stage1initcommon.MaxMilliValue {
+			log.Fatalf("cpu limit exceeds the maximum millivalue: %d", flagCPU)
+		}
+		props = append(props, "CPUQuota="+strconv.FormatInt(flagCPU/10, 10)+"%")
+	}
+	if len(props) == 0 {
+		log.Fatal("no resource limit to update")
+	}
+
+	enterCmd := stage1common.PrepareEnterCmd(false)
+
+	args := enterCmd
+	args = append(args, "/usr/bin/systemctl")
+	args = append(args, "set-property")
+	args = append(args, "--runtime")
+	args = append(args, appName.String())
+	args = append(args, props...)
+
+	diag.Printf("updating app %q: %v", appName, props)
+	cmd := exec.Cmd{
+		Path: args[0],
+		Args: args,
+	}
+
+	if err := cmd.Run(); err != nil {
+		status, err := common.GetExitStatus(err)
+		if err != nil {
+			os.Exit(254)
+		}
+		os.Exit(status)
+	}
+
+	os.Exit(0)
+}
@@ -0,0 +1,697 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/appc/spec/schema/types"
+	"github.com/appc/spec/schema/types/resource"
+	"github.com/hashicorp/errwrap"
+)
+
+// rkt-specific isolators, understood by the stage1 flavors but not part
+// of the appc spec.
+const (
+	// MaskedPathsIsolatorName makes paths inside the app rootfs
+	// inaccessible to the app.
+	MaskedPathsIsolatorName = "coreos.com/rkt/linux/masked-paths"
+	// ReadOnlyPathsIsolatorName makes paths inside the app rootfs
+	// read-only for the app.
+	ReadOnlyPathsIsolatorName = "coreos.com/rkt/linux/readonly-paths"
+)
+
+type pathsIsolatorValue struct {
+	Paths []string `json:"paths"`
+}
+
+// NewPathsIsolator returns an isolator of the given name holding a set
+// of absolute paths, like the masked paths or the read-only paths
+// isolators.
+func NewPathsIsolator(name types.ACIdentifier, paths []string) (*types.Isolator, error) {
+	for _, p := range paths {
+		if !filepath.IsAbs(p) {
+			return nil, fmt.Errorf("path %q in isolator %q is not absolute", p, name)
+		}
+	}
+	return newIsolator(name, pathsIsolatorValue{Paths: paths})
+}
+
+// newIsolator returns an isolator of the given name with the given
+// value, going through JSON like the isolators of the manifests.
+func newIsolator(name types.ACIdentifier, v interface{}) (*types.Isolator, error) {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(struct {
+		Name  types.ACIdentifier `json:"name"`
+		Value json.RawMessage    `json:"value"`
+	}{name, value})
+	if err != nil {
+		return nil, err
+	}
+	var isolator types.Isolator
+	if err := json.Unmarshal(raw, &isolator); err != nil {
+		return nil, err
+	}
+	return &isolator, nil
+}
+
+// IsolatorPaths returns the paths of all the isolators of the given
+// name, in order.
+func IsolatorPaths(isolators types.Isolators, name types.ACIdentifier) ([]string, error) {
+	var paths []string
+	for _, isolator := range isolators {
+		if isolator.Name != name || isolator.ValueRaw == nil {
+			continue
+		}
+		var v pathsIsolatorValue
+		if err := json.Unmarshal(*isolator.ValueRaw, &v); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("error parsing isolator %q", name), err)
+		}
+		for _, p := range v.Paths {
+			if !filepath.IsAbs(p) {
+				return nil, fmt.Errorf("path %q in isolator %q is not absolute", p, name)
+			}
+		}
+		paths = append(paths, v.Paths...)
+	}
+	return paths, nil
+}
+
+// MergePathsIsolator adds the given paths to the isolator of the given
+// name, creating it if the app has none.
+func MergePathsIsolator(isolators *types.Isolators, name types.ACIdentifier, paths []string) error {
+	existing, err := IsolatorPaths(*isolators, name)
+	if err != nil {
+		return err
+	}
+	seen := make(map[string]struct{}, len(existing))
+	for _, p := range existing {
+		seen[p] = struct{}{}
+	}
+	merged := existing
+	for _, p := range paths {
+		if _, ok := seen[p]; !ok {
+			seen[p] = struct{}{}
+			merged = append(merged, p)
+		}
+	}
+	isolator, err := NewPathsIsolator(name, merged)
+	if err != nil {
+		return err
+	}
+	isolators.ReplaceIsolatorsByName(*isolator, []types.ACIdentifier{name})
+	return nil
+}
+
+// RLimitsIsolatorName sets resource limits of the app, as with
+// setrlimit(2).
+const RLimitsIsolatorName = "coreos.com/rkt/linux/rlimits"
+
+// RLimitInfinity is the value of an unlimited resource.
+const RLimitInfinity = ^uint64(0)
+
+// RLimitResources maps the names of the resource limits, as in
+// getrlimit(2), to their numbers on Linux.
+var RLimitResources = map[string]int{
+	"RLIMIT_CPU":        0,
+	"RLIMIT_FSIZE":      1,
+	"RLIMIT_DATA":       2,
+	"RLIMIT_STACK":      3,
+	"RLIMIT_CORE":       4,
+	"RLIMIT_RSS":        5,
+	"RLIMIT_NPROC":      6,
+	"RLIMIT_NOFILE":     7,
+	"RLIMIT_MEMLOCK":    8,
+	"RLIMIT_AS":         9,
+	"RLIMIT_LOCKS":      10,
+	"RLIMIT_SIGPENDING": 11,
+	"RLIMIT_MSGQUEUE":   12,
+	"RLIMIT_NICE":       13,
+	"RLIMIT_RTPRIO":     14,
+	"RLIMIT_RTTIME":     15,
+}
+
+// RLimit is a soft and a hard limit of a resource.
+type RLimit struct {
+	Resource string `json:"resource"`
+	Soft     uint64 `json:"soft"`
+	Hard     uint64 `json:"hard"`
+}
+
+type rlimitsIsolatorValue struct {
+	Limits []RLimit `json:"limits"`
+}
+
+// ParseRLimit parses a resource limit in the form RESOURCE=SOFT[:HARD],
+// where RESOURCE is the name of the limit with or without the RLIMIT_
+// prefix, in any case, like nofile or RLIMIT_NOFILE, and the limits are
+// numbers or "unlimited". The hard limit defaults to the soft one.
+func ParseRLimit(s string) (RLimit, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return RLimit{}, fmt.Errorf("invalid resource limit %q, expected RESOURCE=SOFT[:HARD]", s)
+	}
+	resource := strings.ToUpper(parts[0])
+	if !strings.HasPrefix(resource, "RLIMIT_") {
+		resource = "RLIMIT_" + resource
+	}
+	values := strings.SplitN(parts[1], ":", 2)
+	soft, err := parseRLimitValue(values[0])
+	if err != nil {
+		return RLimit{}, errwrap.Wrap(fmt.Errorf("invalid resource limit %q", s), err)
+	}
+	hard := soft
+	if len(values) == 2 {
+		if hard, err = parseRLimitValue(values[1]); err != nil {
+			return RLimit{}, errwrap.Wrap(fmt.Errorf("invalid resource limit %q", s), err)
+		}
+	}
+	l := RLimit{Resource: resource, Soft: soft, Hard: hard}
+	if err := l.validate(); err != nil {
+		return RLimit{}, err
+	}
+	return l, nil
+}
+
+func parseRLimitValue(s string) (uint64, error) {
+	switch s {
+	case "unlimited", "infinity":
+		return RLimitInfinity, nil
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func (l RLimit) validate() error {
+	if _, ok := RLimitResources[l.Resource]; !ok {
+		return fmt.Errorf("unknown resource limit %q", l.Resource)
+	}
+	if l.Soft > l.Hard {
+		return fmt.Errorf("soft limit of %s greater than its hard limit", l.Resource)
+	}
+	return nil
+}
+
+// IsolatorRLimits returns the resource limits of the rlimits isolators,
+// with at most one limit per resource: the last one set.
+func IsolatorRLimits(isolators types.Isolators) ([]RLimit, error) {
+	var limits []RLimit
+	for _, isolator := range isolators {
+		if isolator.Name != RLimitsIsolatorName || isolator.ValueRaw == nil {
+			continue
+		}
+		var v rlimitsIsolatorValue
+		if err := json.Unmarshal(*isolator.ValueRaw, &v); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("error parsing isolator %q", RLimitsIsolatorName), err)
+		}
+		for _, l := range v.Limits {
+			if err := l.validate(); err != nil {
+				return nil, err
+			}
+		}
+		limits = mergeRLimits(limits, v.Limits)
+	}
+	return limits, nil
+}
+
+// MergeRLimitsIsolator sets the given resource limits in the rlimits
+// isolator, creating it if the app has none. The limits of the
+// resources already set are overridden.
+func MergeRLimitsIsolator(isolators *types.Isolators, limits []RLimit) error {
+	existing, err := IsolatorRLimits(*isolators)
+	if err != nil {
+		return err
+	}
+	for _, l := range limits {
+		if err := l.validate(); err != nil {
+			return err
+		}
+	}
+	isolator, err := newIsolator(RLimitsIsolatorName, rlimitsIsolatorValue{Limits: mergeRLimits(existing, limits)})
+	if err != nil {
+		return err
+	}
+	isolators.ReplaceIsolatorsByName(*isolator, []types.ACIdentifier{RLimitsIsolatorName})
+	return nil
+}
+
+func mergeRLimits(limits, overrides []RLimit) []RLimit {
+	for _, o := range overrides {
+		found := false
+		for i := range limits {
+			if limits[i].Resource == o.Resource {
+				limits[i] = o
+				found = true
+			}
+		}
+		if !found {
+			limits = append(limits, o)
+		}
+	}
+	return limits
+}
+
+// Memory isolators complementing resource/memory.
+const (
+	// MemorySwapIsolatorName limits the swap the app can use, on top
+	// of its memory limit. Its value is like the one of
+	// resource/memory, in the Kubernetes resource model: {"limit": "512M"}.
+	MemorySwapIsolatorName = "coreos.com/rkt/linux/memory-swap"
+	// MemorySwappinessIsolatorName sets the swappiness of the memory
+	// cgroup of the app, from 0 to 100.
+	MemorySwappinessIsolatorName = "coreos.com/rkt/linux/memory-swappiness"
+)
+
+type memorySwapIsolatorValue struct {
+	Limit string `json:"limit"`
+}
+
+// NewMemorySwapIsolator returns a memory swap isolator with the given
+// limit, like "512M".
+func NewMemorySwapIsolator(limit string) (*types.Isolator, error) {
+	if _, err := parseMemorySwap(limit); err != nil {
+		return nil, err
+	}
+	return newIsolator(MemorySwapIsolatorName, memorySwapIsolatorValue{Limit: limit})
+}
+
+func parseMemorySwap(limit string) (uint64, error) {
+	q, err := resource.ParseQuantity(limit)
+	if err != nil {
+		return 0, errwrap.Wrap(fmt.Errorf("invalid swap limit %q", limit), err)
+	}
+	if q.Value() < 0 {
+		return 0, fmt.Errorf("invalid swap limit %q", limit)
+	}
+	return uint64(q.Value()), nil
+}
+
+// IsolatorMemorySwap returns the swap limit in bytes of the last memory
+// swap isolator, or nil if there is none.
+func IsolatorMemorySwap(isolators types.Isolators) (*uint64, error) {
+	isolator := isolators.GetByName(MemorySwapIsolatorName)
+	if isolator == nil || isolator.ValueRaw == nil {
+		return nil, nil
+	}
+	var v memorySwapIsolatorValue
+	if err := json.Unmarshal(*isolator.ValueRaw, &v); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error parsing isolator %q", MemorySwapIsolatorName), err)
+	}
+	limit, err := parseMemorySwap(v.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+// NewMemorySwappinessIsolator returns a memory swappiness isolator with
+// the given swappiness.
+func NewMemorySwappinessIsolator(swappiness int) (*types.Isolator, error) {
+	if err := validateSwappiness(swappiness); err != nil {
+		return nil, err
+	}
+	return newIsolator(MemorySwappinessIsolatorName, swappiness)
+}
+
+func validateSwappiness(swappiness int) error {
+	if swappiness < 0 || swappiness > 100 {
+		return fmt.Errorf("invalid swappiness %d, must be between 0 and 100", swappiness)
+	}
+	return nil
+}
+
+// IsolatorMemorySwappiness returns the swappiness of the last memory
+// swappiness isolator, or nil if there is none.
+func IsolatorMemorySwappiness(isolators types.Isolators) (*int, error) {
+	isolator := isolators.GetByName(MemorySwappinessIsolatorName)
+	if isolator == nil || isolator.ValueRaw == nil {
+		return nil, nil
+	}
+	var swappiness int
+	if err := json.Unmarshal(*isolator.ValueRaw, &swappiness); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error parsing isolator %q", MemorySwappinessIsolatorName), err)
+	}
+	if err := validateSwappiness(swappiness); err != nil {
+		return nil, err
+	}
+	return &swappiness, nil
+}
+
+// CPUSetIsolatorName pins the app to a set of CPUs and NUMA memory
+// nodes. Its value has the lists in the format of cpuset(7), like
+// {"cpus": "0-3,8", "mems": "0"}, either can be omitted.
+const CPUSetIsolatorName = "coreos.com/rkt/resource/cpuset"
+
+// CPUSet is the value of a cpuset isolator.
+type CPUSet struct {
+	CPUs string `json:"cpus,omitempty"`
+	Mems string `json:"mems,omitempty"`
+}
+
+// ParseCPUList parses a list of CPUs or memory nodes in the format of
+// cpuset(7), like "0-3,8".
+func ParseCPUList(s string) ([]int, error) {
+	var list []int
+	for _, r := range strings.Split(s, ",") {
+		bounds := strings.SplitN(r, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil || first < 0 {
+			return nil, fmt.Errorf("invalid list %q", s)
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil || last < first {
+				return nil, fmt.Errorf("invalid list %q", s)
+			}
+		}
+		for i := first; i <= last; i++ {
+			list = append(list, i)
+		}
+	}
+	return list, nil
+}
+
+func (c CPUSet) validate() error {
+	if c.CPUs == "" && c.Mems == "" {
+		return errors.New("empty cpuset")
+	}
+	if c.CPUs != "" {
+		if _, err := ParseCPUList(c.CPUs); err != nil {
+			return errwrap.Wrap(errors.New("invalid cpuset cpus"), err)
+		}
+	}
+	if c.Mems != "" {
+		if _, err := ParseCPUList(c.Mems); err != nil {
+			return errwrap.Wrap(errors.New("invalid cpuset mems"), err)
+		}
+	}
+	return nil
+}
+
+// NewCPUSetIsolator returns a cpuset isolator with the given CPUs and
+// memory nodes.
+func NewCPUSetIsolator(c CPUSet) (*types.Isolator, error) {
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return newIsolator(CPUSetIsolatorName, c)
+}
+
+// IsolatorCPUSet returns the value of the last cpuset isolator, or nil
+// if there is none.
+func IsolatorCPUSet(isolators types.Isolators) (*CPUSet, error) {
+	isolator := isolators.GetByName(CPUSetIsolatorName)
+	if isolator == nil || isolator.ValueRaw == nil {
+		return nil, nil
+	}
+	var c CPUSet
+	if err := json.Unmarshal(*isolator.ValueRaw, &c); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error parsing isolator %q", CPUSetIsolatorName), err)
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// HugepagesIsolatorName grants the app hugetlb pages, like
+// {"pages": [{"pageSize": "2M", "limit": "1Gi"}]}: up to the limit of
+// pages of each size.
+const HugepagesIsolatorName = "coreos.com/rkt/resource/hugepages"
+
+// Hugepages is an amount of hugetlb pages of a size.
+type Hugepages struct {
+	// PageSize is the size of the pages, as in the hugetlb cgroup
+	// knobs, like 2MB or 1GB. The MB suffix is the same as M or Mi.
+	PageSize string `json:"pageSize"`
+	// Limit is the amount of memory in these pages, in the Kubernetes
+	// resource model format.
+	Limit string `json:"limit"`
+}
+
+type hugepagesIsolatorValue struct {
+	Pages []Hugepages `json:"pages"`
+}
+
+// ParseHugepages parses an amount of hugetlb pages in the form
+// PAGESIZE:LIMIT, like 2MB:1Gi.
+func ParseHugepages(s string) (Hugepages, error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return Hugepages{}, fmt.Errorf("invalid hugepages %q, expected PAGESIZE:LIMIT", s)
+	}
+	h := Hugepages{PageSize: parts[0], Limit: parts[1]}
+	if err := h.validate(); err != nil {
+		return Hugepages{}, err
+	}
+	return h, nil
+}
+
+// PageSizeBytes returns the size of the pages in bytes.
+func (h Hugepages) PageSizeBytes() (uint64, error) {
+	s := strings.TrimSuffix(strings.TrimSuffix(strings.ToUpper(h.PageSize), "B"), "I")
+	var shift uint
+	switch {
+	case strings.HasSuffix(s, "K"):
+		shift = 10
+	case strings.HasSuffix(s, "M"):
+		shift = 20
+	case strings.HasSuffix(s, "G"):
+		shift = 30
+	}
+	if shift != 0 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseUint(s, 10, 64)
+	if err != nil || n == 0 || n&(n-1) != 0 {
+		return 0, fmt.Errorf("invalid page size %q", h.PageSize)
+	}
+	size := n << shift
+	if size < 4096 {
+		return 0, fmt.Errorf("invalid page size %q", h.PageSize)
+	}
+	return size, nil
+}
+
+// LimitBytes returns the limit in bytes.
+func (h Hugepages) LimitBytes() (uint64, error) {
+	q, err := resource.ParseQuantity(h.Limit)
+	if err != nil {
+		return 0, errwrap.Wrap(fmt.Errorf("invalid hugepages limit %q", h.Limit), err)
+	}
+	if q.Value() <= 0 {
+		return 0, fmt.Errorf("invalid hugepages limit %q", h.Limit)
+	}
+	return uint64(q.Value()), nil
+}
+
+// Label returns the size of the pages as named by the kernel in the
+// hugetlb cgroup knobs, like 2MB.
+func (h Hugepages) Label() (string, error) {
+	size, err := h.PageSizeBytes()
+	if err != nil {
+		return "", err
+	}
+	switch {
+	case size%(1<<30) == 0:
+		return fmt.Sprintf("%dGB", size>>30), nil
+	case size%(1<<20) == 0:
+		return fmt.Sprintf("%dMB", size>>20), nil
+	}
+	return fmt.Sprintf("%dKB", size>>10), nil
+}
+
+func (h Hugepages) validate() error {
+	if _, err := h.PageSizeBytes(); err != nil {
+		return err
+	}
+	_, err := h.LimitBytes()
+	return err
+}
+
+// IsolatorHugepages returns the hugetlb pages of the hugepages
+// isolators, with one amount per page size: the last one set.
+func IsolatorHugepages(isolators types.Isolators) ([]Hugepages, error) {
+	var pages []Hugepages
+	for _, isolator := range isolators {
+		if isolator.Name != HugepagesIsolatorName || isolator.ValueRaw == nil {
+			continue
+		}
+		var v hugepagesIsolatorValue
+		if err := json.Unmarshal(*isolator.ValueRaw, &v); err != nil {
+			return nil, errwrap.Wrap(fmt.Errorf("error parsing isolator %q", HugepagesIsolatorName), err)
+		}
+		for _, h := range v.Pages {
+			if err := h.validate(); err != nil {
+				return nil, err
+			}
+		}
+		pages = mergeHugepages(pages, v.Pages)
+	}
+	return pages, nil
+}
+
+// MergeHugepagesIsolator sets the given hugetlb pages in the hugepages
+// isolator, creating it if the app has none. The amounts of the page
+// sizes already set are overridden.
+func MergeHugepagesIsolator(isolators *types.Isolators, pages []Hugepages) error {
+	existing, err := IsolatorHugepages(*isolators)
+	if err != nil {
+		return err
+	}
+	for _, h := range pages {
+		if err := h.validate(); err != nil {
+			return err
+		}
+	}
+	isolator, err := newIsolator(HugepagesIsolatorName, hugepagesIsolatorValue{Pages: mergeHugepages(existing, pages)})
+	if err != nil {
+		return err
+	}
+	isolators.ReplaceIsolatorsByName(*isolator, []types.ACIdentifier{HugepagesIsolatorName})
+	return nil
+}
+
+// mergeHugepages expects valid pages.
+func mergeHugepages(pages, overrides []Hugepages) []Hugepages {
+	for _, o := range overrides {
+		label, _ := o.Label()
+		found := false
+		for i := range pages {
+			if l, _ := pages[i].Label(); l == label {
+				pages[i] = o
+				found = true
+			}
+		}
+		if !found {
+			pages = append(pages, o)
+		}
+	}
+	return pages
+}
+
+// RealtimeIsolatorName allows the app to run with real-time scheduling,
+// like {"runtime": 950000, "priority": 50}: within each scheduling
+// period of a second, its real-time processes can run for up to the
+// runtime, in microseconds, and with up to the priority, from 1 to 99.
+// Either can be omitted.
+const RealtimeIsolatorName = "coreos.com/rkt/linux/realtime"
+
+// RealtimeMaxPriority is the maximum priority of the SCHED_FIFO and
+// SCHED_RR policies.
+const RealtimeMaxPriority = 99
+
+// Realtime is the value of a realtime isolator.
+type Realtime struct {
+	Runtime  uint64 `json:"runtime,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+}
+
+// Validate checks the runtime and the priority.
+func (r Realtime) Validate() error {
+	if r.Runtime == 0 && r.Priority == 0 {
+		return errors.New("empty realtime isolator")
+	}
+	// The runtime can't exceed the default period of the cgroups
+	if r.Runtime > 1000000 {
+		return fmt.Errorf("invalid real-time runtime %d, must be at most 1000000 microseconds", r.Runtime)
+	}
+	if r.Priority < 0 || r.Priority > RealtimeMaxPriority {
+		return fmt.Errorf("invalid real-time priority %d, must be between 1 and %d", r.Priority, RealtimeMaxPriority)
+	}
+	return nil
+}
+
+// NewRealtimeIsolator returns a realtime isolator with the given runtime
+// and priority.
+func NewRealtimeIsolator(r Realtime) (*types.Isolator, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return newIsolator(RealtimeIsolatorName, r)
+}
+
+// IsolatorRealtime returns the value of the last realtime isolator, or
+// nil if there is none.
+func IsolatorRealtime(isolators types.Isolators) (*Realtime, error) {
+	isolator := isolators.GetByName(RealtimeIsolatorName)
+	if isolator == nil || isolator.ValueRaw == nil {
+		return nil, nil
+	}
+	var r Realtime
+	if err := json.Unmarshal(*isolator.ValueRaw, &r); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error parsing isolator %q", RealtimeIsolatorName), err)
+	}
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// StorageIsolatorName limits the disk usage of the writable layer of the
+// app, like {"limit": "10G"}. The limits of the apps of a pod add up to the
+// limit of the pod directory, enforced with a project quota.
+const StorageIsolatorName = "coreos.com/rkt/linux/storage"
+
+type storageIsolatorValue struct {
+	Limit string `json:"limit"`
+}
+
+// NewStorageIsolator returns a storage isolator with the given limit, like
+// "10G".
+func NewStorageIsolator(limit string) (*types.Isolator, error) {
+	if _, err := ParseStorageLimit(limit); err != nil {
+		return nil, err
+	}
+	return newIsolator(StorageIsolatorName, storageIsolatorValue{Limit: limit})
+}
+
+// ParseStorageLimit returns the storage limit in bytes, given in the
+// Kubernetes resource model like "10G".
+func ParseStorageLimit(limit string) (uint64, error) {
+	q, err := resource.ParseQuantity(limit)
+	if err != nil {
+		return 0, errwrap.Wrap(fmt.Errorf("invalid storage limit %q", limit), err)
+	}
+	if q.Value() <= 0 {
+		return 0, fmt.Errorf("invalid storage limit %q", limit)
+	}
+	return uint64(q.Value()), nil
+}
+
+// IsolatorStorage returns the storage limit in bytes of the last storage
+// isolator, or nil if there is none.
+func IsolatorStorage(isolators types.Isolators) (*uint64, error) {
+	isolator := isolators.GetByName(StorageIsolatorName)
+	if isolator == nil || isolator.ValueRaw == nil {
+		return nil, nil
+	}
+	var v storageIsolatorValue
+	if err := json.Unmarshal(*isolator.ValueRaw, &v); err != nil {
+		return nil, errwrap.Wrap(fmt.Errorf("error parsing isolator %q", StorageIsolatorName), err)
+	}
+	limit, err := ParseStorageLimit(v.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
@@ -0,0 +1,327 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package types
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/appc/spec/schema/types"
+)
+
+func TestPathsIsolator(t *testing.T) {
+	var isolators types.Isolators
+	if err := json.Unmarshal([]byte(`[
+		{"name": "coreos.com/rkt/linux/masked-paths", "value": {"paths": ["/proc/kcore"]}},
+		{"name": "os/linux/no-new-privileges", "value": true}
+	]`), &isolators); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := MergePathsIsolator(&isolators, MaskedPathsIsolatorName, []string{"/sys/firmware", "/proc/kcore"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := MergePathsIsolator(&isolators, ReadOnlyPathsIsolatorName, []string{"/proc/sys"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(isolators) != 3 {
+		t.Errorf("expected 3 isolators, got %d", len(isolators))
+	}
+
+	// The isolators survive a round trip through the pod manifest.
+	b, err := json.Marshal(isolators)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	isolators = nil
+	if err := json.Unmarshal(b, &isolators); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	masked, err := IsolatorPaths(isolators, MaskedPathsIsolatorName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"/proc/kcore", "/sys/firmware"}; !reflect.DeepEqual(masked, expected) {
+		t.Errorf("expected masked paths %v, got %v", expected, masked)
+	}
+	readOnly, err := IsolatorPaths(isolators, ReadOnlyPathsIsolatorName)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"/proc/sys"}; !reflect.DeepEqual(readOnly, expected) {
+		t.Errorf("expected read-only paths %v, got %v", expected, readOnly)
+	}
+
+	if err := MergePathsIsolator(&isolators, MaskedPathsIsolatorName, []string{"proc/keys"}); err == nil {
+		t.Errorf("expected error with a relative path")
+	}
+}
+
+func TestParseRLimit(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected RLimit
+		werr     bool
+	}{
+		{"nofile=1024:65536", RLimit{"RLIMIT_NOFILE", 1024, 65536}, false},
+		{"RLIMIT_NPROC=512", RLimit{"RLIMIT_NPROC", 512, 512}, false},
+		{"core=0:unlimited", RLimit{"RLIMIT_CORE", 0, RLimitInfinity}, false},
+		{"nofile=65536:1024", RLimit{}, true},
+		{"files=1024", RLimit{}, true},
+		{"nofile", RLimit{}, true},
+		{"nofile=many", RLimit{}, true},
+	}
+
+	for _, tt := range tests {
+		l, err := ParseRLimit(tt.in)
+		if tt.werr != (err != nil) {
+			t.Errorf("%s: expected error %t, got %v", tt.in, tt.werr, err)
+			continue
+		}
+		if l != tt.expected {
+			t.Errorf("%s: expected %+v, got %+v", tt.in, tt.expected, l)
+		}
+	}
+}
+
+func TestRLimitsIsolator(t *testing.T) {
+	var isolators types.Isolators
+	if err := json.Unmarshal([]byte(`[
+		{"name": "coreos.com/rkt/linux/rlimits", "value": {"limits": [
+			{"resource": "RLIMIT_NOFILE", "soft": 1024, "hard": 4096},
+			{"resource": "RLIMIT_CORE", "soft": 0, "hard": 0}
+		]}}
+	]`), &isolators); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := MergeRLimitsIsolator(&isolators, []RLimit{{"RLIMIT_NOFILE", 65536, 65536}, {"RLIMIT_NPROC", 512, 512}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(isolators) != 1 {
+		t.Errorf("expected 1 isolator, got %d", len(isolators))
+	}
+	limits, err := IsolatorRLimits(isolators)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []RLimit{
+		{"RLIMIT_NOFILE", 65536, 65536},
+		{"RLIMIT_CORE", 0, 0},
+		{"RLIMIT_NPROC", 512, 512},
+	}
+	if !reflect.DeepEqual(limits, expected) {
+		t.Errorf("expected limits %v, got %v", expected, limits)
+	}
+
+	if err := MergeRLimitsIsolator(&isolators, []RLimit{{"RLIMIT_FILES", 1, 1}}); err == nil {
+		t.Errorf("expected error with an unknown resource")
+	}
+}
+
+func TestMemoryIsolators(t *testing.T) {
+	var isolators types.Isolators
+	swap, err := NewMemorySwapIsolator("512M")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	swappiness, err := NewMemorySwappinessIsolator(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	isolators = append(isolators, *swap, *swappiness)
+
+	limit, err := IsolatorMemorySwap(isolators)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if limit == nil || *limit != 512*1000*1000 {
+		t.Errorf("expected swap limit of 512M, got %v", limit)
+	}
+	s, err := IsolatorMemorySwappiness(isolators)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s == nil || *s != 10 {
+		t.Errorf("expected swappiness 10, got %v", s)
+	}
+
+	if limit, err := IsolatorMemorySwap(nil); limit != nil || err != nil {
+		t.Errorf("expected no swap limit, got %v, %v", limit, err)
+	}
+	if _, err := NewMemorySwapIsolator("lots"); err == nil {
+		t.Errorf("expected error with an invalid swap limit")
+	}
+	if _, err := NewMemorySwappinessIsolator(101); err == nil {
+		t.Errorf("expected error with an invalid swappiness")
+	}
+}
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected []int
+		werr     bool
+	}{
+		{"0", []int{0}, false},
+		{"0-3,8", []int{0, 1, 2, 3, 8}, false},
+		{"2,4-5", []int{2, 4, 5}, false},
+		{"", nil, true},
+		{"3-1", nil, true},
+		{"a-b", nil, true},
+		{"-1", nil, true},
+	}
+
+	for _, tt := range tests {
+		list, err := ParseCPUList(tt.in)
+		if tt.werr != (err != nil) {
+			t.Errorf("%q: expected error %t, got %v", tt.in, tt.werr, err)
+			continue
+		}
+		if !reflect.DeepEqual(list, tt.expected) {
+			t.Errorf("%q: expected %v, got %v", tt.in, tt.expected, list)
+		}
+	}
+}
+
+func TestCPUSetIsolator(t *testing.T) {
+	isolator, err := NewCPUSetIsolator(CPUSet{CPUs: "0-3", Mems: "0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cpuset, err := IsolatorCPUSet(types.Isolators{*isolator})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (CPUSet{CPUs: "0-3", Mems: "0"}); cpuset == nil || *cpuset != expected {
+		t.Errorf("expected cpuset %v, got %v", expected, cpuset)
+	}
+
+	if _, err := NewCPUSetIsolator(CPUSet{}); err == nil {
+		t.Errorf("expected error with an empty cpuset")
+	}
+	if _, err := NewCPUSetIsolator(CPUSet{CPUs: "0-"}); err == nil {
+		t.Errorf("expected error with an invalid cpuset")
+	}
+}
+
+func TestParseHugepages(t *testing.T) {
+	tests := []struct {
+		in       string
+		label    string
+		pageSize uint64
+		limit    uint64
+		err      bool
+	}{
+		{in: "2MB:1Gi", label: "2MB", pageSize: 2 << 20, limit: 1 << 30},
+		{in: "2Mi:512Mi", label: "2MB", pageSize: 2 << 20, limit: 512 << 20},
+		{in: "1G:2Gi", label: "1GB", pageSize: 1 << 30, limit: 2 << 30},
+		{in: "64kB:1Mi", label: "64KB", pageSize: 64 << 10, limit: 1 << 20},
+		{in: "2MB", err: true},
+		{in: "3MB:1Gi", err: true},
+		{in: "1K:1Gi", err: true},
+		{in: "2MB:0", err: true},
+		{in: "2MB:lots", err: true},
+	}
+	for _, tt := range tests {
+		h, err := ParseHugepages(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tt.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.in, err)
+			continue
+		}
+		label, _ := h.Label()
+		pageSize, _ := h.PageSizeBytes()
+		limit, _ := h.LimitBytes()
+		if label != tt.label || pageSize != tt.pageSize || limit != tt.limit {
+			t.Errorf("%q: expected %s/%d/%d, got %s/%d/%d", tt.in, tt.label, tt.pageSize, tt.limit, label, pageSize, limit)
+		}
+	}
+}
+
+func TestHugepagesIsolator(t *testing.T) {
+	var isolators types.Isolators
+	if err := MergeHugepagesIsolator(&isolators, []Hugepages{{PageSize: "2MB", Limit: "1Gi"}, {PageSize: "1GB", Limit: "2Gi"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := MergeHugepagesIsolator(&isolators, []Hugepages{{PageSize: "2Mi", Limit: "512Mi"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(isolators) != 1 {
+		t.Fatalf("expected a single isolator, got %d", len(isolators))
+	}
+	pages, err := IsolatorHugepages(isolators)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []Hugepages{{PageSize: "2Mi", Limit: "512Mi"}, {PageSize: "1GB", Limit: "2Gi"}}
+	if !reflect.DeepEqual(pages, expected) {
+		t.Errorf("expected pages %v, got %v", expected, pages)
+	}
+
+	if err := MergeHugepagesIsolator(&isolators, []Hugepages{{PageSize: "3MB", Limit: "1Gi"}}); err == nil {
+		t.Errorf("expected error with an invalid page size")
+	}
+}
+
+func TestRealtimeIsolator(t *testing.T) {
+	isolator, err := NewRealtimeIsolator(Realtime{Runtime: 950000, Priority: 50})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rt, err := IsolatorRealtime(types.Isolators{*isolator})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (Realtime{Runtime: 950000, Priority: 50}); rt == nil || *rt != expected {
+		t.Errorf("expected realtime %v, got %v", expected, rt)
+	}
+
+	for _, r := range []Realtime{{}, {Runtime: 2000000}, {Priority: 100}, {Priority: -1}} {
+		if _, err := NewRealtimeIsolator(r); err == nil {
+			t.Errorf("expected error with realtime %v", r)
+		}
+	}
+}
+
+func TestStorageIsolator(t *testing.T) {
+	isolator, err := NewStorageIsolator("10G")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	limit, err := IsolatorStorage(types.Isolators{*isolator})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if limit == nil || *limit != 10*1000*1000*1000 {
+		t.Errorf("expected storage limit of 10G, got %v", limit)
+	}
+
+	if limit, err := IsolatorStorage(nil); limit != nil || err != nil {
+		t.Errorf("expected no storage limit, got %v, %v", limit, err)
+	}
+	for _, l := range []string{"lots", "0", "-1G"} {
+		if _, err := NewStorageIsolator(l); err == nil {
+			t.Errorf("expected error with storage limit %q", l)
+		}
+	}
+}
@@ -47,6 +47,7 @@ type Pod struct {
 	Manifest   *schema.PodManifest
 	Images     map[string]*schema.ImageManifest
 	UidRange   user.UidRange
+	EnvFacts   map[string]string // host facts usable in the apps' environment, see common.ExpandEnvTemplates
 }
 
 // RuntimePod stores internal state we'd like access to. There is no interface,
@@ -72,6 +73,7 @@ type RuntimePod struct {
 		DisableSeccomp      bool `json:"DisableSeccomp"`
 	} `json:"InsecureOptions"`
 	IPCMode string `json:"IPCMode"`
+	Secrets bool   `json:"Secrets"`
 }
 
 // AppNameToImageName takes the name of an app in the Pod and returns the name
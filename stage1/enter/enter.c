@@ -37,6 +37,10 @@ static int setns(int fd, int nstype) {
 
 #endif /* NO_SETNS_AVAILABLE */
 
+#ifndef CLONE_NEWTIME
+#define CLONE_NEWTIME 0x00000080
+#endif
+
 static int errornum;
 #define exit_if(_cond, _fmt, _args...)				\
 	errornum++;						\
@@ -47,6 +51,20 @@ static int errornum;
 #define pexit_if(_cond, _fmt, _args...)				\
 	exit_if(_cond, _fmt ": %s", ##_args, strerror(errno))
 
+/* Time namespaces are only available since Linux 5.6, and pods only
+ * have their own when started with clock offsets */
+static int has_own_timens(int pid) {
+	char	path[PATH_MAX];
+	struct stat	self_st, pod_st;
+	exit_if(snprintf(path, sizeof(path),
+			 "/proc/%i/ns/time", pid) == sizeof(path),
+		"Path overflow");
+	if(stat("/proc/self/ns/time", &self_st) == -1 ||
+	   stat(path, &pod_st) == -1)
+		return 0;
+	return self_st.st_ino != pod_st.st_ino;
+}
+
 static int openpidfd(int pid, char *which) {
 	char	path[PATH_MAX];
 	int	fd;
@@ -117,6 +135,9 @@ int main(int argc, char *argv[])
 	ns(CLONE_NEWUTS,  "ns/uts");
 	ns(CLONE_NEWNET,  "ns/net");
 	ns(CLONE_NEWPID,  "ns/pid");
+	if(has_own_timens(pid)) {
+		ns(CLONE_NEWTIME, "ns/time");
+	}
 	ns(CLONE_NEWNS,	  "ns/mnt");
 
 	pexit_if(fchdir(root_fd) < 0,
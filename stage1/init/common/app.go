@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 
 	"github.com/appc/spec/schema"
 	"github.com/appc/spec/schema/types"
@@ -28,6 +29,7 @@ import (
 
 	"github.com/rkt/rkt/common"
 	"github.com/rkt/rkt/common/cgroup"
+	"github.com/rkt/rkt/common/cgroup/v1"
 	stage1commontypes "github.com/rkt/rkt/stage1/common/types"
 )
 
@@ -44,6 +46,9 @@ type preparedApp struct {
 	noNewPrivileges bool
 	capabilities    []string
 	seccomp         *seccompFilter
+	rlimits         []stage1commontypes.RLimit
+	cpuset          *stage1commontypes.CPUSet
+	realtime        *stage1commontypes.Realtime
 
 	// Path restrictions
 	roPaths     []string
@@ -56,6 +61,8 @@ type appResources struct {
 	CPUQuota            *uint64 // The hard (absolute) CPU quota as a percent (100 = 1 core)
 	LinuxCPUShares      *uint64 // The relative CPU weight in the app's cgroup.
 	LinuxOOMScoreAdjust *int    // OOMScoreAdjust knob
+	MemorySwap          *uint64 // Swap limit in bytes, on top of the memory limit
+	MemorySwappiness    *int    // memory.swappiness knob
 }
 
 /*
@@ -119,6 +126,7 @@ func prepareApp(p *stage1commontypes.Pod, ra *schema.RuntimeApp) (*preparedApp,
 	if p.MetadataServiceURL != "" {
 		pa.env.Set("AC_METADATA_URL", p.MetadataServiceURL)
 	}
+	common.ExpandEnvTemplates(pa.env, p.EnvFacts)
 
 	// Determine capability set
 	pa.capabilities, err = getAppCapabilities(ra.App.Isolators)
@@ -146,6 +154,36 @@ func prepareApp(p *stage1commontypes.Pod, ra *schema.RuntimeApp) (*preparedApp,
 		pa.hiddenDirs = append(pa.hiddenDirs, protectKernelHiddenDirs...)
 	}
 
+	// Additional paths masked or made read-only on request
+	masked, err := stage1commontypes.IsolatorPaths(ra.App.Isolators, stage1commontypes.MaskedPathsIsolatorName)
+	if err != nil {
+		return nil, err
+	}
+	pa.hiddenPaths = append(pa.hiddenPaths, masked...)
+	readOnly, err := stage1commontypes.IsolatorPaths(ra.App.Isolators, stage1commontypes.ReadOnlyPathsIsolatorName)
+	if err != nil {
+		return nil, err
+	}
+	pa.roPaths = append(pa.roPaths, readOnly...)
+
+	// Resource limits
+	pa.rlimits, err = stage1commontypes.IsolatorRLimits(ra.App.Isolators)
+	if err != nil {
+		return nil, err
+	}
+
+	// CPU and NUMA pinning
+	pa.cpuset, err = stage1commontypes.IsolatorCPUSet(ra.App.Isolators)
+	if err != nil {
+		return nil, err
+	}
+
+	// Real-time scheduling
+	pa.realtime, err = stage1commontypes.IsolatorRealtime(ra.App.Isolators)
+	if err != nil {
+		return nil, err
+	}
+
 	// Seccomp
 	if !p.InsecureOptions.DisableSeccomp {
 		pa.seccomp, err = generateSeccompFilter(p, &pa)
@@ -224,10 +262,66 @@ func computeAppResources(isolators types.Isolators) (appResources, error) {
 			res.LinuxOOMScoreAdjust = &val
 		}
 	}
+	if err != nil {
+		return res, err
+	}
+
+	// rkt-specific memory isolators
+	swap, err := stage1commontypes.IsolatorMemorySwap(isolators)
+	if err != nil {
+		return res, err
+	}
+	if swap != nil {
+		err = withIsolator("memory", func() error {
+			res.MemorySwap = swap
+			return nil
+		})
+		if err != nil {
+			return res, err
+		}
+	}
+	swappiness, err := stage1commontypes.IsolatorMemorySwappiness(isolators)
+	if err != nil {
+		return res, err
+	}
+	if swappiness != nil {
+		err = withIsolator("memory", func() error {
+			res.MemorySwappiness = swappiness
+			return nil
+		})
+	}
 
 	return res, err
 }
 
+// AppV1MemoryKnobs returns the knobs of the v1 memory cgroup of the app
+// which systemd has no option for: the swap limit, which is written
+// with the memory limit as the kernel requires, and the swappiness.
+func AppV1MemoryKnobs(ra *schema.RuntimeApp) ([]v1.Knob, error) {
+	res, err := computeAppResources(ra.App.Isolators)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("unable to compute resources"), err)
+	}
+
+	var knobs []v1.Knob
+	if res.MemorySwap != nil {
+		if res.MemoryLimit == nil {
+			fmt.Fprintf(os.Stderr, "warning: swap limit set for app %q without a memory limit, skipping\n", ra.Name)
+		} else if !v1.IsSwapAccountingEnabled() {
+			return nil, errwrap.Wrap(fmt.Errorf("cannot limit the swap of app %q", ra.Name), cgroup.ErrNoSwapAccounting)
+		} else {
+			knobs = append(knobs,
+				v1.Knob{Name: "memory.limit_in_bytes", Value: strconv.FormatUint(*res.MemoryLimit, 10)},
+				v1.Knob{Name: "memory.memsw.limit_in_bytes", Value: strconv.FormatUint(*res.MemoryLimit+*res.MemorySwap, 10)},
+			)
+		}
+	}
+	if res.MemorySwappiness != nil {
+		knobs = append(knobs, v1.Knob{Name: "memory.swappiness", Value: strconv.Itoa(*res.MemorySwappiness)})
+	}
+	return knobs, nil
+}
+
 // relAppPaths prepends the relative app path (/opt/stage1/rootfs/) to a list
 // of paths. Useful for systemd unit directives.
 func (pa *preparedApp) relAppPaths(paths []string) []string {
@@ -529,6 +529,14 @@ func appToNspawnArgs(p *stage1commontypes.Pod, ra *schema.RuntimeApp) ([]string,
 		args = append(args, strings.Join(opt, ""))
 	}
 
+	if p.Secrets {
+		secretsArg, err := appSecretsNspawnArg(p, appName)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, secretsArg)
+	}
+
 	if !p.InsecureOptions.DisableCapabilities {
 		capabilitiesStr, err := getAppCapabilities(app.Isolators)
 		if err != nil {
@@ -541,6 +549,24 @@ func appToNspawnArgs(p *stage1commontypes.Pod, ra *schema.RuntimeApp) ([]string,
 	return args, nil
 }
 
+// appSecretsNspawnArg returns the systemd-nspawn argument making the pod
+// secrets available read-only inside the given app.
+func appSecretsNspawnArg(p *stage1commontypes.Pod, appName types.ACName) (string, error) {
+	absRoot, err := filepath.Abs(p.Root)
+	if err != nil {
+		return "", errwrap.Wrap(errors.New("could not get pod's root absolute path"), err)
+	}
+	appRootfs := common.AppRootfsPath(absRoot, appName)
+	mntPath, err := EvaluateSymlinksInsideApp(appRootfs, common.SecretsMountPath)
+	if err != nil {
+		return "", errwrap.Wrap(fmt.Errorf("could not evaluate path %v", common.SecretsMountPath), err)
+	}
+	if err := os.MkdirAll(filepath.Join(appRootfs, mntPath), common.DefaultRegularDirPerm); err != nil {
+		return "", errwrap.Wrap(errors.New("could not create secrets mountpoint"), err)
+	}
+	return fmt.Sprintf("--bind-ro=%s:%s", common.SecretsPath(absRoot), filepath.Join(common.RelAppRootfsPath(appName), mntPath)), nil
+}
+
 // PodToNspawnArgs renders a prepared Pod as a systemd-nspawn
 // argument list ready to be executed
 func PodToNspawnArgs(p *stage1commontypes.Pod) ([]string, error) {
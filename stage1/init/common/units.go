@@ -29,6 +29,7 @@ import (
 	"github.com/appc/spec/schema"
 	"github.com/appc/spec/schema/types"
 	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/common/cgroup"
 	"github.com/rkt/rkt/pkg/user"
 	stage1commontypes "github.com/rkt/rkt/stage1/common/types"
 
@@ -591,24 +592,28 @@ func (uw *UnitWriter) appSystemdUnit(pa *preparedApp, binPath string, opts []*un
 		opts = appendOptionsList(opts, "Service", "ReadOnlyDirectories", "", common.RelAppRootfsPath(ra.Name))
 	}
 
+	// Hide or protect the paths in the path restrictions. With
+	// --insecure-options=paths, only the paths explicitly requested for
+	// the app are there.
+	//
+	// Systemd 231+ has InaccessiblePaths
+	// older versions only have InaccessibleDirectories
+	// Paths prepended with "-" are ignored if they don't exist.
+	if systemdVersion >= 231 {
+		opts = appendOptionsList(opts, "Service", "InaccessiblePaths", "-", pa.relAppPaths(pa.hiddenPaths)...)
+		opts = appendOptionsList(opts, "Service", "InaccessiblePaths", "-", pa.relAppPaths(pa.hiddenDirs)...)
+		opts = appendOptionsList(opts, "Service", "ReadOnlyPaths", "-", pa.relAppPaths(pa.roPaths)...)
+	} else {
+		opts = appendOptionsList(opts, "Service", "InaccessibleDirectories", "-", pa.relAppPaths(pa.hiddenDirs)...)
+		opts = appendOptionsList(opts, "Service", "ReadOnlyDirectories", "-", pa.relAppPaths(pa.roPaths)...)
+	}
+
 	// Unless we have --insecure-options=paths, then do some path protections:
 	//
 	// * prevent access to sensitive kernel tunables
 	// * Run the app in a separate mount namespace
 	//
 	if !uw.p.InsecureOptions.DisablePaths {
-		// Systemd 231+ has InaccessiblePaths
-		// older versions only have InaccessibleDirectories
-		// Paths prepended with "-" are ignored if they don't exist.
-		if systemdVersion >= 231 {
-			opts = appendOptionsList(opts, "Service", "InaccessiblePaths", "-", pa.relAppPaths(pa.hiddenPaths)...)
-			opts = appendOptionsList(opts, "Service", "InaccessiblePaths", "-", pa.relAppPaths(pa.hiddenDirs)...)
-			opts = appendOptionsList(opts, "Service", "ReadOnlyPaths", "-", pa.relAppPaths(pa.roPaths)...)
-		} else {
-			opts = appendOptionsList(opts, "Service", "InaccessibleDirectories", "-", pa.relAppPaths(pa.hiddenDirs)...)
-			opts = appendOptionsList(opts, "Service", "ReadOnlyDirectories", "-", pa.relAppPaths(pa.roPaths)...)
-		}
-
 		if systemdVersion >= 233 {
 			// ProtectKernelTunables is introduced in systemd-v232 but didn't work
 			// until v233 due to a systemd bug, see
@@ -672,6 +677,70 @@ func (uw *UnitWriter) appSystemdUnit(pa *preparedApp, binPath string, opts []*un
 		opts = append(opts, unit.NewUnitOption("Service", "OOMScoreAdjust", strconv.Itoa(*pa.resources.LinuxOOMScoreAdjust)))
 	}
 
+	// The swap limit and the swappiness are written to the memory cgroup
+	// of the app, which systemd only creates with memory accounting
+	if pa.resources.MemorySwap != nil || pa.resources.MemorySwappiness != nil {
+		opts = append(opts, unit.NewUnitOption("Service", "MemoryAccounting", "true"))
+	}
+	// MemorySwapMax is introduced in systemd-232 and only applies to the
+	// unified hierarchy. On the legacy one, stage1 init writes the swap
+	// limit to the cgroup of the app itself.
+	if pa.resources.MemorySwap != nil && systemdVersion >= 232 {
+		opts = append(opts, unit.NewUnitOption("Service", "MemorySwapMax", strconv.FormatUint(*pa.resources.MemorySwap, 10)))
+	}
+
+	// CPU and NUMA pinning. Within the kvm flavor, the apps run on the
+	// vCPUs of the VM: stage1 init pins the hypervisor instead.
+	if pa.cpuset != nil && flavor != "kvm" {
+		if pa.cpuset.CPUs != "" {
+			cpus, err := stage1commontypes.ParseCPUList(pa.cpuset.CPUs)
+			if err != nil {
+				uw.err = err
+				return nil
+			}
+			var affinity []string
+			for _, cpu := range cpus {
+				affinity = append(affinity, strconv.Itoa(cpu))
+			}
+			opts = append(opts, unit.NewUnitOption("Service", "CPUAffinity", strings.Join(affinity, " ")))
+		}
+		// NUMAPolicy and NUMAMask are introduced in systemd-243
+		if pa.cpuset.Mems != "" {
+			if systemdVersion >= 243 {
+				opts = append(opts, unit.NewUnitOption("Service", "NUMAPolicy", "bind"))
+				opts = append(opts, unit.NewUnitOption("Service", "NUMAMask", pa.cpuset.Mems))
+			} else {
+				fmt.Fprintf(os.Stderr, "warning: NUMA memory nodes set for app %q but not supported by systemd v%d in stage1, skipping\n", appName, systemdVersion)
+			}
+		}
+	}
+
+	// Real-time scheduling. The priority allowance is the RLIMIT_RTPRIO
+	// limit, which --rlimit overrides. The runtime is written to the cpu
+	// cgroup of the app by stage1 init, which systemd only creates with
+	// CPU accounting, on the legacy hierarchy only.
+	if pa.realtime != nil {
+		if pa.realtime.Priority != 0 {
+			opts = append(opts, unit.NewUnitOption("Service", "LimitRTPRIO", strconv.Itoa(pa.realtime.Priority)))
+		}
+		if pa.realtime.Runtime != 0 && flavor != "kvm" {
+			unified, err := cgroup.IsCgroupUnified("/")
+			if err != nil {
+				uw.err = err
+				return nil
+			}
+			if !unified {
+				opts = append(opts, unit.NewUnitOption("Service", "CPUAccounting", "true"))
+			}
+		}
+	}
+
+	// Resource limits, as LimitNOFILE= and the like
+	for _, l := range pa.rlimits {
+		property := "Limit" + strings.TrimPrefix(l.Resource, "RLIMIT_")
+		opts = append(opts, unit.NewUnitOption("Service", property, rlimitValue(l)))
+	}
+
 	var saPorts []types.Port
 	for _, p := range ra.App.Ports {
 		if p.SocketActivated {
@@ -789,6 +858,21 @@ func (uw *UnitWriter) AppSocketUnit(appName types.ACName, binPath string, stream
 // an array of new properties, one entry at a time.
 // This is the preferred method to avoid hitting line length limits
 // in unit files. Target property must support multi-line entries.
+// rlimitValue formats a resource limit for the Limit*= options of
+// systemd, as SOFT:HARD or as a single value when both are equal.
+func rlimitValue(l stage1commontypes.RLimit) string {
+	format := func(v uint64) string {
+		if v == stage1commontypes.RLimitInfinity {
+			return "infinity"
+		}
+		return strconv.FormatUint(v, 10)
+	}
+	if l.Soft == l.Hard {
+		return format(l.Hard)
+	}
+	return format(l.Soft) + ":" + format(l.Hard)
+}
+
 func appendOptionsList(opts []*unit.UnitOption, section, property, prefix string, vals ...string) []*unit.UnitOption {
 	for _, v := range vals {
 		opts = append(opts, unit.NewUnitOption(section, property, fmt.Sprintf("%s%s", prefix, v)))
@@ -0,0 +1,138 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/common"
+	"github.com/rkt/rkt/common/cgroup/v1"
+	"github.com/rkt/rkt/pkg/fs"
+	stage1commontypes "github.com/rkt/rkt/stage1/common/types"
+	"github.com/rkt/rkt/stage1/init/kvm"
+)
+
+// setupPodHugetlbCgroup limits the hugetlb pages of the pod to the sum of
+// the pages granted to its apps, and moves stage1 to the hugetlb
+// subcgroup of the pod. systemd doesn't manage the hugetlb controller, so
+// the limits apply to the whole pod rather than to each app.
+func setupPodHugetlbCgroup(p *stage1commontypes.Pod, subcgroup string) error {
+	limits := make(map[string]uint64)
+	for i := range p.Manifest.Apps {
+		pages, err := stage1commontypes.IsolatorHugepages(p.Manifest.Apps[i].App.Isolators)
+		if err != nil {
+			return err
+		}
+		for _, h := range pages {
+			label, err := h.Label()
+			if err != nil {
+				return err
+			}
+			limit, err := h.LimitBytes()
+			if err != nil {
+				return err
+			}
+			limits[label] += limit
+		}
+	}
+	if len(limits) == 0 {
+		return nil
+	}
+
+	mounted, err := v1.IsControllerMounted("hugetlb")
+	if err != nil {
+		return err
+	}
+	if !mounted {
+		return errors.New("hugepages requested but the hugetlb cgroup controller is not available")
+	}
+	if err := v1.JoinSubcgroup("hugetlb", subcgroup); err != nil {
+		return err
+	}
+	var knobs []v1.Knob
+	for label, limit := range limits {
+		knobs = append(knobs, v1.Knob{
+			Name:  fmt.Sprintf("hugetlb.%s.limit_in_bytes", label),
+			Value: strconv.FormatUint(limit, 10),
+		})
+	}
+	return v1.WriteKnobs("hugetlb", subcgroup, knobs)
+}
+
+// mountAppsHugetlbfs mounts a hugetlbfs in /dev/hugepages-<size> of the
+// apps, like /dev/hugepages-2MB, for each size of the pages granted to
+// them. Its size is the amount of pages granted.
+func mountAppsHugetlbfs(m fs.Mounter, p *stage1commontypes.Pod) error {
+	for i := range p.Manifest.Apps {
+		ra := &p.Manifest.Apps[i]
+		pages, err := stage1commontypes.IsolatorHugepages(ra.App.Isolators)
+		if err != nil {
+			return err
+		}
+		for _, h := range pages {
+			label, err := h.Label()
+			if err != nil {
+				return err
+			}
+			target := filepath.Join(common.AppRootfsPath(p.Root, ra.Name), "dev", "hugepages-"+label)
+			if err := mountHugetlbfs(m, target, h, true); err != nil {
+				return errwrap.Wrap(fmt.Errorf("failed to mount hugetlbfs for app %q", ra.Name), err)
+			}
+		}
+	}
+	return nil
+}
+
+// kvmHugetlbfsPath is where the hugetlbfs backing the memory of the VM is
+// mounted.
+func kvmHugetlbfsPath(p *stage1commontypes.Pod) string {
+	return filepath.Join(p.Root, "hugepages")
+}
+
+// mountKvmHugetlbfs mounts the hugetlbfs backing the memory of the VM, if
+// the apps were granted hugepages.
+func mountKvmHugetlbfs(m fs.Mounter, p *stage1commontypes.Pod) error {
+	h, err := kvm.GetAppsHugepages(p.Manifest.Apps)
+	if err != nil || h == nil {
+		return err
+	}
+	return mountHugetlbfs(m, kvmHugetlbfsPath(p), *h, false)
+}
+
+func mountHugetlbfs(m fs.Mounter, target string, h stage1commontypes.Hugepages, limited bool) error {
+	pageSize, err := h.PageSizeBytes()
+	if err != nil {
+		return err
+	}
+	opts := fmt.Sprintf("pagesize=%d,mode=1777", pageSize)
+	if limited {
+		limit, err := h.LimitBytes()
+		if err != nil {
+			return err
+		}
+		opts += fmt.Sprintf(",size=%d", limit)
+	}
+	if err := os.MkdirAll(target, 0755); err != nil {
+		return err
+	}
+	return m.Mount("hugetlbfs", target, "hugetlbfs", 0, opts)
+}
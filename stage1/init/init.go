@@ -29,6 +29,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -40,6 +41,7 @@ import (
 	"github.com/godbus/dbus/introspect"
 	"github.com/hashicorp/errwrap"
 
+	"github.com/rkt/rkt/pkg/exitcode"
 	stage1common "github.com/rkt/rkt/stage1/common"
 	stage1commontypes "github.com/rkt/rkt/stage1/common/types"
 	stage1initcommon "github.com/rkt/rkt/stage1/init/common"
@@ -102,12 +104,14 @@ func mirrorLocalZoneInfo(root string) {
 }
 
 var (
-	debug       bool
-	localhostIP net.IP
-	localConfig string
-	log         *rktlog.Logger
-	diag        *rktlog.Logger
-	interpBin   string // Path to the interpreter within the stage1 rootfs, set by the linker
+	debug         bool
+	localhostIP   net.IP
+	localConfig   string
+	timeOffsets   common.TimeOffsets
+	kvmHypervisor string // Hypervisor of the kvm flavor, the first one found in the stage1 if empty
+	log           *rktlog.Logger
+	diag          *rktlog.Logger
+	interpBin     string // Path to the interpreter within the stage1 rootfs, set by the linker
 )
 
 func parseFlags() *stage1commontypes.RuntimePod {
@@ -115,6 +119,8 @@ func parseFlags() *stage1commontypes.RuntimePod {
 
 	flag.BoolVar(&debug, "debug", false, "Run in debug mode")
 	flag.StringVar(&localConfig, "local-config", common.DefaultLocalConfigDir, "Local config path")
+	flag.Var(&timeOffsets, "time-offset", "Run in a time namespace with the given clock offsets")
+	flag.StringVar(&kvmHypervisor, "hypervisor", "", "Hypervisor of the kvm flavor (lkvm or qemu)")
 
 	// These flags are persisted in the PodRuntime
 	flag.BoolVar(&rp.Interactive, "interactive", false, "The pod is interactive")
@@ -135,6 +141,7 @@ func parseFlags() *stage1commontypes.RuntimePod {
 	})
 	flag.Var(dnsConfMode, "dns-conf-mode", "DNS config file modes")
 	flag.StringVar(&rp.IPCMode, "ipc", "", "IPC mode --ipc=[auto|private|parent]")
+	flag.BoolVar(&rp.Secrets, "secrets", false, "Make the pod secrets available to all the apps")
 
 	flag.Parse()
 
@@ -261,6 +268,31 @@ func installAssets(systemdVersion int) error {
 	return proj2aci.PrepareAssets(assets, "./stage1/rootfs/", nil)
 }
 
+// envFacts returns the host facts that apps can reference in their
+// environment variables. n is nil when the pod doesn't have a contained
+// network.
+func envFacts(p *stage1commontypes.Pod, n *networking.Networking) map[string]string {
+	facts := map[string]string{
+		common.EnvFactHostname: p.Hostname,
+		common.EnvFactUUID:     p.UUID.String(),
+	}
+	if n == nil {
+		return facts
+	}
+	if podIP, err := n.GetForwardableNetPodIP(); err == nil {
+		facts[common.EnvFactDefaultIP] = podIP.String()
+	}
+	if hostIP, err := n.GetForwardableNetHostIP(); err == nil {
+		facts[common.EnvFactHostIP] = hostIP.String()
+	}
+	for _, an := range n.GetActiveNetworks() {
+		if ip := an.GuestIP(); ip != nil {
+			facts[common.EnvFactNetIP(an.Name())] = ip.String()
+		}
+	}
+	return facts
+}
+
 // getArgsEnv returns the nspawn or lkvm args and env according to the flavor
 // as the first two return values respectively.
 func getArgsEnv(p *stage1commontypes.Pod, flavor string, canMachinedRegister bool, debug bool, n *networking.Networking, parentIPC bool) ([]string, []string, error) {
@@ -292,19 +324,42 @@ func getArgsEnv(p *stage1commontypes.Pod, flavor string, canMachinedRegister boo
 
 		cpu, mem := kvm.GetAppsResources(p.Manifest.Apps)
 
+		// Pin the hypervisor, and so its vCPUs, to the CPUs of the apps
+		pinnedCPUs, err := kvm.GetAppsCPUs(p.Manifest.Apps)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(pinnedCPUs) > 0 {
+			if err := sys.SchedSetaffinity(pinnedCPUs); err != nil {
+				return nil, nil, errwrap.Wrap(errors.New("failed to pin the hypervisor"), err)
+			}
+			if int64(len(pinnedCPUs)) < cpu {
+				cpu = int64(len(pinnedCPUs))
+			}
+		}
+
 		// Parse hypervisor
-		hv, err := KvmCheckHypervisor(common.Stage1RootfsPath(p.Root))
+		hv, err := KvmCheckHypervisor(common.Stage1RootfsPath(p.Root), kvmHypervisor)
 		if err != nil {
 			return nil, nil, err
 		}
 
 		// Set start command for hypervisor
 		StartCmd := hvlkvm.StartCmd
+		HugetlbfsArgs := hvlkvm.HugetlbfsArgs
+		DevicesArgs := hvlkvm.DevicesArgs
+		OptionsArgs := hvlkvm.OptionsArgs
 		switch hv {
 		case "lkvm":
 			StartCmd = hvlkvm.StartCmd
+			HugetlbfsArgs = hvlkvm.HugetlbfsArgs
+			DevicesArgs = hvlkvm.DevicesArgs
+			OptionsArgs = hvlkvm.OptionsArgs
 		case "qemu":
 			StartCmd = hvqemu.StartCmd
+			HugetlbfsArgs = hvqemu.HugetlbfsArgs
+			DevicesArgs = hvqemu.DevicesArgs
+			OptionsArgs = hvqemu.OptionsArgs
 		default:
 			return nil, nil, fmt.Errorf("unrecognized hypervisor")
 		}
@@ -325,6 +380,37 @@ func getArgsEnv(p *stage1commontypes.Pod, flavor string, canMachinedRegister boo
 
 		args = append(args, hvStartCmd...)
 
+		// Back the memory of the VM with the hugepages granted to the apps
+		hugepages, err := kvm.GetAppsHugepages(p.Manifest.Apps)
+		if err != nil {
+			return nil, nil, err
+		}
+		if hugepages != nil {
+			args = append(args, HugetlbfsArgs(kvmHugetlbfsPath(p))...)
+		}
+
+		// Attach the extra devices requested by the pod annotations
+		devices, err := kvm.GetDevices(p.Manifest.Annotations)
+		if err != nil {
+			return nil, nil, err
+		}
+		devicesArgs, err := DevicesArgs(devices)
+		if err != nil {
+			return nil, nil, errwrap.Wrap(fmt.Errorf("cannot attach the devices with %s", hv), err)
+		}
+		args = append(args, devicesArgs...)
+
+		// Tune the VM as requested by the pod annotations
+		options, err := kvm.GetHypervisorOptions(p.Manifest.Annotations)
+		if err != nil {
+			return nil, nil, err
+		}
+		optionsArgs, err := OptionsArgs(options)
+		if err != nil {
+			return nil, nil, errwrap.Wrap(fmt.Errorf("cannot apply the hypervisor options with %s", hv), err)
+		}
+		args = append(args, optionsArgs...)
+
 		// lkvm requires $HOME to be defined,
 		// see https://github.com/rkt/rkt/issues/1393
 		if os.Getenv("HOME") == "" {
@@ -574,7 +660,8 @@ func stage1(rp *stage1commontypes.RuntimePod) int {
 		noDNS := p.ResolvConfMode != "default" // force ignore CNI DNS results
 		n, err = networking.Setup(root, p.UUID, fps, p.NetList, localConfig, flavor, noDNS, debug)
 		if err != nil {
-			log.FatalE("failed to setup network", err)
+			log.PrintE("failed to setup network", err)
+			return exitcode.NetworkSetupFailed
 		}
 
 		if err = n.Save(); err != nil {
@@ -622,6 +709,8 @@ func stage1(rp *stage1commontypes.RuntimePod) int {
 		log.PrintE("error shifting "+hostnamePath, err)
 	}
 
+	p.EnvFacts = envFacts(p, n)
+
 	if p.ResolvConfMode == "host" {
 		stage1initcommon.UseHostResolv(mnt, root)
 	}
@@ -693,6 +782,15 @@ func stage1(rp *stage1commontypes.RuntimePod) int {
 	if parentIPC && flavor == "kvm" {
 		log.Fatal("flavor kvm requires private IPC namespace (try to remove --ipc)")
 	}
+	if p.Secrets && flavor == "kvm" {
+		log.Fatal("flavor kvm does not support secrets")
+	}
+	if !timeOffsets.Empty() && flavor == "kvm" {
+		log.Fatal("flavor kvm does not support time namespaces")
+	}
+	if kvmHypervisor != "" && flavor != "kvm" {
+		log.Fatalf("flavor %s does not support choosing a hypervisor", flavor)
+	}
 
 	args, env, err := getArgsEnv(p, flavor, canMachinedRegister, debug, n, parentIPC)
 	if err != nil {
@@ -765,6 +863,42 @@ func stage1(rp *stage1commontypes.RuntimePod) int {
 			log.FatalE("couldn't mount the container v1 cgroups", err)
 		}
 
+		if err := writeAppsV1MemoryKnobs(p, subcgroup); err != nil {
+			log.FatalE("couldn't set the memory cgroup knobs of the apps", err)
+		}
+
+		if err := setupPodHugetlbCgroup(p, subcgroup); err != nil {
+			log.FatalE("couldn't set the hugetlb cgroup of the pod", err)
+		}
+
+		// Within the kvm flavor, the apps are scheduled by the kernel
+		// of the VM
+		if flavor != "kvm" {
+			if err := writeAppsV1RealtimeKnobs(p, subcgroup); err != nil {
+				log.FatalE("couldn't set the real-time runtime of the apps", err)
+			}
+		}
+
+	} else if flavor != "kvm" {
+		// The cpu controller of the unified hierarchy doesn't limit the
+		// runtime of real-time processes
+		for _, ra := range p.Manifest.Apps {
+			if rt, err := stage1commontypes.IsolatorRealtime(ra.App.Isolators); err == nil && rt != nil && rt.Runtime != 0 {
+				fmt.Fprintf(os.Stderr, "warning: real-time runtime set for app %q but not supported on the unified cgroup hierarchy, skipping\n", ra.Name)
+			}
+		}
+	}
+
+	// Hugepages: apps get a hugetlbfs of their own, while with the kvm
+	// flavor they back the memory of the VM
+	if flavor == "kvm" {
+		if err := mountKvmHugetlbfs(mnt, p); err != nil {
+			log.FatalE("couldn't mount hugetlbfs for the VM", err)
+		}
+	} else {
+		if err := mountAppsHugetlbfs(mnt, p); err != nil {
+			log.FatalE("couldn't mount hugetlbfs for the apps", err)
+		}
 	}
 
 	// KVM flavor has a bit different logic in handling pid vs ppid, for details look into #2389
@@ -785,6 +919,13 @@ func stage1(rp *stage1commontypes.RuntimePod) int {
 		}
 	}
 
+	if !timeOffsets.Empty() {
+		diag.Printf("time offsets %s", timeOffsets.String())
+		if err := unshareTimeNamespace(timeOffsets); err != nil {
+			log.FatalE("error setting up the time namespace", err)
+		}
+	}
+
 	err = stage1common.WithClearedCloExec(lfd, func() error {
 		return syscall.Exec(args[0], args, env)
 	})
@@ -854,6 +995,65 @@ func mountContainerV1Cgroups(m fs.Mounter, p *stage1commontypes.Pod, enabledCgro
 	return nil
 }
 
+// writeAppsV1MemoryKnobs writes the memory cgroup knobs of the apps
+// which systemd inside stage1 has no option for, before it starts them.
+func writeAppsV1MemoryKnobs(p *stage1commontypes.Pod, subcgroup string) error {
+	for i := range p.Manifest.Apps {
+		ra := &p.Manifest.Apps[i]
+		knobs, err := stage1initcommon.AppV1MemoryKnobs(ra)
+		if err != nil {
+			return err
+		}
+		if len(knobs) == 0 {
+			continue
+		}
+		if err := v1.WriteServiceKnobs("memory", subcgroup, stage1initcommon.ServiceUnitName(ra.Name), knobs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeAppsV1RealtimeKnobs reserves the real-time runtime of the apps in
+// the cpu cgroups of the pod, and writes the runtime of each app to its
+// own cgroup.
+func writeAppsV1RealtimeKnobs(p *stage1commontypes.Pod, subcgroup string) error {
+	var total uint64
+	runtimes := make(map[string]uint64)
+	for i := range p.Manifest.Apps {
+		ra := &p.Manifest.Apps[i]
+		rt, err := stage1commontypes.IsolatorRealtime(ra.App.Isolators)
+		if err != nil {
+			return err
+		}
+		if rt == nil || rt.Runtime == 0 {
+			continue
+		}
+		runtimes[stage1initcommon.ServiceUnitName(ra.Name)] = rt.Runtime
+		total += rt.Runtime
+	}
+	if total == 0 {
+		return nil
+	}
+
+	// Without real-time group scheduling, the kernel doesn't limit the
+	// runtime of real-time processes per cgroup
+	if _, err := os.Stat("/sys/fs/cgroup/cpu/cpu.rt_runtime_us"); os.IsNotExist(err) {
+		fmt.Fprintln(os.Stderr, "warning: real-time runtime set but the kernel has no real-time group scheduling, skipping")
+		return nil
+	}
+	if err := v1.ReserveRTRuntime(filepath.Join(subcgroup, "system.slice"), total); err != nil {
+		return err
+	}
+	for service, runtime := range runtimes {
+		knobs := []v1.Knob{{Name: "cpu.rt_runtime_us", Value: strconv.FormatUint(runtime, 10)}}
+		if err := v1.WriteServiceKnobs("cpu", subcgroup, service, knobs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func getContainerSubCgroup(machineID string, canMachinedRegister, unified bool) (string, error) {
 	var fromUnit bool
 
@@ -184,7 +184,26 @@ func KvmPrepareMounts(p *stage1commontypes.Pod) error {
 	return nil
 }
 
-func KvmCheckHypervisor(s1Root string) (string, error) {
+// KvmCheckHypervisor returns the hypervisor to run the pod with. A requested
+// hypervisor must be shipped in the stage1, otherwise the first one found
+// there is used.
+func KvmCheckHypervisor(s1Root, requested string) (string, error) {
+	if requested != "" {
+		known := false
+		for _, hv := range hypervisors {
+			if hv == requested {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", fmt.Errorf("unrecognized hypervisor %q, expected one of %v", requested, hypervisors)
+		}
+		if _, err := os.Stat(filepath.Join(s1Root, requested)); err != nil {
+			return "", errwrap.Wrap(fmt.Errorf("hypervisor %q is not available in the stage1", requested), err)
+		}
+		return requested, nil
+	}
 	for _, hv := range hypervisors {
 		if _, err := os.Stat(filepath.Join(s1Root, hv)); err == nil {
 			return hv, nil
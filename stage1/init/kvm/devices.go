@@ -0,0 +1,83 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+
+	"github.com/appc/spec/schema/types"
+)
+
+// Pod annotations attaching extra devices to the VM
+const (
+	// AnnotationGPU attaches a virtio-gpu device when "true"
+	AnnotationGPU = "coreos.com/rkt/stage1/kvm/gpu"
+	// AnnotationVsock attaches a vhost-vsock device with the given guest
+	// context ID
+	AnnotationVsock = "coreos.com/rkt/stage1/kvm/vsock-cid"
+	// AnnotationRNG attaches a virtio-rng device fed by the given host
+	// device, or by /dev/urandom when "true"
+	AnnotationRNG = "coreos.com/rkt/stage1/kvm/rng"
+
+	defaultRNGSource = "/dev/urandom"
+	// The context IDs below 3 are reserved: 2 is the host's
+	minVsockCID = 3
+)
+
+// Devices describes the extra devices of the VM
+type Devices struct {
+	GPU bool
+	// VsockCID is the guest context ID of the vsock device, 0 for none
+	VsockCID uint32
+	// RNGSource is the host device feeding the rng device, empty for none
+	RNGSource string
+}
+
+// GetDevices returns the extra devices requested by the pod annotations
+func GetDevices(annotations types.Annotations) (*Devices, error) {
+	d := &Devices{}
+
+	if v, ok := annotations.Get(AnnotationGPU); ok {
+		gpu, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation %q", AnnotationGPU, v)
+		}
+		d.GPU = gpu
+	}
+
+	if v, ok := annotations.Get(AnnotationVsock); ok {
+		cid, err := strconv.ParseUint(v, 10, 32)
+		if err != nil || cid < minVsockCID || cid == 1<<32-1 {
+			return nil, fmt.Errorf("invalid %s annotation %q, expected a context ID from %d", AnnotationVsock, v, minVsockCID)
+		}
+		d.VsockCID = uint32(cid)
+	}
+
+	if v, ok := annotations.Get(AnnotationRNG); ok {
+		switch enabled, err := strconv.ParseBool(v); {
+		case err == nil && enabled:
+			d.RNGSource = defaultRNGSource
+		case err == nil:
+		case filepath.IsAbs(v):
+			d.RNGSource = v
+		default:
+			return nil, fmt.Errorf("invalid %s annotation %q, expected a boolean or an absolute path", AnnotationRNG, v)
+		}
+	}
+
+	return d, nil
+}
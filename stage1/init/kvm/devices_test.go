@@ -0,0 +1,83 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvm
+
+import (
+	"testing"
+
+	"github.com/appc/spec/schema/types"
+)
+
+func TestGetDevices(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		expected    Devices
+		err         bool
+	}{
+		{
+			annotations: nil,
+			expected:    Devices{},
+		},
+		{
+			annotations: map[string]string{
+				AnnotationGPU:   "true",
+				AnnotationVsock: "42",
+				AnnotationRNG:   "true",
+			},
+			expected: Devices{GPU: true, VsockCID: 42, RNGSource: "/dev/urandom"},
+		},
+		{
+			annotations: map[string]string{AnnotationRNG: "/dev/hwrng"},
+			expected:    Devices{RNGSource: "/dev/hwrng"},
+		},
+		{
+			annotations: map[string]string{AnnotationRNG: "false"},
+			expected:    Devices{},
+		},
+		{
+			annotations: map[string]string{AnnotationRNG: "hwrng"},
+			err:         true,
+		},
+		{
+			annotations: map[string]string{AnnotationVsock: "2"},
+			err:         true,
+		},
+		{
+			annotations: map[string]string{AnnotationGPU: "yes please"},
+			err:         true,
+		},
+	}
+
+	for i, tt := range tests {
+		var annotations types.Annotations
+		for name, value := range tt.annotations {
+			annotations.Set(types.ACIdentifier(name), value)
+		}
+		d, err := GetDevices(annotations)
+		if tt.err {
+			if err == nil {
+				t.Errorf("#%d: expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+			continue
+		}
+		if *d != tt.expected {
+			t.Errorf("#%d: expected %+v, got %+v", i, tt.expected, *d)
+		}
+	}
+}
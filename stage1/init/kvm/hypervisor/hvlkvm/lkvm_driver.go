@@ -15,6 +15,7 @@
 package hvlkvm
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
 	"strconv"
@@ -55,6 +56,40 @@ func StartCmd(wdPath, uuid, kernelPath string, nds []kvm.NetDescriber, cpu, mem
 	return append(startCmd, kvmNetArgs(nds)...)
 }
 
+// HugetlbfsArgs returns the arguments backing the memory of the VM with
+// the hugetlbfs mounted at the given path.
+func HugetlbfsArgs(path string) []string {
+	return []string{"--hugetlbfs", path}
+}
+
+// DevicesArgs returns the arguments attaching the extra devices to the VM.
+// lkvm has no virtio-gpu device, and its rng device reads /dev/urandom.
+func DevicesArgs(d *kvm.Devices) ([]string, error) {
+	var args []string
+	if d.GPU {
+		return nil, errors.New("lkvm does not support virtio-gpu devices, use the qemu hypervisor")
+	}
+	if d.VsockCID != 0 {
+		args = append(args, "--vsock", strconv.FormatUint(uint64(d.VsockCID), 10))
+	}
+	if d.RNGSource != "" {
+		if d.RNGSource != "/dev/urandom" {
+			return nil, fmt.Errorf("lkvm cannot feed its rng device from %q", d.RNGSource)
+		}
+		args = append(args, "--rng")
+	}
+	return args, nil
+}
+
+// OptionsArgs returns the arguments tuning the VM. lkvm has neither machine
+// types nor CPU models, and always passes the host CPU through.
+func OptionsArgs(o *kvm.HypervisorOptions) ([]string, error) {
+	if o.MachineType != "" || o.CPUModel != "" || o.Nested {
+		return nil, errors.New("lkvm does not support machine types, CPU models or nested virtualization, use the qemu hypervisor")
+	}
+	return nil, nil
+}
+
 // kvmNetArgs returns additional arguments that need to be passed
 // to lkvm tool to configure networks properly. Logic is based on
 // network configuration extracted from Networking struct
@@ -62,6 +62,51 @@ func StartCmd(wdPath, name, kernelPath string, nds []kvm.NetDescriber, cpu, mem
 	return append(cmd, kvmNetArgs(nds)...)
 }
 
+// HugetlbfsArgs returns the arguments backing the memory of the VM with
+// the hugetlbfs mounted at the given path.
+func HugetlbfsArgs(path string) []string {
+	return []string{"-mem-path", path}
+}
+
+// DevicesArgs returns the arguments attaching the extra devices to the VM.
+func DevicesArgs(d *kvm.Devices) ([]string, error) {
+	var args []string
+	if d.GPU {
+		args = append(args, "-device", "virtio-gpu-pci")
+	}
+	if d.VsockCID != 0 {
+		args = append(args, "-device", fmt.Sprintf("vhost-vsock-pci,guest-cid=%d", d.VsockCID))
+	}
+	if d.RNGSource != "" {
+		args = append(args,
+			"-object", fmt.Sprintf("rng-random,id=rng0,filename=%s", d.RNGSource),
+			"-device", "virtio-rng-pci,rng=rng0",
+		)
+	}
+	return args, nil
+}
+
+// OptionsArgs returns the arguments setting the machine type and the CPU
+// model of the VM. Nested virtualization passes the host CPU through, with
+// its virtualization extensions.
+func OptionsArgs(o *kvm.HypervisorOptions) ([]string, error) {
+	var args []string
+	if o.MachineType != "" {
+		args = append(args, "-machine", o.MachineType)
+	}
+	cpuModel := o.CPUModel
+	if o.Nested {
+		if cpuModel != "" && cpuModel != "host" {
+			return nil, fmt.Errorf("nested virtualization requires the host CPU model, not %q", cpuModel)
+		}
+		cpuModel = "host"
+	}
+	if cpuModel != "" {
+		args = append(args, "-cpu", cpuModel)
+	}
+	return args, nil
+}
+
 // kvmNetArgs returns additional arguments that need to be passed
 // to qemu to configure networks properly. Logic is based on
 // network configuration extracted from Networking struct
@@ -46,6 +46,9 @@ type NetDescriber interface {
 	Name() string
 	Gateway() net.IP
 	Routes() []types.Route
+	ExtraRoutes() []networking.Route
+	SourceRouting() bool
+	GuestIfName() string
 }
 
 // GetKVMNetArgs returns additional arguments that need to be passed
@@ -94,17 +97,68 @@ func addRouteCommand(destination, router string) string {
 	return fmt.Sprintf("/bin/ip route add %s via %s", destination, router)
 }
 
+// addExtraRouteCommand returns the command adding a route to a routing
+// table, or to the main one if table is 0.
+func addExtraRouteCommand(route networking.Route, ifName string, table int) string {
+	cmd := fmt.Sprintf("/bin/ip route add %s", route.Dst.String())
+	if route.GW != nil {
+		cmd += fmt.Sprintf(" via %s", route.GW)
+	}
+	cmd += fmt.Sprintf(" dev %s", ifName)
+	if route.Metric != 0 {
+		cmd += fmt.Sprintf(" metric %d", route.Metric)
+	}
+	if table != 0 {
+		cmd += fmt.Sprintf(" table %d", table)
+	}
+	return cmd
+}
+
+func addSourceRuleCommand(ip net.IP, table, priority int) string {
+	return fmt.Sprintf("/bin/ip rule add from %s table %d priority %d", ip, table, priority)
+}
+
+// sourceRoutingCommands returns the commands setting up the source-based
+// routing of a network: the routes of its own routing table and the rule
+// looking it up for the packets from its address.
+func sourceRoutingCommands(nd NetDescriber, ifName string, i int) []string {
+	table := networking.SourceRoutingTableBase + i
+	mask := net.IPMask(nd.Mask())
+	routes := []networking.Route{
+		{Dst: net.IPNet{IP: nd.GuestIP().Mask(mask), Mask: mask}},
+	}
+	if gw := nd.Gateway(); gw != nil {
+		routes = append(routes, networking.Route{
+			Dst: net.IPNet{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)},
+			GW:  gw,
+		})
+	}
+	routes = append(routes, nd.ExtraRoutes()...)
+
+	var cmds []string
+	for _, route := range routes {
+		cmds = append(cmds, addExtraRouteCommand(route, ifName, table))
+	}
+	return append(cmds, addSourceRuleCommand(nd.GuestIP(), table, networking.SourceRoutingPriorityBase+i))
+}
+
 func downInterfaceCommand(ifName string) string {
 	return fmt.Sprintf("/bin/ip link set dev %s down", ifName)
 }
 
+func renameInterfaceCommand(ifName, newName string) string {
+	return fmt.Sprintf("/bin/ip link set dev %s name %s", ifName, newName)
+}
+
 func upInterfaceCommand(ifName string) string {
 	return fmt.Sprintf("/bin/ip link set dev %s up", ifName)
 }
 
 func GenerateNetworkInterfaceUnits(unitsPath string, netDescriptions []NetDescriber) error {
 	for i, netDescription := range netDescriptions {
-		ifName := fmt.Sprintf(networking.IfNamePattern, i)
+		// the guest kernel names the interfaces ethN in order
+		guestIfName := fmt.Sprintf(networking.IfNamePattern, i)
+		ifName := networking.PodIfName(netDescription.GuestIfName(), i)
 		netAddress := net.IPNet{
 			IP:   netDescription.GuestIP(),
 			Mask: net.IPMask(netDescription.Mask()),
@@ -122,12 +176,17 @@ func GenerateNetworkInterfaceUnits(unitsPath string, netDescriptions []NetDescri
 			unit.NewUnitOption("Unit", "DefaultDependencies", "false"),
 			unit.NewUnitOption("Service", "Type", "oneshot"),
 			unit.NewUnitOption("Service", "RemainAfterExit", "true"),
-			unit.NewUnitOption("Service", "ExecStartPre", downInterfaceCommand(ifName)),
+			unit.NewUnitOption("Service", "ExecStartPre", downInterfaceCommand(guestIfName)),
+		}
+		if ifName != guestIfName {
+			opts = append(opts, unit.NewUnitOption("Service", "ExecStartPre", renameInterfaceCommand(guestIfName, ifName)))
+		}
+		opts = append(opts,
 			unit.NewUnitOption("Service", "ExecStartPre", setMacCommand(ifName, mac.String())),
 			unit.NewUnitOption("Service", "ExecStartPre", upInterfaceCommand(ifName)),
 			unit.NewUnitOption("Service", "ExecStart", addAddressCommand(address, ifName)),
 			unit.NewUnitOption("Install", "RequiredBy", "default.target"),
-		}
+		)
 
 		for _, route := range netDescription.Routes() {
 			gw := route.GW
@@ -145,6 +204,23 @@ func GenerateNetworkInterfaceUnits(unitsPath string, netDescriptions []NetDescri
 			)
 		}
 
+		for _, route := range netDescription.ExtraRoutes() {
+			opts = append(
+				opts,
+				unit.NewUnitOption(
+					"Service",
+					"ExecStartPost",
+					addExtraRouteCommand(route, ifName, 0),
+				),
+			)
+		}
+
+		if netDescription.SourceRouting() {
+			for _, cmd := range sourceRoutingCommands(netDescription, ifName, i) {
+				opts = append(opts, unit.NewUnitOption("Service", "ExecStartPost", cmd))
+			}
+		}
+
 		unitName := fmt.Sprintf("interface-%s", ifName) + ".service"
 		unitBytes, err := ioutil.ReadAll(unit.Serialize(opts))
 		if err != nil {
@@ -15,10 +15,16 @@
 package kvm
 
 import (
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/containernetworking/cni/pkg/types"
+	"github.com/rkt/rkt/networking"
 )
 
 type testNetDescriber struct {
@@ -38,6 +44,18 @@ func (t testNetDescriber) IPMasq() bool          { return t.ipMasq }
 func (t testNetDescriber) Name() string          { return t.name }
 func (t testNetDescriber) Gateway() net.IP       { return net.IP{1, 1, 1, 1} }
 func (t testNetDescriber) Routes() []types.Route { return []types.Route{} }
+func (t testNetDescriber) ExtraRoutes() []networking.Route {
+	return []networking.Route{}
+}
+func (t testNetDescriber) SourceRouting() bool { return false }
+func (t testNetDescriber) GuestIfName() string { return "" }
+
+type testNamedNetDescriber struct {
+	testNetDescriber
+	guestIfName string
+}
+
+func (t testNamedNetDescriber) GuestIfName() string { return t.guestIfName }
 
 func TestGetKVMNetArgs(t *testing.T) {
 	tests := []struct {
@@ -112,3 +130,93 @@ func TestGetKVMNetArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestAddExtraRouteCommand(t *testing.T) {
+	tests := []struct {
+		route    string
+		expected string
+	}{
+		{
+			"10.0.0.0/8",
+			"/bin/ip route add 10.0.0.0/8 dev eth1",
+		},
+		{
+			"default via 192.168.1.1 metric 100",
+			"/bin/ip route add 0.0.0.0/0 via 192.168.1.1 dev eth1 metric 100",
+		},
+	}
+
+	for i, tt := range tests {
+		route, err := networking.ParseRoute(tt.route)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+		if cmd := addExtraRouteCommand(*route, "eth1", 0); cmd != tt.expected {
+			t.Errorf("#%d: expected %q, got %q", i, tt.expected, cmd)
+		}
+	}
+}
+
+func TestSourceRoutingCommands(t *testing.T) {
+	nd := testNetDescriber{
+		net.ParseIP("1.1.1.1"),
+		net.ParseIP("2.2.2.2"),
+		net.IP(net.CIDRMask(24, 32)),
+		"test-net",
+		"fooInt",
+		false,
+	}
+	expected := []string{
+		"/bin/ip route add 2.2.2.0/24 dev eth1 table 101",
+		"/bin/ip route add 0.0.0.0/0 via 1.1.1.1 dev eth1 table 101",
+		"/bin/ip rule add from 2.2.2.2 table 101 priority 101",
+	}
+
+	cmds := sourceRoutingCommands(nd, "eth1", 1)
+	if !reflect.DeepEqual(cmds, expected) {
+		t.Errorf("expected %q, got %q", expected, cmds)
+	}
+}
+
+func TestGenerateNetworkInterfaceUnitsIfName(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rkt-kvm-units-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	nd := testNetDescriber{
+		net.ParseIP("1.1.1.1"),
+		net.ParseIP("2.2.2.2"),
+		net.IP(net.CIDRMask(24, 32)),
+		"test-net",
+		"fooInt",
+		false,
+	}
+	nds := []NetDescriber{nd, testNamedNetDescriber{nd, "lan0"}}
+	if err := GenerateNetworkInterfaceUnits(dir, nds); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(dir, "interface-eth0.service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), " name ") {
+		t.Errorf("unexpected rename of eth0:\n%s", b)
+	}
+
+	b, err = ioutil.ReadFile(filepath.Join(dir, "interface-lan0.service"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cmd := range []string{
+		"ExecStartPre=/bin/ip link set dev eth1 down",
+		"ExecStartPre=/bin/ip link set dev eth1 name lan0",
+		"ExecStartPre=/bin/ip link set dev lan0 up",
+	} {
+		if !strings.Contains(string(b), cmd) {
+			t.Errorf("expected %q in the unit:\n%s", cmd, b)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvm
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/appc/spec/schema/types"
+)
+
+// Pod annotations tuning the virtual machine
+const (
+	// AnnotationMachineType sets the machine type, like "q35"
+	AnnotationMachineType = "coreos.com/rkt/stage1/kvm/machine-type"
+	// AnnotationCPUModel sets the CPU model, like "host" or "Skylake-Server"
+	AnnotationCPUModel = "coreos.com/rkt/stage1/kvm/cpu-model"
+	// AnnotationNested exposes the virtualization extensions of the host
+	// CPU to the VM when "true"
+	AnnotationNested = "coreos.com/rkt/stage1/kvm/nested"
+)
+
+// hypervisorOptionRegexp restricts the option values to names, flags and
+// properties, like "Haswell,-hle,+vmx" or "q35,accel=kvm"
+var hypervisorOptionRegexp = regexp.MustCompile(`^[a-zA-Z0-9_.+-]+(,[a-zA-Z0-9_.+-]+(=[a-zA-Z0-9_.+-]+)?)*$`)
+
+// HypervisorOptions describes how the hypervisor sets up the VM
+type HypervisorOptions struct {
+	MachineType string
+	CPUModel    string
+	Nested      bool
+}
+
+// GetHypervisorOptions returns the options set by the pod annotations
+func GetHypervisorOptions(annotations types.Annotations) (*HypervisorOptions, error) {
+	o := &HypervisorOptions{}
+
+	for _, opt := range []struct {
+		name  string
+		value *string
+	}{
+		{AnnotationMachineType, &o.MachineType},
+		{AnnotationCPUModel, &o.CPUModel},
+	} {
+		v, ok := annotations.Get(opt.name)
+		if !ok {
+			continue
+		}
+		if !hypervisorOptionRegexp.MatchString(v) {
+			return nil, fmt.Errorf("invalid %s annotation %q", opt.name, v)
+		}
+		*opt.value = v
+	}
+
+	if v, ok := annotations.Get(AnnotationNested); ok {
+		nested, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s annotation %q", AnnotationNested, v)
+		}
+		o.Nested = nested
+	}
+
+	return o, nil
+}
@@ -0,0 +1,75 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package kvm
+
+import (
+	"testing"
+
+	"github.com/appc/spec/schema/types"
+)
+
+func TestGetHypervisorOptions(t *testing.T) {
+	tests := []struct {
+		annotations map[string]string
+		expected    HypervisorOptions
+		err         bool
+	}{
+		{
+			annotations: nil,
+			expected:    HypervisorOptions{},
+		},
+		{
+			annotations: map[string]string{
+				AnnotationMachineType: "q35,accel=kvm",
+				AnnotationCPUModel:    "Haswell,-hle,+vmx",
+				AnnotationNested:      "true",
+			},
+			expected: HypervisorOptions{MachineType: "q35,accel=kvm", CPUModel: "Haswell,-hle,+vmx", Nested: true},
+		},
+		{
+			annotations: map[string]string{AnnotationMachineType: "pc -drive file=/etc/shadow"},
+			err:         true,
+		},
+		{
+			annotations: map[string]string{AnnotationCPUModel: "host,"},
+			err:         true,
+		},
+		{
+			annotations: map[string]string{AnnotationNested: "maybe"},
+			err:         true,
+		},
+	}
+
+	for i, tt := range tests {
+		var annotations types.Annotations
+		for name, value := range tt.annotations {
+			annotations.Set(types.ACIdentifier(name), value)
+		}
+		o, err := GetHypervisorOptions(annotations)
+		if tt.err {
+			if err == nil {
+				t.Errorf("#%d: expected error", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+			continue
+		}
+		if *o != tt.expected {
+			t.Errorf("#%d: expected %+v, got %+v", i, tt.expected, *o)
+		}
+	}
+}
@@ -16,9 +16,11 @@ package kvm
 
 import (
 	"runtime"
+	"sort"
 
 	"github.com/appc/spec/schema"
 	"github.com/appc/spec/schema/types"
+	stage1commontypes "github.com/rkt/rkt/stage1/common/types"
 )
 
 // The algorithm/reasoning:
@@ -84,3 +86,47 @@ func GetAppsResources(apps schema.AppList) (totalCpus, totalMem int64) {
 
 	return totalCpus, totalMem
 }
+
+// GetAppsCPUs returns the CPUs the VM is pinned to: the union of the
+// CPUs the apps are pinned to. It returns nil when one of the apps is not
+// pinned, since pinning the VM would restrict it too.
+func GetAppsCPUs(apps schema.AppList) ([]int, error) {
+	set := make(map[int]struct{})
+	for i := range apps {
+		cpuset, err := stage1commontypes.IsolatorCPUSet(apps[i].App.Isolators)
+		if err != nil {
+			return nil, err
+		}
+		if cpuset == nil || cpuset.CPUs == "" {
+			return nil, nil
+		}
+		cpus, err := stage1commontypes.ParseCPUList(cpuset.CPUs)
+		if err != nil {
+			return nil, err
+		}
+		for _, cpu := range cpus {
+			set[cpu] = struct{}{}
+		}
+	}
+	var cpus []int
+	for cpu := range set {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	return cpus, nil
+}
+
+// GetAppsHugepages returns the hugetlb pages backing the memory of the
+// VM: the first ones granted to the apps, or nil if none is.
+func GetAppsHugepages(apps schema.AppList) (*stage1commontypes.Hugepages, error) {
+	for i := range apps {
+		pages, err := stage1commontypes.IsolatorHugepages(apps[i].App.Isolators)
+		if err != nil {
+			return nil, err
+		}
+		if len(pages) > 0 {
+			return &pages[0], nil
+		}
+	}
+	return nil, nil
+}
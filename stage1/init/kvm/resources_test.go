@@ -15,8 +15,10 @@
 package kvm
 
 import (
+	"reflect"
 	"testing"
 
+	"github.com/appc/spec/schema"
 	"github.com/appc/spec/schema/types"
 )
 
@@ -120,3 +122,60 @@ func newIsolator(body string) (i types.Isolator) {
 	}
 	return
 }
+
+func TestGetAppsCPUs(t *testing.T) {
+	pinned := func(cpus string) schema.RuntimeApp {
+		return schema.RuntimeApp{App: &types.App{Isolators: types.Isolators{
+			newIsolator(`{"name": "coreos.com/rkt/resource/cpuset", "value": {"cpus": "` + cpus + `"}}`),
+		}}}
+	}
+
+	tests := []struct {
+		in   schema.AppList
+		wcpu []int
+	}{
+		{
+			schema.AppList{pinned("0-1"), pinned("1,4")},
+			[]int{0, 1, 4},
+		},
+		{
+			// an app which is not pinned leaves the VM unpinned
+			schema.AppList{pinned("0-1"), {App: &types.App{}}},
+			nil,
+		},
+	}
+
+	for i, tt := range tests {
+		cpus, err := GetAppsCPUs(tt.in)
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(cpus, tt.wcpu) {
+			t.Errorf("#%d: got cpus=%v, want %v", i, cpus, tt.wcpu)
+		}
+	}
+}
+
+func TestGetAppsHugepages(t *testing.T) {
+	app := func(isolators ...types.Isolator) schema.RuntimeApp {
+		return schema.RuntimeApp{App: &types.App{Isolators: isolators}}
+	}
+	hugepages := newIsolator(`{"name": "coreos.com/rkt/resource/hugepages", "value": {"pages": [{"pageSize": "1GB", "limit": "2Gi"}]}}`)
+
+	h, err := GetAppsHugepages(schema.AppList{app(), app(hugepages)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h == nil || h.PageSize != "1GB" || h.Limit != "2Gi" {
+		t.Errorf("got hugepages %v, want 1GB:2Gi", h)
+	}
+
+	h, err = GetAppsHugepages(schema.AppList{app()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if h != nil {
+		t.Errorf("got hugepages %v, want none", h)
+	}
+}
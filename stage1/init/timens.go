@@ -0,0 +1,64 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//+build linux
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/common"
+)
+
+// cloneNewTime is CLONE_NEWTIME, introduced in Linux 5.6
+const cloneNewTime = 0x80
+
+// unshareTimeNamespace creates a new time namespace with the given clock
+// offsets. As with PID namespaces, the calling process stays in its time
+// namespace and only its children created afterwards enter the new one,
+// so this must run right before executing the process starting the pod.
+func unshareTimeNamespace(offsets common.TimeOffsets) error {
+	if _, err := os.Stat("/proc/self/ns/time"); os.IsNotExist(err) {
+		return errors.New("time namespaces are not supported by the kernel")
+	}
+	if err := syscall.Unshare(cloneNewTime); err != nil {
+		return errwrap.Wrap(errors.New("error unsharing the time namespace"), err)
+	}
+	// The offsets can only be written before a process enters the
+	// namespace
+	content := timensOffset("monotonic", offsets.Monotonic) + timensOffset("boottime", offsets.Boottime)
+	if err := ioutil.WriteFile("/proc/self/timens_offsets", []byte(content), 0644); err != nil {
+		return errwrap.Wrap(errors.New("error writing the clock offsets"), err)
+	}
+	return nil
+}
+
+// timensOffset returns the line of /proc/PID/timens_offsets setting the
+// offset of a clock, in seconds and nanoseconds, the latter being
+// positive.
+func timensOffset(clock string, d time.Duration) string {
+	secs, nsecs := int64(d/time.Second), int64(d%time.Second)
+	if nsecs < 0 {
+		secs--
+		nsecs += int64(time.Second)
+	}
+	return fmt.Sprintf("%s %d %d\n", clock, secs, nsecs)
+}
@@ -17,7 +17,9 @@ _S1_SS_SUBDIRS_ := \
 	app_add \
 	app_rm \
 	app_start \
+	app_reload \
 	app_stop \
+	app_update \
 	units \
 	aci
 
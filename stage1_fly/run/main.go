@@ -22,6 +22,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -435,6 +436,16 @@ func stage1(rp *stage1commontypes.RuntimePod) int {
 		}
 	}
 
+	if err := restrictPaths(mounter, p, ra); err != nil {
+		log.PrintE("can't restrict paths", err)
+		return 254
+	}
+
+	if err := mountHugetlbfs(mounter, p, ra); err != nil {
+		log.PrintE("can't mount hugetlbfs", err)
+		return 254
+	}
+
 	// stage1 interface: pod-leader pid
 	if err = stage1common.WritePid(os.Getpid(), "pid"); err != nil {
 		log.Error(err)
@@ -482,6 +493,78 @@ func stage1(rp *stage1commontypes.RuntimePod) int {
 		return 254
 	}
 
+	// fly runs the app in the cgroups of rkt, only the oom score
+	// adjustment applies to it
+	if oomAdj := ra.App.Isolators.GetByName(types.LinuxOOMScoreAdjName); oomAdj != nil {
+		if v, ok := oomAdj.Value().(*types.LinuxOOMScoreAdj); ok {
+			if err := ioutil.WriteFile("/proc/self/oom_score_adj", []byte(strconv.Itoa(int(*v))), 0644); err != nil {
+				log.PrintE("can't set the oom score adjustment", err)
+				return 254
+			}
+		}
+	}
+	for _, name := range []types.ACIdentifier{stage1commontypes.MemorySwapIsolatorName, stage1commontypes.MemorySwappinessIsolatorName} {
+		if ra.App.Isolators.GetByName(name) != nil {
+			log.Printf("warning: isolator %q is not supported by the fly stage1, skipping", name)
+		}
+	}
+
+	cpuset, err := stage1commontypes.IsolatorCPUSet(ra.App.Isolators)
+	if err != nil {
+		log.PrintE("can't get the cpuset", err)
+		return 254
+	}
+	if cpuset != nil && cpuset.CPUs != "" {
+		cpus, err := stage1commontypes.ParseCPUList(cpuset.CPUs)
+		if err != nil {
+			log.PrintE("can't parse the cpuset", err)
+			return 254
+		}
+		diag.Printf("pinning to CPUs %v", cpus)
+		if err := sys.SchedSetaffinity(cpus); err != nil {
+			log.PrintE("can't set the CPU affinity", err)
+			return 254
+		}
+	}
+	if cpuset != nil && cpuset.Mems != "" {
+		log.Printf("warning: NUMA memory nodes are not supported by the fly stage1, skipping")
+	}
+
+	// The real-time priority allowance is the RLIMIT_RTPRIO limit, which
+	// --rlimit overrides. The app runs in the cgroups of rkt, so its
+	// real-time runtime isn't limited.
+	rt, err := stage1commontypes.IsolatorRealtime(ra.App.Isolators)
+	if err != nil {
+		log.PrintE("can't get the real-time scheduling parameters", err)
+		return 254
+	}
+	if rt != nil && rt.Priority != 0 {
+		limit := uint64(rt.Priority)
+		diag.Printf("setting real-time priority limit %d", limit)
+		if err := syscall.Setrlimit(stage1commontypes.RLimitResources["RLIMIT_RTPRIO"], &syscall.Rlimit{Cur: limit, Max: limit}); err != nil {
+			log.PrintE("can't set the real-time priority limit", err)
+			return 254
+		}
+	}
+	if rt != nil && rt.Runtime != 0 {
+		log.Printf("warning: the real-time runtime is not supported by the fly stage1, skipping")
+	}
+
+	// Set the resource limits before dropping the privileges, which
+	// raising the hard limits requires
+	rlimits, err := stage1commontypes.IsolatorRLimits(ra.App.Isolators)
+	if err != nil {
+		log.PrintE("can't get resource limits", err)
+		return 254
+	}
+	for _, l := range rlimits {
+		diag.Printf("setting resource limit %+v", l)
+		if err := syscall.Setrlimit(stage1commontypes.RLimitResources[l.Resource], &syscall.Rlimit{Cur: l.Soft, Max: l.Hard}); err != nil {
+			log.PrintE(fmt.Sprintf("can't set resource limit %s", l.Resource), err)
+			return 254
+		}
+	}
+
 	diag.Printf("setting credentials: %+v", credentials)
 	if err := stage1_fly.SetProcessCredentials(credentials); err != nil {
 		log.PrintE("can't set process credentials", err)
@@ -504,6 +587,109 @@ func stage1(rp *stage1commontypes.RuntimePod) int {
 	return 0
 }
 
+// restrictPaths masks the paths of the masked paths isolator and makes
+// read-only the paths of the read-only paths isolator. It runs once the
+// other mounts are in place, so that the paths under /proc or /sys can
+// be restricted too. Paths which don't exist in the app are skipped.
+func restrictPaths(mounter fs.Mounter, p *stage1commontypes.Pod, ra schema.RuntimeApp) error {
+	absStage2RootFS := common.AppRootfsPath(p.Root, ra.Name)
+	resolve := func(path string) (string, os.FileInfo, error) {
+		targetPath, err := stage1initcommon.EvaluateSymlinksInsideApp(absStage2RootFS, path)
+		if err != nil {
+			return "", nil, errwrap.Wrap(fmt.Errorf("evaluate path %q in %q", path, absStage2RootFS), err)
+		}
+		absPath := filepath.Join(absStage2RootFS, targetPath)
+		info, err := os.Stat(absPath)
+		if os.IsNotExist(err) {
+			diag.Printf("skipping restriction of missing path %q", path)
+			return "", nil, nil
+		}
+		if err != nil {
+			return "", nil, errwrap.Wrap(fmt.Errorf("stat of path %s", absPath), err)
+		}
+		return absPath, info, nil
+	}
+
+	masked, err := stage1commontypes.IsolatorPaths(ra.App.Isolators, stage1commontypes.MaskedPathsIsolatorName)
+	if err != nil {
+		return err
+	}
+	for _, path := range masked {
+		absPath, info, err := resolve(path)
+		if err != nil {
+			return err
+		}
+		switch {
+		case info == nil:
+			continue
+		case info.IsDir():
+			// An empty read-only tmpfs hides the directory
+			err = mounter.Mount("tmpfs", absPath, "tmpfs", syscall.MS_RDONLY|syscall.MS_NOSUID|syscall.MS_NODEV|syscall.MS_NOEXEC, "mode=000")
+		default:
+			err = mounter.Mount("/dev/null", absPath, "none", syscall.MS_BIND, "")
+		}
+		if err != nil {
+			return errwrap.Wrap(fmt.Errorf("can't mask %q", path), err)
+		}
+	}
+
+	readOnly, err := stage1commontypes.IsolatorPaths(ra.App.Isolators, stage1commontypes.ReadOnlyPathsIsolatorName)
+	if err != nil {
+		return err
+	}
+	for _, path := range readOnly {
+		absPath, info, err := resolve(path)
+		if err != nil {
+			return err
+		}
+		if info == nil {
+			continue
+		}
+		// Bind mount the path onto itself, so that it can be remounted
+		// read-only on its own
+		if err := mounter.Mount(absPath, absPath, "none", syscall.MS_BIND|syscall.MS_REC, ""); err != nil {
+			return errwrap.Wrap(fmt.Errorf("can't bind mount %q", path), err)
+		}
+		if err := mounter.Mount("", absPath, "none", syscall.MS_BIND|syscall.MS_REMOUNT|syscall.MS_RDONLY, ""); err != nil {
+			return errwrap.Wrap(fmt.Errorf("can't remount %q read-only", path), err)
+		}
+	}
+	return nil
+}
+
+// mountHugetlbfs mounts a hugetlbfs in /dev/hugepages-<size> of the app
+// for each size of the pages granted to it. There is no pod cgroup with
+// fly, so the size of the hugetlbfs is the only limit.
+func mountHugetlbfs(mounter fs.Mounter, p *stage1commontypes.Pod, ra schema.RuntimeApp) error {
+	pages, err := stage1commontypes.IsolatorHugepages(ra.App.Isolators)
+	if err != nil {
+		return err
+	}
+	for _, h := range pages {
+		label, err := h.Label()
+		if err != nil {
+			return err
+		}
+		pageSize, err := h.PageSizeBytes()
+		if err != nil {
+			return err
+		}
+		limit, err := h.LimitBytes()
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(common.AppRootfsPath(p.Root, ra.Name), "dev", "hugepages-"+label)
+		if err := os.MkdirAll(target, 0755); err != nil {
+			return errwrap.Wrap(fmt.Errorf("can't create %q", target), err)
+		}
+		opts := fmt.Sprintf("pagesize=%d,size=%d,mode=1777", pageSize, limit)
+		if err := mounter.Mount("hugetlbfs", target, "hugetlbfs", 0, opts); err != nil {
+			return errwrap.Wrap(fmt.Errorf("can't mount hugetlbfs on %q", target), err)
+		}
+	}
+	return nil
+}
+
 func copyResolv(p *stage1commontypes.Pod) error {
 	ra := p.Manifest.Apps[0]
 
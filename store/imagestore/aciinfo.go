@@ -26,6 +26,14 @@ type ACIFetchInfo struct {
 	// Latest defines if the ACI was imported using the latest pattern
 	// (no version label was provided on ACI discovery).
 	Latest bool
+	// Source is the distribution URI the ACI was fetched from.
+	Source string
+	// Digest is the digest or the ETag the remote announced for the
+	// ACI, if any.
+	Digest string
+	// Verification is the result of the verification of the ACI
+	// signature.
+	Verification string
 }
 
 // ACIInfo is used to store information about an ACI.
@@ -46,6 +54,18 @@ type ACIInfo struct {
 	// Latest defines if the ACI was imported using the latest pattern (no
 	// version label was provided on ACI discovery)
 	Latest bool
+	// Source is the distribution URI the ACI was fetched from, the
+	// time of the fetch being ImportTime.
+	Source string
+	// Digest is the digest or the ETag the remote announced for the
+	// ACI, if any.
+	Digest string
+	// Verification is the result of the verification of the ACI
+	// signature.
+	Verification string
+	// Pinned defines if the ACI is protected from removal, see
+	// Store.SetPinned.
+	Pinned bool
 }
 
 func NewACIInfo(blobKey string, latest bool, t time.Time, size int64, treeStoreSize int64) *ACIInfo {
@@ -61,7 +81,7 @@ func NewACIInfo(blobKey string, latest bool, t time.Time, size int64, treeStoreS
 
 func aciinfoRowScan(rows *sql.Rows, aciinfo *ACIInfo) error {
 	// This ordering MUST match that in schema.go
-	return rows.Scan(&aciinfo.BlobKey, &aciinfo.Name, &aciinfo.ImportTime, &aciinfo.LastUsed, &aciinfo.Latest, &aciinfo.Size, &aciinfo.TreeStoreSize)
+	return rows.Scan(&aciinfo.BlobKey, &aciinfo.Name, &aciinfo.ImportTime, &aciinfo.LastUsed, &aciinfo.Latest, &aciinfo.Size, &aciinfo.TreeStoreSize, &aciinfo.Source, &aciinfo.Digest, &aciinfo.Verification, &aciinfo.Pinned)
 }
 
 // GetAciInfosWithKeyPrefix returns all the ACIInfos with a blobkey starting with the given prefix.
@@ -172,7 +192,7 @@ func WriteACIInfo(tx *sql.Tx, aciinfo *ACIInfo) error {
 	if err != nil {
 		return err
 	}
-	_, err = tx.Exec("INSERT into aciinfo (blobkey, name, importtime, lastused, latest, size, treestoresize) VALUES ($1, $2, $3, $4, $5, $6, $7)", aciinfo.BlobKey, aciinfo.Name, aciinfo.ImportTime, aciinfo.LastUsed, aciinfo.Latest, aciinfo.Size, aciinfo.TreeStoreSize)
+	_, err = tx.Exec("INSERT into aciinfo (blobkey, name, importtime, lastused, latest, size, treestoresize, source, digest, verification, pinned) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)", aciinfo.BlobKey, aciinfo.Name, aciinfo.ImportTime, aciinfo.LastUsed, aciinfo.Latest, aciinfo.Size, aciinfo.TreeStoreSize, aciinfo.Source, aciinfo.Digest, aciinfo.Verification, aciinfo.Pinned)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,264 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagestore
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/errwrap"
+)
+
+// The encrypted blobs start with a header made of encryptedBlobMagic
+// and of a random nonce prefix, followed by the chunks of the blob, each
+// one sealed with AES-GCM. The nonce of a chunk is the nonce prefix
+// followed by the index of the chunk, and the last chunk is
+// authenticated as such, so that the chunks cannot be reordered or the
+// blob truncated.
+const (
+	encryptedBlobMagic = "rktenc1\n"
+	// EncryptionKeySize is the size in bytes of the keys used to
+	// encrypt the blobs.
+	EncryptionKeySize = 32
+
+	noncePrefixSize = 8
+	chunkSize       = 64 * 1024
+)
+
+var (
+	ErrEncryptedBlob = errors.New("the image is encrypted and no encryption key is configured")
+
+	lastChunk  = []byte{1}
+	otherChunk = []byte{0}
+)
+
+// blobCodec encrypts the blobs written to the store when it has an
+// AEAD, and decrypts the encrypted blobs read from the store. The
+// blobs written before the encryption was enabled are read as they are.
+type blobCodec struct {
+	aead cipher.AEAD
+}
+
+func newBlobCodec(key []byte) (*blobCodec, error) {
+	if len(key) != EncryptionKeySize {
+		return nil, fmt.Errorf("invalid encryption key size %d, expected %d", len(key), EncryptionKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error creating cipher"), err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errwrap.Wrap(errors.New("error creating cipher"), err)
+	}
+	return &blobCodec{aead: aead}, nil
+}
+
+func (c *blobCodec) encrypts() bool {
+	return c.aead != nil
+}
+
+// Writer implements diskv.Compression.
+func (c *blobCodec) Writer(dst io.Writer) (io.WriteCloser, error) {
+	if !c.encrypts() {
+		return nopWriteCloser{dst}, nil
+	}
+	w := &encryptingWriter{
+		aead: c.aead,
+		dst:  dst,
+		buf:  make([]byte, 0, chunkSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, w.noncePrefix[:]); err != nil {
+		return nil, errwrap.Wrap(errors.New("error generating nonce"), err)
+	}
+	if _, err := io.WriteString(dst, encryptedBlobMagic); err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(w.noncePrefix[:]); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Reader implements diskv.Compression.
+func (c *blobCodec) Reader(src io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReaderSize(src, chunkSize+c.overhead()+1)
+	magic, err := br.Peek(len(encryptedBlobMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, []byte(encryptedBlobMagic)) {
+		return nopReadCloser{br}, nil
+	}
+	if !c.encrypts() {
+		return nil, ErrEncryptedBlob
+	}
+	if _, err := br.Discard(len(encryptedBlobMagic)); err != nil {
+		return nil, err
+	}
+	r := &decryptingReader{
+		aead: c.aead,
+		src:  br,
+		buf:  make([]byte, chunkSize+c.aead.Overhead()),
+	}
+	if _, err := io.ReadFull(br, r.noncePrefix[:]); err != nil {
+		return nil, errwrap.Wrap(errors.New("error reading encrypted image header"), err)
+	}
+	return r, nil
+}
+
+func (c *blobCodec) overhead() int {
+	if !c.encrypts() {
+		return 0
+	}
+	return c.aead.Overhead()
+}
+
+func chunkNonce(prefix [noncePrefixSize]byte, index uint32) []byte {
+	nonce := make([]byte, noncePrefixSize+4)
+	copy(nonce, prefix[:])
+	binary.BigEndian.PutUint32(nonce[noncePrefixSize:], index)
+	return nonce
+}
+
+type encryptingWriter struct {
+	aead        cipher.AEAD
+	dst         io.Writer
+	noncePrefix [noncePrefixSize]byte
+	index       uint32
+	buf         []byte
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data comes, as
+		// the last chunk is sealed differently.
+		if len(w.buf) == chunkSize {
+			if err := w.seal(otherChunk); err != nil {
+				return 0, err
+			}
+		}
+		l := copy(w.buf[len(w.buf):chunkSize], p)
+		w.buf = w.buf[:len(w.buf)+l]
+		p = p[l:]
+	}
+	return n, nil
+}
+
+func (w *encryptingWriter) Close() error {
+	return w.seal(lastChunk)
+}
+
+func (w *encryptingWriter) seal(kind []byte) error {
+	sealed := w.aead.Seal(nil, chunkNonce(w.noncePrefix, w.index), w.buf, kind)
+	if _, err := w.dst.Write(sealed); err != nil {
+		return err
+	}
+	w.index++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+type decryptingReader struct {
+	aead        cipher.AEAD
+	src         *bufio.Reader
+	noncePrefix [noncePrefixSize]byte
+	index       uint32
+	buf         []byte
+	plain       []byte
+	done        bool
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *decryptingReader) open() error {
+	n, err := io.ReadFull(r.src, r.buf)
+	switch err {
+	case nil:
+		if _, err := r.src.Peek(1); err == io.EOF {
+			r.done = true
+		}
+	case io.ErrUnexpectedEOF:
+		r.done = true
+	case io.EOF:
+		return errors.New("truncated encrypted image")
+	default:
+		return err
+	}
+	kind := otherChunk
+	if r.done {
+		kind = lastChunk
+	}
+	plain, err := r.aead.Open(r.buf[:0], chunkNonce(r.noncePrefix, r.index), r.buf[:n], kind)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error decrypting image"), err)
+	}
+	r.index++
+	r.plain = plain
+	return nil
+}
+
+func (r *decryptingReader) Close() error {
+	return nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+type nopReadCloser struct {
+	io.Reader
+}
+
+func (nopReadCloser) Close() error {
+	return nil
+}
+
+// SetEncryptionKey makes the store encrypt the images it writes with
+// the given key, and decrypt the encrypted images it reads. The images
+// written before are still read unencrypted.
+func (s *Store) SetEncryptionKey(key []byte) error {
+	codec, err := newBlobCodec(key)
+	if err != nil {
+		return err
+	}
+	s.blobCodec = codec
+	s.stores[blobType].Compression = codec
+	return nil
+}
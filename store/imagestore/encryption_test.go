@@ -0,0 +1,176 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package imagestore
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rkt/rkt/pkg/aci"
+	"github.com/rkt/rkt/pkg/aci/acitest"
+)
+
+func TestBlobCodec(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, EncryptionKeySize)
+	codec, err := newBlobCodec(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, size := range []int{0, 1, chunkSize - 1, chunkSize, chunkSize + 1, 3 * chunkSize} {
+		plain := bytes.Repeat([]byte("rkt"), size/3+1)[:size]
+
+		encrypted := new(bytes.Buffer)
+		w, err := codec.Writer(encrypted)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", size, err)
+		}
+		if _, err := w.Write(plain); err != nil {
+			t.Fatalf("%d: unexpected error: %v", size, err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%d: unexpected error: %v", size, err)
+		}
+		if !strings.HasPrefix(encrypted.String(), encryptedBlobMagic) {
+			t.Errorf("%d: encrypted blob without header", size)
+		}
+
+		r, err := codec.Reader(bytes.NewReader(encrypted.Bytes()))
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", size, err)
+		}
+		decrypted, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", size, err)
+		}
+		if !bytes.Equal(plain, decrypted) {
+			t.Errorf("%d: decrypted blob differs", size)
+		}
+
+		// Truncating the blob by a whole chunk must be detected.
+		if size > chunkSize {
+			truncated := encrypted.Bytes()[:len(encryptedBlobMagic)+noncePrefixSize+chunkSize+codec.overhead()]
+			r, err := codec.Reader(bytes.NewReader(truncated))
+			if err != nil {
+				t.Fatalf("%d: unexpected error: %v", size, err)
+			}
+			if _, err := ioutil.ReadAll(r); err == nil {
+				t.Errorf("%d: expected error reading truncated blob", size)
+			}
+		}
+	}
+
+	// The unencrypted blobs are read as they are, whether a key is
+	// set or not.
+	for _, c := range []*blobCodec{codec, {}} {
+		r, err := c.Reader(strings.NewReader("plain"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data, err := ioutil.ReadAll(r)
+		if err != nil || string(data) != "plain" {
+			t.Errorf("unexpected data %q, error %v", data, err)
+		}
+	}
+
+	if _, err := newBlobCodec(key[:16]); err == nil {
+		t.Errorf("expected error with a short key")
+	}
+}
+
+func TestEncryptedStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", tstprefix)
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+	if err := s.SetEncryptionKey(bytes.Repeat([]byte{0x42}, EncryptionKeySize)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	imj, err := acitest.ImageManifestString(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a, err := aci.NewACI(dir, imj, nil)
+	if err != nil {
+		t.Fatalf("error creating test tar: %v", err)
+	}
+	defer a.Close()
+	if _, err := a.Seek(0, 0); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	plain, err := ioutil.ReadAll(a)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := a.Seek(0, 0); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	key, err := s.WriteACI(a, ACIFetchInfo{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var blobs []string
+	filepath.Walk(filepath.Join(dir, "blob"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && info.Mode().IsRegular() {
+			blobs = append(blobs, path)
+		}
+		return nil
+	})
+	if len(blobs) != 1 {
+		t.Fatalf("expected one blob, got %v", blobs)
+	}
+	onDisk, err := ioutil.ReadFile(blobs[0])
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(onDisk, []byte(encryptedBlobMagic)) {
+		t.Errorf("the blob is not encrypted on disk")
+	}
+
+	rs, err := s.ReadStream(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rs.Close()
+	data, err := ioutil.ReadAll(rs)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(data, plain) {
+		t.Errorf("the blob read differs from the image written")
+	}
+
+	// A store without the key cannot read the blob.
+	s2, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s2.Close()
+	if _, err := s2.ReadStream(key); err != ErrEncryptedBlob {
+		t.Errorf("expected ErrEncryptedBlob, got %v", err)
+	}
+}
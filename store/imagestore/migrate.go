@@ -36,6 +36,8 @@ var (
 		5: migrateToV5,
 		6: migrateToV6,
 		7: migrateToV7,
+		8: migrateToV8,
+		9: migrateToV9,
 	}
 
 	clock = clockwork.NewRealClock()
@@ -198,3 +200,43 @@ func migrateToV7(tx *sql.Tx) error {
 
 	return nil
 }
+
+func migrateToV8(tx *sql.Tx) error {
+	for _, t := range []string{
+		"CREATE TABLE aciinfo_tmp (blobkey string, name string, importtime time, lastused time, latest bool, size int64, treestoresize int64);",
+		"INSERT INTO aciinfo_tmp (blobkey, name, importtime, lastused, latest, size, treestoresize) SELECT blobkey, name, importtime, lastused, latest, size, treestoresize from aciinfo",
+		"DROP TABLE aciinfo",
+		"CREATE TABLE aciinfo (blobkey string, name string, importtime time, lastused time, latest bool, size int64 DEFAULT 0, treestoresize int64 DEFAULT 0, source string DEFAULT \"\", digest string DEFAULT \"\", verification string DEFAULT \"\");",
+		"CREATE UNIQUE INDEX IF NOT EXISTS blobkeyidx ON aciinfo (blobkey)",
+		"CREATE INDEX IF NOT EXISTS nameidx ON aciinfo (name)",
+		"INSERT INTO aciinfo (blobkey, name, importtime, lastused, latest, size, treestoresize) SELECT blobkey, name, importtime, lastused, latest, size, treestoresize from aciinfo_tmp",
+		"DROP TABLE aciinfo_tmp",
+	} {
+		_, err := tx.Exec(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func migrateToV9(tx *sql.Tx) error {
+	for _, t := range []string{
+		"CREATE TABLE aciinfo_tmp (blobkey string, name string, importtime time, lastused time, latest bool, size int64, treestoresize int64, source string, digest string, verification string);",
+		"INSERT INTO aciinfo_tmp (blobkey, name, importtime, lastused, latest, size, treestoresize, source, digest, verification) SELECT blobkey, name, importtime, lastused, latest, size, treestoresize, source, digest, verification from aciinfo",
+		"DROP TABLE aciinfo",
+		"CREATE TABLE aciinfo (blobkey string, name string, importtime time, lastused time, latest bool, size int64 DEFAULT 0, treestoresize int64 DEFAULT 0, source string DEFAULT \"\", digest string DEFAULT \"\", verification string DEFAULT \"\", pinned bool DEFAULT false);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS blobkeyidx ON aciinfo (blobkey)",
+		"CREATE INDEX IF NOT EXISTS nameidx ON aciinfo (name)",
+		"INSERT INTO aciinfo (blobkey, name, importtime, lastused, latest, size, treestoresize, source, digest, verification) SELECT blobkey, name, importtime, lastused, latest, size, treestoresize, source, digest, verification from aciinfo_tmp",
+		"DROP TABLE aciinfo_tmp",
+	} {
+		_, err := tx.Exec(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -401,6 +401,96 @@ func (d *DBV7) compare(td testdb) bool {
 	return true
 }
 
+type DBV8 struct {
+	aciinfos []*ACIInfoV8
+	remotes  []*RemoteV2_7
+}
+
+func (d *DBV8) version() int {
+	return 8
+}
+func (d *DBV8) populate(db *db.DB) error {
+	return populateDBV8(db, d.version(), d.aciinfos, d.remotes)
+}
+
+func (d *DBV8) load(db *db.DB) error {
+	fn := func(tx *sql.Tx) error {
+		var err error
+		d.aciinfos, err = getAllACIInfosV8(tx)
+		if err != nil {
+			return err
+		}
+		d.remotes, err = getAllRemoteV2_7(tx)
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+	if err := db.Do(fn); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DBV8) compare(td testdb) bool {
+	d8, ok := td.(*DBV8)
+	if !ok {
+		return false
+	}
+	if !compareSlicesNoOrder(d.aciinfos, d8.aciinfos) {
+		return false
+	}
+	if !compareSlicesNoOrder(d.remotes, d8.remotes) {
+		return false
+	}
+	return true
+}
+
+type DBV9 struct {
+	aciinfos []*ACIInfoV9
+	remotes  []*RemoteV2_7
+}
+
+func (d *DBV9) version() int {
+	return 9
+}
+func (d *DBV9) populate(db *db.DB) error {
+	return populateDBV9(db, d.version(), d.aciinfos, d.remotes)
+}
+
+func (d *DBV9) load(db *db.DB) error {
+	fn := func(tx *sql.Tx) error {
+		var err error
+		d.aciinfos, err = getAllACIInfosV9(tx)
+		if err != nil {
+			return err
+		}
+		d.remotes, err = getAllRemoteV2_7(tx)
+		if err != nil {
+			return err
+		}
+		return nil
+	}
+	if err := db.Do(fn); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *DBV9) compare(td testdb) bool {
+	d9, ok := td.(*DBV9)
+	if !ok {
+		return false
+	}
+	if !compareSlicesNoOrder(d.aciinfos, d9.aciinfos) {
+		return false
+	}
+	if !compareSlicesNoOrder(d.remotes, d9.remotes) {
+		return false
+	}
+	return true
+}
+
 // The ACIInfo struct for different db versions. The ending VX_Y represent the
 // first and the last version where the format isn't changed
 // The latest existing struct should be updated when updating the db version
@@ -460,6 +550,33 @@ type ACIInfoV7 struct {
 	TreeStoreSize int64
 }
 
+type ACIInfoV8 struct {
+	BlobKey       string
+	Name          string
+	ImportTime    time.Time
+	LastUsed      time.Time
+	Latest        bool
+	Size          int64
+	TreeStoreSize int64
+	Source        string
+	Digest        string
+	Verification  string
+}
+
+type ACIInfoV9 struct {
+	BlobKey       string
+	Name          string
+	ImportTime    time.Time
+	LastUsed      time.Time
+	Latest        bool
+	Size          int64
+	TreeStoreSize int64
+	Source        string
+	Digest        string
+	Verification  string
+	Pinned        bool
+}
+
 func getAllACIInfosV0_2(tx *sql.Tx) ([]*ACIInfoV0_2, error) {
 	var aciinfos []*ACIInfoV0_2
 	rows, err := tx.Query("SELECT * FROM aciinfo")
@@ -580,6 +697,46 @@ func getAllACIInfosV7(tx *sql.Tx) ([]*ACIInfoV7, error) {
 	return aciinfos, nil
 }
 
+func getAllACIInfosV8(tx *sql.Tx) ([]*ACIInfoV8, error) {
+	var aciinfos []*ACIInfoV8
+	rows, err := tx.Query("SELECT * FROM aciinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		aciinfo := &ACIInfoV8{}
+		if err := rows.Scan(&aciinfo.BlobKey, &aciinfo.Name, &aciinfo.ImportTime, &aciinfo.LastUsed, &aciinfo.Latest, &aciinfo.Size, &aciinfo.TreeStoreSize, &aciinfo.Source, &aciinfo.Digest, &aciinfo.Verification); err != nil {
+			return nil, err
+		}
+		aciinfos = append(aciinfos, aciinfo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return aciinfos, nil
+}
+
+func getAllACIInfosV9(tx *sql.Tx) ([]*ACIInfoV9, error) {
+	var aciinfos []*ACIInfoV9
+	rows, err := tx.Query("SELECT * FROM aciinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		aciinfo := &ACIInfoV9{}
+		if err := rows.Scan(&aciinfo.BlobKey, &aciinfo.Name, &aciinfo.ImportTime, &aciinfo.LastUsed, &aciinfo.Latest, &aciinfo.Size, &aciinfo.TreeStoreSize, &aciinfo.Source, &aciinfo.Digest, &aciinfo.Verification, &aciinfo.Pinned); err != nil {
+			return nil, err
+		}
+		aciinfos = append(aciinfos, aciinfo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return aciinfos, nil
+}
+
 type RemoteV0_1 struct {
 	ACIURL  string
 	SigURL  string
@@ -1037,6 +1194,120 @@ func populateDBV7(db *db.DB, dbVersion int, aciInfos []*ACIInfoV7, remotes []*Re
 	return nil
 }
 
+func populateDBV8(db *db.DB, dbVersion int, aciInfos []*ACIInfoV8, remotes []*RemoteV2_7) error {
+	var dbCreateStmts = [...]string{
+		// version table
+		"CREATE TABLE IF NOT EXISTS version (version int);",
+		fmt.Sprintf("INSERT INTO version VALUES (%d)", dbVersion),
+
+		// remote table. The primary key is "aciurl".
+		"CREATE TABLE IF NOT EXISTS remote (aciurl string, sigurl string, etag string, blobkey string, cachemaxage int, downloadtime time);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS aciurlidx ON remote (aciurl)",
+
+		// aciinfo table. The primary key is "blobkey" and it matches the key used to save that aci in the blob store
+		"CREATE TABLE IF NOT EXISTS aciinfo (blobkey string, name string, importtime time, lastused time, latest bool, size int64, treestoresize int64, source string, digest string, verification string);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS blobkeyidx ON aciinfo (blobkey)",
+		"CREATE INDEX IF NOT EXISTS nameidx ON aciinfo (name)",
+	}
+	fn := func(tx *sql.Tx) error {
+		for _, stmt := range dbCreateStmts {
+			_, err := tx.Exec(stmt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := db.Do(fn); err != nil {
+		return err
+	}
+
+	fn = func(tx *sql.Tx) error {
+		for _, aciinfo := range aciInfos {
+			_, err := tx.Exec("INSERT INTO aciinfo (blobkey, name, importtime, lastused, latest, size, treestoresize, source, digest, verification) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)", aciinfo.BlobKey, aciinfo.Name, aciinfo.ImportTime, aciinfo.LastUsed, aciinfo.Latest, aciinfo.Size, aciinfo.TreeStoreSize, aciinfo.Source, aciinfo.Digest, aciinfo.Verification)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := db.Do(fn); err != nil {
+		return err
+	}
+
+	fn = func(tx *sql.Tx) error {
+		for _, remote := range remotes {
+			_, err := tx.Exec("INSERT into remote values ($1, $2, $3, $4, $5, $6)", remote.ACIURL, remote.SigURL, remote.ETag, remote.BlobKey, remote.CacheMaxAge, remote.DownloadTime)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := db.Do(fn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func populateDBV9(db *db.DB, dbVersion int, aciInfos []*ACIInfoV9, remotes []*RemoteV2_7) error {
+	var dbCreateStmts = [...]string{
+		// version table
+		"CREATE TABLE IF NOT EXISTS version (version int);",
+		fmt.Sprintf("INSERT INTO version VALUES (%d)", dbVersion),
+
+		// remote table. The primary key is "aciurl".
+		"CREATE TABLE IF NOT EXISTS remote (aciurl string, sigurl string, etag string, blobkey string, cachemaxage int, downloadtime time);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS aciurlidx ON remote (aciurl)",
+
+		// aciinfo table. The primary key is "blobkey" and it matches the key used to save that aci in the blob store
+		"CREATE TABLE IF NOT EXISTS aciinfo (blobkey string, name string, importtime time, lastused time, latest bool, size int64, treestoresize int64, source string, digest string, verification string, pinned bool);",
+		"CREATE UNIQUE INDEX IF NOT EXISTS blobkeyidx ON aciinfo (blobkey)",
+		"CREATE INDEX IF NOT EXISTS nameidx ON aciinfo (name)",
+	}
+	fn := func(tx *sql.Tx) error {
+		for _, stmt := range dbCreateStmts {
+			_, err := tx.Exec(stmt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := db.Do(fn); err != nil {
+		return err
+	}
+
+	fn = func(tx *sql.Tx) error {
+		for _, aciinfo := range aciInfos {
+			_, err := tx.Exec("INSERT INTO aciinfo (blobkey, name, importtime, lastused, latest, size, treestoresize, source, digest, verification, pinned) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)", aciinfo.BlobKey, aciinfo.Name, aciinfo.ImportTime, aciinfo.LastUsed, aciinfo.Latest, aciinfo.Size, aciinfo.TreeStoreSize, aciinfo.Source, aciinfo.Digest, aciinfo.Verification, aciinfo.Pinned)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := db.Do(fn); err != nil {
+		return err
+	}
+
+	fn = func(tx *sql.Tx) error {
+		for _, remote := range remotes {
+			_, err := tx.Exec("INSERT into remote values ($1, $2, $3, $4, $5, $6)", remote.ACIURL, remote.SigURL, remote.ETag, remote.BlobKey, remote.CacheMaxAge, remote.DownloadTime)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := db.Do(fn); err != nil {
+		return err
+	}
+
+	return nil
+}
+
 type migrateTest struct {
 	predb  testdb
 	postdb testdb
@@ -1317,6 +1588,54 @@ func TestMigrate(t *testing.T) {
 			},
 			&DBV7{},
 		},
+		{
+			// Test migration from V7 to V8
+			&DBV7{
+				[]*ACIInfoV7{
+					{BlobKey: blobKeys[0], Name: names[0], ImportTime: now, LastUsed: now, Latest: false, Size: sizes[0], TreeStoreSize: treeStoreSizes[0]},
+					{BlobKey: blobKeys[1], Name: names[1], ImportTime: now, LastUsed: now, Latest: true, Size: sizes[1], TreeStoreSize: treeStoreSizes[1]},
+				},
+				[]*RemoteV2_7{
+					{"http://example.com/app01.aci", "http://example.com/app01.aci.asc", "", blobKeys[0], 0, time.Time{}.UTC()},
+					{"http://example.com/app02.aci", "http://example.com/app02.aci.asc", "", blobKeys[1], 0, time.Time{}.UTC()},
+				},
+			},
+			&DBV8{
+				[]*ACIInfoV8{
+					{BlobKey: blobKeys[0], Name: names[0], ImportTime: now, LastUsed: now, Latest: false, Size: sizes[0], TreeStoreSize: treeStoreSizes[0]},
+					{BlobKey: blobKeys[1], Name: names[1], ImportTime: now, LastUsed: now, Latest: true, Size: sizes[1], TreeStoreSize: treeStoreSizes[1]},
+				},
+				[]*RemoteV2_7{
+					{"http://example.com/app01.aci", "http://example.com/app01.aci.asc", "", blobKeys[0], 0, time.Time{}.UTC()},
+					{"http://example.com/app02.aci", "http://example.com/app02.aci.asc", "", blobKeys[1], 0, time.Time{}.UTC()},
+				},
+			},
+			&DBV8{},
+		},
+		{
+			// Test migration from V8 to V9
+			&DBV8{
+				[]*ACIInfoV8{
+					{BlobKey: blobKeys[0], Name: names[0], ImportTime: now, LastUsed: now, Latest: false, Size: sizes[0], TreeStoreSize: treeStoreSizes[0], Source: "docker://busybox", Digest: "sha256-0123", Verification: "none"},
+					{BlobKey: blobKeys[1], Name: names[1], ImportTime: now, LastUsed: now, Latest: true, Size: sizes[1], TreeStoreSize: treeStoreSizes[1]},
+				},
+				[]*RemoteV2_7{
+					{"http://example.com/app01.aci", "http://example.com/app01.aci.asc", "", blobKeys[0], 0, time.Time{}.UTC()},
+					{"http://example.com/app02.aci", "http://example.com/app02.aci.asc", "", blobKeys[1], 0, time.Time{}.UTC()},
+				},
+			},
+			&DBV9{
+				[]*ACIInfoV9{
+					{BlobKey: blobKeys[0], Name: names[0], ImportTime: now, LastUsed: now, Latest: false, Size: sizes[0], TreeStoreSize: treeStoreSizes[0], Source: "docker://busybox", Digest: "sha256-0123", Verification: "none", Pinned: false},
+					{BlobKey: blobKeys[1], Name: names[1], ImportTime: now, LastUsed: now, Latest: true, Size: sizes[1], TreeStoreSize: treeStoreSizes[1], Pinned: false},
+				},
+				[]*RemoteV2_7{
+					{"http://example.com/app01.aci", "http://example.com/app01.aci.asc", "", blobKeys[0], 0, time.Time{}.UTC()},
+					{"http://example.com/app02.aci", "http://example.com/app02.aci.asc", "", blobKeys[1], 0, time.Time{}.UTC()},
+				},
+			},
+			&DBV9{},
+		},
 	}
 
 	for i, tt := range tests {
@@ -30,8 +30,10 @@ import (
 	"time"
 
 	"github.com/rkt/rkt/pkg/backup"
+	"github.com/rkt/rkt/pkg/erase"
 	"github.com/rkt/rkt/pkg/lock"
 	"github.com/rkt/rkt/store/db"
+	"github.com/rkt/rkt/store/manifestcache"
 
 	"github.com/appc/spec/aci"
 	"github.com/appc/spec/schema"
@@ -68,6 +70,7 @@ var diskvStores = [...]string{
 var (
 	ErrKeyNotFound       = errors.New("no image IDs found")
 	ErrDBUpdateNeedsRoot = errors.New("database schema needs to be updated, re-run as root to perform the update")
+	ErrImagePinned       = errors.New("image is pinned, unpin it before removing it")
 )
 
 // ACINotFoundError is returned when an ACI cannot be found by GetACI
@@ -113,6 +116,14 @@ type Store struct {
 	// be taken on the whole store.
 	storeLock    *lock.FileLock
 	imageLockDir string
+	// manifestCache caches the image manifests so listing and inspecting
+	// many images doesn't require reading every manifest from disk.
+	manifestCache *manifestcache.Cache
+	// blobCodec encrypts and decrypts the blobs, see SetEncryptionKey.
+	blobCodec *blobCodec
+	// scrubOnRemove is whether the files of the removed images are
+	// overwritten first, see SetScrubOnRemove.
+	scrubOnRemove bool
 }
 
 func (s *Store) updateSize(key string, newSize int64) error {
@@ -199,12 +210,21 @@ func NewStore(dir string) (*Store, error) {
 			Transform: blockTransform,
 		})
 	}
+	// The blobs are only encrypted once a key is set, but the encrypted
+	// blobs must be detected anyway.
+	s.blobCodec = &blobCodec{}
+	s.stores[blobType].Compression = s.blobCodec
 	db, err := db.NewDB(s.dbDir())
 	if err != nil {
 		return nil, err
 	}
 	s.db = db
 
+	s.manifestCache, err = manifestcache.New(filepath.Join(dir, "manifestcache"))
+	if err != nil {
+		return nil, err
+	}
+
 	needsMigrate := false
 	needsSizePopulation := false
 	fn := func(tx *sql.Tx) error {
@@ -276,6 +296,9 @@ func NewStore(dir string) (*Store, error) {
 
 // Close closes a Store opened with NewStore().
 func (s *Store) Close() error {
+	if s.manifestCache != nil {
+		s.manifestCache.Close()
+	}
 	return s.storeLock.Close()
 }
 
@@ -468,7 +491,7 @@ func (s *Store) WriteACI(r io.ReadSeeker, fetchInfo ACIFetchInfo) (string, error
 	}
 	defer keyLock.Close()
 
-	if err = s.stores[blobType].Import(fh.Name(), key, true); err != nil {
+	if err = s.importBlob(fh.Name(), key); err != nil {
 		return "", errwrap.Wrap(errors.New("error importing image"), err)
 	}
 
@@ -481,15 +504,23 @@ func (s *Store) WriteACI(r io.ReadSeeker, fetchInfo ACIFetchInfo) (string, error
 		return "", errwrap.Wrap(errors.New("error importing image manifest"), err)
 	}
 
-	// Save aciinfo
+	// Save aciinfo, keeping the image pinned if it was already
 	if err = s.db.Do(func(tx *sql.Tx) error {
+		old, found, err := GetACIInfoWithBlobKey(tx, key)
+		if err != nil {
+			return err
+		}
 		aciinfo := &ACIInfo{
-			BlobKey:    key,
-			Name:       im.Name.String(),
-			ImportTime: time.Now(),
-			LastUsed:   time.Now(),
-			Latest:     fetchInfo.Latest,
-			Size:       sz,
+			BlobKey:      key,
+			Name:         im.Name.String(),
+			ImportTime:   time.Now(),
+			LastUsed:     time.Now(),
+			Latest:       fetchInfo.Latest,
+			Size:         sz,
+			Source:       fetchInfo.Source,
+			Digest:       fetchInfo.Digest,
+			Verification: fetchInfo.Verification,
+			Pinned:       found && old.Pinned,
 		}
 		return WriteACIInfo(tx, aciinfo)
 	}); err != nil {
@@ -499,10 +530,28 @@ func (s *Store) WriteACI(r io.ReadSeeker, fetchInfo ACIFetchInfo) (string, error
 	return key, nil
 }
 
+// importBlob moves the file to the blob store. The file is copied
+// through the encryption when it is enabled.
+func (s *Store) importBlob(path, key string) error {
+	if !s.blobCodec.encrypts() {
+		return s.stores[blobType].Import(path, key, true)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := s.stores[blobType].WriteStream(key, f, true); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
 // RemoveACI removes the ACI with the given key. It firstly removes the aci
 // infos inside the db, then it tries to remove the non transactional data.
 // If some error occurs removing some non transactional data a
-// StoreRemovalError is returned.
+// StoreRemovalError is returned. If the ACI is pinned, ErrImagePinned is
+// returned and nothing is removed.
 func (s *Store) RemoveACI(key string) error {
 	imageKeyLock, err := lock.ExclusiveKeyLock(s.imageLockDir, key)
 	if err != nil {
@@ -514,10 +563,12 @@ func (s *Store) RemoveACI(key string) error {
 	// remote needs to be removed or a GetRemote will return a blobKey not
 	// referenced by any ACIInfo.
 	err = s.db.Do(func(tx *sql.Tx) error {
-		if _, found, err := GetACIInfoWithBlobKey(tx, key); err != nil {
+		if aciinfo, found, err := GetACIInfoWithBlobKey(tx, key); err != nil {
 			return errwrap.Wrap(errors.New("error getting aciinfo"), err)
 		} else if !found {
 			return fmt.Errorf("cannot find image with key: %s", key)
+		} else if aciinfo.Pinned {
+			return ErrImagePinned
 		}
 
 		if err := RemoveACIInfo(tx, key); err != nil {
@@ -528,6 +579,9 @@ func (s *Store) RemoveACI(key string) error {
 		}
 		return nil
 	})
+	if err == ErrImagePinned {
+		return err
+	}
 	if err != nil {
 		return errwrap.Wrap(fmt.Errorf("cannot remove image with ID: %s from db", key), err)
 	}
@@ -538,7 +592,15 @@ func (s *Store) RemoveACI(key string) error {
 	// exists anymore, but errors removing non transactional entries can
 	// leave stale data that will require a cas GC to be implemented.
 	var storeErrors []error
+	if err := s.manifestCache.Invalidate(key); err != nil {
+		storeErrors = append(storeErrors, err)
+	}
 	for _, ds := range s.stores {
+		if s.scrubOnRemove {
+			if err := erase.Scrub(filepath.Join(ds.BasePath, filepath.Join(blockTransform(key)...), key)); err != nil {
+				storeErrors = append(storeErrors, err)
+			}
+		}
 		if err := ds.Erase(key); err != nil {
 			// If there's an error save it and continue with the other stores
 			storeErrors = append(storeErrors, err)
@@ -550,6 +612,42 @@ func (s *Store) RemoveACI(key string) error {
 	return nil
 }
 
+// SetPinned pins or unpins the ACI with the given key. A pinned ACI cannot
+// be removed until it is unpinned.
+func (s *Store) SetPinned(key string, pinned bool) error {
+	imageKeyLock, err := lock.ExclusiveKeyLock(s.imageLockDir, key)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error locking image"), err)
+	}
+	defer imageKeyLock.Close()
+
+	err = s.db.Do(func(tx *sql.Tx) error {
+		if _, found, err := GetACIInfoWithBlobKey(tx, key); err != nil {
+			return errwrap.Wrap(errors.New("error getting aciinfo"), err)
+		} else if !found {
+			return fmt.Errorf("cannot find image with key: %s", key)
+		}
+		_, err := tx.Exec("UPDATE aciinfo SET pinned = $1 WHERE blobkey == $2", pinned, key)
+		return err
+	})
+	if err != nil {
+		return errwrap.Wrap(fmt.Errorf("cannot update image with ID: %s in db", key), err)
+	}
+	return nil
+}
+
+// SetScrubOnRemove makes the store overwrite the files of the images and of
+// the rendered trees with random data before removing them.
+func (s *Store) SetScrubOnRemove(scrub bool) {
+	s.scrubOnRemove = scrub
+}
+
+// ScrubOnRemove returns whether the files of the removed images are
+// overwritten first.
+func (s *Store) ScrubOnRemove() bool {
+	return s.scrubOnRemove
+}
+
 // GetRemote tries to retrieve a remote with the given ACIURL.
 // If remote doesn't exist, it returns ErrRemoteNotFound error.
 func (s *Store) GetRemote(aciURL string) (*Remote, error) {
@@ -579,7 +677,15 @@ func (s *Store) WriteRemote(remote *Remote) error {
 
 // GetImageManifestJSON gets the ImageManifest JSON bytes with the
 // specified key.
+// When a full key is provided and its manifest is in the manifest cache, the
+// db isn't queried at all.
 func (s *Store) GetImageManifestJSON(key string) ([]byte, error) {
+	if len(key) == lenKey && s.HasFullKey(key) {
+		if imj, ok := s.manifestCache.Get(key); ok {
+			return imj, nil
+		}
+	}
+
 	key, err := s.ResolveKey(key)
 	if err != nil {
 		return nil, errwrap.Wrap(errors.New("error resolving image ID"), err)
@@ -594,9 +700,28 @@ func (s *Store) GetImageManifestJSON(key string) ([]byte, error) {
 	if err != nil {
 		return nil, errwrap.Wrap(errors.New("error retrieving image manifest"), err)
 	}
+	// The cache is only an optimization, failing to populate it isn't fatal
+	s.manifestCache.Put(key, imj)
 	return imj, nil
 }
 
+// GCManifestCache removes from the manifest cache all the manifests of images
+// not in the store anymore.
+func (s *Store) GCManifestCache() error {
+	aciInfos, err := s.GetAllACIInfos(nil, true)
+	if err != nil {
+		return errwrap.Wrap(errors.New("error retrieving ACI Infos"), err)
+	}
+	keys := make(map[string]struct{}, len(aciInfos))
+	for _, ai := range aciInfos {
+		keys[ai.BlobKey] = struct{}{}
+	}
+	return s.manifestCache.Retain(func(key string) bool {
+		_, ok := keys[key]
+		return ok
+	})
+}
+
 // GetImageManifest gets the ImageManifest with the specified key.
 func (s *Store) GetImageManifest(key string) (*schema.ImageManifest, error) {
 	imj, err := s.GetImageManifestJSON(key)
@@ -196,6 +196,48 @@ func TestGetImageManifest(t *testing.T) {
 	}
 }
 
+func TestWriteACIProvenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", tstprefix)
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	imj, err := acitest.ImageManifestString(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	aci, err := aci.NewACI(dir, imj, nil)
+	if err != nil {
+		t.Fatalf("error creating test tar: %v", err)
+	}
+	if _, err := aci.Seek(0, 0); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	fetchInfo := ACIFetchInfo{
+		Source:       "cimd:aci-archive:v=0:https%3A%2F%2Fexample.com%2Ftest01.aci",
+		Digest:       `"0123456789abcdef"`,
+		Verification: "signed by ABCDEF0123456789 (example.com)",
+	}
+	key, err := s.WriteACI(aci, fetchInfo)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := s.GetACIInfoWithBlobKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Source != fetchInfo.Source || info.Digest != fetchInfo.Digest || info.Verification != fetchInfo.Verification {
+		t.Errorf("unexpected provenance %q, %q, %q", info.Source, info.Digest, info.Verification)
+	}
+}
+
 func TestGetAci(t *testing.T) {
 	type test struct {
 		name     types.ACIdentifier
@@ -427,3 +469,65 @@ func TestRemoveACI(t *testing.T) {
 		t.Fatalf("expected StoreRemovalError got: %v", err)
 	}
 }
+
+func TestPinnedACI(t *testing.T) {
+	dir, err := ioutil.TempDir("", tstprefix)
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	imj, err := acitest.ImageManifestString(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	writeACI := func() string {
+		aciFile, err := aci.NewACI(dir, imj, nil)
+		if err != nil {
+			t.Fatalf("error creating test tar: %v", err)
+		}
+		defer aciFile.Close()
+		if _, err := aciFile.Seek(0, 0); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		key, err := s.WriteACI(aciFile, ACIFetchInfo{Latest: false})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return key
+	}
+
+	key := writeACI()
+	if err := s.SetPinned(key, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.RemoveACI(key); err != ErrImagePinned {
+		t.Fatalf("expected ErrImagePinned, got: %v", err)
+	}
+
+	// Fetching the image again keeps it pinned
+	writeACI()
+	info, err := s.GetACIInfoWithBlobKey(key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !info.Pinned {
+		t.Fatalf("expected image %q to be pinned", key)
+	}
+
+	if err := s.SetPinned(key, false); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.RemoveACI(key); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := s.SetPinned("sha512-aaaaaaaaaaaaaaaaa", true); err == nil {
+		t.Fatalf("expected error pinning a non-existent image")
+	}
+}
@@ -0,0 +1,406 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package manifestcache implements an on-disk cache of image manifests
+// shared across rkt invocations.
+//
+// The cache is made of two files: a pack file where the manifests are
+// appended one after the other and an index mapping an image digest (the
+// store blob key) to the position of its manifest inside the pack file. The
+// pack file is memory mapped, so looking up thousands of manifests only costs
+// a single open and a single read of the index.
+//
+// The index is always replaced atomically by renaming a temporary file over
+// it, so readers notice changes done by other processes by checking its
+// inode.
+package manifestcache
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/hashicorp/errwrap"
+	"github.com/rkt/rkt/pkg/lock"
+)
+
+const (
+	indexFilename = "index.json"
+	packFilename  = "manifests.pack"
+
+	indexVersion = 1
+
+	defaultPathPerm = os.FileMode(0770 | os.ModeSetgid)
+	defaultFilePerm = os.FileMode(0660)
+)
+
+// entry describes where a manifest is stored inside the pack file.
+type entry struct {
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+}
+
+type index struct {
+	Version int              `json:"version"`
+	Entries map[string]entry `json:"entries"`
+}
+
+// Cache is a manifest cache living in a directory. It's safe for concurrent
+// use by multiple goroutines and multiple processes.
+type Cache struct {
+	dir string
+
+	mu sync.Mutex
+	// ino is the inode of the index file loaded in idx, used to detect
+	// changes done by other processes.
+	ino  uint64
+	idx  index
+	pack []byte
+}
+
+// New opens the manifest cache in dir, creating the directory if needed.
+func New(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, defaultPathPerm); err != nil {
+		return nil, errwrap.Wrap(errors.New("cannot create manifest cache directory"), err)
+	}
+	return &Cache{
+		dir: dir,
+		idx: newIndex(),
+	}, nil
+}
+
+func newIndex() index {
+	return index{
+		Version: indexVersion,
+		Entries: make(map[string]entry),
+	}
+}
+
+// Close releases the memory mapping of the pack file.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.unmap()
+}
+
+// Get returns a copy of the manifest stored under key. The returned boolean
+// is false on a cache miss. Errors reading the cache are reported as misses,
+// since the caller can always fall back to the real store.
+func (c *Cache) Get(key string) ([]byte, bool) {
+	l, err := lock.SharedLock(c.dir, lock.Dir)
+	if err != nil {
+		return nil, false
+	}
+	defer l.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refresh(); err != nil {
+		return nil, false
+	}
+	e, ok := c.idx.Entries[key]
+	if !ok || e.Offset < 0 || e.Offset+e.Size > int64(len(c.pack)) {
+		return nil, false
+	}
+	b := make([]byte, e.Size)
+	copy(b, c.pack[e.Offset:e.Offset+e.Size])
+	return b, true
+}
+
+// Put adds the manifest imj under key. Adding an already cached key is a
+// no-op.
+func (c *Cache) Put(key string, imj []byte) error {
+	return c.update(func() (bool, error) {
+		if _, ok := c.idx.Entries[key]; ok {
+			return false, nil
+		}
+		f, err := os.OpenFile(c.packPath(), os.O_WRONLY|os.O_CREATE|os.O_APPEND, defaultFilePerm)
+		if err != nil {
+			return false, err
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return false, err
+		}
+		if _, err := f.Write(imj); err != nil {
+			return false, err
+		}
+		c.idx.Entries[key] = entry{Offset: fi.Size(), Size: int64(len(imj))}
+		return true, nil
+	})
+}
+
+// Invalidate removes the provided keys from the cache. The pack file is
+// compacted when most of it is made of removed manifests.
+func (c *Cache) Invalidate(keys ...string) error {
+	return c.update(func() (bool, error) {
+		changed := false
+		for _, k := range keys {
+			if _, ok := c.idx.Entries[k]; ok {
+				delete(c.idx.Entries, k)
+				changed = true
+			}
+		}
+		if changed && c.needsCompaction() {
+			return true, c.compact()
+		}
+		return changed, nil
+	})
+}
+
+// Retain removes from the cache all the keys not satisfying keep and compacts
+// the pack file. It's meant to be called when garbage collecting the store,
+// to also get rid of entries left behind by removals not going through the
+// cache.
+func (c *Cache) Retain(keep func(key string) bool) error {
+	return c.update(func() (bool, error) {
+		for k := range c.idx.Entries {
+			if !keep(k) {
+				delete(c.idx.Entries, k)
+			}
+		}
+		return true, c.compact()
+	})
+}
+
+// Len returns the number of cached manifests.
+func (c *Cache) Len() int {
+	l, err := lock.SharedLock(c.dir, lock.Dir)
+	if err != nil {
+		return 0
+	}
+	defer l.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.refresh(); err != nil {
+		return 0
+	}
+	return len(c.idx.Entries)
+}
+
+// update runs fn with an exclusive lock on the cache and an up to date index.
+// If fn reports a change, the index is written back to disk.
+func (c *Cache) update(fn func() (bool, error)) error {
+	// We need to allow the store's setgid bits (if any) to propagate, so
+	// disable umask
+	um := syscall.Umask(0)
+	defer syscall.Umask(um)
+
+	l, err := lock.ExclusiveLock(c.dir, lock.Dir)
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot lock manifest cache"), err)
+	}
+	defer l.Close()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.refresh(); err != nil {
+		// A corrupted cache is just thrown away
+		if err := c.reset(); err != nil {
+			return err
+		}
+	}
+	changed, err := fn()
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot update manifest cache"), err)
+	}
+	if !changed {
+		return nil
+	}
+	if err := c.writeIndex(); err != nil {
+		return errwrap.Wrap(errors.New("cannot write manifest cache index"), err)
+	}
+	// Force a reload of the index and a remap of the pack file
+	c.ino = 0
+	return c.refresh()
+}
+
+// refresh reloads the index and remaps the pack file if the index file
+// changed since the last time it was loaded. The caller must hold c.mu and a
+// lock on the cache directory.
+func (c *Cache) refresh() error {
+	fi, err := os.Stat(c.indexPath())
+	if os.IsNotExist(err) {
+		c.ino = 0
+		c.idx = newIndex()
+		return c.unmap()
+	}
+	if err != nil {
+		return err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("cannot get inode of %q", c.indexPath())
+	}
+	if c.ino != 0 && c.ino == st.Ino {
+		return nil
+	}
+
+	b, err := ioutil.ReadFile(c.indexPath())
+	if err != nil {
+		return err
+	}
+	idx := newIndex()
+	if err := json.Unmarshal(b, &idx); err != nil {
+		return errwrap.Wrap(errors.New("cannot decode manifest cache index"), err)
+	}
+	if idx.Version != indexVersion {
+		return fmt.Errorf("unsupported manifest cache index version %d", idx.Version)
+	}
+	if idx.Entries == nil {
+		idx.Entries = make(map[string]entry)
+	}
+	if err := c.remap(); err != nil {
+		return err
+	}
+	c.idx = idx
+	c.ino = st.Ino
+	return nil
+}
+
+func (c *Cache) remap() error {
+	if err := c.unmap(); err != nil {
+		return err
+	}
+	f, err := os.Open(c.packPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() == 0 {
+		return nil
+	}
+	pack, err := syscall.Mmap(int(f.Fd()), 0, int(fi.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return errwrap.Wrap(errors.New("cannot map manifest cache"), err)
+	}
+	c.pack = pack
+	return nil
+}
+
+func (c *Cache) unmap() error {
+	if c.pack == nil {
+		return nil
+	}
+	pack := c.pack
+	c.pack = nil
+	return syscall.Munmap(pack)
+}
+
+// reset drops the whole cache content.
+func (c *Cache) reset() error {
+	if err := c.unmap(); err != nil {
+		return err
+	}
+	for _, p := range []string{c.indexPath(), c.packPath()} {
+		if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	c.ino = 0
+	c.idx = newIndex()
+	return nil
+}
+
+// needsCompaction returns whether more than half of the pack file is taken
+// by manifests not referenced by the index anymore.
+func (c *Cache) needsCompaction() bool {
+	var live int64
+	for _, e := range c.idx.Entries {
+		live += e.Size
+	}
+	return live*2 < int64(len(c.pack))
+}
+
+// compact rewrites the pack file keeping only the manifests referenced by the
+// index.
+func (c *Cache) compact() error {
+	tmp, err := ioutil.TempFile(c.dir, packFilename+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := tmp.Chmod(defaultFilePerm); err != nil {
+		return err
+	}
+
+	entries := make(map[string]entry, len(c.idx.Entries))
+	var off int64
+	for k, e := range c.idx.Entries {
+		if e.Offset < 0 || e.Offset+e.Size > int64(len(c.pack)) {
+			continue
+		}
+		if _, err := tmp.Write(c.pack[e.Offset : e.Offset+e.Size]); err != nil {
+			return err
+		}
+		entries[k] = entry{Offset: off, Size: e.Size}
+		off += e.Size
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), c.packPath()); err != nil {
+		return err
+	}
+	c.idx.Entries = entries
+	return nil
+}
+
+func (c *Cache) writeIndex() error {
+	b, err := json.Marshal(c.idx)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(c.dir, indexFilename+".")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := tmp.Chmod(defaultFilePerm); err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.indexPath())
+}
+
+func (c *Cache) indexPath() string {
+	return filepath.Join(c.dir, indexFilename)
+}
+
+func (c *Cache) packPath() string {
+	return filepath.Join(c.dir, packFilename)
+}
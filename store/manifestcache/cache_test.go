@@ -0,0 +1,116 @@
+// Copyright 2017 The rkt Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package manifestcache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifestcache-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	c, err := New(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	if _, ok := c.Get("sha512-aa"); ok {
+		t.Fatalf("unexpected hit on an empty cache")
+	}
+
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("sha512-%02d", i)
+		if err := c.Put(key, []byte(fmt.Sprintf(`{"name":"image%d"}`, i))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if c.Len() != 10 {
+		t.Fatalf("expected 10 entries, got %d", c.Len())
+	}
+
+	// A second cache on the same dir simulates another rkt invocation
+	c2, err := New(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c2.Close()
+
+	imj, ok := c2.Get("sha512-03")
+	if !ok {
+		t.Fatalf("expected a cache hit")
+	}
+	if string(imj) != `{"name":"image3"}` {
+		t.Fatalf("unexpected manifest %q", imj)
+	}
+
+	if err := c2.Invalidate("sha512-03"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := c.Get("sha512-03"); ok {
+		t.Fatalf("expected invalidated key to be a miss")
+	}
+
+	// Removing most keys compacts the pack file
+	if err := c.Retain(func(key string) bool { return key == "sha512-07" }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	imj, ok = c2.Get("sha512-07")
+	if !ok || string(imj) != `{"name":"image7"}` {
+		t.Fatalf("unexpected manifest %q after compaction", imj)
+	}
+	fi, err := os.Stat(filepath.Join(dir, packFilename))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fi.Size() != int64(len(imj)) {
+		t.Fatalf("expected pack file of size %d, got %d", len(imj), fi.Size())
+	}
+}
+
+func TestCacheCorruptedIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifestcache-test")
+	if err != nil {
+		t.Fatalf("error creating tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, indexFilename), []byte("garbage"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := New(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer c.Close()
+
+	if _, ok := c.Get("sha512-aa"); ok {
+		t.Fatalf("unexpected hit on a corrupted cache")
+	}
+	if err := c.Put("sha512-aa", []byte("{}")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := c.Get("sha512-aa"); !ok {
+		t.Fatalf("expected a cache hit")
+	}
+}
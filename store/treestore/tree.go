@@ -35,6 +35,7 @@ import (
 	"github.com/appc/spec/schema/types"
 	"github.com/hashicorp/errwrap"
 	"github.com/rkt/rkt/pkg/aci"
+	"github.com/rkt/rkt/pkg/erase"
 	"github.com/rkt/rkt/pkg/fileutil"
 	"github.com/rkt/rkt/pkg/lock"
 	"github.com/rkt/rkt/pkg/sys"
@@ -304,6 +305,11 @@ func (ts *Store) remove(id string) error {
 	// Ignore error retrieving image hash
 	key, _ := ts.GetImageHash(id)
 
+	if ts.store != nil && ts.store.ScrubOnRemove() {
+		if err := erase.Scrub(treepath); err != nil {
+			return err
+		}
+	}
 	if err := os.RemoveAll(treepath); err != nil {
 		return err
 	}